@@ -4,7 +4,11 @@ import (
 	"flag"
 	"github.com/igwen6w/syt-go-queue/internal/config"
 	"github.com/igwen6w/syt-go-queue/internal/logger"
+	"github.com/igwen6w/syt-go-queue/internal/metrics"
+	"github.com/igwen6w/syt-go-queue/internal/reload"
 	"github.com/igwen6w/syt-go-queue/internal/server"
+	"github.com/igwen6w/syt-go-queue/internal/utils"
+	"github.com/igwen6w/syt-go-queue/internal/version"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"os"
@@ -33,16 +37,41 @@ func main() {
 	}
 
 	// 初始化日志
-	logger.Init(cfg.Logger.Level, cfg.Logger.Development)
+	logger.Init(cfg.Logger)
 	defer logger.Sync()
 
+	// 按配置的命名空间和子系统初始化指标名称
+	if err := metrics.Init(cfg.Metrics.Namespace, cfg.Metrics.Subsystem); err != nil {
+		logger.Fatal("Failed to initialize metrics", zap.Error(err))
+	}
+
+	// 设置回调主机白名单和额外禁止的IP范围
+	utils.SetCallbackAllowedHosts(cfg.Callback.AllowedHosts)
+	if err := utils.SetCallbackBlockedCIDRs(cfg.Callback.BlockedCIDRs); err != nil {
+		logger.Fatal("Invalid callback blocked CIDRs", zap.Error(err))
+	}
+
 	logger.Info("API server starting",
 		zap.String("app", cfg.App.Name),
 		zap.String("mode", cfg.App.Mode),
-		zap.String("config_file", *configFile))
+		zap.String("version", version.Version),
+		zap.String("commit", version.Commit),
+		zap.String("build_time", version.BuildTime),
+		zap.String("config_file", *configFile),
+		zap.String("dsn", utils.MaskDSN(cfg.MySQL.DSN)))
 
 	// 创建服务器
-	srv := server.NewServer(&cfg)
+	srv, err := server.NewServer(&cfg)
+	if err != nil {
+		logger.Fatal("Failed to create API server", zap.Error(err))
+	}
+
+	// 监听配置变化，热加载日志级别
+	reload.Watch(&cfg, func(newCfg *config.Config) {
+		if err := logger.SetLevel(newCfg.Logger.Level); err != nil {
+			logger.Error("Failed to apply log level", zap.Error(err))
+		}
+	})
 
 	// 优雅关闭
 	go func() {
@@ -51,6 +80,11 @@ func main() {
 		<-sigCh
 		logger.Info("Shutting down API server...")
 		srv.Stop()
+
+		// 退出前推送指标，未配置 Pushgateway 时不做处理
+		if err := metrics.Push(cfg.Metrics.Pushgateway, cfg.Metrics.PushJob(cfg.App.Name), "api"); err != nil {
+			logger.Error("Failed to push metrics", zap.Error(err))
+		}
 	}()
 
 	// 启动服务器
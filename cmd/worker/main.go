@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 	"os/signal"
@@ -9,8 +10,11 @@ import (
 	"github.com/igwen6w/syt-go-queue/internal/config"
 	"github.com/igwen6w/syt-go-queue/internal/database"
 	"github.com/igwen6w/syt-go-queue/internal/logger"
+	"github.com/igwen6w/syt-go-queue/internal/metrics"
+	"github.com/igwen6w/syt-go-queue/internal/reload"
+	"github.com/igwen6w/syt-go-queue/internal/utils"
+	"github.com/igwen6w/syt-go-queue/internal/version"
 	"github.com/igwen6w/syt-go-queue/internal/worker"
-	"github.com/jmoiron/sqlx"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
@@ -36,37 +40,93 @@ func main() {
 	}
 
 	// 初始化日志
-	logger.Init(cfg.Logger.Level, cfg.Logger.Development)
+	logger.Init(cfg.Logger)
 	defer logger.Sync()
 
+	// 按配置的命名空间和子系统初始化指标名称
+	if err := metrics.Init(cfg.Metrics.Namespace, cfg.Metrics.Subsystem); err != nil {
+		logger.Fatal("Failed to initialize metrics", zap.Error(err))
+	}
+
+	// 配置 LLM API 调用时间直方图的桶边界
+	if len(cfg.Metrics.LLMLatencyBuckets) > 0 {
+		if err := metrics.SetLLMAPIBuckets(cfg.Metrics.LLMLatencyBuckets); err != nil {
+			logger.Fatal("Failed to configure LLM latency buckets", zap.Error(err))
+		}
+	}
+
+	// 设置回调主机白名单和额外禁止的IP范围
+	utils.SetCallbackAllowedHosts(cfg.Callback.AllowedHosts)
+	if err := utils.SetCallbackBlockedCIDRs(cfg.Callback.BlockedCIDRs); err != nil {
+		logger.Fatal("Invalid callback blocked CIDRs", zap.Error(err))
+	}
+
 	logger.Info("Worker starting",
 		zap.String("app", cfg.App.Name),
 		zap.String("mode", cfg.App.Mode),
+		zap.String("version", version.Version),
+		zap.String("commit", version.Commit),
+		zap.String("build_time", version.BuildTime),
 		zap.String("config_file", *configFile))
 
 	// 旧的工作者配置验证已被替换为全局配置验证
 
 	// 初始化数据库连接
-	logger.Info("Connecting to database", zap.String("dsn", maskDSN(cfg.MySQL.DSN)))
-	db := sqlx.MustConnect("mysql", cfg.MySQL.DSN)
-	db.SetMaxIdleConns(cfg.MySQL.MaxIdleConns)
-	db.SetMaxOpenConns(cfg.MySQL.MaxOpenConns)
-	newDatabase := database.NewDatabase(db)
-	logger.Info("Database connected successfully")
+	logger.Info("Connecting to database", zap.String("dsn", utils.MaskDSN(cfg.MySQL.DSN)))
+	db, err := database.Connect(cfg.MySQL, cfg.MySQL.ConnectRetries, cfg.MySQL.ConnectBackoff)
+	if err != nil {
+		logger.Fatal("Failed to connect to database", zap.Error(err))
+	}
+	replica, err := database.ConnectReplica(cfg.MySQL, cfg.MySQL.ConnectRetries, cfg.MySQL.ConnectBackoff)
+	if err != nil {
+		logger.Fatal("Failed to connect to database replica", zap.Error(err))
+	}
+	newDatabase, err := database.NewDatabaseWithReplica(db, replica).
+		WithQueryTimeout(cfg.MySQL.QueryTimeout).
+		WithColumns(cfg.MySQL.Columns)
+	if err != nil {
+		logger.Fatal("Invalid mysql columns", zap.Error(err))
+	}
+	logger.Info("Database connected successfully", zap.Bool("replica", replica != nil))
 
 	// 创建worker
 	logger.Info("Creating worker",
 		zap.Int("concurrency", cfg.Queue.Concurrency),
-		zap.String("redis", cfg.Redis.Addr))
-	w := worker.NewWorker(&cfg, newDatabase)
+		zap.String("redis_mode", cfg.Redis.Mode()))
+	w, err := worker.NewWorker(&cfg, newDatabase)
+	if err != nil {
+		logger.Fatal("Failed to create worker", zap.Error(err))
+	}
+
+	// 监听配置变化，热加载日志级别和断路器参数
+	reload.Watch(&cfg, func(newCfg *config.Config) {
+		if err := logger.SetLevel(newCfg.Logger.Level); err != nil {
+			logger.Error("Failed to apply log level", zap.Error(err))
+		}
+		w.ApplyConfig(newCfg)
+	})
 
-	// 优雅关闭
+	// 优雅关闭，等待进行中的任务在排空时间内完成
+	drained := make(chan struct{})
 	go func() {
+		defer close(drained)
 		sigCh := make(chan os.Signal, 1)
 		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 		<-sigCh
-		logger.Info("Shutting down worker...")
-		w.Stop()
+
+		drainTimeout := worker.DrainTimeout(&cfg)
+		fields := []zap.Field{zap.Duration("drain_timeout", drainTimeout)}
+		if stats, err := w.Stats(); err != nil {
+			logger.Warn("Failed to get worker stats", zap.Error(err))
+		} else {
+			fields = append(fields, stats.LogFields()...)
+		}
+		logger.Info("Shutting down worker...", fields...)
+		ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+		defer cancel()
+		if err := w.Shutdown(ctx); err != nil {
+			logger.Warn("Worker did not drain in time", zap.Error(err))
+		}
 	}()
 
 	// 启动worker
@@ -74,11 +134,11 @@ func main() {
 	if err := w.Run(); err != nil {
 		logger.Fatal("Worker failed to start", zap.Error(err))
 	}
-}
+	<-drained
 
-// maskDSN 隐藏 DSN 中的敏感信息
-func maskDSN(dsn string) string {
-	// 简单实现，只返回一个提示信息
-	// 实际应用中可以更复杂，例如保留主机名但隐藏密码
-	return "[MASKED]"
+	// 退出前推送指标，未配置 Pushgateway 时不做处理
+	if err := metrics.Push(cfg.Metrics.Pushgateway, cfg.Metrics.PushJob(cfg.App.Name), "worker"); err != nil {
+		logger.Error("Failed to push metrics", zap.Error(err))
+	}
+	logger.Info("Worker stopped")
 }
@@ -4,12 +4,19 @@ import (
 	"github.com/igwen6w/syt-go-queue/internal/logger"
 	"github.com/sony/gobreaker"
 	"go.uber.org/zap"
+	"math"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // CircuitBreaker 封装了断路器功能
 type CircuitBreaker struct {
-	cb *gobreaker.CircuitBreaker
+	mu            sync.RWMutex // 保护 cb 和 config，Reconfigure 时替换底层断路器
+	cb            *gobreaker.CircuitBreaker
+	config        CircuitBreakerConfig
+	failThreshold atomic.Uint64 // 错误率阈值，以 float64 位模式存储以支持运行时调整
+	forcedUntil   atomic.Int64  // 强制打开的截止时间（UnixNano），已过期时不生效
 }
 
 // CircuitBreakerConfig 断路器配置
@@ -23,6 +30,15 @@ type CircuitBreakerConfig struct {
 
 // NewCircuitBreaker 创建一个新的断路器
 func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
+	c := &CircuitBreaker{}
+	c.SetFailThreshold(config.FailThreshold)
+	c.cb = c.newBreaker(config)
+	c.config = config
+	return c
+}
+
+// newBreaker 按配置创建底层断路器，错误率阈值从 c 中实时读取
+func (c *CircuitBreaker) newBreaker(config CircuitBreakerConfig) *gobreaker.CircuitBreaker {
 	settings := gobreaker.Settings{
 		Name:        config.Name,
 		MaxRequests: config.MaxRequests,
@@ -30,7 +46,7 @@ func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
 		Timeout:     config.Timeout,
 		ReadyToTrip: func(counts gobreaker.Counts) bool {
 			failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
-			return counts.Requests >= 5 && failureRatio >= config.FailThreshold
+			return counts.Requests >= 5 && failureRatio >= c.FailThreshold()
 		},
 		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
 			logger.Info("Circuit breaker state changed",
@@ -40,24 +56,89 @@ func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
 		},
 	}
 
-	return &CircuitBreaker{
-		cb: gobreaker.NewCircuitBreaker(settings),
+	return gobreaker.NewCircuitBreaker(settings)
+}
+
+// Reconfigure 在运行时使用新配置重建底层断路器，用于配置热加载。
+// 仅错误率阈值变化时直接调整阈值，保留当前状态和统计；其他参数变化时
+// 重建的断路器从关闭状态重新开始统计。进行中的 Execute 调用在原断路器上完成，
+// 强制打开的截止时间不受影响
+func (c *CircuitBreaker) Reconfigure(config CircuitBreakerConfig) {
+	c.SetFailThreshold(config.FailThreshold)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prev := c.config
+	c.config = config
+	if prev.Name == config.Name && prev.MaxRequests == config.MaxRequests &&
+		prev.Interval == config.Interval && prev.Timeout == config.Timeout {
+		return
+	}
+
+	c.cb = c.newBreaker(config)
+	logger.Info("Circuit breaker reconfigured",
+		zap.String("name", config.Name),
+		zap.Uint32("max_requests", config.MaxRequests),
+		zap.Duration("interval", config.Interval),
+		zap.Duration("timeout", config.Timeout),
+		zap.Float64("fail_threshold", config.FailThreshold))
+}
+
+// breaker 返回当前的底层断路器
+func (c *CircuitBreaker) breaker() *gobreaker.CircuitBreaker {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cb
+}
+
+// SetFailThreshold 在运行时调整触发断路器的错误率阈值
+func (c *CircuitBreaker) SetFailThreshold(threshold float64) {
+	c.failThreshold.Store(math.Float64bits(threshold))
+}
+
+// FailThreshold 获取当前的错误率阈值
+func (c *CircuitBreaker) FailThreshold() float64 {
+	return math.Float64frombits(c.failThreshold.Load())
+}
+
+// ForceOpen 在指定时间内强制打开断路器，期间 Execute 直接返回 gobreaker.ErrOpenState。
+// 用于服务方明确要求退避的场景，如限流响应的 Retry-After。
+// 已经强制打开时只会延长截止时间，不会缩短
+func (c *CircuitBreaker) ForceOpen(d time.Duration) {
+	until := time.Now().Add(d).UnixNano()
+	for {
+		current := c.forcedUntil.Load()
+		if current >= until || c.forcedUntil.CompareAndSwap(current, until) {
+			return
+		}
 	}
 }
 
+// forcedOpen 判断断路器当前是否处于强制打开状态
+func (c *CircuitBreaker) forcedOpen() bool {
+	return time.Now().UnixNano() < c.forcedUntil.Load()
+}
+
 // Execute 执行受断路器保护的函数
 func (c *CircuitBreaker) Execute(req func() (interface{}, error)) (interface{}, error) {
-	return c.cb.Execute(req)
+	if c.forcedOpen() {
+		return nil, gobreaker.ErrOpenState
+	}
+	return c.breaker().Execute(req)
 }
 
-// State 获取断路器当前状态
+// State 获取断路器当前状态，强制打开期间返回 gobreaker.StateOpen
 func (c *CircuitBreaker) State() gobreaker.State {
-	return c.cb.State()
+	if c.forcedOpen() {
+		return gobreaker.StateOpen
+	}
+	return c.breaker().State()
 }
 
 // Counts 获取断路器统计信息
 func (c *CircuitBreaker) Counts() gobreaker.Counts {
-	return c.cb.Counts()
+	return c.breaker().Counts()
 }
 
 // DefaultLLMCircuitBreaker 创建默认的LLM API断路器
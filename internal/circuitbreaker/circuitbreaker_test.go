@@ -0,0 +1,80 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"github.com/sony/gobreaker"
+	"testing"
+	"time"
+)
+
+func TestReconfigure(t *testing.T) {
+	cfg := CircuitBreakerConfig{
+		Name:          "test",
+		MaxRequests:   1,
+		Interval:      time.Minute,
+		Timeout:       time.Minute,
+		FailThreshold: 0.5,
+	}
+	cb := NewCircuitBreaker(cfg)
+
+	fail := func() (interface{}, error) { return nil, errors.New("failed") }
+	for i := 0; i < 5; i++ {
+		cb.Execute(fail)
+	}
+	if cb.State() != gobreaker.StateOpen {
+		t.Fatalf("State() = %v, want open", cb.State())
+	}
+
+	// 仅调整阈值时保留当前状态
+	cfg.FailThreshold = 0.9
+	cb.Reconfigure(cfg)
+	if cb.FailThreshold() != 0.9 {
+		t.Errorf("FailThreshold() = %v, want 0.9", cb.FailThreshold())
+	}
+	if cb.State() != gobreaker.StateOpen {
+		t.Errorf("State() after threshold change = %v, want open", cb.State())
+	}
+
+	// 调整其他参数时重建断路器，从关闭状态重新开始
+	cfg.Timeout = 30 * time.Second
+	cb.Reconfigure(cfg)
+	if cb.State() != gobreaker.StateClosed {
+		t.Errorf("State() after rebuild = %v, want closed", cb.State())
+	}
+	if counts := cb.Counts(); counts.Requests != 0 {
+		t.Errorf("Counts().Requests after rebuild = %d, want 0", counts.Requests)
+	}
+
+	// 强制打开不受重建影响
+	cb.ForceOpen(time.Minute)
+	cfg.MaxRequests = 3
+	cb.Reconfigure(cfg)
+	if cb.State() != gobreaker.StateOpen {
+		t.Errorf("State() while forced open = %v, want open", cb.State())
+	}
+}
+
+func TestReconfigure_ConcurrentExecute(t *testing.T) {
+	cfg := CircuitBreakerConfig{
+		Name:          "test",
+		MaxRequests:   1,
+		Interval:      time.Minute,
+		Timeout:       time.Minute,
+		FailThreshold: 0.5,
+	}
+	cb := NewCircuitBreaker(cfg)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			cb.Execute(func() (interface{}, error) { return nil, nil })
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		cfg.Interval = time.Duration(i+1) * time.Second
+		cb.Reconfigure(cfg)
+	}
+	<-done
+}
@@ -1,8 +1,15 @@
 package config
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/utils"
+	"net"
 	"net/url"
+	"os"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -15,33 +22,237 @@ type Config struct {
 	Queue    QueueConfig    `mapstructure:"queue"`
 	Logger   LoggerConfig   `mapstructure:"logger"`
 	Auth     AuthConfig     `mapstructure:"auth"`
+	Callback CallbackConfig `mapstructure:"callback"`
+	Metrics  MetricsConfig  `mapstructure:"metrics"`
+	CORS     CORSConfig     `mapstructure:"cors"`
+	// API 响应的 gzip 压缩，默认关闭
+	Compression CompressionConfig `mapstructure:"compression"`
+	// /api 路由的请求处理超时
+	RequestTimeout RequestTimeoutConfig `mapstructure:"request_timeout"`
+	// 报告的存储位置，默认直接写入记录的 report 列
+	ResultStore ResultStoreConfig `mapstructure:"result_store"`
 }
 
 type AppConfig struct {
 	Name string `mapstructure:"name"`
 	Mode string `mapstructure:"mode"`
 	Port int    `mapstructure:"port"`
+	// 请求体大小上限（字节），超过时返回 413，为 0 时使用默认值 1MB
+	MaxBodyBytes int64 `mapstructure:"max_body_bytes"`
+	// 就绪检查中数据库 Ping 的超时时间，为 0 时默认 2 秒
+	HealthCheckTimeout time.Duration `mapstructure:"health_check_timeout"`
 }
 
 type RedisConfig struct {
 	Addr     string `mapstructure:"addr"`
 	Password string `mapstructure:"password"`
 	DB       int    `mapstructure:"db"`
+	// Sentinel 模式：哨兵地址列表和主节点名称
+	SentinelAddrs []string `mapstructure:"sentinel_addrs"`
+	MasterName    string   `mapstructure:"master_name"`
+	// Cluster 模式：集群节点地址列表，集群模式不支持 db
+	ClusterAddrs []string `mapstructure:"cluster_addrs"`
+	// TLS 连接配置，托管 Redis 服务通常要求 TLS
+	TLS RedisTLSConfig `mapstructure:"tls"`
+}
+
+// RedisTLSConfig Redis TLS 连接配置
+type RedisTLSConfig struct {
+	Enabled            bool   `mapstructure:"enabled"`
+	CAFile             string `mapstructure:"ca_file"`              // 用于校验服务端证书的 CA 证书文件（PEM），为空时使用系统根证书
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"` // 跳过服务端证书校验，仅用于测试环境
+}
+
+// TLSConfig 创建 Redis 连接使用的 TLS 配置，未启用时返回 nil
+func (c RedisTLSConfig) TLSConfig() (*tls.Config, error) {
+	if !c.Enabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_file %s contains no valid PEM certificates", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// Redis 连接模式
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+	RedisModeCluster    = "cluster"
+)
+
+// Mode 返回配置的 Redis 连接模式
+func (c RedisConfig) Mode() string {
+	switch {
+	case len(c.SentinelAddrs) > 0:
+		return RedisModeSentinel
+	case len(c.ClusterAddrs) > 0:
+		return RedisModeCluster
+	default:
+		return RedisModeStandalone
+	}
+}
+
+// ConnOpt 按连接模式创建 asynq 的 Redis 连接配置，API 服务和 worker 共用
+func (c RedisConfig) ConnOpt() (asynq.RedisConnOpt, error) {
+	tlsConfig, err := c.TLS.TLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("redis tls: %w", err)
+	}
+
+	switch c.Mode() {
+	case RedisModeSentinel:
+		return asynq.RedisFailoverClientOpt{
+			MasterName:    c.MasterName,
+			SentinelAddrs: c.SentinelAddrs,
+			Password:      c.Password,
+			DB:            c.DB,
+			TLSConfig:     tlsConfig,
+		}, nil
+	case RedisModeCluster:
+		return asynq.RedisClusterClientOpt{
+			Addrs:     c.ClusterAddrs,
+			Password:  c.Password,
+			TLSConfig: tlsConfig,
+		}, nil
+	default:
+		return asynq.RedisClientOpt{
+			Addr:      c.Addr,
+			Password:  c.Password,
+			DB:        c.DB,
+			TLSConfig: tlsConfig,
+		}, nil
+	}
 }
 
 type MySQLConfig struct {
-	DSN          string `mapstructure:"dsn"`
-	MaxIdleConns int    `mapstructure:"max_idle_conns"`
-	MaxOpenConns int    `mapstructure:"max_open_conns"`
+	DSN            string        `mapstructure:"dsn"`
+	ReplicaDSN     string        `mapstructure:"replica_dsn"` // 只读副本的 DSN，配置后记录查询使用副本，为空时全部使用主库
+	MaxIdleConns   int           `mapstructure:"max_idle_conns"`
+	MaxOpenConns   int           `mapstructure:"max_open_conns"`
+	ConnectRetries int           `mapstructure:"connect_retries"` // 启动时连接失败的重试次数
+	ConnectBackoff time.Duration `mapstructure:"connect_backoff"` // 连接重试的初始退避时间，每次重试翻倍
+	QueryTimeout   time.Duration `mapstructure:"query_timeout"`   // 调用方未设置截止时间时单次数据库操作的超时，0 表示默认 10s，负数表示不限制
+	// 逻辑字段名到实际列名的映射，如 user_message: prompt，未配置的字段使用逻辑字段名
+	Columns map[string]string `mapstructure:"columns"`
 }
 
 type DeepseekConfig struct {
-	APIKey         string               `mapstructure:"api_key"`
-	BaseURL        string               `mapstructure:"base_url"`
-	Timeout        time.Duration        `mapstructure:"timeout"`
-	Model          string               `mapstructure:"model"`
-	MaxTokens      int                  `mapstructure:"max_tokens"`
-	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+	APIKey           string               `mapstructure:"api_key"`
+	BaseURL          string               `mapstructure:"base_url"`
+	Timeout          time.Duration        `mapstructure:"timeout"`
+	Model            string               `mapstructure:"model"`
+	MaxTokens        int                  `mapstructure:"max_tokens"`
+	Temperature      *float64             `mapstructure:"temperature"`        // 为空时不发送，使用服务端默认值
+	TopP             *float64             `mapstructure:"top_p"`              // 为空时不发送，使用服务端默认值
+	Headers          map[string]string    `mapstructure:"headers"`            // 附加到 LLM 请求的自定义请求头，如 User-Agent
+	MaxResponseBytes int64                `mapstructure:"max_response_bytes"` // 响应体最大字节数，为 0 时使用默认值 4MB
+	MaxConcurrency   int                  `mapstructure:"max_concurrency"`    // 同时进行的 LLM 调用上限，为 0 时不限制
+	MaxPromptChars   int                  `mapstructure:"max_prompt_chars"`   // 系统消息和用户消息的总字符数上限，为 0 时不限制
+	Examples         []ExampleMessage     `mapstructure:"examples"`           // 插入到系统消息和用户消息之间的示例消息，为空时不插入
+	CircuitBreaker   CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+	FallbackBaseURL  string               `mapstructure:"fallback_base_url"` // 断路器打开时使用的备用 LLM 地址，为空时不启用
+	FallbackModel    string               `mapstructure:"fallback_model"`    // 备用 LLM 使用的模型，为空时沿用主模型
+	FallbackAPIKey   string               `mapstructure:"fallback_api_key"`  // 备用 LLM 的 API 密钥，为空时沿用主密钥
+	Transport        TransportConfig      `mapstructure:"transport"`
+	// 允许 base_url 和 fallback_base_url 指向本地或内网地址，如内网部署的模型服务。
+	// development 模式下始终允许
+	AllowPrivateBaseURL bool `mapstructure:"allow_private_base_url"`
+	// 将 LLM 回复解析为 JSON 并取出该路径的字段作为报告，如 result.summary，
+	// 数组用下标表示。为空时原样保存回复
+	ResponseJSONPath string `mapstructure:"response_json_path"`
+	// 提示词超过 max_prompt_chars 时的处理方式：none 直接失败，head 保留用户消息开头、截掉结尾，
+	// tail 保留用户消息结尾、截掉开头。为空时等同于 none
+	TrimStrategy string `mapstructure:"trim_strategy"`
+	// 允许使用的模型，model、fallback_model 和创建任务时指定的模型都必须在列表中，为空时不限制
+	AllowedModels []string `mapstructure:"allowed_models"`
+	// 各模型允许的 max_tokens 上限，如 deepseek-chat: 8192。请求超过上限时不调用 API，未列出的模型不限制
+	ModelMaxTokens map[string]int `mapstructure:"model_max_tokens"`
+	// LLM API 返回这些 HTTP 状态码时任务按重试策略重试，其他非 200 状态码直接失败、不再重试。
+	// 为空时使用 DefaultRetryableStatusCodes
+	RetryableStatusCodes []int `mapstructure:"retryable_status_codes"`
+	// 相同请求体（模型、消息和生成参数）的回复缓存，命中时不调用 API
+	PromptCache PromptCacheConfig `mapstructure:"prompt_cache"`
+}
+
+// DefaultRetryableStatusCodes 未配置 retryable_status_codes 时可以重试的 LLM API 状态码：限流和服务端的临时错误
+var DefaultRetryableStatusCodes = []int{429, 500, 502, 503, 504}
+
+// ModelAllowed 判断模型是否在 allowed_models 中，未配置 allowed_models 时允许任意模型
+func (c DeepseekConfig) ModelAllowed(model string) bool {
+	if len(c.AllowedModels) == 0 {
+		return true
+	}
+	for _, allowed := range c.AllowedModels {
+		if allowed == model {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxTokensLimit 返回模型在 model_max_tokens 中的 max_tokens 上限，未配置时返回 0 表示不限制
+func (c DeepseekConfig) MaxTokensLimit(model string) int {
+	return c.ModelMaxTokens[model]
+}
+
+// StatusRetryable 判断 LLM API 返回的 HTTP 状态码是否可以重试
+func (c DeepseekConfig) StatusRetryable(code int) bool {
+	codes := c.RetryableStatusCodes
+	if len(codes) == 0 {
+		codes = DefaultRetryableStatusCodes
+	}
+	for _, retryable := range codes {
+		if retryable == code {
+			return true
+		}
+	}
+	return false
+}
+
+// ExampleMessage 是发送给 LLM 的一条示例消息（few-shot），按配置顺序插入到系统消息之后
+type ExampleMessage struct {
+	Role    string `mapstructure:"role"` // system、user 或 assistant
+	Content string `mapstructure:"content"`
+}
+
+// 提示词超长时的处理方式
+const (
+	TrimNone = "none" // 不截断，任务失败
+	TrimHead = "head" // 保留用户消息的开头
+	TrimTail = "tail" // 保留用户消息的结尾
+)
+
+// exampleRoles 示例消息允许的角色
+var exampleRoles = map[string]bool{"system": true, "user": true, "assistant": true}
+
+// TransportConfig LLM HTTP 客户端的连接池设置，为 0 的字段使用 http.DefaultTransport 的值
+type TransportConfig struct {
+	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host"` // 每个主机保留的空闲连接数
+	IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout"`       // 空闲连接关闭前的保留时间
+	TLSHandshakeTimeout time.Duration `mapstructure:"tls_handshake_timeout"`   // TLS 握手超时时间
+}
+
+// PromptCacheConfig LLM 回复的内存缓存，以请求体的哈希为键，只在当前工作者进程内共享
+type PromptCacheConfig struct {
+	Enabled bool          `mapstructure:"enabled"` // 是否启用，默认关闭
+	Size    int           `mapstructure:"size"`    // 最多缓存的回复数，超出时淘汰最久未使用的，为 0 时默认 1000
+	TTL     time.Duration `mapstructure:"ttl"`     // 回复的缓存时间，为 0 时默认 1h
 }
 
 type CircuitBreakerConfig struct {
@@ -53,20 +264,319 @@ type CircuitBreakerConfig struct {
 }
 
 type QueueConfig struct {
-	Concurrency int           `mapstructure:"concurrency"`
-	Retry       int           `mapstructure:"retry"`
-	Retention   time.Duration `mapstructure:"retention"`
+	Concurrency  int           `mapstructure:"concurrency"`
+	Retry        int           `mapstructure:"retry"`
+	Retention    time.Duration `mapstructure:"retention"`
+	MaxBatchSize int           `mapstructure:"max_batch_size"` // 批量创建任务的最大数量，为 0 时默认 100
+	// 列出任务时 offset 不是 limit 的整数倍需要在内存中截取，offset+limit 不能超过该值，为 0 时默认 1000
+	MaxListSize int `mapstructure:"max_list_size"`
+	// 处理期间写入进度心跳的间隔，为 0 时不写入
+	ProgressInterval time.Duration `mapstructure:"progress_interval"`
+	// 记录失败次数上限，达到上限后不允许再重试，worker 也不再处理该记录的任务，为 0 时不限制
+	MaxFailedTimes int `mapstructure:"max_failed_times"`
+	// 关闭时等待进行中任务完成的时间，为 0 时默认 30 秒
+	DrainTimeout time.Duration `mapstructure:"drain_timeout"`
+	// 关闭时等待进行中 LLM 调用的宽限期，超过后取消调用，任务由 asynq 重试。
+	// 为 0 时不取消，一直等待到 drain_timeout
+	ShutdownGracePeriod time.Duration `mapstructure:"shutdown_grace_period"`
+	// 任务入队和查询使用的队列名称，为空时使用 "default"
+	DefaultQueue string `mapstructure:"default_queue"`
+	// 单个任务的总处理时间上限，包括数据库读写、LLM 调用和回调，为 0 时不限制
+	TaskTimeout time.Duration `mapstructure:"task_timeout"`
+	// 重试延迟按指数增长并加入随机抖动：第 n 次重试的上限为 retry_base_delay * 2^n，
+	// 不超过 retry_max_delay。为 0 时分别默认 1 分钟和 1 小时
+	RetryBaseDelay time.Duration `mapstructure:"retry_base_delay"`
+	RetryMaxDelay  time.Duration `mapstructure:"retry_max_delay"`
+	// 网络错误或超时的重试基础延迟，为 0 时默认 10 秒
+	RetryTransientDelay time.Duration `mapstructure:"retry_transient_delay"`
+	// LLM API 限流（429）的重试基础延迟，实际延迟不短于 Retry-After，为 0 时默认 5 分钟
+	RetryRateLimitDelay time.Duration `mapstructure:"retry_rate_limit_delay"`
+	// 是否重新处理状态已为已完成的记录，默认跳过，避免重复或重试的任务再次调用 LLM
+	ReprocessCompleted bool `mapstructure:"reprocess_completed"`
+	// LLM 调用失败且记录已有报告时，保留原报告并将状态标记为已过期而不是失败，
+	// 适用于旧结果仍然可用的非关键记录。默认关闭
+	KeepStaleReport bool `mapstructure:"keep_stale_report"`
+	// 处理中记录的认领有效期，超过该时间仍处于处理中的记录视为工作者崩溃遗留，
+	// 由定期巡检重置为待处理并重新入队。为 0 时不巡检，启用时表中需要有 processing_started_at 列
+	ClaimTTL time.Duration `mapstructure:"claim_ttl"`
+	// 巡检的间隔，为 0 时默认 1 分钟
+	ClaimSweepInterval time.Duration `mapstructure:"claim_sweep_interval"`
+	// 需要巡检的表，启用 claim_ttl 时不能为空
+	ClaimSweepTables []string `mapstructure:"claim_sweep_tables"`
+	// 按任务类型路由到独立的队列，asynq 按队列权重分配 worker 的并发，
+	// 避免一种任务大量积压时占满所有 worker。未路由的任务类型使用 default_queue
+	Routes []QueueRoute `mapstructure:"routes"`
+	// 按优先级入队的队列，客户端创建任务时通过 priority 选择，normal 使用任务类型所在的队列
+	PriorityQueues PriorityQueues `mapstructure:"priority_queues"`
+	// LLM 任务成功后写入 asynq 任务结果的报告字节数上限，超出部分截断，任务结果随任务保留 retention。
+	// 为 0 时默认 64KB，为负数时不写入
+	TaskResultMaxBytes int `mapstructure:"task_result_max_bytes"`
+	// 写入记录 status 列的状态值，用于对接状态取值不同的已有表结构，未配置的状态使用默认的中文取值
+	Statuses StatusValues `mapstructure:"statuses"`
+}
+
+// StatusValues 记录各个状态在 status 列中的取值
+type StatusValues struct {
+	Pending    string `mapstructure:"pending"`    // 等待处理，为空时默认 待处理
+	Processing string `mapstructure:"processing"` // 已被工作者认领，正在处理，为空时默认 处理中
+	Completed  string `mapstructure:"completed"`  // 处理完成，为空时默认 已完成
+	Failed     string `mapstructure:"failed"`     // 处理失败，为空时默认 失败
+	Stale      string `mapstructure:"stale"`      // 本次处理失败，保留了之前的报告，为空时默认 已过期
+}
+
+// 未配置 queue.statuses 时使用的状态值
+const (
+	DefaultStatusPending    = "待处理"
+	DefaultStatusProcessing = "处理中"
+	DefaultStatusCompleted  = "已完成"
+	DefaultStatusFailed     = "失败"
+	DefaultStatusStale      = "已过期"
+)
+
+// PriorityQueues high 和 low 优先级对应的队列
+type PriorityQueues struct {
+	High PriorityQueue `mapstructure:"high"`
+	Low  PriorityQueue `mapstructure:"low"`
+}
+
+// PriorityQueue 一个优先级对应的队列
+type PriorityQueue struct {
+	Name   string `mapstructure:"name"`   // 队列名称，为空时该优先级使用 normal 的队列
+	Weight int    `mapstructure:"weight"` // 队列权重，为 0 时 high 默认 20，low 默认 5
+}
+
+// 创建任务时可选的优先级
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+// 优先级队列未配置权重时使用的默认权重
+const (
+	DefaultHighPriorityWeight = 2 * DefaultQueueWeight
+	DefaultLowPriorityWeight  = DefaultQueueWeight / 2
+)
+
+// QueueRoute 一个独立队列及路由到该队列的任务类型
+type QueueRoute struct {
+	Name      string   `mapstructure:"name"`       // 队列名称
+	Weight    int      `mapstructure:"weight"`     // 队列权重，为 0 时使用 DefaultQueueWeight
+	TaskTypes []string `mapstructure:"task_types"` // 路由到该队列的任务类型，如 llm:process、callback:send
+}
+
+// DefaultQueueName 是未配置 queue.default_queue 时使用的队列名称，与 asynq 的默认队列一致
+const DefaultQueueName = "default"
+
+// DefaultQueueWeight 是默认队列及未配置权重的路由队列的权重
+const DefaultQueueWeight = 10
+
+// queueNamePattern 队列名称允许的字符。
+// asynq 将队列名称拼入 Redis 键 asynq:{<queue>}:...，花括号和空白会破坏键的结构
+var queueNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]+$`)
+
+// tableNamePattern 数据表名允许的字符，与数据库层的校验一致
+var tableNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+// QueueName 返回任务使用的队列名称，未配置时返回 DefaultQueueName
+func (c QueueConfig) QueueName() string {
+	if c.DefaultQueue != "" {
+		return c.DefaultQueue
+	}
+	return DefaultQueueName
+}
+
+// QueueFor 返回任务类型所在的队列，未配置路由时返回 QueueName
+func (c QueueConfig) QueueFor(taskType string) string {
+	for _, route := range c.Routes {
+		for _, t := range route.TaskTypes {
+			if t == taskType {
+				return route.Name
+			}
+		}
+	}
+	return c.QueueName()
+}
+
+// PriorityQueueFor 返回任务类型在指定优先级下使用的队列。
+// normal、空值以及未配置队列的优先级都使用 QueueFor 返回的队列
+func (c QueueConfig) PriorityQueueFor(taskType, priority string) string {
+	switch {
+	case priority == PriorityHigh && c.PriorityQueues.High.Name != "":
+		return c.PriorityQueues.High.Name
+	case priority == PriorityLow && c.PriorityQueues.Low.Name != "":
+		return c.PriorityQueues.Low.Name
+	}
+	return c.QueueFor(taskType)
+}
+
+// QueuesFor 返回任务类型可能所在的所有队列，包括 QueueFor 返回的队列和配置的优先级队列，
+// 用于按任务ID查找任务
+func (c QueueConfig) QueuesFor(taskType string) []string {
+	queues := []string{c.QueueFor(taskType)}
+	for _, pq := range []PriorityQueue{c.PriorityQueues.High, c.PriorityQueues.Low} {
+		if pq.Name != "" {
+			queues = append(queues, pq.Name)
+		}
+	}
+	return queues
+}
+
+// RecordStatuses 返回记录的状态值，未配置的状态使用默认值
+func (c QueueConfig) RecordStatuses() StatusValues {
+	s := c.Statuses
+	if s.Pending == "" {
+		s.Pending = DefaultStatusPending
+	}
+	if s.Processing == "" {
+		s.Processing = DefaultStatusProcessing
+	}
+	if s.Completed == "" {
+		s.Completed = DefaultStatusCompleted
+	}
+	if s.Failed == "" {
+		s.Failed = DefaultStatusFailed
+	}
+	if s.Stale == "" {
+		s.Stale = DefaultStatusStale
+	}
+	return s
+}
+
+// Weights 返回 worker 处理的所有队列及其权重，包括默认队列、所有路由队列和优先级队列。
+// 每个队列分到的并发大致为 concurrency * 权重 / 权重之和
+func (c QueueConfig) Weights() map[string]int {
+	weights := map[string]int{c.QueueName(): DefaultQueueWeight}
+	for _, route := range c.Routes {
+		weight := route.Weight
+		if weight == 0 {
+			weight = DefaultQueueWeight
+		}
+		weights[route.Name] = weight
+	}
+	if pq := c.PriorityQueues.High; pq.Name != "" {
+		weights[pq.Name] = pq.weightOr(DefaultHighPriorityWeight)
+	}
+	if pq := c.PriorityQueues.Low; pq.Name != "" {
+		weights[pq.Name] = pq.weightOr(DefaultLowPriorityWeight)
+	}
+	return weights
+}
+
+// weightOr 返回队列权重，未配置时返回 def
+func (pq PriorityQueue) weightOr(def int) int {
+	if pq.Weight > 0 {
+		return pq.Weight
+	}
+	return def
 }
 
 type LoggerConfig struct {
 	Level       string `mapstructure:"level"`
 	Development bool   `mapstructure:"development"`
+	File        string `mapstructure:"file"`        // 日志文件路径，为空时只输出到标准输出
+	MaxSize     int    `mapstructure:"max_size"`    // 单个日志文件的最大大小（MB），为 0 时默认 100MB
+	MaxBackups  int    `mapstructure:"max_backups"` // 保留的旧日志文件数量，为 0 时全部保留
+	MaxAge      int    `mapstructure:"max_age"`     // 旧日志文件的保留天数，为 0 时不按时间清理
+	Compress    bool   `mapstructure:"compress"`    // 是否压缩轮转后的日志文件
+	// 日志采样配置，故障期间大量重复日志时限制输出量，开发模式下不生效
+	Sampling SamplingConfig `mapstructure:"sampling"`
+}
+
+// SamplingConfig 日志采样配置
+// 每秒内相同级别和内容的日志，先输出前 Initial 条，之后每 Thereafter 条输出一条
+type SamplingConfig struct {
+	Initial    int `mapstructure:"initial"`    // 每秒完整输出的条数，为 0 时不采样
+	Thereafter int `mapstructure:"thereafter"` // 超出 Initial 后每隔多少条输出一条，为 0 时丢弃超出部分
 }
 
 type AuthConfig struct {
 	Enabled bool              `mapstructure:"enabled"`
 	Users   map[string]string `mapstructure:"users"` // username -> password
 	Realm   string            `mapstructure:"realm"` // Basic Auth realm
+	// 访问 /metrics 的独立凭据，username -> password，为空时 /metrics 不需要认证
+	MetricsUsers map[string]string `mapstructure:"metrics_users"`
+}
+
+// CORSConfig 浏览器跨域访问设置，未配置允许的来源时不启用 CORS
+type CORSConfig struct {
+	AllowedOrigins   []string      `mapstructure:"allowed_origins"`   // 允许的来源，如 https://dashboard.example.com，"*" 表示允许所有来源
+	AllowedMethods   []string      `mapstructure:"allowed_methods"`   // 允许的请求方法，为空时使用默认值
+	AllowedHeaders   []string      `mapstructure:"allowed_headers"`   // 允许的请求头，为空时使用默认值
+	AllowCredentials bool          `mapstructure:"allow_credentials"` // 是否允许携带凭据（如 Basic Auth）
+	MaxAge           time.Duration `mapstructure:"max_age"`           // 预检结果的缓存时间，为 0 时不设置
+}
+
+type CompressionConfig struct {
+	Enabled bool `mapstructure:"enabled"`  // 是否对声明了 Accept-Encoding: gzip 的请求压缩响应
+	MinSize int  `mapstructure:"min_size"` // 响应体超过该字节数才压缩，为 0 时默认 1024
+}
+
+// RequestTimeoutConfig /api 路由的请求处理超时，健康检查和指标端点不受限制
+type RequestTimeoutConfig struct {
+	Default time.Duration `mapstructure:"default"` // 默认超时，为 0 时使用 30s，为负数时不限制
+	// 按路径前缀覆盖超时，如 /api/queues: 2m，最长的匹配前缀生效，为 0 时使用 default，为负数时不限制
+	Routes map[string]time.Duration `mapstructure:"routes"`
+}
+
+type CallbackConfig struct {
+	DeadLetterURL string            `mapstructure:"dead_letter_url"` // 任务重试耗尽后的死信回调地址，为空时不发送
+	Headers       map[string]string `mapstructure:"headers"`         // 附加到回调请求的自定义请求头
+	AllowedHosts  []string          `mapstructure:"allowed_hosts"`   // 回调主机白名单，支持子域名匹配，为空时允许所有公网主机
+	BlockedCIDRs  []string          `mapstructure:"blocked_cidrs"`   // 在默认内网范围之外额外禁止的回调IP范围
+	Verbose       bool              `mapstructure:"verbose"`         // 结果回调是否包含 table_name、record_id、task_id 和 usage，默认只发送 result、status、timestamp
+	// 是否将结果回调作为独立的 callback:send 任务入队发送，失败时按队列的重试策略重新发送。
+	// 默认在处理任务时直接发送，失败只记录日志
+	Async    bool `mapstructure:"async"`
+	MaxRetry int  `mapstructure:"max_retry"` // 回调任务的最大重试次数，为 0 时使用 queue.retry
+	// 单次回调请求的超时时间，从任务上下文中分离，不受 LLM 调用耗尽的任务截止时间影响。
+	// 为 0 时使用 deepseek.timeout
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+type MetricsConfig struct {
+	LLMLatencyBuckets []float64 `mapstructure:"llm_latency_buckets"` // LLM API 调用时间直方图的桶边界（秒），为空时使用默认值
+	// Pushgateway 地址，配置后进程退出前推送一次指标，适用于来不及被抓取的短生命周期部署
+	Pushgateway    string `mapstructure:"pushgateway"`
+	PushgatewayJob string `mapstructure:"pushgateway_job"` // 推送使用的 job 名称，为空时使用 app.name
+	// 指标名称的命名空间和子系统，指标名称为 <namespace>_<subsystem>_<name>，
+	// namespace 为空时使用 syt_go_queue
+	Namespace string `mapstructure:"namespace"`
+	Subsystem string `mapstructure:"subsystem"`
+	// 定期按状态统计记录数的表，结果写入 records_by_status 指标，为空时不统计
+	StatusTables []string `mapstructure:"status_tables"`
+	// 按状态统计的间隔，为 0 时默认 1 分钟
+	StatusInterval time.Duration `mapstructure:"status_interval"`
+}
+
+type ResultStoreConfig struct {
+	// 存储类型：db（默认）将报告写入 report 列；s3 将报告写入 S3 兼容的对象存储，report 列只保存对象地址
+	Type    string   `mapstructure:"type"`
+	MinSize int      `mapstructure:"min_size"` // 报告超过该字节数时才写入对象存储，更小的报告仍写入 report 列，为 0 时全部写入对象存储
+	S3      S3Config `mapstructure:"s3"`
+}
+
+type S3Config struct {
+	Endpoint        string `mapstructure:"endpoint"`          // 对象存储地址，如 https://s3.us-east-1.amazonaws.com 或 MinIO 地址，使用路径风格访问
+	Region          string `mapstructure:"region"`            // 签名使用的区域，MinIO 通常为 us-east-1
+	Bucket          string `mapstructure:"bucket"`            // 存储桶名称
+	AccessKeyID     string `mapstructure:"access_key_id"`     // 访问密钥 ID
+	SecretAccessKey string `mapstructure:"secret_access_key"` // 访问密钥
+	Prefix          string `mapstructure:"prefix"`            // 对象键前缀，如 reports/
+	// 对象的公开访问地址前缀，配置后 report 列保存 {public_base_url}/{key}，否则保存 s3://{bucket}/{key}
+	PublicBaseURL string        `mapstructure:"public_base_url"`
+	Timeout       time.Duration `mapstructure:"timeout"` // 单次上传的超时时间，为 0 时默认 30 秒
+}
+
+// metricNamePattern Prometheus 指标名称组成部分允许的字符
+var metricNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// PushJob 返回推送指标使用的 job 名称
+func (c MetricsConfig) PushJob(appName string) string {
+	if c.PushgatewayJob != "" {
+		return c.PushgatewayJob
+	}
+	return appName
 }
 
 // ValidateConfig 验证所有配置部分
@@ -91,11 +601,34 @@ func ValidateConfig(cfg *Config) error {
 		return fmt.Errorf("deepseek config: %w", err)
 	}
 
+	// 非 development 模式下，LLM 地址不能指向本地或内网，除非显式允许
+	if cfg.App.Mode != "development" && !cfg.Deepseek.AllowPrivateBaseURL {
+		if err := validateLLMHosts(&cfg.Deepseek); err != nil {
+			return fmt.Errorf("deepseek config: %w", err)
+		}
+	}
+
 	// 验证 Queue 配置
 	if err := validateQueueConfig(&cfg.Queue); err != nil {
 		return fmt.Errorf("queue config: %w", err)
 	}
 
+	// 任务总超时包含 LLM 调用，不能小于单次 LLM 调用的超时时间
+	if cfg.Queue.TaskTimeout > 0 && cfg.Queue.TaskTimeout < cfg.Deepseek.Timeout {
+		return fmt.Errorf("queue config: task_timeout (%v) must not be less than deepseek timeout (%v)", cfg.Queue.TaskTimeout, cfg.Deepseek.Timeout)
+	}
+
+	// 认领有效期必须长于任务可能的处理时间，否则仍在处理的记录会被当作遗留记录重新入队。
+	// 任务的处理时间（包括等待 LLM 并发名额）只受 task_timeout 限制，因此启用巡检时必须设置 task_timeout
+	if cfg.Queue.ClaimTTL > 0 {
+		if cfg.Queue.TaskTimeout <= 0 {
+			return fmt.Errorf("queue config: task_timeout must be set when claim_ttl is set")
+		}
+		if cfg.Queue.ClaimTTL <= cfg.Queue.TaskTimeout {
+			return fmt.Errorf("queue config: claim_ttl (%v) must be greater than task_timeout (%v)", cfg.Queue.ClaimTTL, cfg.Queue.TaskTimeout)
+		}
+	}
+
 	// 验证 Logger 配置
 	if err := validateLoggerConfig(&cfg.Logger); err != nil {
 		return fmt.Errorf("logger config: %w", err)
@@ -106,6 +639,38 @@ func ValidateConfig(cfg *Config) error {
 		return fmt.Errorf("auth config: %w", err)
 	}
 
+	// 验证 Callback 配置
+	if err := validateCallbackConfig(&cfg.Callback); err != nil {
+		return fmt.Errorf("callback config: %w", err)
+	}
+
+	// 验证 CORS 配置
+	if err := validateCORSConfig(&cfg.CORS); err != nil {
+		return fmt.Errorf("cors config: %w", err)
+	}
+
+	// 验证 Compression 配置
+	if cfg.Compression.MinSize < 0 {
+		return fmt.Errorf("compression config: min_size must not be negative, got %d", cfg.Compression.MinSize)
+	}
+
+	// 验证 RequestTimeout 配置
+	for prefix := range cfg.RequestTimeout.Routes {
+		if !strings.HasPrefix(prefix, "/api") {
+			return fmt.Errorf("request_timeout config: route %q must start with /api", prefix)
+		}
+	}
+
+	// 验证 Metrics 配置
+	if err := validateMetricsConfig(&cfg.Metrics); err != nil {
+		return fmt.Errorf("metrics config: %w", err)
+	}
+
+	// 验证 ResultStore 配置
+	if err := validateResultStoreConfig(&cfg.ResultStore); err != nil {
+		return fmt.Errorf("result_store config: %w", err)
+	}
+
 	return nil
 }
 
@@ -129,25 +694,85 @@ func validateAppConfig(cfg *AppConfig) error {
 		return fmt.Errorf("mode must be one of [development, production, test], got %s", cfg.Mode)
 	}
 
+	if cfg.MaxBodyBytes < 0 {
+		return fmt.Errorf("max_body_bytes must be non-negative, got %d", cfg.MaxBodyBytes)
+	}
+
+	if cfg.HealthCheckTimeout < 0 {
+		return fmt.Errorf("health_check_timeout must be non-negative, got %v", cfg.HealthCheckTimeout)
+	}
+
 	return nil
 }
 
 // validateRedisConfig 验证 Redis 配置
 func validateRedisConfig(cfg *RedisConfig) error {
-	if cfg.Addr == "" {
-		return fmt.Errorf("addr is required")
+	// 单机、Sentinel 和 Cluster 模式只能配置一种
+	modes := 0
+	if cfg.Addr != "" {
+		modes++
+	}
+	if len(cfg.SentinelAddrs) > 0 {
+		modes++
+	}
+	if len(cfg.ClusterAddrs) > 0 {
+		modes++
+	}
+	if modes == 0 {
+		return fmt.Errorf("one of addr, sentinel_addrs or cluster_addrs is required")
+	}
+	if modes > 1 {
+		return fmt.Errorf("only one of addr, sentinel_addrs or cluster_addrs may be set")
 	}
 
-	// 检查地址格式
-	parts := strings.Split(cfg.Addr, ":")
-	if len(parts) != 2 {
-		return fmt.Errorf("addr must be in format host:port, got %s", cfg.Addr)
+	if cfg.MasterName != "" && len(cfg.SentinelAddrs) == 0 {
+		return fmt.Errorf("master_name requires sentinel_addrs")
+	}
+
+	switch cfg.Mode() {
+	case RedisModeSentinel:
+		if cfg.MasterName == "" {
+			return fmt.Errorf("master_name is required with sentinel_addrs")
+		}
+		for i, addr := range cfg.SentinelAddrs {
+			if err := validateRedisAddr(addr); err != nil {
+				return fmt.Errorf("sentinel_addrs[%d]: %w", i, err)
+			}
+		}
+	case RedisModeCluster:
+		if cfg.DB != 0 {
+			return fmt.Errorf("db is not supported in cluster mode, got %d", cfg.DB)
+		}
+		for i, addr := range cfg.ClusterAddrs {
+			if err := validateRedisAddr(addr); err != nil {
+				return fmt.Errorf("cluster_addrs[%d]: %w", i, err)
+			}
+		}
+	default:
+		if err := validateRedisAddr(cfg.Addr); err != nil {
+			return fmt.Errorf("addr: %w", err)
+		}
 	}
 
 	if cfg.DB < 0 {
 		return fmt.Errorf("db must be non-negative, got %d", cfg.DB)
 	}
 
+	if cfg.TLS.Enabled && cfg.TLS.CAFile != "" {
+		if _, err := os.Stat(cfg.TLS.CAFile); err != nil {
+			return fmt.Errorf("tls.ca_file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// validateRedisAddr 检查 Redis 地址格式
+func validateRedisAddr(addr string) error {
+	parts := strings.Split(addr, ":")
+	if len(parts) != 2 {
+		return fmt.Errorf("must be in format host:port, got %s", addr)
+	}
 	return nil
 }
 
@@ -165,6 +790,14 @@ func validateMySQLConfig(cfg *MySQLConfig) error {
 		return fmt.Errorf("max_open_conns must be positive, got %d", cfg.MaxOpenConns)
 	}
 
+	if cfg.ConnectRetries < 0 {
+		return fmt.Errorf("connect_retries must be non-negative, got %d", cfg.ConnectRetries)
+	}
+
+	if cfg.ConnectBackoff < 0 {
+		return fmt.Errorf("connect_backoff must be non-negative, got %v", cfg.ConnectBackoff)
+	}
+
 	return nil
 }
 
@@ -179,8 +812,7 @@ func validateDeepseekConfig(cfg *DeepseekConfig) error {
 	}
 
 	// 验证 URL 格式
-	_, err := url.Parse(cfg.BaseURL)
-	if err != nil {
+	if _, err := parseLLMURL(cfg.BaseURL); err != nil {
 		return fmt.Errorf("base_url is invalid: %w", err)
 	}
 
@@ -196,6 +828,119 @@ func validateDeepseekConfig(cfg *DeepseekConfig) error {
 		return fmt.Errorf("max_tokens must be positive, got %d", cfg.MaxTokens)
 	}
 
+	if cfg.Temperature != nil && (*cfg.Temperature < 0 || *cfg.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2, got %f", *cfg.Temperature)
+	}
+
+	if cfg.TopP != nil && (*cfg.TopP < 0 || *cfg.TopP > 1) {
+		return fmt.Errorf("top_p must be between 0 and 1, got %f", *cfg.TopP)
+	}
+
+	if cfg.MaxConcurrency < 0 {
+		return fmt.Errorf("max_concurrency must not be negative, got %d", cfg.MaxConcurrency)
+	}
+
+	if cfg.MaxResponseBytes < 0 {
+		return fmt.Errorf("max_response_bytes must not be negative, got %d", cfg.MaxResponseBytes)
+	}
+
+	if cfg.MaxPromptChars < 0 {
+		return fmt.Errorf("max_prompt_chars must not be negative, got %d", cfg.MaxPromptChars)
+	}
+
+	switch cfg.TrimStrategy {
+	case "", TrimNone:
+	case TrimHead, TrimTail:
+		if cfg.MaxPromptChars <= 0 {
+			return fmt.Errorf("max_prompt_chars must be positive when trim_strategy is %s", cfg.TrimStrategy)
+		}
+	default:
+		return fmt.Errorf("trim_strategy must be one of [none, head, tail], got %s", cfg.TrimStrategy)
+	}
+
+	if cfg.ResponseJSONPath != "" {
+		for _, key := range strings.Split(cfg.ResponseJSONPath, ".") {
+			if key == "" {
+				return fmt.Errorf("response_json_path contains an empty segment: %q", cfg.ResponseJSONPath)
+			}
+		}
+	}
+
+	for i, example := range cfg.Examples {
+		if !exampleRoles[example.Role] {
+			return fmt.Errorf("examples[%d].role must be one of system, user, assistant, got %q", i, example.Role)
+		}
+		if example.Content == "" {
+			return fmt.Errorf("examples[%d].content is required", i)
+		}
+	}
+
+	if cfg.Transport.MaxIdleConnsPerHost < 0 {
+		return fmt.Errorf("transport.max_idle_conns_per_host must not be negative, got %d", cfg.Transport.MaxIdleConnsPerHost)
+	}
+
+	if cfg.Transport.IdleConnTimeout < 0 {
+		return fmt.Errorf("transport.idle_conn_timeout must not be negative, got %v", cfg.Transport.IdleConnTimeout)
+	}
+
+	if cfg.Transport.TLSHandshakeTimeout < 0 {
+		return fmt.Errorf("transport.tls_handshake_timeout must not be negative, got %v", cfg.Transport.TLSHandshakeTimeout)
+	}
+
+	if cfg.PromptCache.Size < 0 {
+		return fmt.Errorf("prompt_cache.size must not be negative, got %d", cfg.PromptCache.Size)
+	}
+
+	if cfg.PromptCache.TTL < 0 {
+		return fmt.Errorf("prompt_cache.ttl must not be negative, got %v", cfg.PromptCache.TTL)
+	}
+
+	if cfg.FallbackBaseURL != "" {
+		if _, err := parseLLMURL(cfg.FallbackBaseURL); err != nil {
+			return fmt.Errorf("fallback_base_url is invalid: %w", err)
+		}
+	} else if cfg.FallbackModel != "" || cfg.FallbackAPIKey != "" {
+		return fmt.Errorf("fallback_base_url is required when fallback_model or fallback_api_key is set")
+	}
+
+	for i, model := range cfg.AllowedModels {
+		if strings.TrimSpace(model) == "" {
+			return fmt.Errorf("allowed_models[%d] must not be empty", i)
+		}
+	}
+
+	if !cfg.ModelAllowed(cfg.Model) {
+		return fmt.Errorf("model %s is not in allowed_models", cfg.Model)
+	}
+
+	if cfg.FallbackModel != "" && !cfg.ModelAllowed(cfg.FallbackModel) {
+		return fmt.Errorf("fallback_model %s is not in allowed_models", cfg.FallbackModel)
+	}
+
+	for model, limit := range cfg.ModelMaxTokens {
+		if strings.TrimSpace(model) == "" {
+			return fmt.Errorf("model_max_tokens contains an empty model name")
+		}
+		if limit <= 0 {
+			return fmt.Errorf("model_max_tokens.%s must be positive, got %d", model, limit)
+		}
+	}
+
+	for i, code := range cfg.RetryableStatusCodes {
+		if code < 400 || code > 599 {
+			return fmt.Errorf("retryable_status_codes[%d] must be an HTTP error status between 400 and 599, got %d", i, code)
+		}
+	}
+
+	// 配置的 max_tokens 超过主模型或备用模型的上限时，所有任务都会失败
+	if limit := cfg.MaxTokensLimit(cfg.Model); limit > 0 && cfg.MaxTokens > limit {
+		return fmt.Errorf("max_tokens %d exceeds model_max_tokens.%s %d", cfg.MaxTokens, cfg.Model, limit)
+	}
+
+	if limit := cfg.MaxTokensLimit(cfg.FallbackModel); cfg.FallbackModel != "" && limit > 0 && cfg.MaxTokens > limit {
+		return fmt.Errorf("max_tokens %d exceeds model_max_tokens.%s %d", cfg.MaxTokens, cfg.FallbackModel, limit)
+	}
+
 	// 验证断路器配置
 	if cfg.CircuitBreaker.Enabled {
 		if cfg.CircuitBreaker.MaxRequests <= 0 {
@@ -218,6 +963,44 @@ func validateDeepseekConfig(cfg *DeepseekConfig) error {
 	return nil
 }
 
+// parseLLMURL 解析 LLM 服务地址，只接受带主机名的 http 或 https 地址，
+// 避免携带 API 密钥的请求发往 file:// 等其他协议
+func parseLLMURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("scheme must be http or https, got %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("host is required")
+	}
+	return u, nil
+}
+
+// validateLLMHosts 检查 LLM 服务地址不是本地或内网地址。
+// 只检查 IP 地址和 localhost 等主机名，不解析域名
+func validateLLMHosts(cfg *DeepseekConfig) error {
+	urls := []struct{ field, raw string }{
+		{"base_url", cfg.BaseURL},
+		{"fallback_base_url", cfg.FallbackBaseURL},
+	}
+	for _, u := range urls {
+		if u.raw == "" {
+			continue
+		}
+		parsed, err := parseLLMURL(u.raw)
+		if err != nil {
+			return fmt.Errorf("%s is invalid: %w", u.field, err)
+		}
+		if err := utils.CheckPrivateHost(parsed.Hostname()); err != nil {
+			return fmt.Errorf("%s points to a private address (set allow_private_base_url to allow): %w", u.field, err)
+		}
+	}
+	return nil
+}
+
 // validateQueueConfig 验证 Queue 配置
 func validateQueueConfig(cfg *QueueConfig) error {
 	if cfg.Concurrency <= 0 {
@@ -232,6 +1015,172 @@ func validateQueueConfig(cfg *QueueConfig) error {
 		return fmt.Errorf("retention must be positive, got %v", cfg.Retention)
 	}
 
+	if cfg.MaxBatchSize < 0 {
+		return fmt.Errorf("max_batch_size must be non-negative, got %d", cfg.MaxBatchSize)
+	}
+
+	if cfg.MaxListSize < 0 {
+		return fmt.Errorf("max_list_size must be non-negative, got %d", cfg.MaxListSize)
+	}
+
+	if cfg.MaxFailedTimes < 0 {
+		return fmt.Errorf("max_failed_times must be non-negative, got %d", cfg.MaxFailedTimes)
+	}
+
+	if cfg.ProgressInterval < 0 {
+		return fmt.Errorf("progress_interval must be non-negative, got %v", cfg.ProgressInterval)
+	}
+
+	if cfg.DrainTimeout < 0 {
+		return fmt.Errorf("drain_timeout must be non-negative, got %v", cfg.DrainTimeout)
+	}
+
+	if cfg.ShutdownGracePeriod < 0 {
+		return fmt.Errorf("shutdown_grace_period must be non-negative, got %v", cfg.ShutdownGracePeriod)
+	}
+
+	// 宽限期不短于排空时间时，取消调用前工作者已经退出
+	if cfg.ShutdownGracePeriod > 0 && cfg.DrainTimeout > 0 && cfg.ShutdownGracePeriod >= cfg.DrainTimeout {
+		return fmt.Errorf("shutdown_grace_period (%v) must be less than drain_timeout (%v)", cfg.ShutdownGracePeriod, cfg.DrainTimeout)
+	}
+
+	if cfg.TaskTimeout < 0 {
+		return fmt.Errorf("task_timeout must be non-negative, got %v", cfg.TaskTimeout)
+	}
+
+	if cfg.RetryBaseDelay < 0 {
+		return fmt.Errorf("retry_base_delay must be non-negative, got %v", cfg.RetryBaseDelay)
+	}
+
+	if cfg.RetryMaxDelay < 0 {
+		return fmt.Errorf("retry_max_delay must be non-negative, got %v", cfg.RetryMaxDelay)
+	}
+
+	if cfg.RetryBaseDelay > 0 && cfg.RetryMaxDelay > 0 && cfg.RetryMaxDelay < cfg.RetryBaseDelay {
+		return fmt.Errorf("retry_max_delay (%v) must not be less than retry_base_delay (%v)", cfg.RetryMaxDelay, cfg.RetryBaseDelay)
+	}
+
+	if cfg.RetryTransientDelay < 0 {
+		return fmt.Errorf("retry_transient_delay must be non-negative, got %v", cfg.RetryTransientDelay)
+	}
+
+	if cfg.RetryRateLimitDelay < 0 {
+		return fmt.Errorf("retry_rate_limit_delay must be non-negative, got %v", cfg.RetryRateLimitDelay)
+	}
+
+	if cfg.ClaimTTL < 0 {
+		return fmt.Errorf("claim_ttl must be non-negative, got %v", cfg.ClaimTTL)
+	}
+
+	if cfg.ClaimSweepInterval < 0 {
+		return fmt.Errorf("claim_sweep_interval must be non-negative, got %v", cfg.ClaimSweepInterval)
+	}
+
+	if cfg.ClaimTTL > 0 && len(cfg.ClaimSweepTables) == 0 {
+		return fmt.Errorf("claim_sweep_tables must not be empty when claim_ttl is set")
+	}
+
+	for _, table := range cfg.ClaimSweepTables {
+		if !tableNamePattern.MatchString(table) {
+			return fmt.Errorf("claim_sweep_tables may only contain letters, digits and '_', got %q", table)
+		}
+	}
+
+	if cfg.DefaultQueue != "" && !queueNamePattern.MatchString(cfg.DefaultQueue) {
+		return fmt.Errorf("default_queue may only contain letters, digits, '_', '.', ':' and '-', got %q", cfg.DefaultQueue)
+	}
+
+	if err := validateQueueRoutes(cfg.Routes); err != nil {
+		return err
+	}
+
+	if err := validateStatusValues(cfg.RecordStatuses()); err != nil {
+		return err
+	}
+
+	return validatePriorityQueues(cfg)
+}
+
+// validateStatusValues 校验状态值互不相同，否则无法区分记录所处的状态
+func validateStatusValues(s StatusValues) error {
+	names := map[string]string{}
+	for _, status := range []struct{ name, value string }{
+		{"pending", s.Pending},
+		{"processing", s.Processing},
+		{"completed", s.Completed},
+		{"failed", s.Failed},
+		{"stale", s.Stale},
+	} {
+		if other, ok := names[status.value]; ok {
+			return fmt.Errorf("statuses.%s and statuses.%s must not both be %q", other, status.name, status.value)
+		}
+		names[status.value] = status.name
+	}
+	return nil
+}
+
+// validatePriorityQueues 校验优先级队列，优先级队列不能与默认队列、路由队列或另一个优先级队列同名，
+// 否则它们的权重会相互覆盖
+func validatePriorityQueues(cfg *QueueConfig) error {
+	used := map[string]bool{cfg.QueueName(): true}
+	for _, route := range cfg.Routes {
+		used[route.Name] = true
+	}
+
+	for _, pq := range []struct {
+		priority string
+		queue    PriorityQueue
+	}{
+		{PriorityHigh, cfg.PriorityQueues.High},
+		{PriorityLow, cfg.PriorityQueues.Low},
+	} {
+		if pq.queue.Weight < 0 {
+			return fmt.Errorf("priority_queues.%s.weight must be non-negative, got %d", pq.priority, pq.queue.Weight)
+		}
+		if pq.queue.Name == "" {
+			continue
+		}
+		if !queueNamePattern.MatchString(pq.queue.Name) {
+			return fmt.Errorf("priority_queues.%s.name may only contain letters, digits, '_', '.', ':' and '-', got %q", pq.priority, pq.queue.Name)
+		}
+		if used[pq.queue.Name] {
+			return fmt.Errorf("priority_queues.%s.name %q is already used by another queue", pq.priority, pq.queue.Name)
+		}
+		used[pq.queue.Name] = true
+	}
+	return nil
+}
+
+// validateQueueRoutes 校验队列路由，队列名称不能重复，一个任务类型只能路由到一个队列
+func validateQueueRoutes(routes []QueueRoute) error {
+	names := make(map[string]bool, len(routes))
+	types := make(map[string]string)
+	for i, route := range routes {
+		if !queueNamePattern.MatchString(route.Name) {
+			return fmt.Errorf("routes[%d].name may only contain letters, digits, '_', '.', ':' and '-', got %q", i, route.Name)
+		}
+		if names[route.Name] {
+			return fmt.Errorf("routes[%d].name %q is duplicated", i, route.Name)
+		}
+		names[route.Name] = true
+
+		if route.Weight < 0 {
+			return fmt.Errorf("routes[%d].weight must be non-negative, got %d", i, route.Weight)
+		}
+
+		if len(route.TaskTypes) == 0 {
+			return fmt.Errorf("routes[%d].task_types must not be empty", i)
+		}
+		for _, t := range route.TaskTypes {
+			if strings.TrimSpace(t) == "" {
+				return fmt.Errorf("routes[%d].task_types must not contain empty entries", i)
+			}
+			if other, ok := types[t]; ok {
+				return fmt.Errorf("task type %q is routed to both %q and %q", t, other, route.Name)
+			}
+			types[t] = route.Name
+		}
+	}
 	return nil
 }
 
@@ -248,6 +1197,26 @@ func validateLoggerConfig(cfg *LoggerConfig) error {
 		return fmt.Errorf("level must be one of [debug, info, warn, error], got %s", cfg.Level)
 	}
 
+	if cfg.MaxSize < 0 {
+		return fmt.Errorf("max_size must be non-negative, got %d", cfg.MaxSize)
+	}
+
+	if cfg.MaxBackups < 0 {
+		return fmt.Errorf("max_backups must be non-negative, got %d", cfg.MaxBackups)
+	}
+
+	if cfg.MaxAge < 0 {
+		return fmt.Errorf("max_age must be non-negative, got %d", cfg.MaxAge)
+	}
+
+	if cfg.Sampling.Initial < 0 {
+		return fmt.Errorf("sampling.initial must be non-negative, got %d", cfg.Sampling.Initial)
+	}
+
+	if cfg.Sampling.Thereafter < 0 {
+		return fmt.Errorf("sampling.thereafter must be non-negative, got %d", cfg.Sampling.Thereafter)
+	}
+
 	return nil
 }
 
@@ -263,5 +1232,153 @@ func validateAuthConfig(cfg *AuthConfig) error {
 		}
 	}
 
+	for username := range cfg.MetricsUsers {
+		if username == "" {
+			return fmt.Errorf("metrics_users must not contain an empty username")
+		}
+	}
+
+	return nil
+}
+
+// validateCallbackConfig 验证 Callback 配置
+func validateCallbackConfig(cfg *CallbackConfig) error {
+	if cfg.DeadLetterURL != "" {
+		u, err := url.Parse(cfg.DeadLetterURL)
+		if err != nil {
+			return fmt.Errorf("dead_letter_url is invalid: %w", err)
+		}
+
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("dead_letter_url must use http or https, got %s", u.Scheme)
+		}
+	}
+
+	for i, host := range cfg.AllowedHosts {
+		if strings.TrimSpace(host) == "" {
+			return fmt.Errorf("allowed_hosts[%d] must not be empty", i)
+		}
+	}
+
+	if cfg.MaxRetry < 0 {
+		return fmt.Errorf("max_retry must not be negative, got %d", cfg.MaxRetry)
+	}
+
+	if cfg.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative, got %v", cfg.Timeout)
+	}
+
+	for i, cidr := range cfg.BlockedCIDRs {
+		if _, _, err := net.ParseCIDR(strings.TrimSpace(cidr)); err != nil {
+			return fmt.Errorf("blocked_cidrs[%d] is invalid: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// validateMetricsConfig 验证 Metrics 配置
+func validateMetricsConfig(cfg *MetricsConfig) error {
+	for i, bucket := range cfg.LLMLatencyBuckets {
+		if bucket <= 0 {
+			return fmt.Errorf("llm_latency_buckets[%d] must be positive, got %v", i, bucket)
+		}
+
+		if i > 0 && bucket <= cfg.LLMLatencyBuckets[i-1] {
+			return fmt.Errorf("llm_latency_buckets must be strictly increasing, got %v after %v", bucket, cfg.LLMLatencyBuckets[i-1])
+		}
+	}
+
+	if cfg.Namespace != "" && !metricNamePattern.MatchString(cfg.Namespace) {
+		return fmt.Errorf("namespace may only contain letters, digits and '_' and must not start with a digit, got %q", cfg.Namespace)
+	}
+
+	if cfg.Subsystem != "" && !metricNamePattern.MatchString(cfg.Subsystem) {
+		return fmt.Errorf("subsystem may only contain letters, digits and '_' and must not start with a digit, got %q", cfg.Subsystem)
+	}
+
+	if cfg.Pushgateway != "" {
+		u, err := url.Parse(cfg.Pushgateway)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("pushgateway must be an http(s) URL, got %s", cfg.Pushgateway)
+		}
+	}
+
+	for _, table := range cfg.StatusTables {
+		if !tableNamePattern.MatchString(table) {
+			return fmt.Errorf("status_tables may only contain letters, digits and '_', got %q", table)
+		}
+	}
+
+	if cfg.StatusInterval < 0 {
+		return fmt.Errorf("status_interval must be non-negative, got %v", cfg.StatusInterval)
+	}
+
+	return nil
+}
+
+// validateResultStoreConfig 验证 ResultStore 配置
+func validateResultStoreConfig(cfg *ResultStoreConfig) error {
+	if cfg.MinSize < 0 {
+		return fmt.Errorf("min_size must not be negative, got %d", cfg.MinSize)
+	}
+
+	switch cfg.Type {
+	case "", "db":
+		return nil
+	case "s3":
+	default:
+		return fmt.Errorf("type must be one of db, s3, got %s", cfg.Type)
+	}
+
+	s3 := cfg.S3
+	u, err := url.Parse(s3.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("s3.endpoint must be an http(s) URL, got %s", s3.Endpoint)
+	}
+
+	if s3.Region == "" {
+		return fmt.Errorf("s3.region is required")
+	}
+
+	if s3.Bucket == "" {
+		return fmt.Errorf("s3.bucket is required")
+	}
+
+	if s3.AccessKeyID == "" || s3.SecretAccessKey == "" {
+		return fmt.Errorf("s3.access_key_id and s3.secret_access_key are required")
+	}
+
+	if s3.PublicBaseURL != "" {
+		u, err := url.Parse(s3.PublicBaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("s3.public_base_url must be an http(s) URL, got %s", s3.PublicBaseURL)
+		}
+	}
+
+	if s3.Timeout < 0 {
+		return fmt.Errorf("s3.timeout must not be negative, got %v", s3.Timeout)
+	}
+
+	return nil
+}
+
+// validateCORSConfig 验证 CORS 配置
+func validateCORSConfig(cfg *CORSConfig) error {
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return fmt.Errorf("allowed_origins contains invalid origin %q, expected scheme://host[:port]", origin)
+		}
+	}
+
+	if cfg.MaxAge < 0 {
+		return fmt.Errorf("max_age must be non-negative, got %v", cfg.MaxAge)
+	}
+
 	return nil
 }
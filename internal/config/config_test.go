@@ -1,6 +1,12 @@
 package config
 
 import (
+	"encoding/pem"
+	"github.com/hibiken/asynq"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -95,6 +101,27 @@ func TestValidateConfig(t *testing.T) {
 			},
 			wantError: true,
 		},
+		{
+			name: "negative max body bytes",
+			modifyFn: func(c *Config) {
+				c.App.MaxBodyBytes = -1
+			},
+			wantError: true,
+		},
+		{
+			name: "negative health check timeout",
+			modifyFn: func(c *Config) {
+				c.App.HealthCheckTimeout = -time.Second
+			},
+			wantError: true,
+		},
+		{
+			name: "custom max body bytes",
+			modifyFn: func(c *Config) {
+				c.App.MaxBodyBytes = 4 << 20
+			},
+			wantError: false,
+		},
 
 		// Redis 配置测试
 		{
@@ -164,6 +191,52 @@ func TestValidateConfig(t *testing.T) {
 			},
 			wantError: true,
 		},
+		{
+			name: "deepseek base url with file scheme",
+			modifyFn: func(c *Config) {
+				c.Deepseek.BaseURL = "file:///etc/passwd"
+			},
+			wantError: true,
+		},
+		{
+			name: "deepseek base url without host",
+			modifyFn: func(c *Config) {
+				c.Deepseek.BaseURL = "https:///v1"
+			},
+			wantError: true,
+		},
+		{
+			name: "private deepseek base url in development mode",
+			modifyFn: func(c *Config) {
+				c.Deepseek.BaseURL = "http://localhost:11434/v1"
+			},
+			wantError: false,
+		},
+		{
+			name: "private deepseek base url in production mode",
+			modifyFn: func(c *Config) {
+				c.App.Mode = "production"
+				c.Deepseek.BaseURL = "http://10.0.0.5:8000/v1"
+			},
+			wantError: true,
+		},
+		{
+			name: "private fallback base url in production mode",
+			modifyFn: func(c *Config) {
+				c.App.Mode = "production"
+				c.Deepseek.FallbackBaseURL = "http://127.0.0.1:8000/v1"
+			},
+			wantError: true,
+		},
+		{
+			name: "private deepseek base url explicitly allowed",
+			modifyFn: func(c *Config) {
+				c.App.Mode = "production"
+				c.Deepseek.BaseURL = "http://10.0.0.5:8000/v1"
+				c.Deepseek.AllowPrivateBaseURL = true
+			},
+			wantError: false,
+		},
 		{
 			name: "zero deepseek timeout",
 			modifyFn: func(c *Config) {
@@ -248,6 +321,76 @@ func TestValidateConfig(t *testing.T) {
 			},
 			wantError: true,
 		},
+		{
+			name: "negative task timeout",
+			modifyFn: func(c *Config) {
+				c.Queue.TaskTimeout = -1
+			},
+			wantError: true,
+		},
+		{
+			name: "task timeout shorter than llm timeout",
+			modifyFn: func(c *Config) {
+				c.Queue.TaskTimeout = 10 * time.Second
+			},
+			wantError: true,
+		},
+		{
+			name: "task timeout covers llm timeout",
+			modifyFn: func(c *Config) {
+				c.Queue.TaskTimeout = 2 * time.Minute
+			},
+			wantError: false,
+		},
+		{
+			name: "claim ttl with sweep tables",
+			modifyFn: func(c *Config) {
+				c.Queue.TaskTimeout = 5 * time.Minute
+				c.Queue.ClaimTTL = 10 * time.Minute
+				c.Queue.ClaimSweepTables = []string{"valuation_records"}
+			},
+			wantError: false,
+		},
+		{
+			name: "claim ttl without task timeout",
+			modifyFn: func(c *Config) {
+				c.Queue.ClaimTTL = 10 * time.Minute
+				c.Queue.ClaimSweepTables = []string{"valuation_records"}
+			},
+			wantError: true,
+		},
+		{
+			name: "claim ttl not longer than task timeout",
+			modifyFn: func(c *Config) {
+				c.Queue.TaskTimeout = 5 * time.Minute
+				c.Queue.ClaimTTL = 5 * time.Minute
+				c.Queue.ClaimSweepTables = []string{"valuation_records"}
+			},
+			wantError: true,
+		},
+		{
+			name: "retry backoff",
+			modifyFn: func(c *Config) {
+				c.Queue.RetryBaseDelay = 30 * time.Second
+				c.Queue.RetryMaxDelay = 30 * time.Minute
+			},
+			wantError: false,
+		},
+		{
+			name: "retry max delay below base delay",
+			modifyFn: func(c *Config) {
+				c.Queue.RetryBaseDelay = time.Minute
+				c.Queue.RetryMaxDelay = time.Second
+			},
+			wantError: true,
+		},
+		{
+			name: "negative retry base delay",
+			modifyFn: func(c *Config) {
+				c.Queue.RetryBaseDelay = -1
+			},
+			wantError: true,
+		},
 
 		// Logger 配置测试
 		{
@@ -284,6 +427,36 @@ func TestValidateConfig(t *testing.T) {
 			},
 			wantError: false,
 		},
+		// Compression 配置测试
+		{
+			name: "negative compression min size",
+			modifyFn: func(c *Config) {
+				c.Compression = CompressionConfig{Enabled: true, MinSize: -1}
+			},
+			wantError: true,
+		},
+		{
+			name: "compression with default min size",
+			modifyFn: func(c *Config) {
+				c.Compression = CompressionConfig{Enabled: true}
+			},
+			wantError: false,
+		},
+		// RequestTimeout 配置测试
+		{
+			name: "request timeout route under /api",
+			modifyFn: func(c *Config) {
+				c.RequestTimeout = RequestTimeoutConfig{Routes: map[string]time.Duration{"/api/queues": time.Minute}}
+			},
+			wantError: false,
+		},
+		{
+			name: "request timeout route outside /api",
+			modifyFn: func(c *Config) {
+				c.RequestTimeout = RequestTimeoutConfig{Routes: map[string]time.Duration{"/metrics": time.Minute}}
+			},
+			wantError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -421,6 +594,69 @@ func TestValidateRedisConfig(t *testing.T) {
 			},
 			wantError: true,
 		},
+		{
+			name: "sentinel",
+			config: RedisConfig{
+				SentinelAddrs: []string{"sentinel-1:26379", "sentinel-2:26379"},
+				MasterName:    "mymaster",
+			},
+			wantError: false,
+		},
+		{
+			name: "sentinel without master name",
+			config: RedisConfig{
+				SentinelAddrs: []string{"sentinel-1:26379"},
+			},
+			wantError: true,
+		},
+		{
+			name: "invalid sentinel addr",
+			config: RedisConfig{
+				SentinelAddrs: []string{"sentinel-1"},
+				MasterName:    "mymaster",
+			},
+			wantError: true,
+		},
+		{
+			name: "master name without sentinel",
+			config: RedisConfig{
+				Addr:       "localhost:6379",
+				MasterName: "mymaster",
+			},
+			wantError: true,
+		},
+		{
+			name: "cluster",
+			config: RedisConfig{
+				ClusterAddrs: []string{"node-1:7000", "node-2:7001"},
+			},
+			wantError: false,
+		},
+		{
+			name: "cluster with db",
+			config: RedisConfig{
+				ClusterAddrs: []string{"node-1:7000"},
+				DB:           1,
+			},
+			wantError: true,
+		},
+		{
+			name: "addr and cluster",
+			config: RedisConfig{
+				Addr:         "localhost:6379",
+				ClusterAddrs: []string{"node-1:7000"},
+			},
+			wantError: true,
+		},
+		{
+			name: "sentinel and cluster",
+			config: RedisConfig{
+				SentinelAddrs: []string{"sentinel-1:26379"},
+				MasterName:    "mymaster",
+				ClusterAddrs:  []string{"node-1:7000"},
+			},
+			wantError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -433,6 +669,85 @@ func TestValidateRedisConfig(t *testing.T) {
 	}
 }
 
+func TestRedisConfig_ConnOpt(t *testing.T) {
+	standalone := RedisConfig{Addr: "localhost:6379", Password: "secret", DB: 2}
+	connOpt, err := standalone.ConnOpt()
+	if err != nil {
+		t.Fatalf("ConnOpt() returned error: %v", err)
+	}
+	if opt, ok := connOpt.(asynq.RedisClientOpt); !ok {
+		t.Errorf("Expected RedisClientOpt, got %T", connOpt)
+	} else if opt.Addr != "localhost:6379" || opt.Password != "secret" || opt.DB != 2 || opt.TLSConfig != nil {
+		t.Errorf("Unexpected standalone options: %+v", opt)
+	}
+
+	sentinel := RedisConfig{SentinelAddrs: []string{"sentinel-1:26379"}, MasterName: "mymaster", DB: 1}
+	connOpt, err = sentinel.ConnOpt()
+	if err != nil {
+		t.Fatalf("ConnOpt() returned error: %v", err)
+	}
+	if opt, ok := connOpt.(asynq.RedisFailoverClientOpt); !ok {
+		t.Errorf("Expected RedisFailoverClientOpt, got %T", connOpt)
+	} else if opt.MasterName != "mymaster" || len(opt.SentinelAddrs) != 1 || opt.DB != 1 {
+		t.Errorf("Unexpected sentinel options: %+v", opt)
+	}
+
+	cluster := RedisConfig{ClusterAddrs: []string{"node-1:7000", "node-2:7001"}, TLS: RedisTLSConfig{Enabled: true}}
+	connOpt, err = cluster.ConnOpt()
+	if err != nil {
+		t.Fatalf("ConnOpt() returned error: %v", err)
+	}
+	if opt, ok := connOpt.(asynq.RedisClusterClientOpt); !ok {
+		t.Errorf("Expected RedisClusterClientOpt, got %T", connOpt)
+	} else if len(opt.Addrs) != 2 || opt.TLSConfig == nil {
+		t.Errorf("Unexpected cluster options: %+v", opt)
+	}
+}
+
+func TestRedisTLSConfig(t *testing.T) {
+	// 未启用时不使用 TLS
+	tlsConfig, err := RedisTLSConfig{CAFile: "missing.pem"}.TLSConfig()
+	if err != nil || tlsConfig != nil {
+		t.Errorf("Expected nil TLS config when disabled, got %v, %v", tlsConfig, err)
+	}
+
+	// 使用测试服务器的证书作为 CA 证书
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+
+	tlsConfig, err = RedisTLSConfig{Enabled: true, CAFile: caFile}.TLSConfig()
+	if err != nil {
+		t.Fatalf("TLSConfig() returned error: %v", err)
+	}
+	if tlsConfig.RootCAs == nil || tlsConfig.InsecureSkipVerify {
+		t.Errorf("Unexpected TLS config: %+v", tlsConfig)
+	}
+
+	// 文件内容不是 PEM 证书
+	invalidFile := filepath.Join(t.TempDir(), "invalid.pem")
+	if err := os.WriteFile(invalidFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("Failed to write invalid CA file: %v", err)
+	}
+	if _, err := (RedisTLSConfig{Enabled: true, CAFile: invalidFile}).TLSConfig(); err == nil {
+		t.Errorf("Expected error for invalid CA file")
+	}
+
+	// 启用 TLS 时校验 CA 证书文件存在
+	cfg := RedisConfig{Addr: "localhost:6379", TLS: RedisTLSConfig{Enabled: true, CAFile: filepath.Join(t.TempDir(), "missing.pem")}}
+	if err := validateRedisConfig(&cfg); err == nil {
+		t.Errorf("Expected error for missing CA file")
+	}
+	cfg.TLS.CAFile = caFile
+	if err := validateRedisConfig(&cfg); err != nil {
+		t.Errorf("validateRedisConfig() with existing CA file returned error: %v", err)
+	}
+}
+
 func TestValidateMySQLConfig(t *testing.T) {
 	validConfig := &MySQLConfig{
 		DSN:          "user:pass@tcp(localhost:3306)/db",
@@ -485,6 +800,26 @@ func TestValidateMySQLConfig(t *testing.T) {
 			},
 			wantError: true,
 		},
+		{
+			name: "negative connect retries",
+			config: MySQLConfig{
+				DSN:            "user:pass@tcp(localhost:3306)/db",
+				MaxIdleConns:   10,
+				MaxOpenConns:   100,
+				ConnectRetries: -1,
+			},
+			wantError: true,
+		},
+		{
+			name: "negative connect backoff",
+			config: MySQLConfig{
+				DSN:            "user:pass@tcp(localhost:3306)/db",
+				MaxIdleConns:   10,
+				MaxOpenConns:   100,
+				ConnectBackoff: -1 * time.Second,
+			},
+			wantError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -555,6 +890,107 @@ func TestValidateDeepseekConfig(t *testing.T) {
 			},
 			wantError: true,
 		},
+		{
+			name: "trim strategy with prompt limit",
+			config: DeepseekConfig{
+				APIKey:         "test-api-key",
+				BaseURL:        "https://api.example.com",
+				Timeout:        30 * time.Second,
+				Model:          "test-model",
+				MaxTokens:      2000,
+				MaxPromptChars: 1000,
+				TrimStrategy:   TrimTail,
+			},
+			wantError: false,
+		},
+		{
+			name: "trim strategy without prompt limit",
+			config: DeepseekConfig{
+				APIKey:       "test-api-key",
+				BaseURL:      "https://api.example.com",
+				Timeout:      30 * time.Second,
+				Model:        "test-model",
+				MaxTokens:    2000,
+				TrimStrategy: TrimHead,
+			},
+			wantError: true,
+		},
+		{
+			name: "invalid trim strategy",
+			config: DeepseekConfig{
+				APIKey:         "test-api-key",
+				BaseURL:        "https://api.example.com",
+				Timeout:        30 * time.Second,
+				Model:          "test-model",
+				MaxTokens:      2000,
+				MaxPromptChars: 1000,
+				TrimStrategy:   "middle",
+			},
+			wantError: true,
+		},
+		{
+			name: "response json path",
+			config: DeepseekConfig{
+				APIKey:           "test-api-key",
+				BaseURL:          "https://api.example.com",
+				Timeout:          30 * time.Second,
+				Model:            "test-model",
+				MaxTokens:        2000,
+				ResponseJSONPath: "result.items.0.text",
+			},
+			wantError: false,
+		},
+		{
+			name: "response json path with empty segment",
+			config: DeepseekConfig{
+				APIKey:           "test-api-key",
+				BaseURL:          "https://api.example.com",
+				Timeout:          30 * time.Second,
+				Model:            "test-model",
+				MaxTokens:        2000,
+				ResponseJSONPath: "result..text",
+			},
+			wantError: true,
+		},
+		{
+			name: "few-shot examples",
+			config: DeepseekConfig{
+				APIKey:    "test-api-key",
+				BaseURL:   "https://api.example.com",
+				Timeout:   30 * time.Second,
+				Model:     "test-model",
+				MaxTokens: 2000,
+				Examples: []ExampleMessage{
+					{Role: "user", Content: "question"},
+					{Role: "assistant", Content: "answer"},
+				},
+			},
+			wantError: false,
+		},
+		{
+			name: "example with invalid role",
+			config: DeepseekConfig{
+				APIKey:    "test-api-key",
+				BaseURL:   "https://api.example.com",
+				Timeout:   30 * time.Second,
+				Model:     "test-model",
+				MaxTokens: 2000,
+				Examples:  []ExampleMessage{{Role: "tool", Content: "result"}},
+			},
+			wantError: true,
+		},
+		{
+			name: "example without content",
+			config: DeepseekConfig{
+				APIKey:    "test-api-key",
+				BaseURL:   "https://api.example.com",
+				Timeout:   30 * time.Second,
+				Model:     "test-model",
+				MaxTokens: 2000,
+				Examples:  []ExampleMessage{{Role: "user"}},
+			},
+			wantError: true,
+		},
 		{
 			name: "zero timeout",
 			config: DeepseekConfig{
@@ -701,7 +1137,177 @@ func TestValidateDeepseekConfig(t *testing.T) {
 			wantError: true,
 		},
 		{
-			name: "circuit breaker disabled with invalid parameters",
+			name: "valid temperature and top_p",
+			config: DeepseekConfig{
+				APIKey:      "test-api-key",
+				BaseURL:     "https://api.example.com",
+				Timeout:     30 * time.Second,
+				Model:       "test-model",
+				MaxTokens:   2000,
+				Temperature: floatPtr(0),
+				TopP:        floatPtr(1),
+			},
+			wantError: false,
+		},
+		{
+			name: "temperature > 2",
+			config: DeepseekConfig{
+				APIKey:      "test-api-key",
+				BaseURL:     "https://api.example.com",
+				Timeout:     30 * time.Second,
+				Model:       "test-model",
+				MaxTokens:   2000,
+				Temperature: floatPtr(2.1),
+			},
+			wantError: true,
+		},
+		{
+			name: "negative temperature",
+			config: DeepseekConfig{
+				APIKey:      "test-api-key",
+				BaseURL:     "https://api.example.com",
+				Timeout:     30 * time.Second,
+				Model:       "test-model",
+				MaxTokens:   2000,
+				Temperature: floatPtr(-0.1),
+			},
+			wantError: true,
+		},
+		{
+			name: "top_p > 1",
+			config: DeepseekConfig{
+				APIKey:    "test-api-key",
+				BaseURL:   "https://api.example.com",
+				Timeout:   30 * time.Second,
+				Model:     "test-model",
+				MaxTokens: 2000,
+				TopP:      floatPtr(1.5),
+			},
+			wantError: true,
+		},
+		{
+			name: "negative max_concurrency",
+			config: DeepseekConfig{
+				APIKey:         "test-api-key",
+				BaseURL:        "https://api.example.com",
+				Timeout:        30 * time.Second,
+				Model:          "test-model",
+				MaxTokens:      2000,
+				MaxConcurrency: -1,
+			},
+			wantError: true,
+		},
+		{
+			name: "negative max_response_bytes",
+			config: DeepseekConfig{
+				APIKey:           "test-api-key",
+				BaseURL:          "https://api.example.com",
+				Timeout:          30 * time.Second,
+				Model:            "test-model",
+				MaxTokens:        2000,
+				MaxResponseBytes: -1,
+			},
+			wantError: true,
+		},
+		{
+			name: "negative max_prompt_chars",
+			config: DeepseekConfig{
+				APIKey:         "test-api-key",
+				BaseURL:        "https://api.example.com",
+				Timeout:        30 * time.Second,
+				Model:          "test-model",
+				MaxTokens:      2000,
+				MaxPromptChars: -1,
+			},
+			wantError: true,
+		},
+		{
+			name: "fallback endpoint",
+			config: DeepseekConfig{
+				APIKey:          "test-api-key",
+				BaseURL:         "https://api.example.com",
+				Timeout:         30 * time.Second,
+				Model:           "test-model",
+				MaxTokens:       2000,
+				FallbackBaseURL: "https://fallback.example.com",
+				FallbackModel:   "fallback-model",
+			},
+			wantError: false,
+		},
+		{
+			name: "negative transport idle conn timeout",
+			config: DeepseekConfig{
+				APIKey:    "test-api-key",
+				BaseURL:   "https://api.example.com",
+				Timeout:   30 * time.Second,
+				Model:     "test-model",
+				MaxTokens: 2000,
+				Transport: TransportConfig{IdleConnTimeout: -1 * time.Second},
+			},
+			wantError: true,
+		},
+		{
+			name: "negative transport max idle conns per host",
+			config: DeepseekConfig{
+				APIKey:    "test-api-key",
+				BaseURL:   "https://api.example.com",
+				Timeout:   30 * time.Second,
+				Model:     "test-model",
+				MaxTokens: 2000,
+				Transport: TransportConfig{MaxIdleConnsPerHost: -1},
+			},
+			wantError: true,
+		},
+		{
+			name: "prompt cache enabled",
+			config: DeepseekConfig{
+				APIKey:      "test-api-key",
+				BaseURL:     "https://api.example.com",
+				Timeout:     30 * time.Second,
+				Model:       "test-model",
+				MaxTokens:   2000,
+				PromptCache: PromptCacheConfig{Enabled: true, Size: 100, TTL: time.Minute},
+			},
+			wantError: false,
+		},
+		{
+			name: "negative prompt cache size",
+			config: DeepseekConfig{
+				APIKey:      "test-api-key",
+				BaseURL:     "https://api.example.com",
+				Timeout:     30 * time.Second,
+				Model:       "test-model",
+				MaxTokens:   2000,
+				PromptCache: PromptCacheConfig{Enabled: true, Size: -1},
+			},
+			wantError: true,
+		},
+		{
+			name: "negative prompt cache ttl",
+			config: DeepseekConfig{
+				APIKey:      "test-api-key",
+				BaseURL:     "https://api.example.com",
+				Timeout:     30 * time.Second,
+				Model:       "test-model",
+				MaxTokens:   2000,
+				PromptCache: PromptCacheConfig{Enabled: true, TTL: -1 * time.Second},
+			},
+			wantError: true,
+		},
+		{
+			name: "fallback model without fallback_base_url",
+			config: DeepseekConfig{
+				APIKey:        "test-api-key",
+				BaseURL:       "https://api.example.com",
+				Timeout:       30 * time.Second,
+				Model:         "test-model",
+				MaxTokens:     2000,
+				FallbackModel: "fallback-model",
+			},
+			wantError: true,
+		},
+		{
+			name: "circuit breaker disabled with invalid parameters",
 			config: DeepseekConfig{
 				APIKey:    "test-api-key",
 				BaseURL:   "https://api.example.com",
@@ -718,6 +1324,130 @@ func TestValidateDeepseekConfig(t *testing.T) {
 			},
 			wantError: false, // 断路器禁用时，其他参数不验证
 		},
+		{
+			name: "model in allowed models",
+			config: DeepseekConfig{
+				APIKey:        "test-api-key",
+				BaseURL:       "https://api.example.com",
+				Timeout:       30 * time.Second,
+				Model:         "test-model",
+				MaxTokens:     2000,
+				AllowedModels: []string{"test-model", "other-model"},
+			},
+			wantError: false,
+		},
+		{
+			name: "model not in allowed models",
+			config: DeepseekConfig{
+				APIKey:        "test-api-key",
+				BaseURL:       "https://api.example.com",
+				Timeout:       30 * time.Second,
+				Model:         "test-model",
+				MaxTokens:     2000,
+				AllowedModels: []string{"other-model"},
+			},
+			wantError: true,
+		},
+		{
+			name: "fallback model not in allowed models",
+			config: DeepseekConfig{
+				APIKey:          "test-api-key",
+				BaseURL:         "https://api.example.com",
+				Timeout:         30 * time.Second,
+				Model:           "test-model",
+				MaxTokens:       2000,
+				FallbackBaseURL: "https://fallback.example.com",
+				FallbackModel:   "fallback-model",
+				AllowedModels:   []string{"test-model"},
+			},
+			wantError: true,
+		},
+		{
+			name: "empty allowed model entry",
+			config: DeepseekConfig{
+				APIKey:        "test-api-key",
+				BaseURL:       "https://api.example.com",
+				Timeout:       30 * time.Second,
+				Model:         "test-model",
+				MaxTokens:     2000,
+				AllowedModels: []string{"test-model", " "},
+			},
+			wantError: true,
+		},
+		{
+			name: "max tokens within model limit",
+			config: DeepseekConfig{
+				APIKey:         "test-api-key",
+				BaseURL:        "https://api.example.com",
+				Timeout:        30 * time.Second,
+				Model:          "test-model",
+				MaxTokens:      2000,
+				ModelMaxTokens: map[string]int{"test-model": 2000, "other-model": 100},
+			},
+			wantError: false,
+		},
+		{
+			name: "max tokens over model limit",
+			config: DeepseekConfig{
+				APIKey:         "test-api-key",
+				BaseURL:        "https://api.example.com",
+				Timeout:        30 * time.Second,
+				Model:          "test-model",
+				MaxTokens:      2000,
+				ModelMaxTokens: map[string]int{"test-model": 1000},
+			},
+			wantError: true,
+		},
+		{
+			name: "max tokens over fallback model limit",
+			config: DeepseekConfig{
+				APIKey:          "test-api-key",
+				BaseURL:         "https://api.example.com",
+				Timeout:         30 * time.Second,
+				Model:           "test-model",
+				MaxTokens:       2000,
+				FallbackBaseURL: "https://fallback.example.com",
+				FallbackModel:   "fallback-model",
+				ModelMaxTokens:  map[string]int{"fallback-model": 1000},
+			},
+			wantError: true,
+		},
+		{
+			name: "non-positive model limit",
+			config: DeepseekConfig{
+				APIKey:         "test-api-key",
+				BaseURL:        "https://api.example.com",
+				Timeout:        30 * time.Second,
+				Model:          "test-model",
+				MaxTokens:      2000,
+				ModelMaxTokens: map[string]int{"other-model": 0},
+			},
+			wantError: true,
+		},
+		{
+			name: "valid retryable status codes",
+			config: DeepseekConfig{
+				APIKey:               "test-api-key",
+				BaseURL:              "https://api.example.com",
+				Timeout:              30 * time.Second,
+				Model:                "test-model",
+				MaxTokens:            2000,
+				RetryableStatusCodes: []int{408, 429, 503},
+			},
+			wantError: false,
+		},
+		{
+			name: "retryable status code out of range",
+			config: DeepseekConfig{
+				APIKey:               "test-api-key",
+				BaseURL:              "https://api.example.com",
+				Timeout:              30 * time.Second,
+				Model:                "test-model",
+				MaxTokens:            2000,
+				RetryableStatusCodes: []int{429, 200},
+			},
+			wantError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -730,6 +1460,50 @@ func TestValidateDeepseekConfig(t *testing.T) {
 	}
 }
 
+func TestDeepseekConfig_ModelAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		model   string
+		want    bool
+	}{
+		{name: "empty list allows any model", allowed: nil, model: "any-model", want: true},
+		{name: "allowed model", allowed: []string{"deepseek-chat", "deepseek-reasoner"}, model: "deepseek-reasoner", want: true},
+		{name: "disallowed model", allowed: []string{"deepseek-chat"}, model: "gpt-4", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DeepseekConfig{AllowedModels: tt.allowed}
+			if got := cfg.ModelAllowed(tt.model); got != tt.want {
+				t.Errorf("ModelAllowed(%q) = %v, want %v", tt.model, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeepseekConfig_StatusRetryable(t *testing.T) {
+	tests := []struct {
+		codes []int
+		code  int
+		want  bool
+	}{
+		{nil, 429, true},
+		{nil, 503, true},
+		{nil, 400, false},
+		{nil, 501, false},
+		{[]int{408}, 408, true},
+		{[]int{408}, 503, false},
+	}
+
+	for _, tt := range tests {
+		cfg := DeepseekConfig{RetryableStatusCodes: tt.codes}
+		if got := cfg.StatusRetryable(tt.code); got != tt.want {
+			t.Errorf("StatusRetryable(%d) with %v = %v, want %v", tt.code, tt.codes, got, tt.want)
+		}
+	}
+}
+
 func TestValidateQueueConfig(t *testing.T) {
 	validConfig := &QueueConfig{
 		Concurrency: 10,
@@ -782,24 +1556,368 @@ func TestValidateQueueConfig(t *testing.T) {
 			},
 			wantError: true,
 		},
-		{
-			name: "negative retention",
-			config: QueueConfig{
-				Concurrency: 10,
-				Retry:       3,
-				Retention:   -1 * time.Hour,
-			},
-			wantError: true,
+		{
+			name: "negative retention",
+			config: QueueConfig{
+				Concurrency: 10,
+				Retry:       3,
+				Retention:   -1 * time.Hour,
+			},
+			wantError: true,
+		},
+		{
+			name: "negative max batch size",
+			config: QueueConfig{
+				Concurrency:  10,
+				Retry:        3,
+				Retention:    24 * time.Hour,
+				MaxBatchSize: -1,
+			},
+			wantError: true,
+		},
+		{
+			name: "negative max failed times",
+			config: QueueConfig{
+				Concurrency:    10,
+				Retry:          3,
+				Retention:      24 * time.Hour,
+				MaxFailedTimes: -1,
+			},
+			wantError: true,
+		},
+		{
+			name: "custom statuses",
+			config: QueueConfig{
+				Concurrency: 10,
+				Retry:       3,
+				Retention:   24 * time.Hour,
+				Statuses:    StatusValues{Pending: "pending", Processing: "processing", Completed: "done", Failed: "failed", Stale: "stale"},
+			},
+			wantError: false,
+		},
+		{
+			name: "duplicate status values",
+			config: QueueConfig{
+				Concurrency: 10,
+				Retry:       3,
+				Retention:   24 * time.Hour,
+				Statuses:    StatusValues{Failed: "error", Stale: "error"},
+			},
+			wantError: true,
+		},
+		{
+			name: "status value equal to a default",
+			config: QueueConfig{
+				Concurrency: 10,
+				Retry:       3,
+				Retention:   24 * time.Hour,
+				Statuses:    StatusValues{Pending: DefaultStatusFailed},
+			},
+			wantError: true,
+		},
+		{
+			name: "negative progress interval",
+			config: QueueConfig{
+				Concurrency:      10,
+				Retry:            3,
+				Retention:        24 * time.Hour,
+				ProgressInterval: -1 * time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "negative drain timeout",
+			config: QueueConfig{
+				Concurrency:  10,
+				Retry:        3,
+				Retention:    24 * time.Hour,
+				DrainTimeout: -1 * time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "claim ttl without sweep tables",
+			config: QueueConfig{
+				Concurrency: 10,
+				Retry:       3,
+				Retention:   24 * time.Hour,
+				ClaimTTL:    10 * time.Minute,
+			},
+			wantError: true,
+		},
+		{
+			name: "invalid claim sweep table",
+			config: QueueConfig{
+				Concurrency:      10,
+				Retry:            3,
+				Retention:        24 * time.Hour,
+				ClaimTTL:         10 * time.Minute,
+				ClaimSweepTables: []string{"records; DROP TABLE x"},
+			},
+			wantError: true,
+		},
+		{
+			name: "shutdown grace period shorter than drain timeout",
+			config: QueueConfig{
+				Concurrency:         10,
+				Retry:               3,
+				Retention:           24 * time.Hour,
+				DrainTimeout:        time.Minute,
+				ShutdownGracePeriod: 20 * time.Second,
+			},
+			wantError: false,
+		},
+		{
+			name: "shutdown grace period not shorter than drain timeout",
+			config: QueueConfig{
+				Concurrency:         10,
+				Retry:               3,
+				Retention:           24 * time.Hour,
+				DrainTimeout:        time.Minute,
+				ShutdownGracePeriod: time.Minute,
+			},
+			wantError: true,
+		},
+		{
+			name: "custom default queue",
+			config: QueueConfig{
+				Concurrency:  10,
+				Retry:        3,
+				Retention:    24 * time.Hour,
+				DefaultQueue: "llm:valuation",
+			},
+			wantError: false,
+		},
+		{
+			name: "default queue with invalid characters",
+			config: QueueConfig{
+				Concurrency:  10,
+				Retry:        3,
+				Retention:    24 * time.Hour,
+				DefaultQueue: "llm {queue}",
+			},
+			wantError: true,
+		},
+		{
+			name: "queue routes",
+			config: QueueConfig{
+				Concurrency: 10,
+				Retry:       3,
+				Retention:   24 * time.Hour,
+				Routes: []QueueRoute{
+					{Name: "llm", Weight: 6, TaskTypes: []string{"llm:process"}},
+					{Name: "callbacks", TaskTypes: []string{"callback:send"}},
+				},
+			},
+			wantError: false,
+		},
+		{
+			name: "route with invalid queue name",
+			config: QueueConfig{
+				Concurrency: 10,
+				Retry:       3,
+				Retention:   24 * time.Hour,
+				Routes:      []QueueRoute{{Name: "", TaskTypes: []string{"llm:process"}}},
+			},
+			wantError: true,
+		},
+		{
+			name: "route with negative weight",
+			config: QueueConfig{
+				Concurrency: 10,
+				Retry:       3,
+				Retention:   24 * time.Hour,
+				Routes:      []QueueRoute{{Name: "llm", Weight: -1, TaskTypes: []string{"llm:process"}}},
+			},
+			wantError: true,
+		},
+		{
+			name: "route without task types",
+			config: QueueConfig{
+				Concurrency: 10,
+				Retry:       3,
+				Retention:   24 * time.Hour,
+				Routes:      []QueueRoute{{Name: "llm"}},
+			},
+			wantError: true,
+		},
+		{
+			name: "duplicate route queue",
+			config: QueueConfig{
+				Concurrency: 10,
+				Retry:       3,
+				Retention:   24 * time.Hour,
+				Routes: []QueueRoute{
+					{Name: "llm", TaskTypes: []string{"llm:process"}},
+					{Name: "llm", TaskTypes: []string{"callback:send"}},
+				},
+			},
+			wantError: true,
+		},
+		{
+			name: "priority queues",
+			config: QueueConfig{
+				Concurrency: 10,
+				Retry:       3,
+				Retention:   24 * time.Hour,
+				PriorityQueues: PriorityQueues{
+					High: PriorityQueue{Name: "llm-high", Weight: 20},
+					Low:  PriorityQueue{Name: "llm-low"},
+				},
+			},
+			wantError: false,
+		},
+		{
+			name: "priority queue named like default queue",
+			config: QueueConfig{
+				Concurrency:    10,
+				Retry:          3,
+				Retention:      24 * time.Hour,
+				PriorityQueues: PriorityQueues{High: PriorityQueue{Name: "default"}},
+			},
+			wantError: true,
+		},
+		{
+			name: "priority queues with same name",
+			config: QueueConfig{
+				Concurrency: 10,
+				Retry:       3,
+				Retention:   24 * time.Hour,
+				PriorityQueues: PriorityQueues{
+					High: PriorityQueue{Name: "llm-priority"},
+					Low:  PriorityQueue{Name: "llm-priority"},
+				},
+			},
+			wantError: true,
+		},
+		{
+			name: "priority queue with negative weight",
+			config: QueueConfig{
+				Concurrency:    10,
+				Retry:          3,
+				Retention:      24 * time.Hour,
+				PriorityQueues: PriorityQueues{Low: PriorityQueue{Name: "llm-low", Weight: -1}},
+			},
+			wantError: true,
+		},
+		{
+			name: "task type routed twice",
+			config: QueueConfig{
+				Concurrency: 10,
+				Retry:       3,
+				Retention:   24 * time.Hour,
+				Routes: []QueueRoute{
+					{Name: "llm", TaskTypes: []string{"llm:process"}},
+					{Name: "llm-slow", TaskTypes: []string{"llm:process"}},
+				},
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateQueueConfig(&tt.config)
+			if (err != nil) != tt.wantError {
+				t.Errorf("validateQueueConfig() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestQueueConfig_QueueName(t *testing.T) {
+	if name := (QueueConfig{}).QueueName(); name != DefaultQueueName {
+		t.Errorf("QueueName() = %q, want %q", name, DefaultQueueName)
+	}
+
+	if name := (QueueConfig{DefaultQueue: "valuation"}).QueueName(); name != "valuation" {
+		t.Errorf("QueueName() = %q, want %q", name, "valuation")
+	}
+}
+
+func TestQueueConfig_RecordStatuses(t *testing.T) {
+	defaults := (QueueConfig{}).RecordStatuses()
+	want := StatusValues{
+		Pending:    DefaultStatusPending,
+		Processing: DefaultStatusProcessing,
+		Completed:  DefaultStatusCompleted,
+		Failed:     DefaultStatusFailed,
+		Stale:      DefaultStatusStale,
+	}
+	if defaults != want {
+		t.Errorf("RecordStatuses() = %+v, want %+v", defaults, want)
+	}
+
+	// 只配置部分状态时，其余状态使用默认值
+	partial := (QueueConfig{Statuses: StatusValues{Processing: "running", Completed: "done"}}).RecordStatuses()
+	want.Processing = "running"
+	want.Completed = "done"
+	if partial != want {
+		t.Errorf("RecordStatuses() = %+v, want %+v", partial, want)
+	}
+}
+
+func TestQueueConfig_QueueFor(t *testing.T) {
+	cfg := QueueConfig{
+		DefaultQueue: "valuation",
+		Routes: []QueueRoute{
+			{Name: "callbacks", Weight: 2, TaskTypes: []string{"callback:send"}},
+			{Name: "llm", TaskTypes: []string{"llm:process"}},
+		},
+	}
+
+	tests := map[string]string{
+		"llm:process":   "llm",
+		"callback:send": "callbacks",
+		"other:type":    "valuation",
+	}
+	for taskType, want := range tests {
+		if got := cfg.QueueFor(taskType); got != want {
+			t.Errorf("QueueFor(%q) = %q, want %q", taskType, got, want)
+		}
+	}
+
+	weights := cfg.Weights()
+	want := map[string]int{"valuation": DefaultQueueWeight, "callbacks": 2, "llm": DefaultQueueWeight}
+	if len(weights) != len(want) {
+		t.Fatalf("Weights() = %v, want %v", weights, want)
+	}
+	for name, weight := range want {
+		if weights[name] != weight {
+			t.Errorf("Weights()[%q] = %d, want %d", name, weights[name], weight)
+		}
+	}
+}
+
+func TestQueueConfig_PriorityQueueFor(t *testing.T) {
+	cfg := QueueConfig{
+		Routes: []QueueRoute{{Name: "llm", TaskTypes: []string{"llm:process"}}},
+		PriorityQueues: PriorityQueues{
+			High: PriorityQueue{Name: "llm-high"},
 		},
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := validateQueueConfig(&tt.config)
-			if (err != nil) != tt.wantError {
-				t.Errorf("validateQueueConfig() error = %v, wantError %v", err, tt.wantError)
-			}
-		})
+	tests := map[string]string{
+		PriorityHigh:   "llm-high",
+		PriorityNormal: "llm",
+		"":             "llm",
+		PriorityLow:    "llm", // 未配置 low 队列时使用 normal 的队列
+	}
+	for priority, want := range tests {
+		if got := cfg.PriorityQueueFor("llm:process", priority); got != want {
+			t.Errorf("PriorityQueueFor(%q) = %q, want %q", priority, got, want)
+		}
+	}
+
+	queues := cfg.QueuesFor("llm:process")
+	if len(queues) != 2 || queues[0] != "llm" || queues[1] != "llm-high" {
+		t.Errorf("QueuesFor() = %v, want [llm llm-high]", queues)
+	}
+
+	cfg.PriorityQueues.Low = PriorityQueue{Name: "llm-low", Weight: 1}
+	weights := cfg.Weights()
+	if weights["llm-high"] != DefaultHighPriorityWeight || weights["llm-low"] != 1 {
+		t.Errorf("Weights() = %v, want llm-high=%d and llm-low=1", weights, DefaultHighPriorityWeight)
 	}
 }
 
@@ -866,6 +1984,65 @@ func TestValidateLoggerConfig(t *testing.T) {
 			},
 			wantError: true,
 		},
+		{
+			name: "file with rotation",
+			config: LoggerConfig{
+				Level:      "info",
+				File:       "logs/app.log",
+				MaxSize:    100,
+				MaxBackups: 3,
+				MaxAge:     7,
+			},
+			wantError: false,
+		},
+		{
+			name: "negative max size",
+			config: LoggerConfig{
+				Level:   "info",
+				MaxSize: -1,
+			},
+			wantError: true,
+		},
+		{
+			name: "negative max backups",
+			config: LoggerConfig{
+				Level:      "info",
+				MaxBackups: -1,
+			},
+			wantError: true,
+		},
+		{
+			name: "negative max age",
+			config: LoggerConfig{
+				Level:  "info",
+				MaxAge: -1,
+			},
+			wantError: true,
+		},
+		{
+			name: "sampling enabled",
+			config: LoggerConfig{
+				Level:    "info",
+				Sampling: SamplingConfig{Initial: 100, Thereafter: 100},
+			},
+			wantError: false,
+		},
+		{
+			name: "negative sampling initial",
+			config: LoggerConfig{
+				Level:    "info",
+				Sampling: SamplingConfig{Initial: -1},
+			},
+			wantError: true,
+		},
+		{
+			name: "negative sampling thereafter",
+			config: LoggerConfig{
+				Level:    "info",
+				Sampling: SamplingConfig{Initial: 100, Thereafter: -1},
+			},
+			wantError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -927,6 +2104,29 @@ func TestValidateAuthConfig(t *testing.T) {
 			},
 			wantError: true,
 		},
+		{
+			name: "metrics users configured",
+			config: AuthConfig{
+				Enabled: true,
+				Realm:   "Test Realm",
+				Users: map[string]string{
+					"test": "password",
+				},
+				MetricsUsers: map[string]string{
+					"prometheus": "secret",
+				},
+			},
+			wantError: false,
+		},
+		{
+			name: "metrics users with empty username",
+			config: AuthConfig{
+				MetricsUsers: map[string]string{
+					"": "secret",
+				},
+			},
+			wantError: true,
+		},
 		{
 			name: "auth disabled with no users and empty realm",
 			config: AuthConfig{
@@ -947,3 +2147,286 @@ func TestValidateAuthConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateCallbackConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    CallbackConfig
+		wantError bool
+	}{
+		{
+			name:      "empty dead letter url",
+			config:    CallbackConfig{},
+			wantError: false,
+		},
+		{
+			name: "valid dead letter url",
+			config: CallbackConfig{
+				DeadLetterURL: "https://example.com/dead-letter",
+			},
+			wantError: false,
+		},
+		{
+			name: "invalid dead letter url",
+			config: CallbackConfig{
+				DeadLetterURL: "://invalid-url",
+			},
+			wantError: true,
+		},
+		{
+			name: "unsupported dead letter url scheme",
+			config: CallbackConfig{
+				DeadLetterURL: "ftp://example.com/dead-letter",
+			},
+			wantError: true,
+		},
+		{
+			name: "valid allowed hosts",
+			config: CallbackConfig{
+				AllowedHosts: []string{"example.com", "callback.example.org"},
+			},
+			wantError: false,
+		},
+		{
+			name: "valid blocked cidrs",
+			config: CallbackConfig{
+				BlockedCIDRs: []string{"203.0.114.0/24", "2001:db9::/32"},
+			},
+			wantError: false,
+		},
+		{
+			name: "invalid blocked cidr",
+			config: CallbackConfig{
+				BlockedCIDRs: []string{"10.0.0.1"},
+			},
+			wantError: true,
+		},
+		{
+			name: "empty allowed host entry",
+			config: CallbackConfig{
+				AllowedHosts: []string{"example.com", " "},
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCallbackConfig(&tt.config)
+			if (err != nil) != tt.wantError {
+				t.Errorf("validateCallbackConfig() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestValidateCORSConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    CORSConfig
+		wantError bool
+	}{
+		{
+			name:      "disabled",
+			config:    CORSConfig{},
+			wantError: false,
+		},
+		{
+			name: "valid origins",
+			config: CORSConfig{
+				AllowedOrigins: []string{"https://dashboard.example.com", "http://localhost:3000", "*"},
+				MaxAge:         10 * time.Minute,
+			},
+			wantError: false,
+		},
+		{
+			name: "origin without scheme",
+			config: CORSConfig{
+				AllowedOrigins: []string{"dashboard.example.com"},
+			},
+			wantError: true,
+		},
+		{
+			name: "origin with path",
+			config: CORSConfig{
+				AllowedOrigins: []string{"https://dashboard.example.com/app"},
+			},
+			wantError: true,
+		},
+		{
+			name: "negative max age",
+			config: CORSConfig{
+				AllowedOrigins: []string{"*"},
+				MaxAge:         -1 * time.Second,
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCORSConfig(&tt.config)
+			if (err != nil) != tt.wantError {
+				t.Errorf("validateCORSConfig() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestValidateMetricsConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    MetricsConfig
+		wantError bool
+	}{
+		{
+			name:      "default buckets",
+			config:    MetricsConfig{},
+			wantError: false,
+		},
+		{
+			name: "valid buckets",
+			config: MetricsConfig{
+				LLMLatencyBuckets: []float64{1, 5, 30, 120, 300},
+			},
+			wantError: false,
+		},
+		{
+			name: "non-positive bucket",
+			config: MetricsConfig{
+				LLMLatencyBuckets: []float64{0, 5},
+			},
+			wantError: true,
+		},
+		{
+			name: "unsorted buckets",
+			config: MetricsConfig{
+				LLMLatencyBuckets: []float64{5, 1},
+			},
+			wantError: true,
+		},
+		{
+			name: "duplicate buckets",
+			config: MetricsConfig{
+				LLMLatencyBuckets: []float64{1, 1},
+			},
+			wantError: true,
+		},
+		{
+			name: "valid pushgateway",
+			config: MetricsConfig{
+				Pushgateway:    "http://pushgateway:9091",
+				PushgatewayJob: "syt-go-queue",
+			},
+			wantError: false,
+		},
+		{
+			name: "custom namespace",
+			config: MetricsConfig{
+				Namespace: "tenant_a",
+				Subsystem: "queue",
+			},
+			wantError: false,
+		},
+		{
+			name: "invalid namespace",
+			config: MetricsConfig{
+				Namespace: "tenant-a",
+			},
+			wantError: true,
+		},
+		{
+			name: "subsystem starting with digit",
+			config: MetricsConfig{
+				Subsystem: "1queue",
+			},
+			wantError: true,
+		},
+		{
+			name: "pushgateway without scheme",
+			config: MetricsConfig{
+				Pushgateway: "pushgateway:9091",
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMetricsConfig(&tt.config)
+			if (err != nil) != tt.wantError {
+				t.Errorf("validateMetricsConfig() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestValidateResultStoreConfig(t *testing.T) {
+	validS3 := S3Config{
+		Endpoint:        "https://s3.us-east-1.amazonaws.com",
+		Region:          "us-east-1",
+		Bucket:          "reports",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+	}
+
+	tests := []struct {
+		name      string
+		config    ResultStoreConfig
+		wantError bool
+	}{
+		{
+			name:      "default db store",
+			config:    ResultStoreConfig{},
+			wantError: false,
+		},
+		{
+			name:      "explicit db store",
+			config:    ResultStoreConfig{Type: "db"},
+			wantError: false,
+		},
+		{
+			name:      "valid s3 store",
+			config:    ResultStoreConfig{Type: "s3", MinSize: 4096, S3: validS3},
+			wantError: false,
+		},
+		{
+			name:      "unknown type",
+			config:    ResultStoreConfig{Type: "gcs"},
+			wantError: true,
+		},
+		{
+			name:      "negative min size",
+			config:    ResultStoreConfig{MinSize: -1},
+			wantError: true,
+		},
+		{
+			name:      "s3 without bucket",
+			config:    ResultStoreConfig{Type: "s3", S3: S3Config{Endpoint: validS3.Endpoint, Region: "us-east-1", AccessKeyID: "a", SecretAccessKey: "b"}},
+			wantError: true,
+		},
+		{
+			name:      "s3 without credentials",
+			config:    ResultStoreConfig{Type: "s3", S3: S3Config{Endpoint: validS3.Endpoint, Region: "us-east-1", Bucket: "reports"}},
+			wantError: true,
+		},
+		{
+			name:      "s3 endpoint without scheme",
+			config:    ResultStoreConfig{Type: "s3", S3: S3Config{Endpoint: "minio:9000", Region: "us-east-1", Bucket: "reports", AccessKeyID: "a", SecretAccessKey: "b"}},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateResultStoreConfig(&tt.config)
+			if (err != nil) != tt.wantError {
+				t.Errorf("validateResultStoreConfig() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"fmt"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/igwen6w/syt-go-queue/internal/logger"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"time"
+)
+
+// 连接重试的退避参数
+const (
+	defaultConnectBackoff = time.Second
+	maxConnectBackoff     = 30 * time.Second
+)
+
+// Connect 连接 MySQL 数据库，失败时按指数退避重试
+// 与 sqlx.MustConnect 不同，重试次数耗尽后返回错误而不是 panic，
+// 适用于容器编排环境中数据库短暂不可用的场景。
+//
+// 参数:
+//   - cfg: MySQL 配置，包含 DSN 和连接池参数
+//   - retries: 首次连接失败后的最大重试次数
+//   - backoff: 初始退避时间，每次重试翻倍，最长不超过 30 秒；未设置时默认 1 秒
+//
+// 返回:
+//   - 连接成功的数据库实例
+//   - 重试次数耗尽后仍无法连接时，返回最后一次的错误
+func Connect(cfg config.MySQLConfig, retries int, backoff time.Duration) (*sqlx.DB, error) {
+	if backoff <= 0 {
+		backoff = defaultConnectBackoff
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		db, err := sqlx.Connect("mysql", cfg.DSN)
+		if err == nil {
+			db.SetMaxIdleConns(cfg.MaxIdleConns)
+			db.SetMaxOpenConns(cfg.MaxOpenConns)
+			return db, nil
+		}
+		lastErr = err
+
+		if attempt == retries {
+			break
+		}
+
+		logger.Warn("Failed to connect to database, retrying",
+			zap.Int("attempt", attempt+1),
+			zap.Int("max_retries", retries),
+			zap.Duration("backoff", backoff),
+			zap.Error(err))
+
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxConnectBackoff {
+			backoff = maxConnectBackoff
+		}
+	}
+
+	return nil, fmt.Errorf("failed to connect to database after %d retries: %w", retries, lastErr)
+}
+
+// ConnectReplica 连接 cfg.ReplicaDSN 指定的只读副本，连接池参数和重试策略与主库相同。
+// 未配置只读副本时返回 nil
+func ConnectReplica(cfg config.MySQLConfig, retries int, backoff time.Duration) (*sqlx.DB, error) {
+	if cfg.ReplicaDSN == "" {
+		return nil, nil
+	}
+	cfg.DSN = cfg.ReplicaDSN
+	replica, err := Connect(cfg, retries, backoff)
+	if err != nil {
+		return nil, fmt.Errorf("replica: %w", err)
+	}
+	return replica, nil
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConnect_RetriesExhausted(t *testing.T) {
+	// 使用一个不可达的地址，确保连接失败
+	cfg := config.MySQLConfig{
+		DSN:          "user:pass@tcp(127.0.0.1:1)/db?timeout=100ms",
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+	}
+
+	start := time.Now()
+	db, err := Connect(cfg, 2, 10*time.Millisecond)
+	if err == nil {
+		db.Close()
+		t.Fatal("Connect() expected error for unreachable database, got nil")
+	}
+	if db != nil {
+		t.Error("Connect() expected nil db on failure")
+	}
+
+	// 两次重试的退避时间分别为 10ms 和 20ms
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Connect() returned after %v, expected backoff between retries", elapsed)
+	}
+}
+
+func TestConnectReplica(t *testing.T) {
+	// 未配置只读副本时不连接
+	replica, err := ConnectReplica(config.MySQLConfig{DSN: "user:pass@tcp(127.0.0.1:1)/db"}, 0, time.Millisecond)
+	if err != nil || replica != nil {
+		t.Fatalf("ConnectReplica() without replica_dsn = %v, %v; expected nil, nil", replica, err)
+	}
+
+	// 只读副本不可达时返回错误
+	cfg := config.MySQLConfig{
+		DSN:        "user:pass@tcp(127.0.0.1:1)/db?timeout=100ms",
+		ReplicaDSN: "user:pass@tcp(127.0.0.1:2)/db?timeout=100ms",
+	}
+	replica, err = ConnectReplica(cfg, 0, time.Millisecond)
+	if err == nil {
+		replica.Close()
+		t.Fatal("ConnectReplica() expected error for unreachable replica, got nil")
+	}
+	if !strings.Contains(err.Error(), "replica") {
+		t.Errorf("Expected error to mention the replica, got %v", err)
+	}
+}
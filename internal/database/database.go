@@ -2,15 +2,62 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	_ "github.com/go-sql-driver/mysql" // MySQL 驱动
+	"github.com/go-sql-driver/mysql" // MySQL 驱动
 	"github.com/igwen6w/syt-go-queue/internal/metrics"
 	"github.com/igwen6w/syt-go-queue/internal/utils"
 	"github.com/jmoiron/sqlx"
 	"regexp"
 	"strings"
+	"time"
 )
 
+// ErrRecordAlreadyClaimed 表示记录已被其他工作者认领，正在处理中
+var ErrRecordAlreadyClaimed = errors.New("record already claimed by another worker")
+
+// ErrRecordCompleted 表示记录已处理完成，不需要再次处理
+var ErrRecordCompleted = errors.New("record already completed")
+
+// ErrInvalidTableName 表示表名不合法，重试也不会成功
+var ErrInvalidTableName = errors.New("invalid table name")
+
+// ErrStaleRecord 表示记录在读取后被并发修改，条件更新没有匹配到记录
+var ErrStaleRecord = errors.New("record was modified concurrently")
+
+// ErrClaimInTransaction 表示在事务实例上认领记录。认领必须单独提交，
+// 否则行锁和未提交的处理中状态会一直保留到外层事务结束，如跨越整个 LLM 调用
+var ErrClaimInTransaction = errors.New("record must not be claimed inside a transaction")
+
+// ErrInvalidUpdate 表示要更新的字段不合法，如 id 是更新的条件，不能被更新
+var ErrInvalidUpdate = errors.New("invalid update")
+
+// mysqlErrLockNowait MySQL 在 NOWAIT 锁定失败时返回的错误码
+const mysqlErrLockNowait = 3572
+
+// ProcessingStartedColumn 记录认领时间的列。启用认领时间后，认领记录时会同时写入该列，
+// 表中需要有同名的 DATETIME 列，用于找出工作者崩溃后一直停留在处理中的记录
+const ProcessingStartedColumn = "processing_started_at"
+
+// 查询目标，用于区分指标中主库和只读副本的查询
+const (
+	targetPrimary = "primary"
+	targetReplica = "replica"
+)
+
+// DefaultQueryTimeout 调用方的 context 没有截止时间时，单次数据库操作的默认超时时间
+const DefaultQueryTimeout = 10 * time.Second
+
+// maxIDsPerQuery 批量查询时单条 IN 语句的最大ID数量，避免超过 MySQL 的占位符上限
+const maxIDsPerQuery = 1000
+
+// recordFields 评估记录的逻辑字段，与 ValuationRecord 的 db 标签一致
+var recordFields = []string{
+	"id", "status", "user_message", "sys_message", "report",
+	"failed_times", "failed_info", "progress", "progress_info", "current_task_node", "callback_url",
+}
+
 type ValuationRecord struct {
 	ID              int64  `db:"id"`
 	Status          string `db:"status"`
@@ -26,25 +73,185 @@ type ValuationRecord struct {
 }
 
 type Database struct {
-	db *sqlx.DB
+	db      *sqlx.DB
+	replica *sqlx.DB          // 只读副本，为 nil 时读取也使用主库
+	tx      *sqlx.Tx          // 非空时表示绑定到事务的实例
+	columns map[string]string // 逻辑字段名到实际列名的映射，未映射的字段使用逻辑字段名
+	// 认领记录时是否写入 ProcessingStartedColumn
+	claimTime bool
+	// 调用方的 context 没有截止时间时单次操作的超时时间，为 0 时不设置超时
+	queryTimeout time.Duration
 }
 
 func NewDatabase(db *sqlx.DB) *Database {
-	return &Database{db: db}
+	return NewDatabaseWithReplica(db, nil)
+}
+
+// NewDatabaseWithReplica 创建读写分离的实例，记录查询（GetValuationRecord、GetValuationRecords）
+// 使用只读副本，写入、认领和事务内的查询使用主库。replica 为 nil 时全部使用主库。
+// 操作的超时时间默认为 DefaultQueryTimeout，可通过 WithQueryTimeout 修改
+func NewDatabaseWithReplica(db, replica *sqlx.DB) *Database {
+	return &Database{db: db, replica: replica, queryTimeout: DefaultQueryTimeout}
+}
+
+// WithColumns 返回使用自定义列名映射的实例，用于对接列名不同的已有表结构。
+// columns 的键是逻辑字段名（如 user_message），值是表中的实际列名（如 prompt）。
+// 键不是已知字段或列名不合法时返回错误
+func (d *Database) WithColumns(columns map[string]string) (*Database, error) {
+	mapped := make(map[string]string, len(columns))
+	for field, column := range columns {
+		if !isRecordField(field) {
+			return nil, fmt.Errorf("unknown record field: %s", field)
+		}
+		if err := validateFieldName(column); err != nil {
+			return nil, fmt.Errorf("column for %s: %w", field, err)
+		}
+		mapped[field] = column
+	}
+	return &Database{db: d.db, replica: d.replica, tx: d.tx, columns: mapped, claimTime: d.claimTime, queryTimeout: d.queryTimeout}, nil
+}
+
+// WithClaimTime 返回认领记录时同时将当前时间写入 ProcessingStartedColumn 的实例
+func (d *Database) WithClaimTime() *Database {
+	return &Database{db: d.db, replica: d.replica, tx: d.tx, columns: d.columns, claimTime: true, queryTimeout: d.queryTimeout}
+}
+
+// WithQueryTimeout 返回修改了操作超时时间的实例。调用方传入的 context 没有截止时间时，
+// 每次操作（包括 ClaimRecord 的整个认领事务）最多执行 timeout，避免 MySQL 无响应时一直阻塞。
+// timeout 为 0 时使用 DefaultQueryTimeout，为负数时不设置超时
+func (d *Database) WithQueryTimeout(timeout time.Duration) *Database {
+	if timeout == 0 {
+		timeout = DefaultQueryTimeout
+	} else if timeout < 0 {
+		timeout = 0
+	}
+	return &Database{db: d.db, replica: d.replica, tx: d.tx, columns: d.columns, claimTime: d.claimTime, queryTimeout: timeout}
+}
+
+// withTimeout 在 ctx 没有截止时间时为其加上 queryTimeout 的超时，已有截止时间或未设置超时时原样返回
+func (d *Database) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.queryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d.queryTimeout)
+}
+
+// isRecordField 判断是否为评估记录的逻辑字段
+func isRecordField(field string) bool {
+	for _, f := range recordFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// column 返回逻辑字段对应的实际列名
+func (d *Database) column(field string) string {
+	if column, ok := d.columns[field]; ok {
+		return column
+	}
+	return field
+}
+
+// selectColumns 返回查询评估记录的字段列表，映射过的列使用别名还原为逻辑字段名
+func (d *Database) selectColumns() string {
+	columns := make([]string, len(recordFields))
+	for i, field := range recordFields {
+		if column := d.column(field); column != field {
+			columns[i] = fmt.Sprintf("%s AS %s", column, field)
+		} else {
+			columns[i] = field
+		}
+	}
+	return strings.Join(columns, ", ")
+}
+
+// ext 返回当前实例使用的执行器，事务实例使用事务，否则使用连接池
+func (d *Database) ext() sqlx.ExtContext {
+	if d.tx != nil {
+		return d.tx
+	}
+	return d.db
+}
+
+// reader 返回只读查询使用的执行器和查询目标。
+// 事务实例使用事务以读取事务内的修改，配置了只读副本时使用副本，否则使用主库
+func (d *Database) reader() (sqlx.ExtContext, string) {
+	if d.tx == nil && d.replica != nil {
+		return d.replica, targetReplica
+	}
+	return d.ext(), targetPrimary
 }
 
-// Ping 检查数据库连接是否正常
+// WithTx 在事务中执行 fn
+// fn 接收一个绑定到事务的 Database 实例，其所有方法均在同一事务内执行。
+// fn 返回错误或发生 panic 时回滚事务，否则提交事务。
+// 如果当前实例已绑定事务，则直接在该事务中执行 fn。
+// 事务本身不受 queryTimeout 限制，事务内的每次操作各自应用超时
+func (d *Database) WithTx(ctx context.Context, fn func(tx *Database) error) error {
+	if d.tx != nil {
+		return fn(d)
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(&Database{db: d.db, tx: tx, columns: d.columns, claimTime: d.claimTime, queryTimeout: d.queryTimeout}); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Ping 检查数据库连接是否正常，配置了只读副本时同时检查副本
 // 返回错误表示连接失败
 func (d *Database) Ping() error {
-	return d.db.Ping()
+	return d.PingContext(context.Background())
+}
+
+// PingContext 与 Ping 相同，但在 ctx 取消或超时时立即返回，
+// 用于就绪检查等不能长时间阻塞的场景
+func (d *Database) PingContext(ctx context.Context) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	if err := d.db.PingContext(ctx); err != nil {
+		return err
+	}
+	if d.replica != nil {
+		if err := d.replica.PingContext(ctx); err != nil {
+			return fmt.Errorf("replica: %w", err)
+		}
+	}
+	return nil
 }
 
-// validateTableName 验证表名是否合法，防止SQL注入
-func validateTableName(tableName string) error {
+// ValidateTableName 验证表名是否合法，防止SQL注入。
+// API 层在入队前使用同样的校验，避免不合法的表名在处理任务时才失败
+func ValidateTableName(tableName string) error {
 	// 只允许字母、数字、下划线和特定前缀
 	validTableNameRegex := regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
 	if !validTableNameRegex.MatchString(tableName) {
-		return fmt.Errorf("invalid table name: %s", tableName)
+		return fmt.Errorf("%w: %s", ErrInvalidTableName, tableName)
 	}
 
 	// 防止使用保留关键字作为表名
@@ -52,7 +259,7 @@ func validateTableName(tableName string) error {
 	tableNameLower := strings.ToLower(tableName)
 	for _, keyword := range reservedKeywords {
 		if tableNameLower == keyword {
-			return fmt.Errorf("table name cannot be a reserved keyword: %s", tableName)
+			return fmt.Errorf("%w: table name cannot be a reserved keyword: %s", ErrInvalidTableName, tableName)
 		}
 	}
 
@@ -85,118 +292,389 @@ func (d *Database) GetValuationRecord(ctx context.Context, tableName string, id
 	// 记录数据库查询指标并计时
 	defer metrics.MeasureDatabaseQueryDuration("get_record")()
 
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	reader, target := d.reader()
+
 	// 验证表名
-	if err := validateTableName(tableName); err != nil {
-		metrics.DatabaseQueryCounter.WithLabelValues("get_record", "validation_error").Inc()
+	if err := ValidateTableName(tableName); err != nil {
+		metrics.DatabaseQueryCounter.WithLabelValues("get_record", "validation_error", target).Inc()
 		return nil, err
 	}
 
 	query := fmt.Sprintf(`
-        SELECT id, status, user_message, sys_message, report,
-               failed_times, failed_info, progress, progress_info, current_task_node, callback_url
-        FROM %s WHERE id = ?`, tableName)
+        SELECT %s
+        FROM %s WHERE %s = ?`, d.selectColumns(), tableName, d.column("id"))
 
 	var record ValuationRecord
-	err := d.db.GetContext(ctx, &record, query, id)
+	err := sqlx.GetContext(ctx, reader, &record, query, id)
 	if err != nil {
-		metrics.DatabaseQueryCounter.WithLabelValues("get_record", "error").Inc()
+		metrics.DatabaseQueryCounter.WithLabelValues("get_record", "error", target).Inc()
 		return nil, fmt.Errorf("failed to get valuation record: %w", err)
 	}
 
 	// 记录成功查询
-	metrics.DatabaseQueryCounter.WithLabelValues("get_record", "success").Inc()
+	metrics.DatabaseQueryCounter.WithLabelValues("get_record", "success", target).Inc()
 	return &record, nil
 }
 
+// GetValuationRecords 批量获取评估记录，返回以记录ID为键的映射。
+// 使用 IN 查询减少数据库往返，ID 数量超过 maxIDsPerQuery 时分批查询。
+// 不存在的ID不会出现在结果中，ids 为空时不查询数据库
+func (d *Database) GetValuationRecords(ctx context.Context, tableName string, ids []int64) (map[int64]*ValuationRecord, error) {
+	// 记录数据库查询指标并计时
+	defer metrics.MeasureDatabaseQueryDuration("get_records")()
+
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	reader, target := d.reader()
+
+	// 验证表名
+	if err := ValidateTableName(tableName); err != nil {
+		metrics.DatabaseQueryCounter.WithLabelValues("get_records", "validation_error", target).Inc()
+		return nil, err
+	}
+
+	records := make(map[int64]*ValuationRecord, len(ids))
+	if len(ids) == 0 {
+		return records, nil
+	}
+
+	for _, chunk := range chunkIDs(ids, maxIDsPerQuery) {
+		query, args, err := sqlx.In(fmt.Sprintf(`
+        SELECT %s
+        FROM %s WHERE %s IN (?)`, d.selectColumns(), tableName, d.column("id")), chunk)
+		if err != nil {
+			metrics.DatabaseQueryCounter.WithLabelValues("get_records", "error", target).Inc()
+			return nil, fmt.Errorf("failed to build valuation records query: %w", err)
+		}
+
+		var rows []ValuationRecord
+		if err := sqlx.SelectContext(ctx, reader, &rows, reader.Rebind(query), args...); err != nil {
+			metrics.DatabaseQueryCounter.WithLabelValues("get_records", "error", target).Inc()
+			return nil, fmt.Errorf("failed to get valuation records: %w", err)
+		}
+
+		for i := range rows {
+			records[rows[i].ID] = &rows[i]
+		}
+	}
+
+	// 记录成功查询
+	metrics.DatabaseQueryCounter.WithLabelValues("get_records", "success", target).Inc()
+	return records, nil
+}
+
+// chunkIDs 将ID切分为每批最多 size 个
+func chunkIDs(ids []int64, size int) [][]int64 {
+	var chunks [][]int64
+	for start := 0; start < len(ids); start += size {
+		end := start + size
+		if end > len(ids) {
+			end = len(ids)
+		}
+		chunks = append(chunks, ids[start:end])
+	}
+	return chunks
+}
+
+// ClaimRecord 认领评估记录
+// 使用 SELECT ... FOR UPDATE NOWAIT 锁定记录（需要 MySQL 8.0 及以上版本），并原子地将状态更新为处理中，
+// 防止同一记录被多个工作者并发处理。
+// 如果记录已处于处理中状态或已被其他事务锁定，返回 ErrRecordAlreadyClaimed。
+// completedStatus 不为空且记录已处于该状态时不认领，返回 ErrRecordCompleted。
+// 返回的记录保留认领前的状态，便于任务出错或取消时恢复。
+// 启用认领时间（WithClaimTime）时同时写入 ProcessingStartedColumn。
+// 认领在独立事务中完成并立即提交，在事务实例上调用时返回 ErrClaimInTransaction
+func (d *Database) ClaimRecord(ctx context.Context, tableName string, id int64, processingStatus, completedStatus string) (*ValuationRecord, error) {
+	// 记录数据库查询指标并计时
+	defer metrics.MeasureDatabaseQueryDuration("claim_record")()
+
+	if d.tx != nil {
+		return nil, ErrClaimInTransaction
+	}
+
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	// 验证表名
+	if err := ValidateTableName(tableName); err != nil {
+		metrics.DatabaseQueryCounter.WithLabelValues("claim_record", "validation_error", targetPrimary).Inc()
+		return nil, err
+	}
+
+	var record ValuationRecord
+	err := d.WithTx(ctx, func(tx *Database) error {
+		query := fmt.Sprintf(`
+        SELECT %s
+        FROM %s WHERE %s = ? FOR UPDATE NOWAIT`, tx.selectColumns(), tableName, tx.column("id"))
+
+		if err := sqlx.GetContext(ctx, tx.ext(), &record, query, id); err != nil {
+			// 记录已被其他事务锁定
+			var mysqlErr *mysql.MySQLError
+			if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrLockNowait {
+				return ErrRecordAlreadyClaimed
+			}
+			return fmt.Errorf("failed to get valuation record: %w", err)
+		}
+
+		// 已被其他工作者认领
+		if record.Status == processingStatus {
+			return ErrRecordAlreadyClaimed
+		}
+
+		// 已处理完成，例如重复或重试的任务
+		if completedStatus != "" && record.Status == completedStatus {
+			return ErrRecordCompleted
+		}
+
+		setClause := fmt.Sprintf("%s = ?", tx.column("status"))
+		args := []interface{}{processingStatus}
+		if tx.claimTime {
+			setClause += fmt.Sprintf(", %s = ?", ProcessingStartedColumn)
+			args = append(args, time.Now())
+		}
+		args = append(args, id)
+
+		updateQuery := fmt.Sprintf("UPDATE %s SET %s WHERE %s = ?", tableName, setClause, tx.column("id"))
+		if _, err := tx.ext().ExecContext(ctx, updateQuery, args...); err != nil {
+			return fmt.Errorf("failed to update status: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrRecordAlreadyClaimed) {
+			metrics.DatabaseQueryCounter.WithLabelValues("claim_record", "already_claimed", targetPrimary).Inc()
+		} else if errors.Is(err, ErrRecordCompleted) {
+			metrics.DatabaseQueryCounter.WithLabelValues("claim_record", "already_completed", targetPrimary).Inc()
+		} else {
+			metrics.DatabaseQueryCounter.WithLabelValues("claim_record", "error", targetPrimary).Inc()
+		}
+		return nil, err
+	}
+
+	// 记录成功认领
+	metrics.DatabaseQueryCounter.WithLabelValues("claim_record", "success", targetPrimary).Inc()
+	return &record, nil
+}
+
+// ListStuckRecordIDs 返回状态为 processingStatus 且认领时间早于 before 的记录ID，按认领时间排序，最多 limit 条。
+// 认领时间为空的记录（如启用认领时间之前认领的记录）不会返回
+func (d *Database) ListStuckRecordIDs(ctx context.Context, tableName, processingStatus string, before time.Time, limit int) ([]int64, error) {
+	// 记录数据库查询指标并计时
+	defer metrics.MeasureDatabaseQueryDuration("list_stuck_records")()
+
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	// 验证表名
+	if err := ValidateTableName(tableName); err != nil {
+		metrics.DatabaseQueryCounter.WithLabelValues("list_stuck_records", "validation_error", targetPrimary).Inc()
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = ? AND %s < ? ORDER BY %s LIMIT ?",
+		d.column("id"), tableName, d.column("status"), ProcessingStartedColumn, ProcessingStartedColumn)
+
+	var ids []int64
+	if err := sqlx.SelectContext(ctx, d.ext(), &ids, query, processingStatus, before, limit); err != nil {
+		metrics.DatabaseQueryCounter.WithLabelValues("list_stuck_records", "error", targetPrimary).Inc()
+		return nil, fmt.Errorf("failed to list stuck records: %w", err)
+	}
+
+	// 记录成功查询
+	metrics.DatabaseQueryCounter.WithLabelValues("list_stuck_records", "success", targetPrimary).Inc()
+	return ids, nil
+}
+
+// CountRecordsByStatus 按状态统计表中的记录数，返回以状态为键的映射。
+// 状态为 NULL 的记录计入空字符串。配置了只读副本时在副本上查询
+func (d *Database) CountRecordsByStatus(ctx context.Context, tableName string) (map[string]int64, error) {
+	// 记录数据库查询指标并计时
+	defer metrics.MeasureDatabaseQueryDuration("count_by_status")()
+
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	reader, target := d.reader()
+
+	// 验证表名
+	if err := ValidateTableName(tableName); err != nil {
+		metrics.DatabaseQueryCounter.WithLabelValues("count_by_status", "validation_error", target).Inc()
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT %s AS status, COUNT(*) AS total FROM %s GROUP BY %s",
+		d.column("status"), tableName, d.column("status"))
+
+	var rows []struct {
+		Status sql.NullString `db:"status"`
+		Total  int64          `db:"total"`
+	}
+	if err := sqlx.SelectContext(ctx, reader, &rows, query); err != nil {
+		metrics.DatabaseQueryCounter.WithLabelValues("count_by_status", "error", target).Inc()
+		return nil, fmt.Errorf("failed to count records by status: %w", err)
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status.String] += row.Total
+	}
+
+	// 记录成功查询
+	metrics.DatabaseQueryCounter.WithLabelValues("count_by_status", "success", target).Inc()
+	return counts, nil
+}
+
 // UpdateStatus 更新状态
 func (d *Database) UpdateStatus(ctx context.Context, tableName string, id int64, status string) error {
 	// 记录数据库更新指标并计时
 	defer metrics.MeasureDatabaseQueryDuration("update_status")()
 
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
 	// 验证表名
-	if err := validateTableName(tableName); err != nil {
-		metrics.DatabaseQueryCounter.WithLabelValues("update_status", "validation_error").Inc()
+	if err := ValidateTableName(tableName); err != nil {
+		metrics.DatabaseQueryCounter.WithLabelValues("update_status", "validation_error", targetPrimary).Inc()
 		return err
 	}
 
-	query := fmt.Sprintf("UPDATE %s SET status = ? WHERE id = ?", tableName)
-	_, err := d.db.ExecContext(ctx, query, status, id)
+	query := fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", tableName, d.column("status"), d.column("id"))
+	_, err := d.ext().ExecContext(ctx, query, status, id)
 	if err != nil {
-		metrics.DatabaseQueryCounter.WithLabelValues("update_status", "error").Inc()
+		metrics.DatabaseQueryCounter.WithLabelValues("update_status", "error", targetPrimary).Inc()
 		return fmt.Errorf("failed to update status: %w", err)
 	}
 
 	// 记录成功更新
-	metrics.DatabaseQueryCounter.WithLabelValues("update_status", "success").Inc()
+	metrics.DatabaseQueryCounter.WithLabelValues("update_status", "success", targetPrimary).Inc()
 	return nil
 }
 
 // UpdateFailedInfo 更新失败信息
 func (d *Database) UpdateFailedInfo(ctx context.Context, tableName string, id int64, failedInfo string, failedTimes int) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
 	// 验证表名
-	if err := validateTableName(tableName); err != nil {
+	if err := ValidateTableName(tableName); err != nil {
 		return err
 	}
 
-	query := fmt.Sprintf("UPDATE %s SET failed_info = ?, failed_times = ? WHERE id = ?", tableName)
-	_, err := d.db.ExecContext(ctx, query, failedInfo, failedTimes, id)
+	query := fmt.Sprintf("UPDATE %s SET %s = ?, %s = ? WHERE %s = ?",
+		tableName, d.column("failed_info"), d.column("failed_times"), d.column("id"))
+	_, err := d.ext().ExecContext(ctx, query, failedInfo, failedTimes, id)
 	if err != nil {
 		return fmt.Errorf("failed to update failed info: %w", err)
 	}
 	return nil
 }
 
-// UpdateRecord 更新记录
+// UpdateRecord 更新记录，updates 为空时不执行任何操作，包含 id 时返回 ErrInvalidUpdate
 func (d *Database) UpdateRecord(ctx context.Context, tableName string, id int64, updates map[string]interface{}) error {
+	_, err := d.updateRecord(ctx, "update_record", tableName, id, nil, updates)
+	return err
+}
+
+// UpdateRecordIf 在 conditions 中的字段值与数据库一致时更新记录，返回受影响的行数。
+// 用于乐观锁：以读取时的字段值作为条件，返回 0 表示记录已被并发修改（或已不存在）。
+// 注意 MySQL 默认只统计实际发生变化的行，updates 应至少修改一个字段的值；updates 为空时不执行更新，返回 0
+func (d *Database) UpdateRecordIf(ctx context.Context, tableName string, id int64, conditions, updates map[string]interface{}) (int64, error) {
+	return d.updateRecord(ctx, "update_record_if", tableName, id, conditions, updates)
+}
+
+// updateRecord 构建并执行 UPDATE 语句，conditions 作为附加的 WHERE 条件
+func (d *Database) updateRecord(ctx context.Context, operation, tableName string, id int64, conditions, updates map[string]interface{}) (int64, error) {
 	// 记录数据库更新指标并计时
-	defer metrics.MeasureDatabaseQueryDuration("update_record")()
+	defer metrics.MeasureDatabaseQueryDuration(operation)()
+
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
 
 	// 验证表名
-	if err := validateTableName(tableName); err != nil {
-		metrics.DatabaseQueryCounter.WithLabelValues("update_record", "validation_error").Inc()
-		return err
+	if err := ValidateTableName(tableName); err != nil {
+		metrics.DatabaseQueryCounter.WithLabelValues(operation, "validation_error", targetPrimary).Inc()
+		return 0, err
+	}
+
+	// 没有要更新的字段时不执行语句，避免生成 SET 子句为空的非法 SQL
+	if len(updates) == 0 {
+		metrics.DatabaseQueryCounter.WithLabelValues(operation, "noop", targetPrimary).Inc()
+		return 0, nil
 	}
 
 	// 验证字段名
 	for field, value := range updates {
 		if err := validateFieldName(field); err != nil {
-			metrics.DatabaseQueryCounter.WithLabelValues("update_record", "field_validation_error").Inc()
-			return err
+			metrics.DatabaseQueryCounter.WithLabelValues(operation, "field_validation_error", targetPrimary).Inc()
+			return 0, err
+		}
+
+		// id 是 WHERE 条件，不能被更新
+		if field == "id" {
+			metrics.DatabaseQueryCounter.WithLabelValues(operation, "field_validation_error", targetPrimary).Inc()
+			return 0, fmt.Errorf("%w: id cannot be updated", ErrInvalidUpdate)
 		}
 
 		// 如果更新的是回调URL，验证URL是否安全
 		if field == "callback_url" {
 			if callbackURL, ok := value.(string); ok && callbackURL != "" {
 				if err := utils.ValidateCallbackURL(callbackURL); err != nil {
-					metrics.DatabaseQueryCounter.WithLabelValues("update_record", "url_validation_error").Inc()
-					return fmt.Errorf("invalid callback URL: %w", err)
+					metrics.DatabaseQueryCounter.WithLabelValues(operation, "url_validation_error", targetPrimary).Inc()
+					return 0, fmt.Errorf("invalid callback URL: %w", err)
 				}
 			}
 		}
 	}
+	for field := range conditions {
+		if err := validateFieldName(field); err != nil {
+			metrics.DatabaseQueryCounter.WithLabelValues(operation, "field_validation_error", targetPrimary).Inc()
+			return 0, err
+		}
+	}
+
+	query, args := d.buildUpdate(tableName, id, conditions, updates)
+	result, err := d.ext().ExecContext(ctx, query, args...)
+	if err != nil {
+		metrics.DatabaseQueryCounter.WithLabelValues(operation, "error", targetPrimary).Inc()
+		return 0, fmt.Errorf("failed to update record: %w", err)
+	}
 
-	// 构建 UPDATE 语句
+	rows, err := result.RowsAffected()
+	if err != nil {
+		metrics.DatabaseQueryCounter.WithLabelValues(operation, "error", targetPrimary).Inc()
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	// 记录成功更新
+	metrics.DatabaseQueryCounter.WithLabelValues(operation, "success", targetPrimary).Inc()
+	return rows, nil
+}
+
+// buildUpdate 构建 UPDATE 语句及其参数，表名和字段名需已经过验证，updates 不能为空
+func (d *Database) buildUpdate(tableName string, id int64, conditions, updates map[string]interface{}) (string, []interface{}) {
 	var setClauses []string
 	var args []interface{}
 
 	for field, value := range updates {
-		setClauses = append(setClauses, fmt.Sprintf("%s = ?", field))
+		setClauses = append(setClauses, fmt.Sprintf("%s = ?", d.column(field)))
 		args = append(args, value)
 	}
 
-	query := fmt.Sprintf("UPDATE %s SET %s WHERE id = ?",
-		tableName,
-		strings.Join(setClauses, ", "))
-
+	whereClauses := []string{fmt.Sprintf("%s = ?", d.column("id"))}
 	args = append(args, id)
 
-	_, err := d.db.ExecContext(ctx, query, args...)
-	if err != nil {
-		metrics.DatabaseQueryCounter.WithLabelValues("update_record", "error").Inc()
-		return fmt.Errorf("failed to update record: %w", err)
+	for field, value := range conditions {
+		whereClauses = append(whereClauses, fmt.Sprintf("%s = ?", d.column(field)))
+		args = append(args, value)
 	}
 
-	// 记录成功更新
-	metrics.DatabaseQueryCounter.WithLabelValues("update_record", "success").Inc()
-	return nil
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+		tableName,
+		strings.Join(setClauses, ", "),
+		strings.Join(whereClauses, " AND "))
+	return query, args
 }
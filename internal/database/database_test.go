@@ -0,0 +1,344 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithColumns(t *testing.T) {
+	tests := []struct {
+		name      string
+		columns   map[string]string
+		wantError bool
+	}{
+		{
+			name:    "no mapping",
+			columns: nil,
+		},
+		{
+			name: "valid mapping",
+			columns: map[string]string{
+				"user_message": "prompt",
+				"sys_message":  "system_prompt",
+			},
+		},
+		{
+			name: "unknown field",
+			columns: map[string]string{
+				"prompt": "user_message",
+			},
+			wantError: true,
+		},
+		{
+			name: "invalid column name",
+			columns: map[string]string{
+				"user_message": "prompt; DROP TABLE x",
+			},
+			wantError: true,
+		},
+		{
+			name: "reserved keyword",
+			columns: map[string]string{
+				"status": "select",
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewDatabase(nil).WithColumns(tt.columns)
+			if (err != nil) != tt.wantError {
+				t.Errorf("WithColumns() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestSelectColumns(t *testing.T) {
+	// 未配置映射时使用逻辑字段名
+	d := NewDatabase(nil)
+	if got, want := d.selectColumns(), strings.Join(recordFields, ", "); got != want {
+		t.Errorf("selectColumns() = %q, want %q", got, want)
+	}
+
+	d, err := d.WithColumns(map[string]string{"user_message": "prompt"})
+	if err != nil {
+		t.Fatalf("WithColumns() returned error: %v", err)
+	}
+
+	// 映射过的列使用别名还原为逻辑字段名
+	got := d.selectColumns()
+	if !strings.Contains(got, "prompt AS user_message") {
+		t.Errorf("selectColumns() = %q, want it to contain %q", got, "prompt AS user_message")
+	}
+	if !strings.Contains(got, "sys_message") {
+		t.Errorf("selectColumns() = %q, want unmapped fields to be kept", got)
+	}
+
+	if got := d.column("user_message"); got != "prompt" {
+		t.Errorf("column(user_message) = %q, want %q", got, "prompt")
+	}
+	if got := d.column("status"); got != "status" {
+		t.Errorf("column(status) = %q, want %q", got, "status")
+	}
+}
+
+func TestClaimRecord_InTransaction(t *testing.T) {
+	// 事务实例上认领时直接返回错误，不访问数据库
+	d := &Database{tx: &sqlx.Tx{}}
+
+	if _, err := d.ClaimRecord(context.Background(), "valuation_records", 1, "处理中", "已完成"); !errors.Is(err, ErrClaimInTransaction) {
+		t.Errorf("Expected ErrClaimInTransaction, got %v", err)
+	}
+}
+
+func TestGetValuationRecords_NoQuery(t *testing.T) {
+	// 空ID列表和非法表名都不会访问数据库
+	d := NewDatabase(nil)
+
+	records, err := d.GetValuationRecords(context.Background(), "valuation_records", nil)
+	if err != nil {
+		t.Fatalf("GetValuationRecords() with empty ids returned error: %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("Expected no records, got %d", len(records))
+	}
+
+	if _, err := d.GetValuationRecords(context.Background(), "records; DROP TABLE x", []int64{1}); !errors.Is(err, ErrInvalidTableName) {
+		t.Errorf("Expected ErrInvalidTableName for invalid table name, got %v", err)
+	}
+	if _, err := d.GetValuationRecords(context.Background(), "select", []int64{1}); !errors.Is(err, ErrInvalidTableName) {
+		t.Errorf("Expected ErrInvalidTableName for reserved keyword, got %v", err)
+	}
+}
+
+func TestWithClaimTime(t *testing.T) {
+	d, err := NewDatabase(nil).WithColumns(map[string]string{"status": "state"})
+	if err != nil {
+		t.Fatalf("WithColumns() returned error: %v", err)
+	}
+
+	claiming := d.WithClaimTime()
+	if !claiming.claimTime {
+		t.Error("Expected claim time to be enabled")
+	}
+	if claiming.column("status") != "state" {
+		t.Errorf("Expected column mapping to be kept, got %s", claiming.column("status"))
+	}
+
+	// 非法表名不会访问数据库
+	if _, err := claiming.ListStuckRecordIDs(context.Background(), "records; DROP TABLE x", "处理中", time.Now(), 10); err == nil {
+		t.Error("Expected error for invalid table name")
+	}
+}
+
+func TestWithQueryTimeout(t *testing.T) {
+	d := NewDatabase(nil)
+	if d.queryTimeout != DefaultQueryTimeout {
+		t.Errorf("Expected default query timeout %v, got %v", DefaultQueryTimeout, d.queryTimeout)
+	}
+
+	tests := []struct {
+		timeout time.Duration
+		want    time.Duration
+	}{
+		{0, DefaultQueryTimeout},
+		{time.Second, time.Second},
+		{-1, 0},
+	}
+	for _, tt := range tests {
+		if got := d.WithQueryTimeout(tt.timeout).queryTimeout; got != tt.want {
+			t.Errorf("WithQueryTimeout(%v) = %v, want %v", tt.timeout, got, tt.want)
+		}
+	}
+
+	// 其他选项保留超时时间
+	mapped, err := d.WithQueryTimeout(time.Second).WithColumns(map[string]string{"status": "state"})
+	if err != nil {
+		t.Fatalf("WithColumns() returned error: %v", err)
+	}
+	if got := mapped.WithClaimTime().queryTimeout; got != time.Second {
+		t.Errorf("Expected query timeout to be kept, got %v", got)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	d := NewDatabase(nil).WithQueryTimeout(time.Second)
+
+	// 没有截止时间时加上超时
+	ctx, cancel := d.withTimeout(context.Background())
+	deadline, ok := ctx.Deadline()
+	cancel()
+	if !ok {
+		t.Fatal("Expected a deadline to be set")
+	}
+	if remaining := time.Until(deadline); remaining > time.Second {
+		t.Errorf("Expected deadline within 1s, got %v", remaining)
+	}
+
+	// 已有截止时间时保持调用方的截止时间
+	parent, parentCancel := context.WithTimeout(context.Background(), time.Minute)
+	defer parentCancel()
+	ctx, cancel = d.withTimeout(parent)
+	defer cancel()
+	if ctx != parent {
+		t.Error("Expected the caller's context to be kept when it has a deadline")
+	}
+
+	// 不限制时原样返回
+	ctx, cancel = d.WithQueryTimeout(-1).withTimeout(context.Background())
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("Expected no deadline when the query timeout is disabled")
+	}
+}
+
+func TestReader(t *testing.T) {
+	primary, err := sqlx.Open("mysql", "user:pass@tcp(127.0.0.1:1)/primary")
+	if err != nil {
+		t.Fatalf("Failed to open primary: %v", err)
+	}
+	defer primary.Close()
+	replica, err := sqlx.Open("mysql", "user:pass@tcp(127.0.0.1:1)/replica")
+	if err != nil {
+		t.Fatalf("Failed to open replica: %v", err)
+	}
+	defer replica.Close()
+
+	// 未配置只读副本时读取使用主库
+	if reader, target := NewDatabase(primary).reader(); reader != primary || target != targetPrimary {
+		t.Errorf("Expected reads to use the primary without a replica, got %s", target)
+	}
+
+	// 配置只读副本后读取使用副本，列名映射等派生实例保留副本
+	d, err := NewDatabaseWithReplica(primary, replica).WithColumns(map[string]string{"status": "state"})
+	if err != nil {
+		t.Fatalf("WithColumns() returned error: %v", err)
+	}
+	if reader, target := d.WithClaimTime().reader(); reader != replica || target != targetReplica {
+		t.Errorf("Expected reads to use the replica, got %s", target)
+	}
+
+	// 写入仍使用主库
+	if d.ext() != primary {
+		t.Error("Expected writes to use the primary")
+	}
+}
+
+func TestChunkIDs(t *testing.T) {
+	// 构造超过单批上限的ID列表
+	ids := make([]int64, 2*maxIDsPerQuery+1)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+
+	tests := []struct {
+		name       string
+		ids        []int64
+		wantChunks []int
+	}{
+		{
+			name:       "empty",
+			ids:        nil,
+			wantChunks: nil,
+		},
+		{
+			name:       "single chunk",
+			ids:        ids[:10],
+			wantChunks: []int{10},
+		},
+		{
+			name:       "exact limit",
+			ids:        ids[:maxIDsPerQuery],
+			wantChunks: []int{maxIDsPerQuery},
+		},
+		{
+			name:       "large slice",
+			ids:        ids,
+			wantChunks: []int{maxIDsPerQuery, maxIDsPerQuery, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := chunkIDs(tt.ids, maxIDsPerQuery)
+			if len(chunks) != len(tt.wantChunks) {
+				t.Fatalf("chunkIDs() returned %d chunks, want %d", len(chunks), len(tt.wantChunks))
+			}
+
+			var total int
+			for i, chunk := range chunks {
+				if len(chunk) != tt.wantChunks[i] {
+					t.Errorf("chunk %d has %d ids, want %d", i, len(chunk), tt.wantChunks[i])
+				}
+				// 分批后保持原有顺序
+				if len(chunk) > 0 && chunk[0] != tt.ids[total] {
+					t.Errorf("chunk %d starts with %d, want %d", i, chunk[0], tt.ids[total])
+				}
+				total += len(chunk)
+			}
+			if total != len(tt.ids) {
+				t.Errorf("chunks contain %d ids, want %d", total, len(tt.ids))
+			}
+		})
+	}
+}
+
+func TestUpdateRecord_EmptyUpdates(t *testing.T) {
+	// 没有要更新的字段时不访问数据库
+	d := NewDatabase(nil)
+
+	if err := d.UpdateRecord(context.Background(), "valuation_records", 1, nil); err != nil {
+		t.Errorf("UpdateRecord() with nil updates returned error: %v", err)
+	}
+	if err := d.UpdateRecord(context.Background(), "valuation_records", 1, map[string]interface{}{}); err != nil {
+		t.Errorf("UpdateRecord() with empty updates returned error: %v", err)
+	}
+
+	rows, err := d.UpdateRecordIf(context.Background(), "valuation_records", 1, map[string]interface{}{"status": "待处理"}, nil)
+	if err != nil || rows != 0 {
+		t.Errorf("UpdateRecordIf() with empty updates = %d, %v, want 0, nil", rows, err)
+	}
+
+	// 表名仍然需要验证
+	if err := d.UpdateRecord(context.Background(), "records; DROP TABLE x", 1, nil); !errors.Is(err, ErrInvalidTableName) {
+		t.Errorf("Expected ErrInvalidTableName, got %v", err)
+	}
+}
+
+func TestUpdateRecord_RejectsID(t *testing.T) {
+	d := NewDatabase(nil)
+
+	updates := map[string]interface{}{"status": "已完成", "id": int64(2)}
+	if err := d.UpdateRecord(context.Background(), "valuation_records", 1, updates); !errors.Is(err, ErrInvalidUpdate) {
+		t.Errorf("Expected ErrInvalidUpdate for id in updates, got %v", err)
+	}
+}
+
+func TestBuildUpdate(t *testing.T) {
+	d, err := NewDatabase(nil).WithColumns(map[string]string{"status": "state"})
+	if err != nil {
+		t.Fatalf("WithColumns() returned error: %v", err)
+	}
+
+	query, args := d.buildUpdate("valuation_records", 7, nil, map[string]interface{}{"status": "已完成"})
+	if want := "UPDATE valuation_records SET state = ? WHERE id = ?"; query != want {
+		t.Errorf("buildUpdate() query = %q, want %q", query, want)
+	}
+	if len(args) != 2 || args[0] != "已完成" || args[1] != int64(7) {
+		t.Errorf("buildUpdate() args = %v, want [已完成 7]", args)
+	}
+
+	query, args = d.buildUpdate("valuation_records", 7, map[string]interface{}{"current_task_node": 1}, map[string]interface{}{"report": "ok"})
+	if want := "UPDATE valuation_records SET report = ? WHERE id = ? AND current_task_node = ?"; query != want {
+		t.Errorf("buildUpdate() query = %q, want %q", query, want)
+	}
+	if len(args) != 3 || args[2] != 1 {
+		t.Errorf("buildUpdate() args = %v, want [ok 7 1]", args)
+	}
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/logger"
+	"github.com/igwen6w/syt-go-queue/internal/metrics"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// CancelTask 取消处理中的任务
+// 只向处理中的任务发送取消信号，工作者收到后中止 LLM 调用并恢复记录状态。
+// 任务不在处理中时返回 409，不会删除任务
+func (h *TaskHandler) CancelTask(c *gin.Context) {
+	// 记录请求处理时间
+	defer metrics.MeasureRequestDuration(c.Request.Method, c.FullPath())()
+
+	taskID := c.Param("id")
+
+	// 先确认任务处于处理中，取消信号对其他状态的任务没有作用
+	taskInfo, err := h.getTaskInfo(taskID)
+	if err != nil && !errors.Is(err, asynq.ErrTaskNotFound) {
+		logger.Error("Failed to get task info",
+			zap.String("task_id", taskID),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.CommonResponse{
+			Code:    500,
+			Message: "Failed to get task info: " + err.Error(),
+		})
+		return
+	}
+
+	if taskInfo == nil {
+		c.JSON(http.StatusNotFound, types.CommonResponse{
+			Code:    404,
+			Message: "Task not found",
+		})
+		return
+	}
+
+	if taskInfo.State != asynq.TaskStateActive {
+		c.JSON(http.StatusConflict, types.CommonResponse{
+			Code:    409,
+			Message: "Task is not active, current state: " + taskInfo.State.String(),
+		})
+		return
+	}
+
+	if err := h.inspector.CancelProcessing(taskID); err != nil {
+		logger.Error("Failed to cancel task",
+			zap.String("task_id", taskID),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.CommonResponse{
+			Code:    500,
+			Message: "Failed to cancel task: " + err.Error(),
+		})
+		return
+	}
+
+	logger.Info("Task cancellation requested", zap.String("task_id", taskID))
+
+	c.JSON(http.StatusOK, types.CommonResponse{
+		Code:    200,
+		Message: "Cancellation signal sent",
+		Data: types.GetTaskStatusResponse{
+			TaskID:    taskInfo.ID,
+			Status:    taskInfo.State.String(),
+			QueueName: taskInfo.Queue,
+		},
+	})
+}
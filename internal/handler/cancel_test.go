@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCancelTask(t *testing.T) {
+	// 创建模拟对象
+	mockInspector := new(MockAsynqInspector)
+
+	// 创建任务处理器
+	handler := &TaskHandler{
+		inspector: mockInspector,
+	}
+
+	// 创建 Gin 路由
+	router := gin.New()
+	router.POST("/api/tasks/:id/cancel", handler.CancelTask)
+
+	tests := []struct {
+		name           string
+		taskID         string
+		mockSetup      func()
+		expectedStatus int
+		expectedCode   int
+		expectedMsg    string
+	}{
+		{
+			name:   "active task",
+			taskID: "task123",
+			mockSetup: func() {
+				mockInspector.On("GetTaskInfo", "default", "task123").Return(&asynq.TaskInfo{
+					ID:    "task123",
+					Queue: "default",
+					State: asynq.TaskStateActive,
+				}, nil)
+				mockInspector.On("CancelProcessing", "task123").Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedCode:   200,
+			expectedMsg:    "Cancellation signal sent",
+		},
+		{
+			name:   "pending task",
+			taskID: "task123",
+			mockSetup: func() {
+				mockInspector.On("GetTaskInfo", "default", "task123").Return(&asynq.TaskInfo{
+					ID:    "task123",
+					Queue: "default",
+					State: asynq.TaskStatePending,
+				}, nil)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedCode:   409,
+			expectedMsg:    "Task is not active, current state: pending",
+		},
+		{
+			name:   "task not found",
+			taskID: "nonexistent",
+			mockSetup: func() {
+				mockInspector.On("GetTaskInfo", "default", "nonexistent").
+					Return(nil, fmt.Errorf("asynq: %w", asynq.ErrTaskNotFound))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   404,
+			expectedMsg:    "Task not found",
+		},
+		{
+			name:   "inspector error",
+			taskID: "task123",
+			mockSetup: func() {
+				mockInspector.On("GetTaskInfo", "default", "task123").Return(nil, errors.New("redis unavailable"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   500,
+			expectedMsg:    "Failed to get task info",
+		},
+		{
+			name:   "cancel error",
+			taskID: "task123",
+			mockSetup: func() {
+				mockInspector.On("GetTaskInfo", "default", "task123").Return(&asynq.TaskInfo{
+					ID:    "task123",
+					Queue: "default",
+					State: asynq.TaskStateActive,
+				}, nil)
+				mockInspector.On("CancelProcessing", "task123").Return(errors.New("redis unavailable"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   500,
+			expectedMsg:    "Failed to cancel task",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 重置模拟对象
+			mockInspector.ExpectedCalls = nil
+
+			// 设置模拟行为
+			tt.mockSetup()
+
+			// 创建请求
+			req, _ := http.NewRequest("POST", "/api/tasks/"+tt.taskID+"/cancel", nil)
+			resp := httptest.NewRecorder()
+
+			// 发送请求
+			router.ServeHTTP(resp, req)
+
+			// 验证响应状态码
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+
+			// 解析响应
+			var response types.CommonResponse
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+
+			// 验证响应内容
+			assert.Equal(t, tt.expectedCode, response.Code)
+			assert.Contains(t, response.Message, tt.expectedMsg)
+
+			// 验证模拟对象的调用
+			mockInspector.AssertExpectations(t)
+		})
+	}
+}
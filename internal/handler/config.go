@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/igwen6w/syt-go-queue/internal/logger"
+	"github.com/igwen6w/syt-go-queue/internal/metrics"
+	"github.com/igwen6w/syt-go-queue/internal/reload"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"go.uber.org/zap"
+	"net/http"
+	"sort"
+)
+
+// GetConfig 返回 API 服务当前生效的队列配置和队列权重，以及正在运行的 worker 上报的并发和权重。
+// asynq 的并发和队列权重在 worker 启动时确定，运行时只能通过暂停队列限制处理，
+// 该接口用于确认 worker 实际使用的配置与预期一致
+func (h *TaskHandler) GetConfig(c *gin.Context) {
+	// 记录请求处理时间
+	defer metrics.MeasureRequestDuration(c.Request.Method, c.FullPath())()
+
+	servers, err := h.inspector.Servers()
+	if err != nil {
+		logger.Error("Failed to list worker servers", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.CommonResponse{
+			Code:    500,
+			Message: "Failed to list worker servers: " + err.Error(),
+		})
+		return
+	}
+
+	resp := types.AdminConfigResponse{
+		Queue:         queueConfigInfo(h.queue),
+		Queues:        queueRouteInfos(h.queue),
+		Servers:       make([]types.WorkerServer, 0, len(servers)),
+		HotReloadable: reload.HotReloadableFields,
+	}
+	for _, s := range servers {
+		resp.Servers = append(resp.Servers, types.WorkerServer{
+			ID:             s.ID,
+			Host:           s.Host,
+			PID:            s.PID,
+			Concurrency:    s.Concurrency,
+			Queues:         s.Queues,
+			StrictPriority: s.StrictPriority,
+			Status:         s.Status,
+			ActiveWorkers:  len(s.ActiveWorkers),
+			Started:        s.Started.Unix(),
+		})
+	}
+
+	c.JSON(http.StatusOK, types.CommonResponse{
+		Code:    200,
+		Message: "Success",
+		Data:    resp,
+	})
+}
+
+// queueConfigInfo 将队列配置转换为响应格式
+func queueConfigInfo(cfg config.QueueConfig) types.QueueConfigInfo {
+	return types.QueueConfigInfo{
+		Concurrency:         cfg.Concurrency,
+		Retry:               cfg.Retry,
+		Retention:           cfg.Retention.String(),
+		DefaultQueue:        cfg.QueueName(),
+		TaskTimeout:         cfg.TaskTimeout.String(),
+		DrainTimeout:        cfg.DrainTimeout.String(),
+		RetryBaseDelay:      cfg.RetryBaseDelay.String(),
+		RetryMaxDelay:       cfg.RetryMaxDelay.String(),
+		RetryTransientDelay: cfg.RetryTransientDelay.String(),
+		RetryRateLimitDelay: cfg.RetryRateLimitDelay.String(),
+		MaxBatchSize:        cfg.MaxBatchSize,
+		MaxFailedTimes:      cfg.MaxFailedTimes,
+	}
+}
+
+// queueRouteInfos 返回配置的所有队列及其生效的权重，按名称排序
+func queueRouteInfos(cfg config.QueueConfig) []types.QueueRouteInfo {
+	weights := cfg.Weights()
+	infos := make(map[string]*types.QueueRouteInfo, len(weights))
+	for name, weight := range weights {
+		infos[name] = &types.QueueRouteInfo{Name: name, Weight: weight}
+	}
+	for _, route := range cfg.Routes {
+		infos[route.Name].TaskTypes = route.TaskTypes
+	}
+	if name := cfg.PriorityQueues.High.Name; name != "" {
+		infos[name].Priority = config.PriorityHigh
+	}
+	if name := cfg.PriorityQueues.Low.Name; name != "" {
+		infos[name].Priority = config.PriorityLow
+	}
+
+	result := make([]types.QueueRouteInfo, 0, len(infos))
+	for _, info := range infos {
+		result = append(result, *info)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
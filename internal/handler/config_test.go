@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetConfig(t *testing.T) {
+	// 创建模拟对象
+	mockInspector := new(MockAsynqInspector)
+
+	// 创建任务处理器
+	handler := &TaskHandler{
+		queue: config.QueueConfig{
+			Concurrency: 10,
+			Retry:       3,
+			Retention:   24 * time.Hour,
+			Routes: []config.QueueRoute{
+				{Name: "callbacks", Weight: 2, TaskTypes: []string{"callback:send"}},
+			},
+			PriorityQueues: config.PriorityQueues{
+				High: config.PriorityQueue{Name: "llm-high"},
+			},
+		},
+		inspector: mockInspector,
+	}
+
+	// 创建 Gin 路由
+	router := gin.New()
+	router.GET("/api/admin/config", handler.GetConfig)
+
+	tests := []struct {
+		name            string
+		mockSetup       func()
+		expectedStatus  int
+		expectedCode    int
+		expectedMsg     string
+		expectedServers int
+	}{
+		{
+			name: "running worker",
+			mockSetup: func() {
+				mockInspector.On("Servers").Return([]*asynq.ServerInfo{{
+					ID:            "server-1",
+					Host:          "worker-1",
+					PID:           42,
+					Concurrency:   10,
+					Queues:        map[string]int{"default": 10, "callbacks": 2, "llm-high": 20},
+					Status:        "active",
+					Started:       time.Unix(1700000000, 0),
+					ActiveWorkers: []*asynq.WorkerInfo{{TaskID: "task123"}},
+				}}, nil)
+			},
+			expectedStatus:  http.StatusOK,
+			expectedCode:    200,
+			expectedMsg:     "Success",
+			expectedServers: 1,
+		},
+		{
+			name: "servers error",
+			mockSetup: func() {
+				mockInspector.On("Servers").Return(nil, errors.New("redis error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   500,
+			expectedMsg:    "Failed to list worker servers",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 重置模拟对象
+			mockInspector.ExpectedCalls = nil
+
+			// 设置模拟行为
+			tt.mockSetup()
+
+			// 创建请求
+			req, _ := http.NewRequest("GET", "/api/admin/config", nil)
+			resp := httptest.NewRecorder()
+
+			// 发送请求
+			router.ServeHTTP(resp, req)
+
+			// 验证响应状态码
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+
+			// 解析响应
+			var response struct {
+				Code    int                       `json:"code"`
+				Message string                    `json:"message"`
+				Data    types.AdminConfigResponse `json:"data"`
+			}
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+
+			// 验证响应内容
+			assert.Equal(t, tt.expectedCode, response.Code)
+			assert.Contains(t, response.Message, tt.expectedMsg)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, 10, response.Data.Queue.Concurrency)
+				assert.Equal(t, "24h0m0s", response.Data.Queue.Retention)
+				assert.Equal(t, []types.QueueRouteInfo{
+					{Name: "callbacks", Weight: 2, TaskTypes: []string{"callback:send"}},
+					{Name: "default", Weight: config.DefaultQueueWeight},
+					{Name: "llm-high", Weight: config.DefaultHighPriorityWeight, Priority: config.PriorityHigh},
+				}, response.Data.Queues)
+				assert.Len(t, response.Data.Servers, tt.expectedServers)
+				assert.Equal(t, 1, response.Data.Servers[0].ActiveWorkers)
+				assert.Equal(t, 20, response.Data.Servers[0].Queues["llm-high"])
+				assert.Contains(t, response.Data.HotReloadable, "logger.level")
+			}
+
+			// 验证模拟对象的调用
+			mockInspector.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/logger"
+	"github.com/igwen6w/syt-go-queue/internal/task"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"github.com/igwen6w/syt-go-queue/internal/utils"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// dryRunLLMTask 校验任务但不入队。
+// 读取记录以确认表和列存在，校验回调地址，然后返回将要发送给 LLM 的消息，
+// 便于客户端在消耗 API 额度之前检查数据和配置
+func (h *TaskHandler) dryRunLLMTask(c *gin.Context, req types.CreateTaskRequest, requestID string) {
+	record, err := h.db.GetValuationRecord(c.Request.Context(), req.TableName, req.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, types.CommonResponse{
+				Code:    404,
+				Message: "Record not found",
+			})
+			return
+		}
+
+		// 表或列不存在时查询失败，返回错误详情便于排查配置
+		logger.Warn("Dry run failed to get record",
+			zap.String("request_id", requestID),
+			zap.String("table_name", req.TableName),
+			zap.Int64("record_id", req.ID),
+			zap.Error(err))
+		c.JSON(http.StatusUnprocessableEntity, types.CommonResponse{
+			Code:    422,
+			Message: "Failed to get record: " + err.Error(),
+		})
+		return
+	}
+
+	if record.CallbackURL != "" {
+		if err := utils.ValidateCallbackURL(record.CallbackURL); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, types.CommonResponse{
+				Code:    422,
+				Message: "Invalid callback URL: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	logger.Info("Dry run completed",
+		zap.String("request_id", requestID),
+		zap.String("table_name", req.TableName),
+		zap.Int64("record_id", req.ID))
+
+	c.JSON(http.StatusOK, types.CommonResponse{
+		Code:    200,
+		Message: "Success",
+		Data: types.DryRunResponse{
+			TableName: req.TableName,
+			ID:        req.ID,
+			Model:     req.Model,
+			MaxTokens: req.MaxTokens,
+			Messages:  task.BuildMessages(record.SysMessage, record.UserMessage),
+		},
+	})
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/database"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateLLMTask_DryRun(t *testing.T) {
+	// 创建模拟对象
+	mockClient := new(MockAsynqClient)
+	mockDB := new(MockDatabase)
+
+	// 创建任务处理器
+	handler := &TaskHandler{
+		client: mockClient,
+		db:     mockDB,
+	}
+
+	// 创建 Gin 路由
+	router := gin.New()
+	router.POST("/api/tasks/llm", handler.CreateLLMTask)
+
+	requestBody := types.CreateTaskRequest{TableName: "test_table", ID: 123, DryRun: true}
+
+	tests := []struct {
+		name           string
+		mockSetup      func()
+		expectedStatus int
+		expectedCode   int
+		expectedMsg    string
+	}{
+		{
+			name: "returns assembled messages",
+			mockSetup: func() {
+				mockDB.On("GetValuationRecord", mock.Anything, "test_table", int64(123)).Return(&database.ValuationRecord{
+					ID:          123,
+					SysMessage:  "system prompt",
+					UserMessage: "user prompt",
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedCode:   200,
+			expectedMsg:    "Success",
+		},
+		{
+			name: "record not found",
+			mockSetup: func() {
+				mockDB.On("GetValuationRecord", mock.Anything, "test_table", int64(123)).
+					Return(nil, fmt.Errorf("failed to get valuation record: %w", sql.ErrNoRows))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   404,
+			expectedMsg:    "Record not found",
+		},
+		{
+			name: "missing column",
+			mockSetup: func() {
+				mockDB.On("GetValuationRecord", mock.Anything, "test_table", int64(123)).
+					Return(nil, errors.New("Unknown column 'sys_message' in 'field list'"))
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   422,
+			expectedMsg:    "Unknown column 'sys_message'",
+		},
+		{
+			name: "invalid callback URL",
+			mockSetup: func() {
+				mockDB.On("GetValuationRecord", mock.Anything, "test_table", int64(123)).Return(&database.ValuationRecord{
+					ID:          123,
+					CallbackURL: "http://127.0.0.1/callback",
+				}, nil)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   422,
+			expectedMsg:    "Invalid callback URL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 重置模拟对象
+			mockClient.ExpectedCalls = nil
+			mockDB.ExpectedCalls = nil
+
+			// 设置模拟行为
+			tt.mockSetup()
+
+			// 创建请求
+			body, _ := json.Marshal(requestBody)
+			req, _ := http.NewRequest("POST", "/api/tasks/llm", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			// 发送请求
+			router.ServeHTTP(resp, req)
+
+			// 验证响应状态码
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+
+			// 解析响应
+			var response types.CommonResponse
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+
+			// 验证响应内容
+			assert.Equal(t, tt.expectedCode, response.Code)
+			assert.Contains(t, response.Message, tt.expectedMsg)
+
+			// 如果是成功响应，验证组装的消息
+			if tt.expectedStatus == http.StatusOK {
+				data, ok := response.Data.(map[string]interface{})
+				assert.True(t, ok)
+				messages, ok := data["messages"].([]interface{})
+				assert.True(t, ok)
+				assert.Len(t, messages, 2)
+				assert.Equal(t, map[string]interface{}{"role": "system", "content": "system prompt"}, messages[0])
+				assert.Equal(t, map[string]interface{}{"role": "user", "content": "user prompt"}, messages[1])
+			}
+
+			// 预演模式不应入队
+			mockClient.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything)
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
@@ -1,29 +1,47 @@
 package handler
 
 import (
+	"context"
 	"github.com/gin-gonic/gin"
 	"github.com/igwen6w/syt-go-queue/internal/logger"
 	"github.com/igwen6w/syt-go-queue/internal/types"
 	"go.uber.org/zap"
 	"net/http"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
+// defaultDBPingTimeout 未配置时就绪检查中数据库 Ping 的超时时间
+const defaultDBPingTimeout = 2 * time.Second
+
 // HealthHandler 处理健康检查相关的请求
 type HealthHandler struct {
 	db interface {
-		Ping() error
+		PingContext(ctx context.Context) error
 	}
 	client interface {
+		Ping() error
 		Close() error
 	}
+	dbPingTimeout time.Duration // 数据库 Ping 的超时时间，为 0 时使用 defaultDBPingTimeout
+	// 进程启动后数据库和 Redis 是否至少 Ping 成功过一次，用于启动检查
+	dbStarted    atomic.Bool
+	redisStarted atomic.Bool
 }
 
 // NewHealthHandler 创建并返回一个新的健康检查处理器
-func NewHealthHandler(db interface{ Ping() error }, client interface{ Close() error }) *HealthHandler {
+// dbPingTimeout 为就绪检查中数据库 Ping 的超时时间，为 0 时默认 2 秒
+func NewHealthHandler(db interface {
+	PingContext(ctx context.Context) error
+}, client interface {
+	Ping() error
+	Close() error
+}, dbPingTimeout time.Duration) *HealthHandler {
 	return &HealthHandler{
-		db:     db,
-		client: client,
+		db:            db,
+		client:        client,
+		dbPingTimeout: dbPingTimeout,
 	}
 }
 
@@ -56,19 +74,32 @@ func (h *HealthHandler) LivenessCheck(c *gin.Context) {
 // ReadinessCheck 处理就绪性检查请求
 // 检查服务是否准备好处理请求，包括检查依赖服务（数据库、Redis等）
 func (h *HealthHandler) ReadinessCheck(c *gin.Context) {
+	var errs []string
+
 	// 检查数据库连接
 	dbStatus := "ok"
-	if err := h.db.Ping(); err != nil {
-		logger.Error("Database connection check failed", zap.Error(err))
+	if err := h.pingDB(c.Request.Context()); err != nil {
 		dbStatus = "error"
+		errs = append(errs, err.Error())
+	}
+
+	// 检查 Redis 连接
+	redisStatus := "ok"
+	if err := h.pingRedis(); err != nil {
+		redisStatus = "error"
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
 		c.JSON(http.StatusServiceUnavailable, types.CommonResponse{
 			Code:    503,
 			Message: "Service is not ready",
 			Data: map[string]interface{}{
 				"status":    "error",
 				"database":  dbStatus,
+				"redis":     redisStatus,
 				"timestamp": time.Now().Unix(),
-				"error":     err.Error(),
+				"error":     strings.Join(errs, "; "),
 			},
 		})
 		return
@@ -80,7 +111,90 @@ func (h *HealthHandler) ReadinessCheck(c *gin.Context) {
 		Data: map[string]interface{}{
 			"status":    "ok",
 			"database":  dbStatus,
+			"redis":     redisStatus,
 			"timestamp": time.Now().Unix(),
 		},
 	})
 }
+
+// StartupCheck 处理启动检查请求
+// 进程启动后数据库和 Redis 都至少 Ping 成功过一次才返回成功，之后不再检查依赖服务。
+// 用作 Kubernetes 启动探针，启动期间连接缓慢不会导致存活探针失败而重启容器
+func (h *HealthHandler) StartupCheck(c *gin.Context) {
+	var errs []string
+
+	// 只检查尚未成功过的依赖服务
+	if !h.dbStarted.Load() {
+		if err := h.pingDB(c.Request.Context()); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if !h.redisStarted.Load() {
+		if err := h.pingRedis(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	dbStatus, redisStatus := startupStatus(h.dbStarted.Load()), startupStatus(h.redisStarted.Load())
+	if len(errs) > 0 {
+		c.JSON(http.StatusServiceUnavailable, types.CommonResponse{
+			Code:    503,
+			Message: "Service is starting",
+			Data: map[string]interface{}{
+				"status":    "starting",
+				"database":  dbStatus,
+				"redis":     redisStatus,
+				"timestamp": time.Now().Unix(),
+				"error":     strings.Join(errs, "; "),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.CommonResponse{
+		Code:    200,
+		Message: "Service has started",
+		Data: map[string]interface{}{
+			"status":    "ok",
+			"database":  dbStatus,
+			"redis":     redisStatus,
+			"timestamp": time.Now().Unix(),
+		},
+	})
+}
+
+// startupStatus 返回依赖服务在启动检查中的状态
+func startupStatus(started bool) string {
+	if started {
+		return "ok"
+	}
+	return "starting"
+}
+
+// pingDB 检查数据库连接，成功时记录数据库已启动。
+// 限制等待时间，避免数据库无响应时探针本身挂起
+func (h *HealthHandler) pingDB(ctx context.Context) error {
+	timeout := h.dbPingTimeout
+	if timeout <= 0 {
+		timeout = defaultDBPingTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := h.db.PingContext(ctx); err != nil {
+		logger.Error("Database connection check failed", zap.Error(err))
+		return err
+	}
+	h.dbStarted.Store(true)
+	return nil
+}
+
+// pingRedis 检查 Redis 连接，成功时记录 Redis 已启动
+func (h *HealthHandler) pingRedis() error {
+	if err := h.client.Ping(); err != nil {
+		logger.Error("Redis connection check failed", zap.Error(err))
+		return err
+	}
+	h.redisStarted.Store(true)
+	return nil
+}
@@ -1,14 +1,17 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/igwen6w/syt-go-queue/internal/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 // 创建 HealthHandler 测试
@@ -120,8 +123,9 @@ func TestReadinessCheck(t *testing.T) {
 		{
 			name: "service ready",
 			mockSetup: func() {
-				// 模拟数据库连接正常
-				mockDB.On("Ping").Return(nil)
+				// 模拟数据库和 Redis 连接正常
+				mockDB.On("PingContext", mock.Anything).Return(nil)
+				mockClient.On("Ping").Return(nil)
 			},
 			expectedStatus: http.StatusOK,
 			expectedCode:   200,
@@ -129,13 +133,15 @@ func TestReadinessCheck(t *testing.T) {
 			expectedData: map[string]string{
 				"status":   "ok",
 				"database": "ok",
+				"redis":    "ok",
 			},
 		},
 		{
 			name: "service not ready - database error",
 			mockSetup: func() {
 				// 模拟数据库连接错误
-				mockDB.On("Ping").Return(errors.New("database connection error"))
+				mockDB.On("PingContext", mock.Anything).Return(errors.New("database connection error"))
+				mockClient.On("Ping").Return(nil)
 			},
 			expectedStatus: http.StatusServiceUnavailable,
 			expectedCode:   503,
@@ -143,6 +149,23 @@ func TestReadinessCheck(t *testing.T) {
 			expectedData: map[string]string{
 				"status":   "error",
 				"database": "error",
+				"redis":    "ok",
+			},
+		},
+		{
+			name: "service not ready - redis error",
+			mockSetup: func() {
+				// 模拟 Redis 连接错误
+				mockDB.On("PingContext", mock.Anything).Return(nil)
+				mockClient.On("Ping").Return(errors.New("redis connection error"))
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedCode:   503,
+			expectedMsg:    "Service is not ready",
+			expectedData: map[string]string{
+				"status":   "error",
+				"database": "ok",
+				"redis":    "error",
 			},
 		},
 	}
@@ -180,10 +203,114 @@ func TestReadinessCheck(t *testing.T) {
 			assert.True(t, ok)
 			assert.Equal(t, tt.expectedData["status"], data["status"])
 			assert.Equal(t, tt.expectedData["database"], data["database"])
+			assert.Equal(t, tt.expectedData["redis"], data["redis"])
 			assert.Contains(t, data, "timestamp")
 
 			// 验证模拟对象的调用
 			mockDB.AssertExpectations(t)
+			mockClient.AssertExpectations(t)
 		})
 	}
 }
+
+func TestReadinessCheck_DatabaseTimeout(t *testing.T) {
+	mockDB := new(MockDatabase)
+	mockClient := new(MockAsynqClient)
+
+	// 模拟无响应的数据库，直到超时才返回
+	mockDB.On("PingContext", mock.Anything).Run(func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+	}).Return(context.DeadlineExceeded)
+	mockClient.On("Ping").Return(nil)
+
+	handler := NewHealthHandler(mockDB, mockClient, 50*time.Millisecond)
+
+	router := gin.New()
+	router.GET("/healthz/ready", handler.ReadinessCheck)
+
+	req, _ := http.NewRequest("GET", "/healthz/ready", nil)
+	resp := httptest.NewRecorder()
+
+	start := time.Now()
+	router.ServeHTTP(resp, req)
+
+	// 超时后立即返回 503
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+
+	var response types.CommonResponse
+	err := json.Unmarshal(resp.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	data, ok := response.Data.(map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, "error", data["database"])
+	assert.Equal(t, "ok", data["redis"])
+}
+
+func TestStartupCheck(t *testing.T) {
+	mockDB := new(MockDatabase)
+	mockClient := new(MockAsynqClient)
+
+	handler := NewHealthHandler(mockDB, mockClient, 0)
+
+	router := gin.New()
+	router.GET("/healthz/startup", handler.StartupCheck)
+
+	get := func() (int, map[string]interface{}) {
+		req, _ := http.NewRequest("GET", "/healthz/startup", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		var response types.CommonResponse
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+		data, ok := response.Data.(map[string]interface{})
+		assert.True(t, ok)
+		return resp.Code, data
+	}
+
+	// 数据库尚未连接成功时返回 503
+	mockDB.On("PingContext", mock.Anything).Return(errors.New("database connection error")).Once()
+	mockClient.On("Ping").Return(nil).Once()
+	code, data := get()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "starting", data["status"])
+	assert.Equal(t, "starting", data["database"])
+	assert.Equal(t, "ok", data["redis"])
+
+	// 数据库连接成功后返回 200，已成功过的 Redis 不再检查
+	mockDB.On("PingContext", mock.Anything).Return(nil).Once()
+	code, data = get()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", data["database"])
+	assert.Equal(t, "ok", data["redis"])
+	mockClient.AssertNumberOfCalls(t, "Ping", 1)
+
+	// 启动完成后不再检查依赖服务，之后的故障由就绪检查负责
+	code, _ = get()
+	assert.Equal(t, http.StatusOK, code)
+	mockDB.AssertNumberOfCalls(t, "PingContext", 2)
+	mockClient.AssertNumberOfCalls(t, "Ping", 1)
+}
+
+func TestStartupCheck_AfterReadiness(t *testing.T) {
+	mockDB := new(MockDatabase)
+	mockClient := new(MockAsynqClient)
+	mockDB.On("PingContext", mock.Anything).Return(nil).Once()
+	mockClient.On("Ping").Return(nil).Once()
+
+	handler := NewHealthHandler(mockDB, mockClient, 0)
+
+	router := gin.New()
+	router.GET("/healthz/ready", handler.ReadinessCheck)
+	router.GET("/healthz/startup", handler.StartupCheck)
+
+	// 就绪检查成功过后，启动检查直接返回成功
+	for _, path := range []string{"/healthz/ready", "/healthz/startup"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code, path)
+	}
+	mockDB.AssertExpectations(t)
+	mockClient.AssertExpectations(t)
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/logger"
+	"github.com/igwen6w/syt-go-queue/internal/metrics"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"go.uber.org/zap"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultMaintenanceMessage 维护模式下未指定说明时返回的提示
+const defaultMaintenanceMessage = "Service is in maintenance mode, new tasks are not accepted"
+
+// maintenanceState 维护模式开关，只保存在当前进程内存中，重启后恢复为关闭
+type maintenanceState struct {
+	mu      sync.RWMutex
+	enabled bool
+	message string
+	since   time.Time
+}
+
+// status 返回当前的维护模式状态
+func (m *maintenanceState) status() types.MaintenanceStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := types.MaintenanceStatus{Enabled: m.enabled, Message: m.message}
+	if !m.since.IsZero() {
+		status.Since = m.since.Unix()
+	}
+	return status
+}
+
+// set 切换维护模式，返回切换后的状态
+func (m *maintenanceState) set(enabled bool, message string) types.MaintenanceStatus {
+	m.mu.Lock()
+	if enabled && message == "" {
+		message = defaultMaintenanceMessage
+	}
+	if !enabled {
+		message = ""
+	}
+	if enabled != m.enabled {
+		m.since = time.Now()
+	}
+	m.enabled = enabled
+	m.message = message
+	m.mu.Unlock()
+
+	return m.status()
+}
+
+// rejectInMaintenance 维护模式开启时返回 503 并中止请求，调用方应直接返回
+func (h *TaskHandler) rejectInMaintenance(c *gin.Context) bool {
+	status := h.maintenance.status()
+	if !status.Enabled {
+		return false
+	}
+
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, types.CommonResponse{
+		Code:    503,
+		Message: status.Message,
+		Data:    status,
+	})
+	return true
+}
+
+// GetMaintenance 返回维护模式的当前状态
+func (h *TaskHandler) GetMaintenance(c *gin.Context) {
+	// 记录请求处理时间
+	defer metrics.MeasureRequestDuration(c.Request.Method, c.FullPath())()
+
+	c.JSON(http.StatusOK, types.CommonResponse{
+		Code:    200,
+		Message: "Success",
+		Data:    h.maintenance.status(),
+	})
+}
+
+// SetMaintenance 开启或关闭维护模式
+// 开启后创建、批量创建和重试任务的接口返回 503，已入队的任务由 worker 继续处理。
+// 状态只保存在当前 API 实例的内存中，多实例部署时需要逐个切换
+func (h *TaskHandler) SetMaintenance(c *gin.Context) {
+	// 记录请求处理时间
+	defer metrics.MeasureRequestDuration(c.Request.Method, c.FullPath())()
+
+	var req types.MaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(bindErrorStatus(err), bindErrorResponse(err))
+		return
+	}
+
+	status := h.maintenance.set(*req.Enabled, req.Message)
+	logger.Warn("Maintenance mode changed",
+		zap.Bool("enabled", status.Enabled),
+		zap.String("message", status.Message),
+		zap.String("user", c.GetString(gin.AuthUserKey)))
+
+	c.JSON(http.StatusOK, types.CommonResponse{
+		Code:    200,
+		Message: "Success",
+		Data:    status,
+	})
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMaintenance(t *testing.T) {
+	// 创建模拟对象，维护模式下不应访问数据库或入队
+	mockClient := new(MockAsynqClient)
+	mockDB := new(MockDatabase)
+
+	// 创建任务处理器
+	handler := &TaskHandler{
+		client: mockClient,
+		db:     mockDB,
+	}
+
+	// 创建 Gin 路由
+	router := gin.New()
+	router.GET("/api/admin/maintenance", handler.GetMaintenance)
+	router.POST("/api/admin/maintenance", handler.SetMaintenance)
+	router.POST("/api/tasks/llm", handler.CreateLLMTask)
+	router.POST("/api/tasks/llm/batch", handler.CreateBatchLLMTask)
+	router.POST("/api/tasks/llm/retry", handler.RetryLLMTask)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedCode   int
+		expectedMsg    string
+		expectedState  bool
+	}{
+		{
+			name:           "disabled by default",
+			method:         "GET",
+			path:           "/api/admin/maintenance",
+			expectedStatus: http.StatusOK,
+			expectedCode:   200,
+			expectedMsg:    "Success",
+			expectedState:  false,
+		},
+		{
+			name:           "missing enabled",
+			method:         "POST",
+			path:           "/api/admin/maintenance",
+			body:           `{"message": "incident"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   400,
+			expectedMsg:    "enabled is required",
+		},
+		{
+			name:           "enable",
+			method:         "POST",
+			path:           "/api/admin/maintenance",
+			body:           `{"enabled": true, "message": "LLM provider outage"}`,
+			expectedStatus: http.StatusOK,
+			expectedCode:   200,
+			expectedMsg:    "Success",
+			expectedState:  true,
+		},
+		{
+			name:           "create rejected",
+			method:         "POST",
+			path:           "/api/tasks/llm",
+			body:           `{"table_name": "test_table", "id": 123}`,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedCode:   503,
+			expectedMsg:    "LLM provider outage",
+			expectedState:  true,
+		},
+		{
+			name:           "batch rejected",
+			method:         "POST",
+			path:           "/api/tasks/llm/batch",
+			body:           `{"table_name": "test_table", "ids": [1, 2]}`,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedCode:   503,
+			expectedMsg:    "LLM provider outage",
+			expectedState:  true,
+		},
+		{
+			name:           "retry rejected",
+			method:         "POST",
+			path:           "/api/tasks/llm/retry",
+			body:           `{"table_name": "test_table", "id": 123}`,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedCode:   503,
+			expectedMsg:    "LLM provider outage",
+			expectedState:  true,
+		},
+		{
+			name:           "disable",
+			method:         "POST",
+			path:           "/api/admin/maintenance",
+			body:           `{"enabled": false}`,
+			expectedStatus: http.StatusOK,
+			expectedCode:   200,
+			expectedMsg:    "Success",
+			expectedState:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 创建请求
+			req, _ := http.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			// 发送请求
+			router.ServeHTTP(resp, req)
+
+			// 验证响应状态码
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+
+			// 解析响应
+			var response struct {
+				Code    int                     `json:"code"`
+				Message string                  `json:"message"`
+				Data    types.MaintenanceStatus `json:"data"`
+			}
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+
+			// 验证响应内容
+			assert.Equal(t, tt.expectedCode, response.Code)
+			assert.Contains(t, response.Message, tt.expectedMsg)
+			if tt.expectedStatus != http.StatusBadRequest {
+				assert.Equal(t, tt.expectedState, response.Data.Enabled)
+			}
+
+			// 验证模拟对象的调用
+			mockClient.AssertNotCalled(t, "Enqueue")
+			mockDB.AssertNotCalled(t, "GetValuationRecord")
+		})
+	}
+}
+
+func TestMaintenanceState_DefaultMessage(t *testing.T) {
+	var m maintenanceState
+
+	status := m.set(true, "")
+	assert.True(t, status.Enabled)
+	assert.Equal(t, defaultMaintenanceMessage, status.Message)
+	assert.NotZero(t, status.Since)
+
+	status = m.set(false, "ignored")
+	assert.False(t, status.Enabled)
+	assert.Empty(t, status.Message)
+}
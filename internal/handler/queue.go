@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/logger"
+	"github.com/igwen6w/syt-go-queue/internal/metrics"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+// GetStats 获取队列统计信息
+// 返回每个队列的任务数量和处理统计，以及所有队列的处理总数
+func (h *TaskHandler) GetStats(c *gin.Context) {
+	// 记录请求处理时间
+	defer metrics.MeasureRequestDuration(c.Request.Method, c.FullPath())()
+
+	queues, err := h.inspector.Queues()
+	if err != nil {
+		logger.Error("Failed to list queues", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.CommonResponse{
+			Code:    500,
+			Message: "Failed to list queues: " + err.Error(),
+		})
+		return
+	}
+
+	resp := types.StatsResponse{
+		Queues:    make([]types.QueueStats, 0, len(queues)),
+		Timestamp: time.Now().Unix(),
+	}
+	for _, queueName := range queues {
+		info, err := h.inspector.GetQueueInfo(queueName)
+		if err != nil {
+			logger.Error("Failed to get queue info",
+				zap.String("queue", queueName),
+				zap.Error(err))
+			c.JSON(http.StatusInternalServerError, types.CommonResponse{
+				Code:    500,
+				Message: "Failed to get queue info: " + err.Error(),
+			})
+			return
+		}
+
+		resp.Queues = append(resp.Queues, types.QueueStats{
+			Queue:          info.Queue,
+			Size:           info.Size,
+			Pending:        info.Pending,
+			Active:         info.Active,
+			Scheduled:      info.Scheduled,
+			Retry:          info.Retry,
+			Archived:       info.Archived,
+			Completed:      info.Completed,
+			Processed:      info.Processed,
+			Failed:         info.Failed,
+			ProcessedTotal: info.ProcessedTotal,
+			FailedTotal:    info.FailedTotal,
+			Paused:         info.Paused,
+			LatencyMs:      info.Latency.Milliseconds(),
+		})
+		resp.ProcessedTotal += info.ProcessedTotal
+		resp.FailedTotal += info.FailedTotal
+	}
+
+	c.JSON(http.StatusOK, types.CommonResponse{
+		Code:    200,
+		Message: "Success",
+		Data:    resp,
+	})
+}
+
+// PauseQueue 暂停队列
+// 暂停后工作者不再从该队列中取出新任务，正在处理的任务不受影响
+func (h *TaskHandler) PauseQueue(c *gin.Context) {
+	// 记录请求处理时间
+	defer metrics.MeasureRequestDuration(c.Request.Method, c.FullPath())()
+
+	queueName := c.Param("name")
+	if err := h.inspector.PauseQueue(queueName); err != nil {
+		logger.Error("Failed to pause queue",
+			zap.String("queue", queueName),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.CommonResponse{
+			Code:    500,
+			Message: "Failed to pause queue: " + err.Error(),
+		})
+		return
+	}
+
+	logger.Info("Queue paused", zap.String("queue", queueName))
+
+	c.JSON(http.StatusOK, types.CommonResponse{
+		Code:    200,
+		Message: "Success",
+		Data: types.QueueStateResponse{
+			Queue:  queueName,
+			Paused: true,
+		},
+	})
+}
+
+// ResumeQueue 恢复已暂停的队列
+func (h *TaskHandler) ResumeQueue(c *gin.Context) {
+	// 记录请求处理时间
+	defer metrics.MeasureRequestDuration(c.Request.Method, c.FullPath())()
+
+	queueName := c.Param("name")
+	if err := h.inspector.UnpauseQueue(queueName); err != nil {
+		logger.Error("Failed to resume queue",
+			zap.String("queue", queueName),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.CommonResponse{
+			Code:    500,
+			Message: "Failed to resume queue: " + err.Error(),
+		})
+		return
+	}
+
+	logger.Info("Queue resumed", zap.String("queue", queueName))
+
+	c.JSON(http.StatusOK, types.CommonResponse{
+		Code:    200,
+		Message: "Success",
+		Data: types.QueueStateResponse{
+			Queue:  queueName,
+			Paused: false,
+		},
+	})
+}
+
+// queueBulkAction 返回队列中指定状态任务的批量操作，不支持的组合返回 nil
+func (h *TaskHandler) queueBulkAction(state, action string) func(queueName string) (int, error) {
+	switch {
+	case state == "pending" && action == "archive":
+		return h.inspector.ArchiveAllPendingTasks
+	case state == "archived" && action == "delete":
+		return h.inspector.DeleteAllArchivedTasks
+	case state == "completed" && action == "delete":
+		return h.inspector.DeleteAllCompletedTasks
+	default:
+		return nil
+	}
+}
+
+// QueueBulkAction 批量归档或删除队列中某一状态的所有任务
+// 支持归档所有等待中的任务、删除所有已归档的任务和删除所有已完成的任务。
+// 该操作不可撤销，需要携带 confirm=true 查询参数
+func (h *TaskHandler) QueueBulkAction(c *gin.Context) {
+	// 记录请求处理时间
+	defer metrics.MeasureRequestDuration(c.Request.Method, c.FullPath())()
+
+	queueName := c.Param("name")
+	state := c.Param("state")
+	action := c.Param("action")
+
+	bulkAction := h.queueBulkAction(state, action)
+	if bulkAction == nil {
+		c.JSON(http.StatusBadRequest, types.CommonResponse{
+			Code:    400,
+			Message: "Unsupported operation: " + action + " " + state + " tasks (supported: archive pending, delete archived, delete completed)",
+		})
+		return
+	}
+
+	if c.Query("confirm") != "true" {
+		c.JSON(http.StatusBadRequest, types.CommonResponse{
+			Code:    400,
+			Message: "This operation cannot be undone, add confirm=true to proceed",
+		})
+		return
+	}
+
+	affected, err := bulkAction(queueName)
+	if err != nil {
+		logger.Error("Failed to "+action+" "+state+" tasks",
+			zap.String("queue", queueName),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.CommonResponse{
+			Code:    500,
+			Message: "Failed to " + action + " " + state + " tasks: " + err.Error(),
+		})
+		return
+	}
+
+	logger.Warn("Queue bulk action completed",
+		zap.String("queue", queueName),
+		zap.String("state", state),
+		zap.String("action", action),
+		zap.Int("affected", affected))
+
+	c.JSON(http.StatusOK, types.CommonResponse{
+		Code:    200,
+		Message: "Success",
+		Data: types.QueueBulkActionResponse{
+			Queue:    queueName,
+			State:    state,
+			Action:   action,
+			Affected: affected,
+		},
+	})
+}
@@ -0,0 +1,358 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetStats(t *testing.T) {
+	// 创建模拟对象
+	mockClient := new(MockAsynqClient)
+	mockDB := new(MockDatabase)
+	mockInspector := new(MockAsynqInspector)
+
+	// 创建任务处理器
+	handler := &TaskHandler{
+		client:    mockClient,
+		db:        mockDB,
+		inspector: mockInspector,
+	}
+
+	// 创建 Gin 路由
+	router := gin.New()
+	router.GET("/api/stats", handler.GetStats)
+
+	tests := []struct {
+		name                   string
+		mockSetup              func()
+		expectedStatus         int
+		expectedCode           int
+		expectedMsg            string
+		expectedQueues         int
+		expectedProcessedTotal float64
+	}{
+		{
+			name: "multiple queues",
+			mockSetup: func() {
+				mockInspector.On("Queues").Return([]string{"default", "critical"}, nil)
+				mockInspector.On("GetQueueInfo", "default").Return(&asynq.QueueInfo{
+					Queue:          "default",
+					Pending:        5,
+					Active:         2,
+					ProcessedTotal: 100,
+					FailedTotal:    3,
+				}, nil)
+				mockInspector.On("GetQueueInfo", "critical").Return(&asynq.QueueInfo{
+					Queue:          "critical",
+					Pending:        1,
+					ProcessedTotal: 20,
+				}, nil)
+			},
+			expectedStatus:         http.StatusOK,
+			expectedCode:           200,
+			expectedMsg:            "Success",
+			expectedQueues:         2,
+			expectedProcessedTotal: 120,
+		},
+		{
+			name: "queues error",
+			mockSetup: func() {
+				mockInspector.On("Queues").Return(nil, errors.New("redis error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   500,
+			expectedMsg:    "Failed to list queues",
+		},
+		{
+			name: "queue info error",
+			mockSetup: func() {
+				mockInspector.On("Queues").Return([]string{"default"}, nil)
+				mockInspector.On("GetQueueInfo", "default").Return(nil, errors.New("redis error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   500,
+			expectedMsg:    "Failed to get queue info",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 重置模拟对象
+			mockInspector.ExpectedCalls = nil
+
+			// 设置模拟行为
+			tt.mockSetup()
+
+			// 创建请求
+			req, _ := http.NewRequest("GET", "/api/stats", nil)
+			resp := httptest.NewRecorder()
+
+			// 发送请求
+			router.ServeHTTP(resp, req)
+
+			// 验证响应状态码
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+
+			// 解析响应
+			var response types.CommonResponse
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+
+			// 验证响应内容
+			assert.Equal(t, tt.expectedCode, response.Code)
+			assert.Contains(t, response.Message, tt.expectedMsg)
+
+			// 如果是成功响应，验证队列数量和汇总
+			if tt.expectedStatus == http.StatusOK {
+				data, ok := response.Data.(map[string]interface{})
+				assert.True(t, ok)
+				queues, ok := data["queues"].([]interface{})
+				assert.True(t, ok)
+				assert.Len(t, queues, tt.expectedQueues)
+				assert.Equal(t, tt.expectedProcessedTotal, data["processed_total"])
+			}
+
+			// 验证模拟对象的调用
+			mockInspector.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPauseAndResumeQueue(t *testing.T) {
+	// 创建模拟对象
+	mockInspector := new(MockAsynqInspector)
+
+	// 创建任务处理器
+	handler := &TaskHandler{
+		inspector: mockInspector,
+	}
+
+	// 创建 Gin 路由
+	router := gin.New()
+	router.POST("/api/queues/:name/pause", handler.PauseQueue)
+	router.POST("/api/queues/:name/resume", handler.ResumeQueue)
+
+	tests := []struct {
+		name           string
+		path           string
+		mockSetup      func()
+		expectedStatus int
+		expectedCode   int
+		expectedMsg    string
+		expectedPaused bool
+	}{
+		{
+			name: "pause queue",
+			path: "/api/queues/default/pause",
+			mockSetup: func() {
+				mockInspector.On("PauseQueue", "default").Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedCode:   200,
+			expectedMsg:    "Success",
+			expectedPaused: true,
+		},
+		{
+			name: "pause queue error",
+			path: "/api/queues/default/pause",
+			mockSetup: func() {
+				mockInspector.On("PauseQueue", "default").Return(errors.New(`queue "default" is already paused`))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   500,
+			expectedMsg:    "Failed to pause queue",
+		},
+		{
+			name: "resume queue",
+			path: "/api/queues/default/resume",
+			mockSetup: func() {
+				mockInspector.On("UnpauseQueue", "default").Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedCode:   200,
+			expectedMsg:    "Success",
+			expectedPaused: false,
+		},
+		{
+			name: "resume queue error",
+			path: "/api/queues/default/resume",
+			mockSetup: func() {
+				mockInspector.On("UnpauseQueue", "default").Return(errors.New(`queue "default" is not paused`))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   500,
+			expectedMsg:    "Failed to resume queue",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 重置模拟对象
+			mockInspector.ExpectedCalls = nil
+
+			// 设置模拟行为
+			tt.mockSetup()
+
+			// 创建请求
+			req, _ := http.NewRequest("POST", tt.path, nil)
+			resp := httptest.NewRecorder()
+
+			// 发送请求
+			router.ServeHTTP(resp, req)
+
+			// 验证响应状态码
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+
+			// 解析响应
+			var response types.CommonResponse
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+
+			// 验证响应内容
+			assert.Equal(t, tt.expectedCode, response.Code)
+			assert.Contains(t, response.Message, tt.expectedMsg)
+
+			// 如果是成功响应，验证暂停状态
+			if tt.expectedStatus == http.StatusOK {
+				data, ok := response.Data.(map[string]interface{})
+				assert.True(t, ok)
+				assert.Equal(t, "default", data["queue"])
+				assert.Equal(t, tt.expectedPaused, data["paused"])
+			}
+
+			// 验证模拟对象的调用
+			mockInspector.AssertExpectations(t)
+		})
+	}
+}
+
+func TestQueueBulkAction(t *testing.T) {
+	// 创建模拟对象
+	mockInspector := new(MockAsynqInspector)
+
+	// 创建任务处理器
+	handler := &TaskHandler{
+		inspector: mockInspector,
+	}
+
+	// 创建 Gin 路由
+	router := gin.New()
+	router.POST("/api/queues/:name/:state/:action", handler.QueueBulkAction)
+
+	tests := []struct {
+		name             string
+		path             string
+		mockSetup        func()
+		expectedStatus   int
+		expectedCode     int
+		expectedMsg      string
+		expectedAffected float64
+	}{
+		{
+			name: "archive pending tasks",
+			path: "/api/queues/default/pending/archive?confirm=true",
+			mockSetup: func() {
+				mockInspector.On("ArchiveAllPendingTasks", "default").Return(5, nil)
+			},
+			expectedStatus:   http.StatusOK,
+			expectedCode:     200,
+			expectedMsg:      "Success",
+			expectedAffected: 5,
+		},
+		{
+			name: "delete archived tasks",
+			path: "/api/queues/default/archived/delete?confirm=true",
+			mockSetup: func() {
+				mockInspector.On("DeleteAllArchivedTasks", "default").Return(3, nil)
+			},
+			expectedStatus:   http.StatusOK,
+			expectedCode:     200,
+			expectedMsg:      "Success",
+			expectedAffected: 3,
+		},
+		{
+			name: "delete completed tasks",
+			path: "/api/queues/default/completed/delete?confirm=true",
+			mockSetup: func() {
+				mockInspector.On("DeleteAllCompletedTasks", "default").Return(0, nil)
+			},
+			expectedStatus:   http.StatusOK,
+			expectedCode:     200,
+			expectedMsg:      "Success",
+			expectedAffected: 0,
+		},
+		{
+			name:           "missing confirmation",
+			path:           "/api/queues/default/archived/delete",
+			mockSetup:      func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   400,
+			expectedMsg:    "confirm=true",
+		},
+		{
+			name:           "unsupported combination",
+			path:           "/api/queues/default/active/delete?confirm=true",
+			mockSetup:      func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   400,
+			expectedMsg:    "Unsupported operation",
+		},
+		{
+			name: "inspector error",
+			path: "/api/queues/default/pending/archive?confirm=true",
+			mockSetup: func() {
+				mockInspector.On("ArchiveAllPendingTasks", "default").Return(0, errors.New("redis unavailable"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   500,
+			expectedMsg:    "Failed to archive pending tasks",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 重置模拟对象
+			mockInspector.ExpectedCalls = nil
+
+			// 设置模拟行为
+			tt.mockSetup()
+
+			// 创建请求
+			req, _ := http.NewRequest("POST", tt.path, nil)
+			resp := httptest.NewRecorder()
+
+			// 发送请求
+			router.ServeHTTP(resp, req)
+
+			// 验证响应状态码
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+
+			// 解析响应
+			var response types.CommonResponse
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+
+			// 验证响应内容
+			assert.Equal(t, tt.expectedCode, response.Code)
+			assert.Contains(t, response.Message, tt.expectedMsg)
+
+			// 如果是成功响应，验证受影响的任务数量
+			if tt.expectedStatus == http.StatusOK {
+				data, ok := response.Data.(map[string]interface{})
+				assert.True(t, ok)
+				assert.Equal(t, "default", data["queue"])
+				assert.Equal(t, tt.expectedAffected, data["affected"])
+			}
+
+			// 验证模拟对象的调用
+			mockInspector.AssertExpectations(t)
+		})
+	}
+}
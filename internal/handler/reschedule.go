@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/logger"
+	"github.com/igwen6w/syt-go-queue/internal/metrics"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+// RunTask 立即处理计划中或等待重试的任务
+// 任务被移到待处理状态，由 worker 按队列顺序处理，重试次数保持不变。
+// 任务不存在时返回 404，不处于计划中或等待重试状态时返回 409
+func (h *TaskHandler) RunTask(c *gin.Context) {
+	// 记录请求处理时间
+	defer metrics.MeasureRequestDuration(c.Request.Method, c.FullPath())()
+
+	taskInfo, ok := h.schedulableTask(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	if err := h.inspector.RunTask(taskInfo.Queue, taskInfo.ID); err != nil {
+		logger.Error("Failed to run task",
+			zap.String("task_id", taskInfo.ID),
+			zap.String("queue", taskInfo.Queue),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.CommonResponse{
+			Code:    500,
+			Message: "Failed to run task: " + err.Error(),
+		})
+		return
+	}
+
+	logger.Info("Task moved to pending",
+		zap.String("task_id", taskInfo.ID),
+		zap.String("queue", taskInfo.Queue),
+		zap.String("previous_state", taskInfo.State.String()))
+
+	c.JSON(http.StatusOK, types.CommonResponse{
+		Code:    200,
+		Message: "Task will run now",
+		Data: types.GetTaskStatusResponse{
+			TaskID:     taskInfo.ID,
+			Status:     asynq.TaskStatePending.String(),
+			QueueName:  taskInfo.Queue,
+			RetryCount: taskInfo.Retried,
+			MaxRetry:   taskInfo.MaxRetry,
+		},
+	})
+}
+
+// RescheduleTask 修改计划中或等待重试的任务的执行时间
+// asynq 不支持直接修改执行时间，因此先删除任务，再以相同的任务ID、队列、载荷和选项重新入队。
+// 重新入队的任务重试次数从 0 开始。任务不存在时返回 404，不处于计划中或等待重试状态时返回 409
+func (h *TaskHandler) RescheduleTask(c *gin.Context) {
+	// 记录请求处理时间
+	defer metrics.MeasureRequestDuration(c.Request.Method, c.FullPath())()
+
+	var req types.RescheduleTaskRequest
+	if err := shouldBindBody(c, &req); err != nil {
+		c.JSON(bindErrorStatus(err), bindErrorResponse(err))
+		return
+	}
+
+	processAt, err := parseProcessAt(req.ProcessAt, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.CommonResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	taskInfo, ok := h.schedulableTask(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	if err := h.inspector.DeleteTask(taskInfo.Queue, taskInfo.ID); err != nil {
+		logger.Error("Failed to delete task for rescheduling",
+			zap.String("task_id", taskInfo.ID),
+			zap.String("queue", taskInfo.Queue),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.CommonResponse{
+			Code:    500,
+			Message: "Failed to reschedule task: " + err.Error(),
+		})
+		return
+	}
+
+	if _, err := h.client.Enqueue(asynq.NewTask(taskInfo.Type, taskInfo.Payload), rescheduleOptions(taskInfo, processAt)...); err != nil {
+		// 任务已被删除，记录载荷以便人工恢复
+		logger.Error("Failed to re-enqueue rescheduled task, task was deleted",
+			zap.String("task_id", taskInfo.ID),
+			zap.String("queue", taskInfo.Queue),
+			zap.String("type", taskInfo.Type),
+			zap.ByteString("payload", taskInfo.Payload),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.CommonResponse{
+			Code:    500,
+			Message: "Task was deleted but could not be re-enqueued: " + err.Error(),
+		})
+		return
+	}
+
+	logger.Info("Task rescheduled",
+		zap.String("task_id", taskInfo.ID),
+		zap.String("queue", taskInfo.Queue),
+		zap.Time("process_at", processAt))
+
+	c.JSON(http.StatusOK, types.CommonResponse{
+		Code:    200,
+		Message: "Task rescheduled",
+		Data: types.GetTaskStatusResponse{
+			TaskID:    taskInfo.ID,
+			Status:    asynq.TaskStateScheduled.String(),
+			QueueName: taskInfo.Queue,
+			MaxRetry:  taskInfo.MaxRetry,
+		},
+	})
+}
+
+// schedulableTask 查找任务并确认其处于计划中或等待重试状态，否则写入错误响应并返回 false
+func (h *TaskHandler) schedulableTask(c *gin.Context, taskID string) (*asynq.TaskInfo, bool) {
+	taskInfo, err := h.getTaskInfo(taskID)
+	if err != nil && !errors.Is(err, asynq.ErrTaskNotFound) {
+		logger.Error("Failed to get task info",
+			zap.String("task_id", taskID),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.CommonResponse{
+			Code:    500,
+			Message: "Failed to get task info: " + err.Error(),
+		})
+		return nil, false
+	}
+
+	if taskInfo == nil {
+		c.JSON(http.StatusNotFound, types.CommonResponse{
+			Code:    404,
+			Message: "Task not found",
+		})
+		return nil, false
+	}
+
+	if taskInfo.State != asynq.TaskStateScheduled && taskInfo.State != asynq.TaskStateRetry {
+		c.JSON(http.StatusConflict, types.CommonResponse{
+			Code:    409,
+			Message: "Task is not scheduled or waiting for retry, current state: " + taskInfo.State.String(),
+		})
+		return nil, false
+	}
+
+	return taskInfo, true
+}
+
+// rescheduleOptions 返回重新入队的选项，保留原任务的ID、队列、重试上限、超时和结果保留时长
+func rescheduleOptions(info *asynq.TaskInfo, processAt time.Time) []asynq.Option {
+	opts := []asynq.Option{
+		asynq.TaskID(info.ID),
+		asynq.Queue(info.Queue),
+		asynq.MaxRetry(info.MaxRetry),
+		asynq.ProcessAt(processAt),
+	}
+	if info.Timeout > 0 {
+		opts = append(opts, asynq.Timeout(info.Timeout))
+	}
+	if !info.Deadline.IsZero() {
+		opts = append(opts, asynq.Deadline(info.Deadline))
+	}
+	if info.Retention > 0 {
+		opts = append(opts, asynq.Retention(info.Retention))
+	}
+	return opts
+}
@@ -0,0 +1,289 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRunTask(t *testing.T) {
+	// 创建模拟对象
+	mockInspector := new(MockAsynqInspector)
+
+	// 创建任务处理器
+	handler := &TaskHandler{
+		inspector: mockInspector,
+	}
+
+	// 创建 Gin 路由
+	router := gin.New()
+	router.POST("/api/tasks/:id/run", handler.RunTask)
+
+	tests := []struct {
+		name           string
+		taskID         string
+		mockSetup      func()
+		expectedStatus int
+		expectedCode   int
+		expectedMsg    string
+	}{
+		{
+			name:   "scheduled task",
+			taskID: "task123",
+			mockSetup: func() {
+				mockInspector.On("GetTaskInfo", "default", "task123").Return(&asynq.TaskInfo{
+					ID:    "task123",
+					Queue: "default",
+					State: asynq.TaskStateScheduled,
+				}, nil)
+				mockInspector.On("RunTask", "default", "task123").Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedCode:   200,
+			expectedMsg:    "Task will run now",
+		},
+		{
+			name:   "retry task",
+			taskID: "task123",
+			mockSetup: func() {
+				mockInspector.On("GetTaskInfo", "default", "task123").Return(&asynq.TaskInfo{
+					ID:      "task123",
+					Queue:   "default",
+					State:   asynq.TaskStateRetry,
+					Retried: 2,
+				}, nil)
+				mockInspector.On("RunTask", "default", "task123").Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedCode:   200,
+			expectedMsg:    "Task will run now",
+		},
+		{
+			name:   "active task",
+			taskID: "task123",
+			mockSetup: func() {
+				mockInspector.On("GetTaskInfo", "default", "task123").Return(&asynq.TaskInfo{
+					ID:    "task123",
+					Queue: "default",
+					State: asynq.TaskStateActive,
+				}, nil)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedCode:   409,
+			expectedMsg:    "current state: active",
+		},
+		{
+			name:   "task not found",
+			taskID: "nonexistent",
+			mockSetup: func() {
+				mockInspector.On("GetTaskInfo", "default", "nonexistent").
+					Return(nil, fmt.Errorf("asynq: %w", asynq.ErrTaskNotFound))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   404,
+			expectedMsg:    "Task not found",
+		},
+		{
+			name:   "run error",
+			taskID: "task123",
+			mockSetup: func() {
+				mockInspector.On("GetTaskInfo", "default", "task123").Return(&asynq.TaskInfo{
+					ID:    "task123",
+					Queue: "default",
+					State: asynq.TaskStateScheduled,
+				}, nil)
+				mockInspector.On("RunTask", "default", "task123").Return(errors.New("redis unavailable"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   500,
+			expectedMsg:    "Failed to run task",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 重置模拟对象
+			mockInspector.ExpectedCalls = nil
+
+			// 设置模拟行为
+			tt.mockSetup()
+
+			// 创建请求
+			req, _ := http.NewRequest("POST", "/api/tasks/"+tt.taskID+"/run", nil)
+			resp := httptest.NewRecorder()
+
+			// 发送请求
+			router.ServeHTTP(resp, req)
+
+			// 验证响应状态码
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+
+			// 解析响应
+			var response types.CommonResponse
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+
+			// 验证响应内容
+			assert.Equal(t, tt.expectedCode, response.Code)
+			assert.Contains(t, response.Message, tt.expectedMsg)
+
+			// 验证模拟对象的调用
+			mockInspector.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRescheduleTask(t *testing.T) {
+	processAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	scheduled := &asynq.TaskInfo{
+		ID:        "task123",
+		Queue:     "llm",
+		Type:      "llm:process",
+		Payload:   []byte(`{"table_name":"test_table","id":123}`),
+		State:     asynq.TaskStateScheduled,
+		MaxRetry:  5,
+		Retention: time.Hour,
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		mockSetup      func(*MockAsynqInspector, *MockAsynqClient)
+		expectedStatus int
+		expectedCode   int
+		expectedMsg    string
+	}{
+		{
+			name: "scheduled task",
+			body: fmt.Sprintf(`{"process_at":%q}`, processAt.Format(time.RFC3339)),
+			mockSetup: func(inspector *MockAsynqInspector, client *MockAsynqClient) {
+				inspector.On("GetTaskInfo", "default", "task123").Return(scheduled, nil)
+				inspector.On("DeleteTask", "llm", "task123").Return(nil)
+				client.On("Enqueue", mock.MatchedBy(func(task *asynq.Task) bool {
+					return task.Type() == "llm:process" && bytes.Equal(task.Payload(), scheduled.Payload)
+				}), mock.Anything).Return(&asynq.TaskInfo{ID: "task123", Queue: "llm"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedCode:   200,
+			expectedMsg:    "Task rescheduled",
+		},
+		{
+			name:           "missing process_at",
+			body:           `{}`,
+			mockSetup:      func(*MockAsynqInspector, *MockAsynqClient) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   400,
+			expectedMsg:    "process_at",
+		},
+		{
+			name:           "process_at in the past",
+			body:           fmt.Sprintf(`{"process_at":%q}`, time.Now().Add(-time.Hour).Format(time.RFC3339)),
+			mockSetup:      func(*MockAsynqInspector, *MockAsynqClient) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   400,
+			expectedMsg:    "is in the past",
+		},
+		{
+			name: "pending task",
+			body: fmt.Sprintf(`{"process_at":%q}`, processAt.Format(time.RFC3339)),
+			mockSetup: func(inspector *MockAsynqInspector, client *MockAsynqClient) {
+				inspector.On("GetTaskInfo", "default", "task123").Return(&asynq.TaskInfo{
+					ID:    "task123",
+					Queue: "default",
+					State: asynq.TaskStatePending,
+				}, nil)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedCode:   409,
+			expectedMsg:    "current state: pending",
+		},
+		{
+			name: "delete error",
+			body: fmt.Sprintf(`{"process_at":%q}`, processAt.Format(time.RFC3339)),
+			mockSetup: func(inspector *MockAsynqInspector, client *MockAsynqClient) {
+				inspector.On("GetTaskInfo", "default", "task123").Return(scheduled, nil)
+				inspector.On("DeleteTask", "llm", "task123").Return(errors.New("redis unavailable"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   500,
+			expectedMsg:    "Failed to reschedule task",
+		},
+		{
+			name: "enqueue error",
+			body: fmt.Sprintf(`{"process_at":%q}`, processAt.Format(time.RFC3339)),
+			mockSetup: func(inspector *MockAsynqInspector, client *MockAsynqClient) {
+				inspector.On("GetTaskInfo", "default", "task123").Return(scheduled, nil)
+				inspector.On("DeleteTask", "llm", "task123").Return(nil)
+				client.On("Enqueue", mock.Anything, mock.Anything).Return(nil, errors.New("redis unavailable"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   500,
+			expectedMsg:    "Task was deleted but could not be re-enqueued",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockInspector := new(MockAsynqInspector)
+			mockClient := new(MockAsynqClient)
+			tt.mockSetup(mockInspector, mockClient)
+
+			handler := &TaskHandler{
+				client:    mockClient,
+				inspector: mockInspector,
+			}
+			router := gin.New()
+			router.POST("/api/tasks/:id/reschedule", handler.RescheduleTask)
+
+			req, _ := http.NewRequest("POST", "/api/tasks/task123/reschedule", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+
+			var response types.CommonResponse
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Equal(t, tt.expectedCode, response.Code)
+			assert.Contains(t, response.Message, tt.expectedMsg)
+
+			mockInspector.AssertExpectations(t)
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRescheduleOptions(t *testing.T) {
+	processAt := time.Now().Add(time.Hour)
+	info := &asynq.TaskInfo{
+		ID:        "task123",
+		Queue:     "llm",
+		MaxRetry:  5,
+		Timeout:   time.Minute,
+		Retention: time.Hour,
+	}
+
+	got := map[asynq.OptionType]interface{}{}
+	for _, opt := range rescheduleOptions(info, processAt) {
+		got[opt.Type()] = opt.Value()
+	}
+
+	assert.Equal(t, "task123", got[asynq.TaskIDOpt])
+	assert.Equal(t, "llm", got[asynq.QueueOpt])
+	assert.Equal(t, 5, got[asynq.MaxRetryOpt])
+	assert.Equal(t, processAt, got[asynq.ProcessAtOpt])
+	assert.Equal(t, time.Minute, got[asynq.TimeoutOpt])
+	assert.Equal(t, time.Hour, got[asynq.RetentionOpt])
+	assert.NotContains(t, got, asynq.DeadlineOpt)
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/igwen6w/syt-go-queue/internal/logger"
+	"github.com/igwen6w/syt-go-queue/internal/metrics"
+	"github.com/igwen6w/syt-go-queue/internal/middleware"
+	"github.com/igwen6w/syt-go-queue/internal/task"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// RetryLLMTask 重新处理失败的记录
+// 将记录状态重置为待处理并清空失败信息，然后重新入队。
+// 配置了 max_failed_times 时，失败次数达到上限的记录不允许重试
+func (h *TaskHandler) RetryLLMTask(c *gin.Context) {
+	// 记录请求处理时间
+	defer metrics.MeasureRequestDuration(c.Request.Method, c.FullPath())()
+
+	if h.rejectInMaintenance(c) {
+		return
+	}
+
+	requestID := middleware.GetRequestID(c)
+
+	var req types.RetryTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Warn("Invalid retry task request",
+			zap.String("request_id", requestID),
+			zap.Error(err))
+		c.JSON(bindErrorStatus(err), bindErrorResponse(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	record, err := h.db.GetValuationRecord(ctx, req.TableName, req.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, types.CommonResponse{
+				Code:    404,
+				Message: "Record not found",
+			})
+			return
+		}
+
+		logger.Error("Failed to get record for retry",
+			zap.String("request_id", requestID),
+			zap.String("table_name", req.TableName),
+			zap.Int64("record_id", req.ID),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.CommonResponse{
+			Code:    500,
+			Message: "Failed to get record",
+		})
+		return
+	}
+
+	// 只允许重试失败的记录，包括保留了旧报告的记录
+	statuses := h.queue.RecordStatuses()
+	if record.Status != statuses.Failed && record.Status != statuses.Stale {
+		c.JSON(http.StatusConflict, types.CommonResponse{
+			Code:    409,
+			Message: fmt.Sprintf("Record is not failed, current status: %s", record.Status),
+		})
+		return
+	}
+
+	// 失败次数达到上限的记录不再重试
+	if h.queue.MaxFailedTimes > 0 && record.FailedTimes >= h.queue.MaxFailedTimes {
+		c.JSON(http.StatusConflict, types.CommonResponse{
+			Code:    409,
+			Message: fmt.Sprintf("Record failed %d times, reaching the limit of %d", record.FailedTimes, h.queue.MaxFailedTimes),
+		})
+		return
+	}
+
+	// 重置状态并清空失败信息，保留失败次数用于上限判断
+	updates := map[string]interface{}{
+		"status":      statuses.Pending,
+		"failed_info": "",
+	}
+	if err := h.db.UpdateRecord(ctx, req.TableName, req.ID, updates); err != nil {
+		logger.Error("Failed to reset record for retry",
+			zap.String("request_id", requestID),
+			zap.String("table_name", req.TableName),
+			zap.Int64("record_id", req.ID),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.CommonResponse{
+			Code:    500,
+			Message: "Failed to reset record",
+		})
+		return
+	}
+
+	t, err := task.NewLLMTask(task.LLMPayload{
+		TableName: req.TableName,
+		ID:        req.ID,
+		RequestID: requestID,
+	})
+	if err != nil {
+		logger.Error("Failed to create task",
+			zap.String("request_id", requestID),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.CommonResponse{
+			Code:    500,
+			Message: "Failed to create task: " + err.Error(),
+		})
+		return
+	}
+
+	taskInfo, err := h.client.Enqueue(t, h.enqueueOptions(0, config.PriorityNormal)...)
+	if err != nil {
+		logger.Error("Failed to enqueue retry task",
+			zap.String("request_id", requestID),
+			zap.String("table_name", req.TableName),
+			zap.Int64("record_id", req.ID),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, types.CommonResponse{
+			Code:    500,
+			Message: "Failed to enqueue task",
+		})
+		return
+	}
+
+	logger.Info("Retry task created",
+		zap.String("request_id", requestID),
+		zap.String("task_id", taskInfo.ID),
+		zap.String("table_name", req.TableName),
+		zap.Int64("record_id", req.ID),
+		zap.Int("failed_times", record.FailedTimes))
+
+	c.JSON(http.StatusOK, types.CommonResponse{
+		Code:    200,
+		Message: "Success",
+		Data: types.CreateTaskResponse{
+			TaskID: taskInfo.ID,
+			Status: "enqueued",
+		},
+	})
+}
@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/igwen6w/syt-go-queue/internal/database"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRetryLLMTask(t *testing.T) {
+	// 创建模拟对象
+	mockClient := new(MockAsynqClient)
+	mockDB := new(MockDatabase)
+
+	// 创建任务处理器
+	handler := &TaskHandler{
+		queue:  config.QueueConfig{MaxFailedTimes: 3},
+		client: mockClient,
+		db:     mockDB,
+	}
+
+	// 创建 Gin 路由
+	router := gin.New()
+	router.POST("/api/tasks/llm/retry", handler.RetryLLMTask)
+
+	tests := []struct {
+		name           string
+		requestBody    interface{}
+		mockSetup      func()
+		expectedStatus int
+		expectedCode   int
+		expectedMsg    string
+	}{
+		{
+			name:        "retry failed record",
+			requestBody: types.RetryTaskRequest{TableName: "test_table", ID: 123},
+			mockSetup: func() {
+				mockDB.On("GetValuationRecord", mock.Anything, "test_table", int64(123)).Return(&database.ValuationRecord{
+					ID:          123,
+					Status:      config.DefaultStatusFailed,
+					FailedTimes: 1,
+				}, nil)
+				mockDB.On("UpdateRecord", mock.Anything, "test_table", int64(123), map[string]interface{}{
+					"status":      config.DefaultStatusPending,
+					"failed_info": "",
+				}).Return(nil)
+				mockClient.On("Enqueue", mock.Anything, mock.Anything).Return(&asynq.TaskInfo{
+					ID:    "task456",
+					Queue: "default",
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedCode:   200,
+			expectedMsg:    "Success",
+		},
+		{
+			name:        "retry stale record",
+			requestBody: types.RetryTaskRequest{TableName: "test_table", ID: 123},
+			mockSetup: func() {
+				mockDB.On("GetValuationRecord", mock.Anything, "test_table", int64(123)).Return(&database.ValuationRecord{
+					ID:          123,
+					Status:      config.DefaultStatusStale,
+					Report:      "previous report",
+					FailedTimes: 1,
+				}, nil)
+				mockDB.On("UpdateRecord", mock.Anything, "test_table", int64(123), map[string]interface{}{
+					"status":      config.DefaultStatusPending,
+					"failed_info": "",
+				}).Return(nil)
+				mockClient.On("Enqueue", mock.Anything, mock.Anything).Return(&asynq.TaskInfo{
+					ID:    "task456",
+					Queue: "default",
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedCode:   200,
+			expectedMsg:    "Success",
+		},
+		{
+			name: "invalid request - missing id",
+			requestBody: map[string]interface{}{
+				"table_name": "test_table",
+			},
+			mockSetup:      func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   400,
+			expectedMsg:    "Validation failed: id is required",
+		},
+		{
+			name:        "record not found",
+			requestBody: types.RetryTaskRequest{TableName: "test_table", ID: 123},
+			mockSetup: func() {
+				mockDB.On("GetValuationRecord", mock.Anything, "test_table", int64(123)).
+					Return(nil, fmt.Errorf("failed to get valuation record: %w", sql.ErrNoRows))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   404,
+			expectedMsg:    "Record not found",
+		},
+		{
+			name:        "record not failed",
+			requestBody: types.RetryTaskRequest{TableName: "test_table", ID: 123},
+			mockSetup: func() {
+				mockDB.On("GetValuationRecord", mock.Anything, "test_table", int64(123)).Return(&database.ValuationRecord{
+					ID:     123,
+					Status: "处理中",
+				}, nil)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedCode:   409,
+			expectedMsg:    "Record is not failed",
+		},
+		{
+			name:        "failed times limit reached",
+			requestBody: types.RetryTaskRequest{TableName: "test_table", ID: 123},
+			mockSetup: func() {
+				mockDB.On("GetValuationRecord", mock.Anything, "test_table", int64(123)).Return(&database.ValuationRecord{
+					ID:          123,
+					Status:      config.DefaultStatusFailed,
+					FailedTimes: 3,
+				}, nil)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedCode:   409,
+			expectedMsg:    "reaching the limit of 3",
+		},
+		{
+			name:        "enqueue error",
+			requestBody: types.RetryTaskRequest{TableName: "test_table", ID: 123},
+			mockSetup: func() {
+				mockDB.On("GetValuationRecord", mock.Anything, "test_table", int64(123)).Return(&database.ValuationRecord{
+					ID:     123,
+					Status: config.DefaultStatusFailed,
+				}, nil)
+				mockDB.On("UpdateRecord", mock.Anything, "test_table", int64(123), mock.Anything).Return(nil)
+				mockClient.On("Enqueue", mock.Anything, mock.Anything).Return(nil, errors.New("redis error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   500,
+			expectedMsg:    "Failed to enqueue task",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 重置模拟对象
+			mockClient.ExpectedCalls = nil
+			mockDB.ExpectedCalls = nil
+
+			// 设置模拟行为
+			tt.mockSetup()
+
+			// 创建请求
+			body, _ := json.Marshal(tt.requestBody)
+			req, _ := http.NewRequest("POST", "/api/tasks/llm/retry", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			// 发送请求
+			router.ServeHTTP(resp, req)
+
+			// 验证响应状态码
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+
+			// 解析响应
+			var response types.CommonResponse
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+
+			// 验证响应内容
+			assert.Equal(t, tt.expectedCode, response.Code)
+			assert.Contains(t, response.Message, tt.expectedMsg)
+
+			// 如果是成功响应，验证新任务ID
+			if tt.expectedStatus == http.StatusOK {
+				data, ok := response.Data.(map[string]interface{})
+				assert.True(t, ok)
+				assert.Equal(t, "task456", data["task_id"])
+			}
+
+			// 验证模拟对象的调用
+			mockDB.AssertExpectations(t)
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
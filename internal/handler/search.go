@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/logger"
+	"github.com/igwen6w/syt-go-queue/internal/metrics"
+	"github.com/igwen6w/syt-go-queue/internal/task"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// searchPageSize 按记录查找任务时每种状态扫描的最大任务数量
+const searchPageSize = 500
+
+// SearchTasks 按表名和记录ID查找任务
+// 未指定 queue_name 时查找 LLM 任务可能所在的所有队列，包括配置的优先级队列。
+// asynq 没有载荷索引，该方法扫描每个队列各状态最近的 searchPageSize 个任务并解析载荷进行匹配，
+// 更早的任务不会被找到
+func (h *TaskHandler) SearchTasks(c *gin.Context) {
+	// 记录请求处理时间
+	defer metrics.MeasureRequestDuration(c.Request.Method, c.FullPath())()
+
+	var req types.SearchTasksRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err))
+		return
+	}
+
+	queueNames := h.queue.QueuesFor(task.TypeLLM)
+	if req.QueueName != "" {
+		queueNames = []string{req.QueueName}
+	}
+
+	listFuncs := []struct {
+		state string
+		list  func(queueName string, opts ...asynq.ListOption) ([]*asynq.TaskInfo, error)
+	}{
+		{"pending", h.inspector.ListPendingTasks},
+		{"active", h.inspector.ListActiveTasks},
+		{"retry", h.inspector.ListRetryTasks},
+		{"archived", h.inspector.ListArchivedTasks},
+		{"completed", h.inspector.ListCompletedTasks},
+	}
+
+	matches := make([]types.TaskInfo, 0)
+	for _, queueName := range queueNames {
+		for _, lf := range listFuncs {
+			tasks, err := lf.list(queueName, asynq.PageSize(searchPageSize))
+			// 还没有任务入队过的优先级队列不存在，跳过
+			if req.QueueName == "" && errors.Is(err, asynq.ErrQueueNotFound) {
+				break
+			}
+			if err != nil {
+				logger.Error("Failed to list tasks",
+					zap.String("queue", queueName),
+					zap.String("state", lf.state),
+					zap.Error(err))
+				c.JSON(http.StatusInternalServerError, types.CommonResponse{
+					Code:    500,
+					Message: "Failed to list " + lf.state + " tasks: " + err.Error(),
+				})
+				return
+			}
+
+			for _, t := range tasks {
+				// 其他任务类型或无法解析的载荷直接跳过
+				var payload task.LLMPayload
+				if err := json.Unmarshal(t.Payload, &payload); err != nil {
+					continue
+				}
+				if payload.TableName != req.TableName || payload.ID != req.ID {
+					continue
+				}
+				matches = append(matches, types.TaskInfo{
+					TaskID:     t.ID,
+					Status:     t.State.String(),
+					QueueName:  t.Queue,
+					CreatedAt:  t.NextProcessAt.Unix(),
+					RetryCount: t.Retried,
+					Type:       t.Type,
+				})
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, types.CommonResponse{
+		Code:    200,
+		Message: "Success",
+		Data: types.SearchTasksResponse{
+			Tasks:    matches,
+			PageSize: searchPageSize,
+		},
+	})
+}
@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSearchTasks(t *testing.T) {
+	// 创建模拟对象
+	mockInspector := new(MockAsynqInspector)
+
+	// 创建任务处理器
+	handler := &TaskHandler{
+		inspector: mockInspector,
+	}
+
+	// 创建 Gin 路由
+	router := gin.New()
+	router.GET("/api/tasks/search", handler.SearchTasks)
+
+	// 各状态的列表默认为空
+	emptyLists := func(except ...string) {
+		for _, method := range []string{"ListPendingTasks", "ListActiveTasks", "ListRetryTasks", "ListArchivedTasks", "ListCompletedTasks"} {
+			skip := false
+			for _, e := range except {
+				skip = skip || e == method
+			}
+			if !skip {
+				mockInspector.On(method, "default", mock.Anything).Return([]*asynq.TaskInfo{}, nil)
+			}
+		}
+	}
+
+	tests := []struct {
+		name           string
+		queryParams    string
+		mockSetup      func()
+		expectedStatus int
+		expectedCode   int
+		expectedMsg    string
+		expectedIDs    []string
+	}{
+		{
+			name:        "matches across states",
+			queryParams: "?table_name=test_table&id=123",
+			mockSetup: func() {
+				emptyLists("ListPendingTasks", "ListCompletedTasks")
+				mockInspector.On("ListPendingTasks", "default", mock.Anything).Return([]*asynq.TaskInfo{
+					{ID: "task1", Queue: "default", State: asynq.TaskStatePending, Payload: []byte(`{"table_name":"test_table","id":123}`)},
+					{ID: "task2", Queue: "default", State: asynq.TaskStatePending, Payload: []byte(`{"table_name":"test_table","id":456}`)},
+					{ID: "task3", Queue: "default", State: asynq.TaskStatePending, Payload: []byte("not-json")},
+				}, nil)
+				mockInspector.On("ListCompletedTasks", "default", mock.Anything).Return([]*asynq.TaskInfo{
+					{ID: "task4", Queue: "default", State: asynq.TaskStateCompleted, Payload: []byte(`{"table_name":"test_table","id":123}`)},
+					{ID: "task5", Queue: "default", State: asynq.TaskStateCompleted, Payload: []byte(`{"table_name":"other_table","id":123}`)},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedCode:   200,
+			expectedMsg:    "Success",
+			expectedIDs:    []string{"task1", "task4"},
+		},
+		{
+			name:        "no matches",
+			queryParams: "?table_name=test_table&id=123",
+			mockSetup: func() {
+				emptyLists()
+			},
+			expectedStatus: http.StatusOK,
+			expectedCode:   200,
+			expectedMsg:    "Success",
+			expectedIDs:    []string{},
+		},
+		{
+			name:           "missing id",
+			queryParams:    "?table_name=test_table",
+			mockSetup:      func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   400,
+			expectedMsg:    "Validation failed: id is required",
+		},
+		{
+			name:        "inspector error",
+			queryParams: "?table_name=test_table&id=123",
+			mockSetup: func() {
+				mockInspector.On("ListPendingTasks", "default", mock.Anything).Return(nil, errors.New("redis unavailable"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   500,
+			expectedMsg:    "Failed to list pending tasks",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 重置模拟对象
+			mockInspector.ExpectedCalls = nil
+
+			// 设置模拟行为
+			tt.mockSetup()
+
+			// 创建请求
+			req, _ := http.NewRequest("GET", "/api/tasks/search"+tt.queryParams, nil)
+			resp := httptest.NewRecorder()
+
+			// 发送请求
+			router.ServeHTTP(resp, req)
+
+			// 验证响应状态码
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+
+			// 解析响应
+			var response struct {
+				Code    int                       `json:"code"`
+				Message string                    `json:"message"`
+				Data    types.SearchTasksResponse `json:"data"`
+			}
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+
+			// 验证响应内容
+			assert.Equal(t, tt.expectedCode, response.Code)
+			assert.Contains(t, response.Message, tt.expectedMsg)
+
+			// 如果是成功响应，验证匹配的任务
+			if tt.expectedStatus == http.StatusOK {
+				ids := make([]string, 0, len(response.Data.Tasks))
+				for _, info := range response.Data.Tasks {
+					ids = append(ids, info.TaskID)
+				}
+				assert.Equal(t, tt.expectedIDs, ids)
+				assert.Equal(t, searchPageSize, response.Data.PageSize)
+			}
+
+			// 验证模拟对象的调用
+			mockInspector.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSearchTasks_PriorityQueues(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	queueCfg := config.QueueConfig{
+		PriorityQueues: config.PriorityQueues{
+			High: config.PriorityQueue{Name: "llm-high"},
+			Low:  config.PriorityQueue{Name: "llm-low"},
+		},
+	}
+	listMethods := []string{"ListPendingTasks", "ListActiveTasks", "ListRetryTasks", "ListArchivedTasks", "ListCompletedTasks"}
+
+	mockInspector := new(MockAsynqInspector)
+	for _, method := range listMethods {
+		mockInspector.On(method, "default", mock.Anything).Return([]*asynq.TaskInfo{}, nil)
+		if method != "ListPendingTasks" {
+			mockInspector.On(method, "llm-high", mock.Anything).Return([]*asynq.TaskInfo{}, nil)
+		}
+	}
+	// 高优先级队列中的任务
+	mockInspector.On("ListPendingTasks", "llm-high", mock.Anything).Return([]*asynq.TaskInfo{
+		{ID: "high-task", Queue: "llm-high", State: asynq.TaskStatePending, Payload: []byte(`{"table_name":"test_table","id":123}`)},
+	}, nil)
+	// 低优先级队列还没有任务入队过，不存在
+	mockInspector.On("ListPendingTasks", "llm-low", mock.Anything).
+		Return(nil, fmt.Errorf("asynq: %w", asynq.ErrQueueNotFound))
+
+	handler := &TaskHandler{queue: queueCfg, inspector: mockInspector}
+	router := gin.New()
+	router.GET("/api/tasks/search", handler.SearchTasks)
+
+	req, _ := http.NewRequest("GET", "/api/tasks/search?table_name=test_table&id=123", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var response struct {
+		Code int                       `json:"code"`
+		Data types.SearchTasksResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	if assert.Len(t, response.Data.Tasks, 1) {
+		assert.Equal(t, "high-task", response.Data.Tasks[0].TaskID)
+		assert.Equal(t, "llm-high", response.Data.Tasks[0].QueueName)
+	}
+
+	mockInspector.AssertExpectations(t)
+}
@@ -2,18 +2,34 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/config"
 	"github.com/igwen6w/syt-go-queue/internal/database"
 	"github.com/igwen6w/syt-go-queue/internal/logger"
 	"github.com/igwen6w/syt-go-queue/internal/metrics"
+	"github.com/igwen6w/syt-go-queue/internal/middleware"
 	"github.com/igwen6w/syt-go-queue/internal/task"
 	"github.com/igwen6w/syt-go-queue/internal/types"
 	"go.uber.org/zap"
 	"net/http"
+	"time"
 )
 
+// defaultMaxBatchSize 未配置时批量创建任务的最大数量
+const defaultMaxBatchSize = 100
+
+// defaultMaxListSize 未配置时列出任务允许在内存中读取的最大任务数量
+const defaultMaxListSize = 1000
+
+// processAtSkew 允许 process_at 早于当前时间的最大偏差，容忍客户端与服务端的时钟差异
+const processAtSkew = time.Minute
+
 type TaskHandler struct {
+	queue  config.QueueConfig
 	client interface {
 		Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error)
 	}
@@ -31,14 +47,31 @@ type TaskHandler struct {
 		ListCompletedTasks(queueName string, opts ...asynq.ListOption) ([]*asynq.TaskInfo, error)
 		ListRetryTasks(queueName string, opts ...asynq.ListOption) ([]*asynq.TaskInfo, error)
 		ListArchivedTasks(queueName string, opts ...asynq.ListOption) ([]*asynq.TaskInfo, error)
+		Queues() ([]string, error)
+		GetQueueInfo(queueName string) (*asynq.QueueInfo, error)
+		PauseQueue(queueName string) error
+		UnpauseQueue(queueName string) error
+		ArchiveAllPendingTasks(queueName string) (int, error)
+		DeleteAllArchivedTasks(queueName string) (int, error)
+		DeleteAllCompletedTasks(queueName string) (int, error)
+		CancelProcessing(taskID string) error
+		RunTask(queueName, taskID string) error
+		DeleteTask(queueName, taskID string) error
+		Servers() ([]*asynq.ServerInfo, error)
 	}
+	// 维护模式开关，开启时拒绝创建新任务
+	maintenance maintenanceState
+	// LLM 配置，用于校验创建任务时指定的模型
+	deepseek config.DeepseekConfig
 }
 
-func NewTaskHandler(client *asynq.Client, db *database.Database, redisOpt asynq.RedisClientOpt) *TaskHandler {
+func NewTaskHandler(client *asynq.Client, db *database.Database, redisOpt asynq.RedisConnOpt, queueCfg config.QueueConfig, deepseekCfg config.DeepseekConfig) *TaskHandler {
 	// 创建任务检查器，用于查询任务状态
 	inspector := asynq.NewInspector(redisOpt)
 
 	return &TaskHandler{
+		queue:     queueCfg,
+		deepseek:  deepseekCfg,
 		client:    client,
 		db:        db,
 		inspector: inspector,
@@ -49,9 +82,47 @@ func (h *TaskHandler) CreateLLMTask(c *gin.Context) {
 	// 记录请求处理时间
 	defer metrics.MeasureRequestDuration(c.Request.Method, c.FullPath())()
 
+	if h.rejectInMaintenance(c) {
+		return
+	}
+
+	requestID := middleware.GetRequestID(c)
+
 	var req types.CreateTaskRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		logger.Warn("Invalid create task request", zap.Error(err))
+	if err := shouldBindBody(c, &req); err != nil {
+		logger.Warn("Invalid create task request",
+			zap.String("request_id", requestID),
+			zap.Error(err))
+		c.JSON(bindErrorStatus(err), bindErrorResponse(err))
+		return
+	}
+
+	// 配置了 allowed_models 时，任务级模型必须在列表中
+	if req.Model != "" && !h.deepseek.ModelAllowed(req.Model) {
+		c.JSON(http.StatusBadRequest, types.CommonResponse{
+			Code:    400,
+			Message: fmt.Sprintf("Model %s is not allowed", req.Model),
+		})
+		return
+	}
+
+	// 任务级 max_tokens 不能超过模型在 model_max_tokens 中的上限
+	if req.MaxTokens > 0 {
+		model := req.Model
+		if model == "" {
+			model = h.deepseek.Model
+		}
+		if limit := h.deepseek.MaxTokensLimit(model); limit > 0 && req.MaxTokens > limit {
+			c.JSON(http.StatusBadRequest, types.CommonResponse{
+				Code:    400,
+				Message: fmt.Sprintf("max_tokens %d exceeds the limit of %d for model %s", req.MaxTokens, limit, model),
+			})
+			return
+		}
+	}
+
+	processAt, err := parseProcessAt(req.ProcessAt, time.Now())
+	if err != nil {
 		c.JSON(http.StatusBadRequest, types.CommonResponse{
 			Code:    400,
 			Message: err.Error(),
@@ -59,10 +130,24 @@ func (h *TaskHandler) CreateLLMTask(c *gin.Context) {
 		return
 	}
 
+	// 预演模式只校验数据，不入队
+	if req.DryRun {
+		h.dryRunLLMTask(c, req, requestID)
+		return
+	}
+
 	// 创建异步任务
-	t, err := task.NewLLMTask(req.TableName, req.ID)
+	t, err := task.NewLLMTask(task.LLMPayload{
+		TableName: req.TableName,
+		ID:        req.ID,
+		RequestID: requestID,
+		Model:     req.Model,
+		MaxTokens: req.MaxTokens,
+	})
 	if err != nil {
-		logger.Error("Failed to create task", zap.Error(err))
+		logger.Error("Failed to create task",
+			zap.String("request_id", requestID),
+			zap.Error(err))
 		c.JSON(http.StatusInternalServerError, types.CommonResponse{
 			Code:    500,
 			Message: "Failed to create task: " + err.Error(),
@@ -70,9 +155,41 @@ func (h *TaskHandler) CreateLLMTask(c *gin.Context) {
 		return
 	}
 
-	taskInfo, err := h.client.Enqueue(t)
+	// 幂等模式下使用确定性任务ID，重复入队时 asynq 会返回冲突错误
+	opts := h.enqueueOptions(req.RetentionSeconds, req.Priority)
+	if req.Idempotent {
+		opts = append(opts, asynq.TaskID(task.LLMTaskID(req.TableName, req.ID)))
+	}
+	status := "enqueued"
+	if !processAt.IsZero() {
+		opts = append(opts, asynq.ProcessAt(processAt))
+		status = "scheduled"
+	}
+
+	taskInfo, err := h.client.Enqueue(t, opts...)
 	if err != nil {
-		logger.Error("Failed to enqueue task", zap.Error(err))
+		// 任务已存在，返回已有任务ID
+		if req.Idempotent && (errors.Is(err, asynq.ErrTaskIDConflict) || errors.Is(err, asynq.ErrDuplicateTask)) {
+			existingID := task.LLMTaskID(req.TableName, req.ID)
+			logger.Info("Task already exists, skipping enqueue",
+				zap.String("request_id", requestID),
+				zap.String("task_id", existingID),
+				zap.String("table_name", req.TableName),
+				zap.Int64("record_id", req.ID))
+			c.JSON(http.StatusOK, types.CommonResponse{
+				Code:    200,
+				Message: "Success",
+				Data: types.CreateTaskResponse{
+					TaskID: existingID,
+					Status: "duplicate",
+				},
+			})
+			return
+		}
+
+		logger.Error("Failed to enqueue task",
+			zap.String("request_id", requestID),
+			zap.Error(err))
 		c.JSON(http.StatusInternalServerError, types.CommonResponse{
 			Code:    500,
 			Message: "Failed to enqueue task",
@@ -81,20 +198,138 @@ func (h *TaskHandler) CreateLLMTask(c *gin.Context) {
 	}
 
 	logger.Info("Task created successfully",
+		zap.String("request_id", requestID),
 		zap.String("task_id", taskInfo.ID),
 		zap.String("table_name", req.TableName),
-		zap.Int64("record_id", req.ID))
+		zap.Int64("record_id", req.ID),
+		zap.String("status", status))
 
 	c.JSON(http.StatusOK, types.CommonResponse{
 		Code:    200,
 		Message: "Success",
 		Data: types.CreateTaskResponse{
 			TaskID: taskInfo.ID,
-			Status: "enqueued",
+			Status: status,
 		},
 	})
 }
 
+// parseProcessAt 解析请求中的计划执行时间，为空时返回零值。
+// 早于当前时间超过 processAtSkew 的时间视为无效
+func parseProcessAt(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	processAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("process_at must be an RFC3339 timestamp, got %q", value)
+	}
+	if processAt.Before(now.Add(-processAtSkew)) {
+		return time.Time{}, fmt.Errorf("process_at %s is in the past", value)
+	}
+	return processAt, nil
+}
+
+// enqueueOptions 返回任务的入队选项，包括目标队列和结果保留时长。
+// overrideSeconds 大于 0 时覆盖配置中的 retention，两者都未设置时不保留结果。
+// priority 为 high 或 low 时使用对应的优先级队列，为空时按 normal 处理
+func (h *TaskHandler) enqueueOptions(overrideSeconds int, priority string) []asynq.Option {
+	opts := []asynq.Option{asynq.Queue(h.queue.PriorityQueueFor(task.TypeLLM, priority))}
+
+	retention := h.queue.Retention
+	if overrideSeconds > 0 {
+		retention = time.Duration(overrideSeconds) * time.Second
+	}
+	if retention > 0 {
+		opts = append(opts, asynq.Retention(retention))
+	}
+	return opts
+}
+
+// CreateBatchLLMTask 批量创建 LLM 任务
+// 每个记录ID独立入队，部分失败不会影响整个批次，
+// 响应中返回每个ID对应的任务ID或错误信息
+func (h *TaskHandler) CreateBatchLLMTask(c *gin.Context) {
+	// 记录请求处理时间
+	defer metrics.MeasureRequestDuration(c.Request.Method, c.FullPath())()
+
+	if h.rejectInMaintenance(c) {
+		return
+	}
+
+	requestID := middleware.GetRequestID(c)
+
+	var req types.CreateBatchTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Warn("Invalid create batch task request",
+			zap.String("request_id", requestID),
+			zap.Error(err))
+		c.JSON(bindErrorStatus(err), bindErrorResponse(err))
+		return
+	}
+
+	// 检查批次大小
+	maxBatchSize := h.queue.MaxBatchSize
+	if maxBatchSize <= 0 {
+		maxBatchSize = defaultMaxBatchSize
+	}
+	if len(req.IDs) > maxBatchSize {
+		c.JSON(http.StatusBadRequest, types.CommonResponse{
+			Code:    400,
+			Message: fmt.Sprintf("Batch size %d exceeds maximum of %d", len(req.IDs), maxBatchSize),
+		})
+		return
+	}
+
+	resp := types.CreateBatchTaskResponse{
+		Results: make([]types.BatchTaskResult, len(req.IDs)),
+	}
+	for i, id := range req.IDs {
+		result := types.BatchTaskResult{ID: id}
+
+		t, err := task.NewLLMTask(task.LLMPayload{
+			TableName: req.TableName,
+			ID:        id,
+			RequestID: requestID,
+		})
+		if err == nil {
+			var taskInfo *asynq.TaskInfo
+			taskInfo, err = h.client.Enqueue(t, h.enqueueOptions(0, config.PriorityNormal)...)
+			if err == nil {
+				result.TaskID = taskInfo.ID
+			}
+		}
+
+		if err != nil {
+			logger.Error("Failed to enqueue batch task",
+				zap.String("request_id", requestID),
+				zap.String("table_name", req.TableName),
+				zap.Int64("record_id", id),
+				zap.Error(err))
+			result.Status = "failed"
+			result.Error = "Failed to enqueue task"
+			resp.Failed++
+		} else {
+			result.Status = "enqueued"
+			resp.Enqueued++
+		}
+		resp.Results[i] = result
+	}
+
+	logger.Info("Batch tasks created",
+		zap.String("request_id", requestID),
+		zap.String("table_name", req.TableName),
+		zap.Int("enqueued", resp.Enqueued),
+		zap.Int("failed", resp.Failed))
+
+	c.JSON(http.StatusOK, types.CommonResponse{
+		Code:    200,
+		Message: "Success",
+		Data:    resp,
+	})
+}
+
 // GetTaskStatus 获取任务状态
 func (h *TaskHandler) GetTaskStatus(c *gin.Context) {
 	// 记录请求处理时间
@@ -111,7 +346,7 @@ func (h *TaskHandler) GetTaskStatus(c *gin.Context) {
 	}
 
 	// 使用检查器获取任务信息
-	taskInfo, err := h.inspector.GetTaskInfo("default", taskID)
+	taskInfo, err := h.getTaskInfo(taskID)
 	if err != nil {
 		logger.Error("Failed to get task info",
 			zap.String("task_id", taskID),
@@ -131,19 +366,58 @@ func (h *TaskHandler) GetTaskStatus(c *gin.Context) {
 		return
 	}
 
+	resp := types.GetTaskStatusResponse{
+		TaskID:     taskInfo.ID,
+		Status:     taskInfo.State.String(),
+		QueueName:  taskInfo.Queue,
+		CreatedAt:  taskInfo.NextProcessAt.Unix(),
+		RetryCount: taskInfo.Retried,
+		MaxRetry:   taskInfo.MaxRetry,
+		LastError:  taskInfo.LastErr,
+	}
+	if !taskInfo.LastFailedAt.IsZero() {
+		resp.LastFailedAt = taskInfo.LastFailedAt.Unix()
+	}
+
+	// 解析任务载荷，便于客户端关联到原始记录
+	// 无法解析的载荷（例如其他任务类型）保持字段为空，不视为错误
+	var payload task.LLMPayload
+	if err := json.Unmarshal(taskInfo.Payload, &payload); err == nil {
+		resp.TableName = payload.TableName
+		resp.RecordID = payload.ID
+	}
+
+	// 已完成的任务返回 worker 写入任务结果的报告，同样忽略无法解析的结果
+	var result task.LLMResult
+	if len(taskInfo.Result) > 0 && json.Unmarshal(taskInfo.Result, &result) == nil {
+		resp.Result = result.Report
+		resp.ResultTruncated = result.Truncated
+	}
+
 	c.JSON(http.StatusOK, types.CommonResponse{
 		Code:    200,
 		Message: "Success",
-		Data: types.GetTaskStatusResponse{
-			TaskID:     taskInfo.ID,
-			Status:     taskInfo.State.String(),
-			QueueName:  taskInfo.Queue,
-			CreatedAt:  taskInfo.NextProcessAt.Unix(),
-			RetryCount: taskInfo.Retried,
-		},
+		Data:    resp,
 	})
 }
 
+// getTaskInfo 在 LLM 任务可能所在的队列中按ID查找任务，包括配置的优先级队列。
+// 所有队列中都不存在时返回最后一个队列的错误
+func (h *TaskHandler) getTaskInfo(taskID string) (*asynq.TaskInfo, error) {
+	var err error
+	for _, queueName := range h.queue.QueuesFor(task.TypeLLM) {
+		var info *asynq.TaskInfo
+		info, err = h.inspector.GetTaskInfo(queueName, taskID)
+		if err == nil {
+			return info, nil
+		}
+		if !errors.Is(err, asynq.ErrTaskNotFound) && !errors.Is(err, asynq.ErrQueueNotFound) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
 // ListTasks 列出任务
 func (h *TaskHandler) ListTasks(c *gin.Context) {
 	// 记录请求处理时间
@@ -152,10 +426,7 @@ func (h *TaskHandler) ListTasks(c *gin.Context) {
 	// 解析查询参数
 	var req types.ListTasksRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, types.CommonResponse{
-			Code:    400,
-			Message: "Invalid query parameters: " + err.Error(),
-		})
+		c.JSON(http.StatusBadRequest, bindErrorResponse(err))
 		return
 	}
 
@@ -170,7 +441,26 @@ func (h *TaskHandler) ListTasks(c *gin.Context) {
 		req.Offset = 0
 	}
 
-	queueName := "default"
+	// asynq 只支持按页读取。offset 是 limit 的整数倍时直接读取对应页，
+	// 否则从第一页读取 offset+limit 个任务，再在内存中跳过前 offset 个
+	pageSize, page, skip := req.Limit, req.Offset/req.Limit+1, 0
+	if req.Offset%req.Limit != 0 {
+		maxListSize := h.queue.MaxListSize
+		if maxListSize <= 0 {
+			maxListSize = defaultMaxListSize
+		}
+		if req.Offset+req.Limit > maxListSize {
+			c.JSON(http.StatusBadRequest, types.CommonResponse{
+				Code: 400,
+				Message: fmt.Sprintf("offset %d is not a multiple of limit %d and offset+limit exceeds maximum of %d",
+					req.Offset, req.Limit, maxListSize),
+			})
+			return
+		}
+		pageSize, page, skip = req.Offset+req.Limit, 1, req.Offset
+	}
+
+	queueName := h.queue.QueueFor(task.TypeLLM)
 	if req.QueueName != "" {
 		queueName = req.QueueName
 	}
@@ -200,7 +490,7 @@ func (h *TaskHandler) ListTasks(c *gin.Context) {
 	var err error
 
 	// 使用适当的列表方法
-	opts := []asynq.ListOption{asynq.PageSize(req.Limit), asynq.Page(req.Offset/req.Limit + 1)}
+	opts := []asynq.ListOption{asynq.PageSize(pageSize), asynq.Page(page)}
 
 	switch state {
 	case asynq.TaskStatePending:
@@ -228,6 +518,12 @@ func (h *TaskHandler) ListTasks(c *gin.Context) {
 		return
 	}
 
+	if skip >= len(tasks) {
+		tasks = nil
+	} else {
+		tasks = tasks[skip:]
+	}
+
 	// 获取总数
 	// 注意：asynq 不提供直接的计数方法，我们使用列表长度作为估计值
 	totalCount := len(tasks)
@@ -4,14 +4,18 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/igwen6w/syt-go-queue/internal/task"
 	"github.com/igwen6w/syt-go-queue/internal/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 // 设置 Gin 测试模式
@@ -69,7 +73,7 @@ func TestCreateLLMTask(t *testing.T) {
 			mockSetup:      func() {},
 			expectedStatus: http.StatusBadRequest,
 			expectedCode:   400,
-			expectedMsg:    "Key: 'CreateTaskRequest.TableName' Error:Field validation for 'TableName' failed on the 'required' tag",
+			expectedMsg:    "Validation failed: table_name is required",
 		},
 		{
 			name: "invalid request - missing id",
@@ -79,7 +83,72 @@ func TestCreateLLMTask(t *testing.T) {
 			mockSetup:      func() {},
 			expectedStatus: http.StatusBadRequest,
 			expectedCode:   400,
-			expectedMsg:    "Key: 'CreateTaskRequest.ID' Error:Field validation for 'ID' failed on the 'required' tag",
+			expectedMsg:    "Validation failed: id is required",
+		},
+		{
+			name: "valid request with model overrides",
+			requestBody: types.CreateTaskRequest{
+				TableName: "test_table",
+				ID:        123,
+				Model:     "deepseek-reasoner",
+				MaxTokens: 500,
+			},
+			mockSetup: func() {
+				// 验证载荷中携带了模型参数
+				mockClient.On("Enqueue", mock.MatchedBy(func(tk *asynq.Task) bool {
+					var p task.LLMPayload
+					if err := json.Unmarshal(tk.Payload(), &p); err != nil {
+						return false
+					}
+					return p.Model == "deepseek-reasoner" && p.MaxTokens == 500 && p.EnqueuedAt > 0
+				}), mock.Anything).Return(&asynq.TaskInfo{
+					ID:    "task123",
+					Queue: "default",
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedCode:   200,
+			expectedMsg:    "Success",
+		},
+		{
+			name: "invalid request - negative max_tokens",
+			requestBody: map[string]interface{}{
+				"table_name": "test_table",
+				"id":         123,
+				"max_tokens": -1,
+			},
+			mockSetup:      func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   400,
+			expectedMsg:    "Validation failed: max_tokens must be at least 1",
+		},
+		{
+			name: "idempotent request with existing task",
+			requestBody: types.CreateTaskRequest{
+				TableName:  "test_table",
+				ID:         123,
+				Idempotent: true,
+			},
+			mockSetup: func() {
+				// 模拟任务ID冲突
+				mockClient.On("Enqueue", mock.Anything, mock.Anything).Return(nil, asynq.ErrTaskIDConflict)
+			},
+			expectedStatus: http.StatusOK,
+			expectedCode:   200,
+			expectedMsg:    "Success",
+		},
+		{
+			name: "non-idempotent request with conflict",
+			requestBody: types.CreateTaskRequest{
+				TableName: "test_table",
+				ID:        123,
+			},
+			mockSetup: func() {
+				mockClient.On("Enqueue", mock.Anything, mock.Anything).Return(nil, asynq.ErrTaskIDConflict)
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   500,
+			expectedMsg:    "Failed to enqueue task",
 		},
 		{
 			name: "enqueue error",
@@ -134,7 +203,7 @@ func TestCreateLLMTask(t *testing.T) {
 	}
 }
 
-func TestGetTaskStatus(t *testing.T) {
+func TestCreateBatchLLMTask(t *testing.T) {
 	// 创建模拟对象
 	mockClient := new(MockAsynqClient)
 	mockDB := new(MockDatabase)
@@ -142,6 +211,7 @@ func TestGetTaskStatus(t *testing.T) {
 
 	// 创建任务处理器
 	handler := &TaskHandler{
+		queue:     config.QueueConfig{MaxBatchSize: 3},
 		client:    mockClient,
 		db:        mockDB,
 		inspector: mockInspector,
@@ -149,15 +219,295 @@ func TestGetTaskStatus(t *testing.T) {
 
 	// 创建 Gin 路由
 	router := gin.New()
-	router.GET("/api/tasks/:id", handler.GetTaskStatus)
+	router.POST("/api/tasks/llm/batch", handler.CreateBatchLLMTask)
 
+	tests := []struct {
+		name             string
+		requestBody      interface{}
+		mockSetup        func()
+		expectedStatus   int
+		expectedCode     int
+		expectedMsg      string
+		expectedEnqueued float64
+		expectedFailed   float64
+	}{
+		{
+			name: "all enqueued",
+			requestBody: types.CreateBatchTaskRequest{
+				TableName: "test_table",
+				IDs:       []int64{1, 2},
+			},
+			mockSetup: func() {
+				mockClient.On("Enqueue", mock.Anything, mock.Anything).Return(&asynq.TaskInfo{
+					ID:    "task123",
+					Queue: "default",
+				}, nil)
+			},
+			expectedStatus:   http.StatusOK,
+			expectedCode:     200,
+			expectedMsg:      "Success",
+			expectedEnqueued: 2,
+			expectedFailed:   0,
+		},
+		{
+			name: "partial failure",
+			requestBody: types.CreateBatchTaskRequest{
+				TableName: "test_table",
+				IDs:       []int64{1, 2, 3},
+			},
+			mockSetup: func() {
+				// 第二个任务入队失败
+				mockClient.On("Enqueue", mock.Anything, mock.Anything).Return(&asynq.TaskInfo{
+					ID:    "task1",
+					Queue: "default",
+				}, nil).Once()
+				mockClient.On("Enqueue", mock.Anything, mock.Anything).Return(nil, errors.New("enqueue error")).Once()
+				mockClient.On("Enqueue", mock.Anything, mock.Anything).Return(&asynq.TaskInfo{
+					ID:    "task3",
+					Queue: "default",
+				}, nil).Once()
+			},
+			expectedStatus:   http.StatusOK,
+			expectedCode:     200,
+			expectedMsg:      "Success",
+			expectedEnqueued: 2,
+			expectedFailed:   1,
+		},
+		{
+			name: "batch too large",
+			requestBody: types.CreateBatchTaskRequest{
+				TableName: "test_table",
+				IDs:       []int64{1, 2, 3, 4},
+			},
+			mockSetup:      func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   400,
+			expectedMsg:    "exceeds maximum of 3",
+		},
+		{
+			name: "empty ids",
+			requestBody: map[string]interface{}{
+				"table_name": "test_table",
+				"ids":        []int64{},
+			},
+			mockSetup:      func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   400,
+			expectedMsg:    "Validation failed: ids must have at least 1 elements",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 重置模拟对象
+			mockClient.ExpectedCalls = nil
+			mockDB.ExpectedCalls = nil
+			mockInspector.ExpectedCalls = nil
+
+			// 设置模拟行为
+			tt.mockSetup()
+
+			// 创建请求
+			jsonData, _ := json.Marshal(tt.requestBody)
+			req, _ := http.NewRequest("POST", "/api/tasks/llm/batch", bytes.NewBuffer(jsonData))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			// 发送请求
+			router.ServeHTTP(resp, req)
+
+			// 验证响应状态码
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+
+			// 解析响应
+			var response types.CommonResponse
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+
+			// 验证响应内容
+			assert.Equal(t, tt.expectedCode, response.Code)
+			assert.Contains(t, response.Message, tt.expectedMsg)
+
+			// 如果是成功响应，验证入队和失败数量
+			if tt.expectedStatus == http.StatusOK {
+				data, ok := response.Data.(map[string]interface{})
+				assert.True(t, ok)
+				assert.Equal(t, tt.expectedEnqueued, data["enqueued"])
+				assert.Equal(t, tt.expectedFailed, data["failed"])
+			}
+
+			// 验证模拟对象的调用
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreateLLMTask_AllowedModels(t *testing.T) {
 	tests := []struct {
 		name           string
-		taskID         string
-		mockSetup      func()
+		allowedModels  []string
+		model          string
 		expectedStatus int
-		expectedCode   int
 		expectedMsg    string
+	}{
+		{
+			name:           "allowed model",
+			allowedModels:  []string{"deepseek-chat", "deepseek-reasoner"},
+			model:          "deepseek-reasoner",
+			expectedStatus: http.StatusOK,
+			expectedMsg:    "Success",
+		},
+		{
+			name:           "disallowed model",
+			allowedModels:  []string{"deepseek-chat"},
+			model:          "gpt-4",
+			expectedStatus: http.StatusBadRequest,
+			expectedMsg:    "Model gpt-4 is not allowed",
+		},
+		{
+			name:           "empty allowlist allows any model",
+			allowedModels:  nil,
+			model:          "gpt-4",
+			expectedStatus: http.StatusOK,
+			expectedMsg:    "Success",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockAsynqClient)
+			mockClient.On("Enqueue", mock.Anything, mock.Anything).Return(&asynq.TaskInfo{
+				ID:    "task123",
+				Queue: "default",
+			}, nil)
+
+			handler := &TaskHandler{
+				client:   mockClient,
+				deepseek: config.DeepseekConfig{Model: "deepseek-chat", AllowedModels: tt.allowedModels},
+			}
+			router := gin.New()
+			router.POST("/api/tasks/llm", handler.CreateLLMTask)
+
+			body, _ := json.Marshal(types.CreateTaskRequest{TableName: "test_table", ID: 123, Model: tt.model})
+			req, _ := http.NewRequest("POST", "/api/tasks/llm", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+
+			var response types.CommonResponse
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Contains(t, response.Message, tt.expectedMsg)
+
+			if tt.expectedStatus != http.StatusOK {
+				mockClient.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestCreateLLMTask_ModelMaxTokens(t *testing.T) {
+	tests := []struct {
+		name           string
+		model          string
+		maxTokens      int
+		expectedStatus int
+		expectedMsg    string
+	}{
+		{
+			name:           "within limit",
+			maxTokens:      8192,
+			expectedStatus: http.StatusOK,
+			expectedMsg:    "Success",
+		},
+		{
+			name:           "over limit of default model",
+			maxTokens:      8193,
+			expectedStatus: http.StatusBadRequest,
+			expectedMsg:    "max_tokens 8193 exceeds the limit of 8192 for model deepseek-chat",
+		},
+		{
+			name:           "over limit of task model",
+			model:          "deepseek-reasoner",
+			maxTokens:      40000,
+			expectedStatus: http.StatusBadRequest,
+			expectedMsg:    "max_tokens 40000 exceeds the limit of 32768 for model deepseek-reasoner",
+		},
+		{
+			name:           "model without limit",
+			model:          "other-model",
+			maxTokens:      100000,
+			expectedStatus: http.StatusOK,
+			expectedMsg:    "Success",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockAsynqClient)
+			mockClient.On("Enqueue", mock.Anything, mock.Anything).Return(&asynq.TaskInfo{
+				ID:    "task123",
+				Queue: "default",
+			}, nil)
+
+			handler := &TaskHandler{
+				client: mockClient,
+				deepseek: config.DeepseekConfig{
+					Model:          "deepseek-chat",
+					ModelMaxTokens: map[string]int{"deepseek-chat": 8192, "deepseek-reasoner": 32768},
+				},
+			}
+			router := gin.New()
+			router.POST("/api/tasks/llm", handler.CreateLLMTask)
+
+			body, _ := json.Marshal(types.CreateTaskRequest{TableName: "test_table", ID: 123, Model: tt.model, MaxTokens: tt.maxTokens})
+			req, _ := http.NewRequest("POST", "/api/tasks/llm", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+
+			var response types.CommonResponse
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Contains(t, response.Message, tt.expectedMsg)
+
+			if tt.expectedStatus != http.StatusOK {
+				mockClient.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestGetTaskStatus(t *testing.T) {
+	// 创建模拟对象
+	mockClient := new(MockAsynqClient)
+	mockDB := new(MockDatabase)
+	mockInspector := new(MockAsynqInspector)
+
+	// 创建任务处理器
+	handler := &TaskHandler{
+		client:    mockClient,
+		db:        mockDB,
+		inspector: mockInspector,
+	}
+
+	// 创建 Gin 路由
+	router := gin.New()
+	router.GET("/api/tasks/:id", handler.GetTaskStatus)
+
+	tests := []struct {
+		name              string
+		taskID            string
+		mockSetup         func()
+		expectedStatus    int
+		expectedCode      int
+		expectedMsg       string
+		expectedTableName string
+		expectedRecordID  float64
+		expectedLastError string
+		expectedResult    string
 	}{
 		{
 			name:   "valid task id",
@@ -169,6 +519,68 @@ func TestGetTaskStatus(t *testing.T) {
 					Queue:   "default",
 					State:   asynq.TaskStateActive,
 					Retried: 0,
+					Payload: []byte(`{"table_name":"test_table","id":123}`),
+				}, nil)
+			},
+			expectedStatus:    http.StatusOK,
+			expectedCode:      200,
+			expectedMsg:       "Success",
+			expectedTableName: "test_table",
+			expectedRecordID:  123,
+		},
+		{
+			name:   "retried task",
+			taskID: "task789",
+			mockSetup: func() {
+				// 模拟重试过的任务，应返回最近一次的错误信息
+				mockInspector.On("GetTaskInfo", "default", "task789").Return(&asynq.TaskInfo{
+					ID:           "task789",
+					Queue:        "default",
+					State:        asynq.TaskStateRetry,
+					Retried:      2,
+					MaxRetry:     3,
+					LastErr:      "LLM API request failed with status: 500",
+					LastFailedAt: time.Unix(1700000000, 0),
+					Payload:      []byte(`{"table_name":"test_table","id":789}`),
+				}, nil)
+			},
+			expectedStatus:    http.StatusOK,
+			expectedCode:      200,
+			expectedMsg:       "Success",
+			expectedTableName: "test_table",
+			expectedRecordID:  789,
+			expectedLastError: "LLM API request failed with status: 500",
+		},
+		{
+			name:   "completed task with result",
+			taskID: "task321",
+			mockSetup: func() {
+				// 模拟已完成的任务，应返回 worker 写入的报告
+				mockInspector.On("GetTaskInfo", "default", "task321").Return(&asynq.TaskInfo{
+					ID:      "task321",
+					Queue:   "default",
+					State:   asynq.TaskStateCompleted,
+					Payload: []byte(`{"table_name":"test_table","id":321}`),
+					Result:  []byte(`{"report":"valuation report","truncated":true}`),
+				}, nil)
+			},
+			expectedStatus:    http.StatusOK,
+			expectedCode:      200,
+			expectedMsg:       "Success",
+			expectedTableName: "test_table",
+			expectedRecordID:  321,
+			expectedResult:    "valuation report",
+		},
+		{
+			name:   "undecodable payload",
+			taskID: "task456",
+			mockSetup: func() {
+				// 模拟无法解析的载荷，字段应保持为空
+				mockInspector.On("GetTaskInfo", "default", "task456").Return(&asynq.TaskInfo{
+					ID:      "task456",
+					Queue:   "default",
+					State:   asynq.TaskStatePending,
+					Payload: []byte("not-json"),
 				}, nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -228,6 +640,33 @@ func TestGetTaskStatus(t *testing.T) {
 			assert.Equal(t, tt.expectedCode, response.Code)
 			assert.Contains(t, response.Message, tt.expectedMsg)
 
+			// 如果是成功响应，验证载荷中的记录信息
+			if tt.expectedStatus == http.StatusOK {
+				data, ok := response.Data.(map[string]interface{})
+				assert.True(t, ok)
+				if tt.expectedTableName != "" {
+					assert.Equal(t, tt.expectedTableName, data["table_name"])
+					assert.Equal(t, tt.expectedRecordID, data["record_id"])
+				} else {
+					assert.NotContains(t, data, "table_name")
+					assert.NotContains(t, data, "record_id")
+				}
+				if tt.expectedLastError != "" {
+					assert.Equal(t, tt.expectedLastError, data["last_error"])
+					assert.Equal(t, float64(1700000000), data["last_failed_at"])
+					assert.Equal(t, float64(3), data["max_retry"])
+				} else {
+					assert.NotContains(t, data, "last_error")
+					assert.NotContains(t, data, "last_failed_at")
+				}
+				if tt.expectedResult != "" {
+					assert.Equal(t, tt.expectedResult, data["result"])
+					assert.Equal(t, true, data["result_truncated"])
+				} else {
+					assert.NotContains(t, data, "result")
+				}
+			}
+
 			// 验证模拟对象的调用
 			mockInspector.AssertExpectations(t)
 		})
@@ -396,3 +835,520 @@ func TestListTasks(t *testing.T) {
 		})
 	}
 }
+
+func TestListTasks_Pagination(t *testing.T) {
+	// newTasks 生成 n 个任务，ID 为 task0、task1 ...
+	newTasks := func(n int) []*asynq.TaskInfo {
+		tasks := make([]*asynq.TaskInfo, n)
+		for i := range tasks {
+			tasks[i] = &asynq.TaskInfo{ID: fmt.Sprintf("task%d", i), Queue: "default", State: asynq.TaskStatePending}
+		}
+		return tasks
+	}
+
+	tests := []struct {
+		name           string
+		queryParams    string
+		maxListSize    int
+		expectedOpts   []asynq.ListOption
+		listed         []*asynq.TaskInfo
+		expectedStatus int
+		expectedFirst  string
+		expectedCount  int
+	}{
+		{
+			name:           "aligned offset reads the matching page",
+			queryParams:    "?status=pending&offset=20&limit=10",
+			expectedOpts:   []asynq.ListOption{asynq.PageSize(10), asynq.Page(3)},
+			listed:         newTasks(10),
+			expectedStatus: http.StatusOK,
+			expectedFirst:  "task0",
+			expectedCount:  10,
+		},
+		{
+			name:           "offset 5 limit 10",
+			queryParams:    "?status=pending&offset=5&limit=10",
+			expectedOpts:   []asynq.ListOption{asynq.PageSize(15), asynq.Page(1)},
+			listed:         newTasks(15),
+			expectedStatus: http.StatusOK,
+			expectedFirst:  "task5",
+			expectedCount:  10,
+		},
+		{
+			name:           "offset 15 limit 10",
+			queryParams:    "?status=pending&offset=15&limit=10",
+			expectedOpts:   []asynq.ListOption{asynq.PageSize(25), asynq.Page(1)},
+			listed:         newTasks(25),
+			expectedStatus: http.StatusOK,
+			expectedFirst:  "task15",
+			expectedCount:  10,
+		},
+		{
+			name:           "offset beyond last task",
+			queryParams:    "?status=pending&offset=15&limit=10",
+			expectedOpts:   []asynq.ListOption{asynq.PageSize(25), asynq.Page(1)},
+			listed:         newTasks(12),
+			expectedStatus: http.StatusOK,
+			expectedCount:  0,
+		},
+		{
+			name:           "unaligned window exceeds max list size",
+			queryParams:    "?status=pending&offset=95&limit=10",
+			maxListSize:    100,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockInspector := new(MockAsynqInspector)
+			if tt.expectedOpts != nil {
+				mockInspector.On("ListPendingTasks", "default", tt.expectedOpts).Return(tt.listed, nil)
+			}
+
+			handler := &TaskHandler{
+				queue:     config.QueueConfig{MaxListSize: tt.maxListSize},
+				inspector: mockInspector,
+			}
+			router := gin.New()
+			router.GET("/api/tasks", handler.ListTasks)
+
+			req, _ := http.NewRequest("GET", "/api/tasks"+tt.queryParams, nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+			mockInspector.AssertExpectations(t)
+			if tt.expectedStatus != http.StatusOK {
+				mockInspector.AssertNotCalled(t, "ListPendingTasks", mock.Anything, mock.Anything)
+				return
+			}
+
+			var response struct {
+				Data types.ListTasksResponse `json:"data"`
+			}
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.Len(t, response.Data.Tasks, tt.expectedCount)
+			if tt.expectedFirst != "" && len(response.Data.Tasks) > 0 {
+				assert.Equal(t, tt.expectedFirst, response.Data.Tasks[0].TaskID)
+			}
+		})
+	}
+}
+
+func TestCreateLLMTask_Retention(t *testing.T) {
+	// retentionOf 从入队选项中取出结果保留时长
+	retentionOf := func(opts []asynq.Option) time.Duration {
+		for _, opt := range opts {
+			if opt.Type() == asynq.RetentionOpt {
+				return opt.Value().(time.Duration)
+			}
+		}
+		return 0
+	}
+
+	tests := []struct {
+		name              string
+		queueRetention    time.Duration
+		requestBody       types.CreateTaskRequest
+		expectedRetention time.Duration
+	}{
+		{
+			name:              "config retention",
+			queueRetention:    24 * time.Hour,
+			requestBody:       types.CreateTaskRequest{TableName: "test_table", ID: 123},
+			expectedRetention: 24 * time.Hour,
+		},
+		{
+			name:           "per-task override",
+			queueRetention: 24 * time.Hour,
+			requestBody: types.CreateTaskRequest{
+				TableName:        "test_table",
+				ID:               123,
+				RetentionSeconds: 600,
+			},
+			expectedRetention: 10 * time.Minute,
+		},
+		{
+			name:              "retention not configured",
+			requestBody:       types.CreateTaskRequest{TableName: "test_table", ID: 123},
+			expectedRetention: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockAsynqClient)
+			handler := &TaskHandler{
+				queue:  config.QueueConfig{Retention: tt.queueRetention},
+				client: mockClient,
+			}
+
+			router := gin.New()
+			router.POST("/api/tasks/llm", handler.CreateLLMTask)
+
+			// 验证入队选项中的保留时长
+			mockClient.On("Enqueue", mock.Anything, mock.MatchedBy(func(opts []asynq.Option) bool {
+				return retentionOf(opts) == tt.expectedRetention
+			})).Return(&asynq.TaskInfo{ID: "task123", Queue: "default"}, nil)
+
+			body, _ := json.Marshal(tt.requestBody)
+			req, _ := http.NewRequest("POST", "/api/tasks/llm", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, http.StatusOK, resp.Code)
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreateLLMTask_ProcessAt(t *testing.T) {
+	// processAtOf 从入队选项中取出计划执行时间
+	processAtOf := func(opts []asynq.Option) time.Time {
+		for _, opt := range opts {
+			if opt.Type() == asynq.ProcessAtOpt {
+				return opt.Value().(time.Time)
+			}
+		}
+		return time.Time{}
+	}
+
+	future := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	tests := []struct {
+		name           string
+		processAt      string
+		expectEnqueue  bool
+		expectedStatus int
+		expectedMsg    string
+		expectedState  string
+	}{
+		{
+			name:           "scheduled",
+			processAt:      future.Format(time.RFC3339),
+			expectEnqueue:  true,
+			expectedStatus: http.StatusOK,
+			expectedMsg:    "Success",
+			expectedState:  "scheduled",
+		},
+		{
+			name:           "within clock skew",
+			processAt:      time.Now().Add(-10 * time.Second).Format(time.RFC3339),
+			expectEnqueue:  true,
+			expectedStatus: http.StatusOK,
+			expectedMsg:    "Success",
+			expectedState:  "scheduled",
+		},
+		{
+			name:           "in the past",
+			processAt:      time.Now().Add(-time.Hour).Format(time.RFC3339),
+			expectedStatus: http.StatusBadRequest,
+			expectedMsg:    "is in the past",
+		},
+		{
+			name:           "invalid format",
+			processAt:      "tomorrow",
+			expectedStatus: http.StatusBadRequest,
+			expectedMsg:    "process_at must be an RFC3339 timestamp",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockAsynqClient)
+			handler := &TaskHandler{client: mockClient}
+
+			router := gin.New()
+			router.POST("/api/tasks/llm", handler.CreateLLMTask)
+
+			if tt.expectEnqueue {
+				expected, _ := time.Parse(time.RFC3339, tt.processAt)
+				mockClient.On("Enqueue", mock.Anything, mock.MatchedBy(func(opts []asynq.Option) bool {
+					return processAtOf(opts).Equal(expected)
+				})).Return(&asynq.TaskInfo{ID: "task123", Queue: "default"}, nil)
+			}
+
+			body, _ := json.Marshal(types.CreateTaskRequest{
+				TableName: "test_table",
+				ID:        123,
+				ProcessAt: tt.processAt,
+			})
+			req, _ := http.NewRequest("POST", "/api/tasks/llm", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+
+			var response types.CommonResponse
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Contains(t, response.Message, tt.expectedMsg)
+			if tt.expectedState != "" {
+				data, ok := response.Data.(map[string]interface{})
+				assert.True(t, ok)
+				assert.Equal(t, tt.expectedState, data["status"])
+			}
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDefaultQueue(t *testing.T) {
+	// queueOf 从入队选项中取出目标队列
+	queueOf := func(opts []asynq.Option) string {
+		for _, opt := range opts {
+			if opt.Type() == asynq.QueueOpt {
+				return opt.Value().(string)
+			}
+		}
+		return ""
+	}
+
+	mockClient := new(MockAsynqClient)
+	mockInspector := new(MockAsynqInspector)
+	handler := &TaskHandler{
+		queue:     config.QueueConfig{DefaultQueue: "valuation"},
+		client:    mockClient,
+		inspector: mockInspector,
+	}
+
+	router := gin.New()
+	router.POST("/api/tasks/llm", handler.CreateLLMTask)
+	router.GET("/api/tasks/:id", handler.GetTaskStatus)
+
+	// 新任务进入配置的队列
+	mockClient.On("Enqueue", mock.Anything, mock.MatchedBy(func(opts []asynq.Option) bool {
+		return queueOf(opts) == "valuation"
+	})).Return(&asynq.TaskInfo{ID: "task123", Queue: "valuation"}, nil)
+
+	body, _ := json.Marshal(types.CreateTaskRequest{TableName: "test_table", ID: 123})
+	req, _ := http.NewRequest("POST", "/api/tasks/llm", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	// 查询任务状态时使用配置的队列
+	mockInspector.On("GetTaskInfo", "valuation", "task123").Return(&asynq.TaskInfo{
+		ID:    "task123",
+		Queue: "valuation",
+		State: asynq.TaskStatePending,
+	}, nil)
+
+	req, _ = http.NewRequest("GET", "/api/tasks/task123", nil)
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	mockClient.AssertExpectations(t)
+	mockInspector.AssertExpectations(t)
+}
+
+func TestCreateLLMTask_Priority(t *testing.T) {
+	// queueOf 从入队选项中取出目标队列
+	queueOf := func(opts []asynq.Option) string {
+		for _, opt := range opts {
+			if opt.Type() == asynq.QueueOpt {
+				return opt.Value().(string)
+			}
+		}
+		return ""
+	}
+
+	queueCfg := config.QueueConfig{
+		PriorityQueues: config.PriorityQueues{
+			High: config.PriorityQueue{Name: "llm-high"},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		priority       string
+		expectedQueue  string
+		expectedStatus int
+		expectedMsg    string
+	}{
+		{
+			name:           "default priority",
+			priority:       "",
+			expectedQueue:  "default",
+			expectedStatus: http.StatusOK,
+			expectedMsg:    "Success",
+		},
+		{
+			name:           "high priority",
+			priority:       "high",
+			expectedQueue:  "llm-high",
+			expectedStatus: http.StatusOK,
+			expectedMsg:    "Success",
+		},
+		{
+			name:           "low priority without queue uses normal queue",
+			priority:       "low",
+			expectedQueue:  "default",
+			expectedStatus: http.StatusOK,
+			expectedMsg:    "Success",
+		},
+		{
+			name:           "invalid priority",
+			priority:       "urgent",
+			expectedStatus: http.StatusBadRequest,
+			expectedMsg:    "priority must be one of [high normal low]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockAsynqClient)
+			handler := &TaskHandler{queue: queueCfg, client: mockClient}
+
+			router := gin.New()
+			router.POST("/api/tasks/llm", handler.CreateLLMTask)
+
+			if tt.expectedQueue != "" {
+				mockClient.On("Enqueue", mock.Anything, mock.MatchedBy(func(opts []asynq.Option) bool {
+					return queueOf(opts) == tt.expectedQueue
+				})).Return(&asynq.TaskInfo{ID: "task123", Queue: tt.expectedQueue}, nil)
+			}
+
+			body, _ := json.Marshal(types.CreateTaskRequest{
+				TableName: "test_table",
+				ID:        123,
+				Priority:  tt.priority,
+			})
+			req, _ := http.NewRequest("POST", "/api/tasks/llm", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+
+			var response types.CommonResponse
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Contains(t, response.Message, tt.expectedMsg)
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetTaskStatus_PriorityQueue(t *testing.T) {
+	mockInspector := new(MockAsynqInspector)
+	handler := &TaskHandler{
+		queue: config.QueueConfig{
+			PriorityQueues: config.PriorityQueues{
+				High: config.PriorityQueue{Name: "llm-high"},
+			},
+		},
+		inspector: mockInspector,
+	}
+
+	router := gin.New()
+	router.GET("/api/tasks/:id", handler.GetTaskStatus)
+
+	// 任务不在普通队列时继续在优先级队列中查找
+	mockInspector.On("GetTaskInfo", "default", "task123").Return(nil, asynq.ErrTaskNotFound)
+	mockInspector.On("GetTaskInfo", "llm-high", "task123").Return(&asynq.TaskInfo{
+		ID:    "task123",
+		Queue: "llm-high",
+		State: asynq.TaskStatePending,
+	}, nil)
+
+	req, _ := http.NewRequest("GET", "/api/tasks/task123", nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var response struct {
+		Data types.GetTaskStatusResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+	assert.Equal(t, "llm-high", response.Data.QueueName)
+
+	mockInspector.AssertExpectations(t)
+}
+
+func TestCreateLLMTask_ContentTypes(t *testing.T) {
+	tests := []struct {
+		name           string
+		contentType    string
+		body           string
+		expectedStatus int
+		expectedMsg    string
+	}{
+		{
+			name:           "json body",
+			contentType:    "application/json",
+			body:           `{"table_name":"test_table","id":123,"max_tokens":500}`,
+			expectedStatus: http.StatusOK,
+			expectedMsg:    "Success",
+		},
+		{
+			name:           "form body",
+			contentType:    "application/x-www-form-urlencoded",
+			body:           "table_name=test_table&id=123&max_tokens=500",
+			expectedStatus: http.StatusOK,
+			expectedMsg:    "Success",
+		},
+		{
+			name:           "json body without content type",
+			contentType:    "",
+			body:           `{"table_name":"test_table","id":123,"max_tokens":500}`,
+			expectedStatus: http.StatusOK,
+			expectedMsg:    "Success",
+		},
+		{
+			name:           "form body missing id",
+			contentType:    "application/x-www-form-urlencoded",
+			body:           "table_name=test_table",
+			expectedStatus: http.StatusBadRequest,
+			expectedMsg:    "Validation failed: id is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(MockAsynqClient)
+			handler := &TaskHandler{client: mockClient}
+
+			router := gin.New()
+			router.POST("/api/tasks/llm", handler.CreateLLMTask)
+
+			// 两种格式绑定出相同的载荷
+			mockClient.On("Enqueue", mock.MatchedBy(func(tk *asynq.Task) bool {
+				var p task.LLMPayload
+				if err := json.Unmarshal(tk.Payload(), &p); err != nil {
+					return false
+				}
+				return p.TableName == "test_table" && p.ID == 123 && p.MaxTokens == 500
+			}), mock.Anything).Return(&asynq.TaskInfo{ID: "task123", Queue: "default"}, nil)
+
+			req, _ := http.NewRequest("POST", "/api/tasks/llm", bytes.NewBufferString(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+
+			var response types.CommonResponse
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedMsg, response.Message)
+
+			if tt.expectedStatus == http.StatusOK {
+				mockClient.AssertExpectations(t)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/metrics"
+	"github.com/igwen6w/syt-go-queue/internal/task"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"net/http"
+)
+
+// ListTaskTypes 列出已注册的任务类型及其载荷的 JSON Schema，便于客户端发现和生成 SDK
+func (h *TaskHandler) ListTaskTypes(c *gin.Context) {
+	// 记录请求处理时间
+	defer metrics.MeasureRequestDuration(c.Request.Method, c.FullPath())()
+
+	c.JSON(http.StatusOK, types.CommonResponse{
+		Code:    200,
+		Message: "Success",
+		Data:    types.TaskTypesResponse{Types: task.Definitions()},
+	})
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/task"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListTaskTypes(t *testing.T) {
+	handler := &TaskHandler{}
+
+	// 与服务器一致，类型列表路由和任务ID路由共存
+	router := gin.New()
+	router.GET("/api/tasks/types", handler.ListTaskTypes)
+	router.GET("/api/tasks/:id", func(c *gin.Context) {
+		c.Status(http.StatusTeapot)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tasks/types", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Code int `json:"code"`
+		Data struct {
+			Types []task.Definition `json:"types"`
+		} `json:"data"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, response.Code)
+
+	var llm *task.Definition
+	for i := range response.Data.Types {
+		if response.Data.Types[i].Type == task.TypeLLM {
+			llm = &response.Data.Types[i]
+		}
+	}
+	if assert.NotNil(t, llm, "LLM task type should be registered") {
+		assert.NotEmpty(t, llm.Description)
+		assert.ElementsMatch(t, []interface{}{"table_name", "id"}, llm.Schema["required"])
+		properties, ok := llm.Schema["properties"].(map[string]interface{})
+		assert.True(t, ok)
+		assert.Contains(t, properties, "table_name")
+		assert.Contains(t, properties, "id")
+	}
+}
@@ -24,6 +24,11 @@ func (m *MockAsynqClient) EnqueueContext(ctx interface{}, task *asynq.Task, opts
 	return nil, nil
 }
 
+func (m *MockAsynqClient) Ping() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
 func (m *MockAsynqClient) Close() error {
 	return nil
 }
@@ -81,6 +86,70 @@ func (m *MockAsynqInspector) ListArchivedTasks(queueName string, opts ...asynq.L
 	return args.Get(0).([]*asynq.TaskInfo), args.Error(1)
 }
 
+func (m *MockAsynqInspector) Queues() ([]string, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockAsynqInspector) GetQueueInfo(queueName string) (*asynq.QueueInfo, error) {
+	args := m.Called(queueName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*asynq.QueueInfo), args.Error(1)
+}
+
+func (m *MockAsynqInspector) PauseQueue(queueName string) error {
+	args := m.Called(queueName)
+	return args.Error(0)
+}
+
+func (m *MockAsynqInspector) UnpauseQueue(queueName string) error {
+	args := m.Called(queueName)
+	return args.Error(0)
+}
+
+func (m *MockAsynqInspector) ArchiveAllPendingTasks(queueName string) (int, error) {
+	args := m.Called(queueName)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockAsynqInspector) DeleteAllArchivedTasks(queueName string) (int, error) {
+	args := m.Called(queueName)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockAsynqInspector) DeleteAllCompletedTasks(queueName string) (int, error) {
+	args := m.Called(queueName)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockAsynqInspector) CancelProcessing(taskID string) error {
+	args := m.Called(taskID)
+	return args.Error(0)
+}
+
+func (m *MockAsynqInspector) RunTask(queueName, taskID string) error {
+	args := m.Called(queueName, taskID)
+	return args.Error(0)
+}
+
+func (m *MockAsynqInspector) DeleteTask(queueName, taskID string) error {
+	args := m.Called(queueName, taskID)
+	return args.Error(0)
+}
+
+func (m *MockAsynqInspector) Servers() ([]*asynq.ServerInfo, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*asynq.ServerInfo), args.Error(1)
+}
+
 // MockDatabase 模拟数据库
 type MockDatabase struct {
 	mock.Mock
@@ -91,6 +160,11 @@ func (m *MockDatabase) Ping() error {
 	return args.Error(0)
 }
 
+func (m *MockDatabase) PingContext(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
 func (m *MockDatabase) GetValuationRecord(ctx context.Context, tableName string, id int64) (*database.ValuationRecord, error) {
 	args := m.Called(ctx, tableName, id)
 	if args.Get(0) == nil {
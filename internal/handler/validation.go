@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/igwen6w/syt-go-queue/internal/database"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+func init() {
+	// 校验错误中使用 json/form 标签中的字段名，与客户端提交的字段保持一致
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(requestFieldName)
+		// 表名在入队前使用与数据库层相同的规则校验，不合法时立即返回 400
+		_ = v.RegisterValidation("table_name", validateTableNameField)
+	}
+}
+
+// validateTableNameField 校验 table_name 字段，规则与 database.ValidateTableName 一致
+func validateTableNameField(fl validator.FieldLevel) bool {
+	return database.ValidateTableName(fl.Field().String()) == nil
+}
+
+// requestFieldName 返回字段在请求中的名称，依次使用 json 标签、form 标签和字段名
+func requestFieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name := strings.Split(field.Tag.Get(tag), ",")[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// shouldBindBody 按 Content-Type 绑定请求体，支持 JSON 和表单编码。
+// 未设置 Content-Type 时按 JSON 解析，兼容之前只接受 JSON 的客户端
+func shouldBindBody(c *gin.Context, obj interface{}) error {
+	if c.ContentType() == "" {
+		return c.ShouldBindJSON(obj)
+	}
+	return c.ShouldBind(obj)
+}
+
+// bindErrorStatus 返回请求绑定错误对应的 HTTP 状态码，
+// 请求体超过 BodyLimit 中间件设置的上限时为 413，其余为 400
+func bindErrorStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// bindErrorResponse 将请求绑定错误转换为 400 响应（请求体过大时为 413）。
+// 校验失败和类型错误会在 Data.fields 中列出每个字段的错误，便于客户端展示
+func bindErrorResponse(err error) types.CommonResponse {
+	var fields []types.FieldError
+
+	var maxBytesErr *http.MaxBytesError
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return types.CommonResponse{
+			Code:    http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("Request body too large, limit is %d bytes", maxBytesErr.Limit),
+		}
+	case errors.As(err, &validationErrs):
+		for _, fe := range validationErrs {
+			fields = append(fields, types.FieldError{
+				Field:   fe.Field(),
+				Rule:    fe.Tag(),
+				Message: validationMessage(fe),
+			})
+		}
+	case errors.As(err, &typeErr):
+		fields = append(fields, types.FieldError{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("%s must be of type %s", typeErr.Field, typeErr.Type),
+		})
+	default:
+		return types.CommonResponse{
+			Code:    400,
+			Message: "Invalid request: " + err.Error(),
+		}
+	}
+
+	messages := make([]string, 0, len(fields))
+	for _, f := range fields {
+		messages = append(messages, f.Message)
+	}
+
+	return types.CommonResponse{
+		Code:    400,
+		Message: "Validation failed: " + strings.Join(messages, "; "),
+		Data:    types.ValidationErrorData{Fields: fields},
+	}
+}
+
+// validationMessage 返回单个字段校验错误的说明
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", fe.Field())
+	case "min":
+		switch fe.Kind() {
+		case reflect.Slice, reflect.Map:
+			return fmt.Sprintf("%s must have at least %s elements", fe.Field(), fe.Param())
+		case reflect.String:
+			return fmt.Sprintf("%s must be at least %s characters long", fe.Field(), fe.Param())
+		}
+		return fmt.Sprintf("%s must be at least %s", fe.Field(), fe.Param())
+	case "max":
+		switch fe.Kind() {
+		case reflect.Slice, reflect.Map:
+			return fmt.Sprintf("%s must have at most %s elements", fe.Field(), fe.Param())
+		case reflect.String:
+			return fmt.Sprintf("%s must be at most %s characters long", fe.Field(), fe.Param())
+		}
+		return fmt.Sprintf("%s must be at most %s", fe.Field(), fe.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of [%s]", fe.Field(), fe.Param())
+	case "table_name":
+		return fmt.Sprintf("%s may only contain letters, digits and '_' and must not be a SQL keyword", fe.Field())
+	default:
+		return fmt.Sprintf("%s failed on the '%s' rule", fe.Field(), fe.Tag())
+	}
+}
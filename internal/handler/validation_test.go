@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/middleware"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBindErrorResponse(t *testing.T) {
+	// 创建任务处理器，校验失败时不会访问依赖
+	handler := &TaskHandler{}
+
+	// 创建 Gin 路由
+	router := gin.New()
+	router.POST("/api/tasks/llm", handler.CreateLLMTask)
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedMsg    string
+		expectedFields []types.FieldError
+	}{
+		{
+			name:        "missing required fields",
+			body:        `{}`,
+			expectedMsg: "Validation failed: table_name is required; id is required",
+			expectedFields: []types.FieldError{
+				{Field: "table_name", Rule: "required", Message: "table_name is required"},
+				{Field: "id", Rule: "required", Message: "id is required"},
+			},
+		},
+		{
+			name:        "value below minimum",
+			body:        `{"table_name":"test_table","id":123,"max_tokens":-1}`,
+			expectedMsg: "Validation failed: max_tokens must be at least 1",
+			expectedFields: []types.FieldError{
+				{Field: "max_tokens", Rule: "min", Message: "max_tokens must be at least 1"},
+			},
+		},
+		{
+			name:        "wrong field type",
+			body:        `{"table_name":"test_table","id":"abc"}`,
+			expectedMsg: "Validation failed: id must be of type int64",
+			expectedFields: []types.FieldError{
+				{Field: "id", Rule: "type", Message: "id must be of type int64"},
+			},
+		},
+		{
+			name:        "invalid table name",
+			body:        `{"table_name":"records; DROP TABLE users","id":123}`,
+			expectedMsg: "Validation failed: table_name may only contain letters, digits and '_' and must not be a SQL keyword",
+			expectedFields: []types.FieldError{
+				{Field: "table_name", Rule: "table_name", Message: "table_name may only contain letters, digits and '_' and must not be a SQL keyword"},
+			},
+		},
+		{
+			name:        "reserved table name",
+			body:        `{"table_name":"select","id":123}`,
+			expectedMsg: "Validation failed: table_name may only contain letters, digits and '_' and must not be a SQL keyword",
+			expectedFields: []types.FieldError{
+				{Field: "table_name", Rule: "table_name", Message: "table_name may only contain letters, digits and '_' and must not be a SQL keyword"},
+			},
+		},
+		{
+			name:        "malformed JSON",
+			body:        `{"table_name":`,
+			expectedMsg: "Invalid request: unexpected EOF",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 创建请求
+			req, _ := http.NewRequest("POST", "/api/tasks/llm", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			// 发送请求
+			router.ServeHTTP(resp, req)
+
+			// 验证响应状态码
+			assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+			// 解析响应
+			var response struct {
+				Code    int                       `json:"code"`
+				Message string                    `json:"message"`
+				Data    types.ValidationErrorData `json:"data"`
+			}
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+
+			// 验证响应内容
+			assert.Equal(t, 400, response.Code)
+			assert.Equal(t, tt.expectedMsg, response.Message)
+			assert.Equal(t, tt.expectedFields, response.Data.Fields)
+		})
+	}
+}
+
+func TestBindErrorResponse_BodyTooLarge(t *testing.T) {
+	handler := &TaskHandler{}
+
+	router := gin.New()
+	router.Use(middleware.BodyLimit(32))
+	router.POST("/api/tasks/llm", handler.CreateLLMTask)
+
+	// 未声明长度的请求体，在绑定时读取超限
+	body := io.MultiReader(strings.NewReader(`{"table_name":"` + strings.Repeat("a", 64) + `","id":1}`))
+	req, _ := http.NewRequest("POST", "/api/tasks/llm", body)
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+
+	var response types.CommonResponse
+	err := json.Unmarshal(resp.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, response.Code)
+	assert.Equal(t, "Request body too large, limit is 32 bytes", response.Message)
+}
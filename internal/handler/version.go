@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"github.com/igwen6w/syt-go-queue/internal/version"
+	"net/http"
+)
+
+// Version 返回构建时注入的版本、提交和构建时间，用于确认部署的二进制
+func Version(c *gin.Context) {
+	c.JSON(http.StatusOK, types.CommonResponse{
+		Code:    200,
+		Message: "Success",
+		Data:    version.Get(),
+	})
+}
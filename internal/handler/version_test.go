@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/version"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	// 模拟构建时注入的版本信息
+	origVersion, origCommit, origBuildTime := version.Version, version.Commit, version.BuildTime
+	version.Version, version.Commit, version.BuildTime = "v1.2.0", "abc1234", "2026-01-01T00:00:00Z"
+	defer func() {
+		version.Version, version.Commit, version.BuildTime = origVersion, origCommit, origBuildTime
+	}()
+
+	router := gin.New()
+	router.GET("/version", Version)
+
+	req, _ := http.NewRequest("GET", "/version", nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var response struct {
+		Code int          `json:"code"`
+		Data version.Info `json:"data"`
+	}
+	err := json.Unmarshal(resp.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, version.Info{
+		Version:   "v1.2.0",
+		Commit:    "abc1234",
+		BuildTime: "2026-01-01T00:00:00Z",
+		GoVersion: runtime.Version(),
+	}, response.Data)
+}
@@ -0,0 +1,25 @@
+package logger
+
+import (
+	"context"
+	"go.uber.org/zap"
+)
+
+// requestIDKey 请求ID在上下文中的键
+type requestIDKey struct{}
+
+// WithRequestID 将请求ID存入上下文
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID 从上下文中获取请求ID，不存在时返回空字符串
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// RequestIDField 返回上下文中请求ID对应的日志字段
+func RequestIDField(ctx context.Context) zap.Field {
+	return zap.String("request_id", RequestID(ctx))
+}
@@ -1,39 +1,51 @@
 package logger
 
 import (
+	"fmt"
+	"github.com/igwen6w/syt-go-queue/internal/config"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 	"sync"
+	"time"
 )
 
 var (
 	// Log 全局日志实例
 	Log  *zap.Logger
 	once sync.Once
+	// level 全局日志级别，支持运行时动态调整
+	level = zap.NewAtomicLevel()
 )
 
+// parseLevel 解析日志级别字符串
+func parseLevel(l string) (zapcore.Level, error) {
+	switch l {
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "warn":
+		return zapcore.WarnLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	default:
+		return zapcore.InfoLevel, fmt.Errorf("unknown log level: %s", l)
+	}
+}
+
 // Init 初始化日志
-func Init(level string, development bool) {
+// 日志始终输出到标准输出；配置了 file 时，同时以 JSON 格式写入按大小轮转的日志文件
+func Init(cfg config.LoggerConfig) {
 	once.Do(func() {
-		// 解析日志级别
-		var logLevel zapcore.Level
-		switch level {
-		case "debug":
-			logLevel = zapcore.DebugLevel
-		case "info":
-			logLevel = zapcore.InfoLevel
-		case "warn":
-			logLevel = zapcore.WarnLevel
-		case "error":
-			logLevel = zapcore.ErrorLevel
-		default:
-			logLevel = zapcore.InfoLevel
-		}
+		// 解析日志级别，未知级别使用 info
+		logLevel, _ := parseLevel(cfg.Level)
+		level.SetLevel(logLevel)
 
 		// 创建日志配置
-		config := zap.Config{
-			Level:       zap.NewAtomicLevelAt(logLevel),
-			Development: development,
+		zapConfig := zap.Config{
+			Level:       level,
+			Development: cfg.Development,
 			Encoding:    "json",
 			EncoderConfig: zapcore.EncoderConfig{
 				TimeKey:        "time",
@@ -53,15 +65,44 @@ func Init(level string, development bool) {
 			ErrorOutputPaths: []string{"stderr"},
 		}
 
+		opts := []zap.Option{zap.AddCallerSkip(1)}
+
+		// 配置了日志文件时，额外写入轮转文件
+		// 文件始终使用 JSON 格式，便于日志采集
+		if cfg.File != "" {
+			fileCore := zapcore.NewCore(
+				zapcore.NewJSONEncoder(zapConfig.EncoderConfig),
+				zapcore.AddSync(&lumberjack.Logger{
+					Filename:   cfg.File,
+					MaxSize:    cfg.MaxSize,
+					MaxBackups: cfg.MaxBackups,
+					MaxAge:     cfg.MaxAge,
+					Compress:   cfg.Compress,
+				}),
+				level,
+			)
+			opts = append(opts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+				return zapcore.NewTee(core, fileCore)
+			}))
+		}
+
+		// 非开发模式下配置了采样时，对标准输出和日志文件统一采样
+		// 避免 LLM 服务不可用等故障期间相同的日志刷屏
+		if !cfg.Development && cfg.Sampling.Initial > 0 {
+			opts = append(opts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+				return zapcore.NewSamplerWithOptions(core, time.Second, cfg.Sampling.Initial, cfg.Sampling.Thereafter)
+			}))
+		}
+
 		// 如果是开发环境，使用更友好的控制台输出
-		if development {
-			config.Encoding = "console"
-			config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		if cfg.Development {
+			zapConfig.Encoding = "console"
+			zapConfig.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
 		}
 
 		// 创建日志实例
 		var err error
-		Log, err = config.Build(zap.AddCallerSkip(1))
+		Log, err = zapConfig.Build(opts...)
 		if err != nil {
 			// 如果初始化失败，使用一个基本的日志配置
 			Log = zap.NewExample()
@@ -70,6 +111,21 @@ func Init(level string, development bool) {
 	})
 }
 
+// SetLevel 在运行时调整日志级别
+func SetLevel(lvl string) error {
+	logLevel, err := parseLevel(lvl)
+	if err != nil {
+		return err
+	}
+	level.SetLevel(logLevel)
+	return nil
+}
+
+// AtomicLevel 返回全局日志的动态级别
+func AtomicLevel() zap.AtomicLevel {
+	return level
+}
+
 // Debug 输出调试级别日志
 func Debug(msg string, fields ...zap.Field) {
 	ensureLogger()
@@ -104,7 +160,7 @@ func Fatal(msg string, fields ...zap.Field) {
 func ensureLogger() {
 	if Log == nil {
 		// 如果日志未初始化，使用默认配置
-		Init("info", false)
+		Init(config.LoggerConfig{Level: "info"})
 	}
 }
 
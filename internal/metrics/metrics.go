@@ -1,104 +1,362 @@
 package metrics
 
 import (
+	"fmt"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/push"
 	"time"
 )
 
+// DefaultNamespace 是未配置 metrics.namespace 时所有指标名称的前缀
+const DefaultNamespace = "syt_go_queue"
+
+// DefaultLLMAPIBuckets LLM API 调用时间的默认桶边界（秒），覆盖到10分钟，
+// 长文本生成通常需要数分钟
+var DefaultLLMAPIBuckets = []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120, 180, 300, 600}
+
+var (
+	// 指标名称的命名空间和子系统，指标名称为 <namespace>_<subsystem>_<name>
+	namespace = DefaultNamespace
+	subsystem string
+	// LLM API 调用时间直方图当前使用的桶边界
+	llmAPIBuckets = DefaultLLMAPIBuckets
+)
+
 var (
 	// RequestCounter 记录API请求总数
-	RequestCounter = promauto.NewCounterVec(
+	RequestCounter *prometheus.CounterVec
+
+	// RequestDuration 记录API请求处理时间
+	RequestDuration *prometheus.HistogramVec
+
+	// TaskCounter 记录任务处理总数
+	TaskCounter *prometheus.CounterVec
+
+	// TaskDuration 记录任务处理时间
+	TaskDuration *prometheus.HistogramVec
+
+	// TaskWaitDuration 记录任务从入队到开始处理的等待时间，持续偏高说明工作者不足
+	TaskWaitDuration *prometheus.HistogramVec
+
+	// DatabaseQueryCounter 记录数据库查询总数，target 区分主库（primary）和只读副本（replica）
+	DatabaseQueryCounter *prometheus.CounterVec
+
+	// DatabaseQueryDuration 记录数据库查询时间
+	DatabaseQueryDuration *prometheus.HistogramVec
+
+	// LLMAPICounter 记录LLM API调用总数
+	LLMAPICounter *prometheus.CounterVec
+
+	// LLMAPIDuration 记录LLM API调用时间
+	// 桶边界可以通过 SetLLMAPIBuckets 配置
+	LLMAPIDuration prometheus.Histogram
+
+	// LLMFallbackCounter 记录主 LLM 断路器打开时备用 LLM 的调用总数
+	LLMFallbackCounter *prometheus.CounterVec
+
+	// LLMCacheCounter 记录 LLM 回复缓存的查询次数，result 为 hit 或 miss
+	LLMCacheCounter *prometheus.CounterVec
+
+	// CircuitBreakerForcedTrips 记录因 LLM API 限流而强制打开断路器的次数
+	CircuitBreakerForcedTrips prometheus.Counter
+
+	// LLMInFlight 记录正在进行的LLM API调用数
+	LLMInFlight prometheus.Gauge
+
+	// StuckRecords 记录最近一次巡检发现的长时间处于处理中的记录数
+	StuckRecords *prometheus.GaugeVec
+
+	// RecordsByStatus 记录最近一次统计时各表每种状态的记录数
+	RecordsByStatus *prometheus.GaugeVec
+
+	// QueueSize 记录队列大小
+	QueueSize *prometheus.GaugeVec
+
+	// WorkerCount 记录工作者数量
+	WorkerCount prometheus.Gauge
+)
+
+func init() {
+	// 使用默认命名空间注册，保证未调用 Init 时指标也可用
+	if err := register(); err != nil {
+		panic(err)
+	}
+}
+
+// Init 使用指定的命名空间和子系统重新创建并注册所有指标。
+// 指标在包初始化时已使用 DefaultNamespace 注册，该函数需要在开始处理请求和任务前调用，
+// 已记录的数据会被丢弃。ns 为空时使用 DefaultNamespace。
+func Init(ns, sub string) error {
+	if ns == "" {
+		ns = DefaultNamespace
+	}
+
+	prevNamespace, prevSubsystem := namespace, subsystem
+
+	unregister()
+	namespace, subsystem = ns, sub
+	if err := register(); err != nil {
+		// 恢复原来的指标，保证指标仍然可用
+		namespace, subsystem = prevNamespace, prevSubsystem
+		_ = register()
+		return err
+	}
+	return nil
+}
+
+// register 按当前的命名空间和子系统创建并注册所有指标，失败时不保留部分注册的指标
+func register() error {
+	RequestCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: "syt_go_queue_requests_total",
-			Help: "The total number of API requests",
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "requests_total",
+			Help:      "The total number of API requests",
 		},
 		[]string{"method", "endpoint", "status"},
 	)
 
-	// RequestDuration 记录API请求处理时间
-	RequestDuration = promauto.NewHistogramVec(
+	RequestDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
-			Name:    "syt_go_queue_request_duration_seconds",
-			Help:    "The request duration in seconds",
-			Buckets: prometheus.DefBuckets,
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "request_duration_seconds",
+			Help:      "The request duration in seconds",
+			Buckets:   prometheus.DefBuckets,
 		},
 		[]string{"method", "endpoint"},
 	)
 
-	// TaskCounter 记录任务处理总数
-	TaskCounter = promauto.NewCounterVec(
+	TaskCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: "syt_go_queue_tasks_total",
-			Help: "The total number of processed tasks",
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "tasks_total",
+			Help:      "The total number of processed tasks",
 		},
-		[]string{"type", "status"},
+		[]string{"type", "queue", "status"},
 	)
 
-	// TaskDuration 记录任务处理时间
-	TaskDuration = promauto.NewHistogramVec(
+	TaskDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "task_duration_seconds",
+			Help:      "The task processing duration in seconds",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"type", "queue"},
+	)
+
+	TaskWaitDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
-			Name:    "syt_go_queue_task_duration_seconds",
-			Help:    "The task processing duration in seconds",
-			Buckets: prometheus.DefBuckets,
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "task_wait_seconds",
+			Help:      "The time tasks wait in the queue before processing starts, in seconds",
+			Buckets:   []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 600, 1800, 3600},
 		},
-		[]string{"type"},
+		[]string{"queue", "type"},
 	)
 
-	// DatabaseQueryCounter 记录数据库查询总数
-	DatabaseQueryCounter = promauto.NewCounterVec(
+	DatabaseQueryCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: "syt_go_queue_database_queries_total",
-			Help: "The total number of database queries",
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "database_queries_total",
+			Help:      "The total number of database queries",
 		},
-		[]string{"operation", "status"},
+		[]string{"operation", "status", "target"},
 	)
 
-	// DatabaseQueryDuration 记录数据库查询时间
-	DatabaseQueryDuration = promauto.NewHistogramVec(
+	DatabaseQueryDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
-			Name:    "syt_go_queue_database_query_duration_seconds",
-			Help:    "The database query duration in seconds",
-			Buckets: prometheus.DefBuckets,
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "database_query_duration_seconds",
+			Help:      "The database query duration in seconds",
+			Buckets:   prometheus.DefBuckets,
 		},
 		[]string{"operation"},
 	)
 
-	// LLMAPICounter 记录LLM API调用总数
-	LLMAPICounter = promauto.NewCounterVec(
+	LLMAPICounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: "syt_go_queue_llm_api_calls_total",
-			Help: "The total number of LLM API calls",
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "llm_api_calls_total",
+			Help:      "The total number of LLM API calls",
 		},
 		[]string{"status"},
 	)
 
-	// LLMAPIDuration 记录LLM API调用时间
-	LLMAPIDuration = promauto.NewHistogram(
-		prometheus.HistogramOpts{
-			Name:    "syt_go_queue_llm_api_duration_seconds",
-			Help:    "The LLM API call duration in seconds",
-			Buckets: prometheus.ExponentialBuckets(0.1, 2, 10), // 从0.1秒开始，指数增长
+	LLMAPIDuration = prometheus.NewHistogram(llmAPIDurationOpts(llmAPIBuckets))
+
+	LLMFallbackCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "llm_fallback_calls_total",
+			Help:      "The total number of fallback LLM API calls",
 		},
+		[]string{"status"},
 	)
 
-	// QueueSize 记录队列大小
-	QueueSize = promauto.NewGaugeVec(
+	LLMCacheCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "llm_cache_lookups_total",
+			Help:      "The total number of LLM response cache lookups",
+		},
+		[]string{"result"},
+	)
+
+	CircuitBreakerForcedTrips = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "circuit_breaker_forced_trips_total",
+			Help:      "The total number of times the LLM circuit breaker was forced open by a rate limit response",
+		},
+	)
+
+	LLMInFlight = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "llm_in_flight",
+			Help:      "The current number of in-flight LLM API calls",
+		},
+	)
+
+	StuckRecords = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "stuck_records",
+			Help:      "The number of records found stuck in processing beyond the claim TTL by the last sweep",
+		},
+		[]string{"table"},
+	)
+
+	RecordsByStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "records_by_status",
+			Help:      "The number of records in each status per table, as of the last status sweep",
+		},
+		[]string{"table", "status"},
+	)
+
+	QueueSize = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
-			Name: "syt_go_queue_size",
-			Help: "The current size of the task queue",
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "size",
+			Help:      "The current size of the task queue",
 		},
 		[]string{"queue"},
 	)
 
-	// WorkerCount 记录工作者数量
-	WorkerCount = promauto.NewGauge(
+	WorkerCount = prometheus.NewGauge(
 		prometheus.GaugeOpts{
-			Name: "syt_go_queue_workers",
-			Help: "The current number of active workers",
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "workers",
+			Help:      "The current number of active workers",
 		},
 	)
-)
+
+	registered := make([]prometheus.Collector, 0, len(collectors()))
+	for _, c := range collectors() {
+		if err := prometheus.Register(c); err != nil {
+			for _, r := range registered {
+				prometheus.Unregister(r)
+			}
+			return fmt.Errorf("failed to register metrics: %w", err)
+		}
+		registered = append(registered, c)
+	}
+	return nil
+}
+
+// unregister 从默认注册表中移除所有指标
+func unregister() {
+	for _, c := range collectors() {
+		prometheus.Unregister(c)
+	}
+}
+
+// collectors 返回当前的所有指标
+func collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		RequestCounter,
+		RequestDuration,
+		TaskCounter,
+		TaskDuration,
+		TaskWaitDuration,
+		DatabaseQueryCounter,
+		DatabaseQueryDuration,
+		LLMAPICounter,
+		LLMAPIDuration,
+		LLMFallbackCounter,
+		LLMCacheCounter,
+		CircuitBreakerForcedTrips,
+		LLMInFlight,
+		StuckRecords,
+		RecordsByStatus,
+		QueueSize,
+		WorkerCount,
+	}
+}
+
+// llmAPIDurationOpts 返回使用指定桶边界的 LLM API 调用时间直方图配置
+func llmAPIDurationOpts(buckets []float64) prometheus.HistogramOpts {
+	return prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "llm_api_duration_seconds",
+		Help:      "The LLM API call duration in seconds",
+		Buckets:   buckets,
+	}
+}
+
+// SetLLMAPIBuckets 使用新的桶边界重新注册 LLM API 调用时间直方图。
+// 直方图在包初始化时已使用默认桶边界注册，该函数需要在开始处理任务前调用，
+// 已记录的观测值会被丢弃。
+func SetLLMAPIBuckets(buckets []float64) error {
+	histogram := prometheus.NewHistogram(llmAPIDurationOpts(buckets))
+
+	prometheus.Unregister(LLMAPIDuration)
+	if err := prometheus.Register(histogram); err != nil {
+		// 恢复原直方图，保证指标仍然可用
+		_ = prometheus.Register(LLMAPIDuration)
+		return fmt.Errorf("failed to register LLM API duration histogram: %w", err)
+	}
+
+	LLMAPIDuration = histogram
+	llmAPIBuckets = buckets
+	return nil
+}
+
+// Push 将默认注册表中的指标推送到 Pushgateway，用于进程退出前刷新指标。
+// component 作为分组标签，避免 API 和 worker 推送的指标相互覆盖。url 为空时不做处理。
+func Push(url, job, component string) error {
+	if url == "" {
+		return nil
+	}
+
+	err := push.New(url, job).
+		Gatherer(prometheus.DefaultGatherer).
+		Grouping("component", component).
+		Push()
+	if err != nil {
+		return fmt.Errorf("failed to push metrics to %s: %w", url, err)
+	}
+	return nil
+}
 
 // MeasureRequestDuration 测量请求处理时间的辅助函数
 func MeasureRequestDuration(method, endpoint string) func() {
@@ -110,11 +368,11 @@ func MeasureRequestDuration(method, endpoint string) func() {
 }
 
 // MeasureTaskDuration 测量任务处理时间的辅助函数
-func MeasureTaskDuration(taskType string) func() {
+func MeasureTaskDuration(taskType, queue string) func() {
 	start := time.Now()
 	return func() {
 		duration := time.Since(start).Seconds()
-		TaskDuration.WithLabelValues(taskType).Observe(duration)
+		TaskDuration.WithLabelValues(taskType, queue).Observe(duration)
 	}
 }
 
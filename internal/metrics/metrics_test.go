@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetLLMAPIBuckets(t *testing.T) {
+	defer func() {
+		if err := SetLLMAPIBuckets(DefaultLLMAPIBuckets); err != nil {
+			t.Fatalf("Failed to restore default buckets: %v", err)
+		}
+	}()
+
+	buckets := []float64{1, 60, 600}
+	if err := SetLLMAPIBuckets(buckets); err != nil {
+		t.Fatalf("SetLLMAPIBuckets() returned error: %v", err)
+	}
+
+	LLMAPIDuration.Observe(90)
+
+	// 从默认注册表读取，确认新直方图已注册且使用新的桶边界
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+
+	var histogram *dto.Histogram
+	for _, family := range families {
+		if family.GetName() == "syt_go_queue_llm_api_duration_seconds" {
+			histogram = family.GetMetric()[0].GetHistogram()
+		}
+	}
+	if histogram == nil {
+		t.Fatalf("LLM API duration histogram not registered")
+	}
+
+	if len(histogram.GetBucket()) != len(buckets) {
+		t.Fatalf("Expected %d buckets, got %d", len(buckets), len(histogram.GetBucket()))
+	}
+	for i, bucket := range histogram.GetBucket() {
+		if bucket.GetUpperBound() != buckets[i] {
+			t.Errorf("Expected bucket %d upper bound %v, got %v", i, buckets[i], bucket.GetUpperBound())
+		}
+	}
+	if histogram.GetSampleCount() != 1 {
+		t.Errorf("Expected 1 sample, got %d", histogram.GetSampleCount())
+	}
+}
+
+func TestPush(t *testing.T) {
+	// 未配置地址时不做处理
+	if err := Push("", "job", "worker"); err != nil {
+		t.Errorf("Push() with empty url returned error: %v", err)
+	}
+
+	var method, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	if err := Push(server.URL, "syt-go-queue", "worker"); err != nil {
+		t.Fatalf("Push() returned error: %v", err)
+	}
+	if method != http.MethodPut {
+		t.Errorf("Expected PUT request, got %s", method)
+	}
+	if path != "/metrics/job/syt-go-queue/component/worker" {
+		t.Errorf("Unexpected push path: %s", path)
+	}
+
+	// Pushgateway 返回错误时返回错误
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	if err := Push(failing.URL, "syt-go-queue", "worker"); err == nil {
+		t.Errorf("Expected error when pushgateway fails")
+	}
+}
+
+func TestInit(t *testing.T) {
+	defer func() {
+		if err := Init("", ""); err != nil {
+			t.Fatalf("Failed to restore default namespace: %v", err)
+		}
+	}()
+
+	hasFamily := func(name string) bool {
+		families, err := prometheus.DefaultGatherer.Gather()
+		if err != nil {
+			t.Fatalf("Failed to gather metrics: %v", err)
+		}
+		for _, family := range families {
+			if family.GetName() == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	// 默认使用 syt_go_queue 前缀
+	WorkerCount.Set(1)
+	if !hasFamily("syt_go_queue_workers") {
+		t.Fatalf("Expected default metric name syt_go_queue_workers")
+	}
+
+	if err := Init("tenant_a", "queue"); err != nil {
+		t.Fatalf("Init() returned error: %v", err)
+	}
+
+	WorkerCount.Set(1)
+	if !hasFamily("tenant_a_queue_workers") {
+		t.Errorf("Expected metric name tenant_a_queue_workers")
+	}
+	if hasFamily("syt_go_queue_workers") {
+		t.Errorf("Expected default metric to be unregistered")
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"net/http"
+)
+
+// DefaultMaxBodyBytes 未配置时的请求体大小上限
+const DefaultMaxBodyBytes int64 = 1 << 20
+
+// BodyLimit 限制请求体大小，limit 为 0 时使用 DefaultMaxBodyBytes。
+// 声明的 Content-Length 超过上限时直接返回 413；未声明长度（如分块传输）时
+// 用 http.MaxBytesReader 包装请求体，读取超限时由处理器返回 413
+func BodyLimit(limit int64) gin.HandlerFunc {
+	if limit <= 0 {
+		limit = DefaultMaxBodyBytes
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, types.CommonResponse{
+				Code:    http.StatusRequestEntityTooLarge,
+				Message: fmt.Sprintf("Request body too large, limit is %d bytes", limit),
+			})
+			return
+		}
+
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+		c.Next()
+	}
+}
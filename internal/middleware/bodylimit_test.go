@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(BodyLimit(16))
+	router.POST("/echo", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.String(http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		c.String(http.StatusOK, string(body))
+	})
+
+	tests := []struct {
+		name         string
+		body         io.Reader
+		expectedCode int
+	}{
+		{
+			name:         "body within limit",
+			body:         bytes.NewBufferString("small body"),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "body exactly at limit",
+			body:         bytes.NewBufferString(strings.Repeat("a", 16)),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "content length over limit",
+			body:         bytes.NewBufferString(strings.Repeat("a", 17)),
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			// 未声明长度的请求体在读取时才会被截断
+			name:         "unknown length over limit",
+			body:         io.MultiReader(strings.NewReader(strings.Repeat("a", 32))),
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/echo", tt.body)
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedCode, resp.Code)
+		})
+	}
+
+	t.Run("rejection uses common response", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/echo", bytes.NewBufferString(strings.Repeat("a", 17)))
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		var response types.CommonResponse
+		err := json.Unmarshal(resp.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, response.Code)
+		assert.Equal(t, "Request body too large, limit is 16 bytes", response.Message)
+	})
+}
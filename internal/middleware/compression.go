@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"strconv"
+	"strings"
+)
+
+// DefaultCompressionMinSize 未配置时启用压缩的最小响应体大小
+const DefaultCompressionMinSize = 1024
+
+// Compression 对声明了 Accept-Encoding: gzip 的请求使用 gzip 压缩响应体。
+// 响应体不超过 min_size 时原样返回，已经设置了 Content-Encoding 的响应（如 /metrics 自行协商的压缩）不再压缩。
+// 未启用时不做任何处理
+func Compression(cfg config.CompressionConfig) gin.HandlerFunc {
+	if !cfg.Enabled {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	minSize := cfg.MinSize
+	if minSize <= 0 {
+		minSize = DefaultCompressionMinSize
+	}
+
+	return func(c *gin.Context) {
+		if !acceptsGzip(c.GetHeader("Accept-Encoding")) {
+			c.Next()
+			return
+		}
+
+		w := &gzipWriter{ResponseWriter: c.Writer, minSize: minSize}
+		c.Writer = w
+		defer func() {
+			w.finish()
+			// 恢复原始写入器，之后的写入（如恢复中间件返回的错误响应）不经过压缩
+			c.Writer = w.ResponseWriter
+		}()
+
+		c.Next()
+	}
+}
+
+// acceptsGzip 判断 Accept-Encoding 是否接受 gzip，q=0 表示不接受
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipWriter 缓存响应体直到超过 minSize 后再开始压缩，较小的响应在 finish 时原样写出
+type gzipWriter struct {
+	gin.ResponseWriter
+	minSize     int
+	buf         []byte
+	gz          *gzip.Writer
+	passthrough bool // 响应已设置 Content-Encoding，不压缩
+}
+
+func (w *gzipWriter) Write(data []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+	if w.gz != nil {
+		return w.gz.Write(data)
+	}
+
+	// 首次写入时检查处理器是否已经自行编码了响应
+	if w.buf == nil && w.Header().Get("Content-Encoding") != "" {
+		w.passthrough = true
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.buf = append(w.buf, data...)
+	if len(w.buf) <= w.minSize {
+		return len(data), nil
+	}
+
+	h := w.Header()
+	h.Set("Content-Encoding", "gzip")
+	h.Add("Vary", "Accept-Encoding")
+	h.Del("Content-Length")
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+	if _, err := w.gz.Write(w.buf); err != nil {
+		return 0, err
+	}
+	w.buf = nil
+	return len(data), nil
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush 刷新已压缩的数据；仍在缓存时不再等待达到压缩阈值，缓存的内容原样写出，之后的写入不再压缩
+func (w *gzipWriter) Flush() {
+	switch {
+	case w.gz != nil:
+		_ = w.gz.Flush()
+	case len(w.buf) > 0:
+		w.passthrough = true
+		w.Header().Add("Vary", "Accept-Encoding")
+		_, _ = w.ResponseWriter.Write(w.buf)
+		w.buf = nil
+	}
+	w.ResponseWriter.Flush()
+}
+
+// finish 结束压缩，或将未达到压缩阈值的响应体原样写出
+func (w *gzipWriter) finish() {
+	if w.gz != nil {
+		_ = w.gz.Close()
+		return
+	}
+	if len(w.buf) > 0 {
+		w.Header().Add("Vary", "Accept-Encoding")
+		_, _ = w.ResponseWriter.Write(w.buf)
+		w.buf = nil
+	}
+}
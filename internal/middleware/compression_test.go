@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	large := strings.Repeat("task ", 100)
+
+	router := gin.New()
+	router.Use(Compression(config.CompressionConfig{Enabled: true, MinSize: 64}))
+	router.GET("/large", func(c *gin.Context) {
+		c.JSON(http.StatusOK, types.CommonResponse{Code: 200, Message: large})
+	})
+	router.GET("/small", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	router.GET("/encoded", func(c *gin.Context) {
+		// 模拟自行协商压缩的处理器，如 /metrics
+		c.Header("Content-Encoding", "identity")
+		c.String(http.StatusOK, large)
+	})
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		wantGzip       bool
+		wantBody       string
+	}{
+		{
+			name:           "large response compressed",
+			path:           "/large",
+			acceptEncoding: "gzip, deflate",
+			wantGzip:       true,
+			wantBody:       large,
+		},
+		{
+			name:           "small response not compressed",
+			path:           "/small",
+			acceptEncoding: "gzip",
+			wantGzip:       false,
+			wantBody:       "ok",
+		},
+		{
+			name:           "client without gzip",
+			path:           "/large",
+			acceptEncoding: "",
+			wantGzip:       false,
+			wantBody:       large,
+		},
+		{
+			name:           "gzip refused with q=0",
+			path:           "/large",
+			acceptEncoding: "gzip;q=0, br",
+			wantGzip:       false,
+			wantBody:       large,
+		},
+		{
+			name:           "already encoded response",
+			path:           "/encoded",
+			acceptEncoding: "gzip",
+			wantGzip:       false,
+			wantBody:       large,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, http.StatusOK, resp.Code)
+
+			var body io.Reader = resp.Body
+			if tt.wantGzip {
+				assert.Equal(t, "gzip", resp.Header().Get("Content-Encoding"))
+				assert.Equal(t, "Accept-Encoding", resp.Header().Get("Vary"))
+				gz, err := gzip.NewReader(resp.Body)
+				assert.NoError(t, err)
+				body = gz
+			} else {
+				assert.NotEqual(t, "gzip", resp.Header().Get("Content-Encoding"))
+			}
+
+			got, err := io.ReadAll(body)
+			assert.NoError(t, err)
+			assert.Contains(t, string(got), tt.wantBody)
+		})
+	}
+}
+
+func TestCompression_Disabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(Compression(config.CompressionConfig{}))
+	router.GET("/large", func(c *gin.Context) {
+		c.String(http.StatusOK, strings.Repeat("a", 4096))
+	})
+
+	req, _ := http.NewRequest("GET", "/large", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Empty(t, resp.Header().Get("Content-Encoding"))
+	assert.Equal(t, 4096, resp.Body.Len())
+}
+
+func TestCompression_KeepsStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(Compression(config.CompressionConfig{Enabled: true, MinSize: 16}))
+	router.GET("/missing", func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, types.CommonResponse{Code: 404, Message: strings.Repeat("not found ", 10)})
+	})
+
+	req, _ := http.NewRequest("GET", "/missing", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.Equal(t, "gzip", resp.Header().Get("Content-Encoding"))
+}
+
+func TestCompression_Metrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(Compression(config.CompressionConfig{Enabled: true, MinSize: 16}))
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	// promhttp 自行压缩，响应只被压缩一次
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "gzip", resp.Header().Get("Content-Encoding"))
+	gz, err := gzip.NewReader(resp.Body)
+	assert.NoError(t, err)
+	body, err := io.ReadAll(gz)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "go_goroutines")
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// 未配置时 CORS 允许的请求方法和请求头
+var (
+	defaultCORSMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	defaultCORSHeaders = []string{"Authorization", "Content-Type", RequestIDHeader}
+)
+
+// CORS 为浏览器跨域请求添加 CORS 响应头，并处理 OPTIONS 预检请求
+// 未配置允许的来源时不做任何处理。允许携带凭据时回显请求的来源，
+// 不使用通配符，因为浏览器会拒绝带凭据的通配符响应
+func CORS(cfg config.CORSConfig) gin.HandlerFunc {
+	if len(cfg.AllowedOrigins) == 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	allowAll := false
+	origins := make(map[string]bool, len(cfg.AllowedOrigins))
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		origins[strings.ToLower(origin)] = true
+	}
+
+	methods := cfg.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	headers := cfg.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(headers, ", ")
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			// 非跨域请求
+			c.Next()
+			return
+		}
+
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		if !allowAll && !origins[strings.ToLower(origin)] {
+			// 来源不在允许列表中，不添加 CORS 头，浏览器会拦截响应
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if allowAll && !cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Writer.Header().Add("Vary", "Origin")
+		}
+		if cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			c.Header("Access-Control-Allow-Methods", allowMethods)
+			c.Header("Access-Control-Allow-Headers", allowHeaders)
+			if cfg.MaxAge > 0 {
+				c.Header("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge.Seconds())))
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		// 允许浏览器脚本读取请求ID，便于排查问题
+		c.Header("Access-Control-Expose-Headers", RequestIDHeader)
+		c.Next()
+	}
+}
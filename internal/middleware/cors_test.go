@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name            string
+		cfg             config.CORSConfig
+		method          string
+		origin          string
+		preflight       bool
+		expectedStatus  int
+		expectedOrigin  string
+		expectedCreds   string
+		expectedMethods string
+		expectedMaxAge  string
+	}{
+		{
+			name:           "disabled without origins",
+			cfg:            config.CORSConfig{},
+			method:         "GET",
+			origin:         "https://dashboard.example.com",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "allowed origin",
+			cfg:            config.CORSConfig{AllowedOrigins: []string{"https://dashboard.example.com"}},
+			method:         "GET",
+			origin:         "https://dashboard.example.com",
+			expectedStatus: http.StatusOK,
+			expectedOrigin: "https://dashboard.example.com",
+		},
+		{
+			name:           "disallowed origin",
+			cfg:            config.CORSConfig{AllowedOrigins: []string{"https://dashboard.example.com"}},
+			method:         "GET",
+			origin:         "https://evil.example.com",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "wildcard without credentials",
+			cfg:            config.CORSConfig{AllowedOrigins: []string{"*"}},
+			method:         "GET",
+			origin:         "https://dashboard.example.com",
+			expectedStatus: http.StatusOK,
+			expectedOrigin: "*",
+		},
+		{
+			name: "wildcard with credentials echoes origin",
+			cfg: config.CORSConfig{
+				AllowedOrigins:   []string{"*"},
+				AllowCredentials: true,
+			},
+			method:         "GET",
+			origin:         "https://dashboard.example.com",
+			expectedStatus: http.StatusOK,
+			expectedOrigin: "https://dashboard.example.com",
+			expectedCreds:  "true",
+		},
+		{
+			name: "preflight",
+			cfg: config.CORSConfig{
+				AllowedOrigins: []string{"https://dashboard.example.com"},
+				AllowedMethods: []string{"GET", "POST"},
+				MaxAge:         10 * time.Minute,
+			},
+			method:          "OPTIONS",
+			origin:          "https://dashboard.example.com",
+			preflight:       true,
+			expectedStatus:  http.StatusNoContent,
+			expectedOrigin:  "https://dashboard.example.com",
+			expectedMethods: "GET, POST",
+			expectedMaxAge:  "600",
+		},
+		{
+			name:           "preflight from disallowed origin",
+			cfg:            config.CORSConfig{AllowedOrigins: []string{"https://dashboard.example.com"}},
+			method:         "OPTIONS",
+			origin:         "https://evil.example.com",
+			preflight:      true,
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(CORS(tt.cfg))
+			router.GET("/api/stats", func(c *gin.Context) {
+				c.String(http.StatusOK, "ok")
+			})
+
+			req, _ := http.NewRequest(tt.method, "/api/stats", nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", "POST")
+			}
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+			assert.Equal(t, tt.expectedOrigin, resp.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, tt.expectedCreds, resp.Header().Get("Access-Control-Allow-Credentials"))
+			assert.Equal(t, tt.expectedMethods, resp.Header().Get("Access-Control-Allow-Methods"))
+			assert.Equal(t, tt.expectedMaxAge, resp.Header().Get("Access-Control-Max-Age"))
+		})
+	}
+}
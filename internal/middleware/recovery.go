@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/logger"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// Recovery 从处理请求时的 panic 中恢复
+// 记录 panic 值和调用栈，并返回 CommonResponse 格式的 500 响应，
+// 替代 gin 默认恢复中间件返回的空响应体
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if err := recover(); err != nil {
+				logger.Error("Panic recovered",
+					zap.String("request_id", GetRequestID(c)),
+					zap.String("method", c.Request.Method),
+					zap.String("path", c.Request.URL.Path),
+					zap.Any("panic", err),
+					zap.Stack("stack"))
+
+				// 响应已经开始写入时无法再修改，只中止后续处理
+				if c.Writer.Written() {
+					c.Abort()
+					return
+				}
+
+				c.AbortWithStatusJSON(http.StatusInternalServerError, types.CommonResponse{
+					Code:    500,
+					Message: "Internal error",
+				})
+			}
+		}()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/igwen6w/syt-go-queue/internal/logger"
+)
+
+const (
+	// RequestIDHeader 请求ID的HTTP头
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDKey 请求ID在Gin上下文中的键
+	RequestIDKey = "request_id"
+)
+
+// RequestID 为每个请求分配请求ID
+// 如果请求头中已携带 X-Request-ID，则复用该值，否则生成新的ID。
+// 请求ID会写入响应头，并存入Gin上下文和请求上下文，便于日志关联
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" {
+			requestID = uuid.NewString()
+		}
+
+		c.Set(RequestIDKey, requestID)
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), requestID))
+		c.Header(RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
+
+// GetRequestID 获取当前请求的请求ID
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(RequestIDKey)
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/test", func(c *gin.Context) {
+		// 请求ID应同时存在于Gin上下文和请求上下文中
+		assert.Equal(t, GetRequestID(c), logger.RequestID(c.Request.Context()))
+		c.String(http.StatusOK, GetRequestID(c))
+	})
+
+	t.Run("reuse incoming request id", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.Header.Set(RequestIDHeader, "incoming-id")
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, "incoming-id", resp.Header().Get(RequestIDHeader))
+		assert.Equal(t, "incoming-id", resp.Body.String())
+	})
+
+	t.Run("generate request id", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/test", nil)
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		requestID := resp.Header().Get(RequestIDHeader)
+		assert.NotEmpty(t, requestID)
+		assert.Equal(t, requestID, resp.Body.String())
+	})
+}
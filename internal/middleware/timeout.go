@@ -0,0 +1,228 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/igwen6w/syt-go-queue/internal/logger"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRequestTimeout 未配置 request_timeout.default 时的请求处理超时
+const DefaultRequestTimeout = 30 * time.Second
+
+// Timeout 限制请求的处理时间，超时时返回 CommonResponse 格式的 503 并记录超时的接口。
+// 请求上下文带有截止时间，使用 c.Request.Context() 的数据库操作会随之取消；
+// 不接受上下文的调用（如 asynq Inspector）无法中断，处理器继续在后台执行，
+// 其写入的响应被丢弃，中间件等待处理器返回后再结束请求，避免 gin.Context 被复用时发生竞争。
+// 超时时间按请求路径的最长匹配前缀从 routes 中选择，未匹配或为 0 时使用 default，为负数时不限制
+func Timeout(cfg config.RequestTimeoutConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := requestTimeout(cfg, c.Request.URL.Path)
+		if timeout < 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		original := c.Writer
+		tw := &timeoutWriter{ResponseWriter: original, header: original.Header().Clone(), status: http.StatusOK}
+		c.Writer = tw
+		defer func() {
+			c.Writer = original
+		}()
+
+		// 在主协程中记录请求信息，超时后不再读取处理器仍在使用的 gin.Context
+		requestID := GetRequestID(c)
+		method := c.Request.Method
+		path := c.FullPath()
+
+		done := make(chan struct{})
+		var panicked interface{}
+		go func() {
+			defer close(done)
+			defer func() {
+				// 在主协程重新抛出，交给恢复中间件处理
+				panicked = recover()
+			}()
+			c.Next()
+		}()
+
+		select {
+		case <-done:
+			if panicked != nil {
+				panic(panicked)
+			}
+			tw.flushTo(original)
+		case <-ctx.Done():
+			// 客户端断开连接时不返回 503，等待处理器结束
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				<-done
+				if panicked != nil {
+					panic(panicked)
+				}
+				tw.flushTo(original)
+				return
+			}
+
+			tw.timeout(original)
+			logger.Warn("Request timed out",
+				zap.String("request_id", requestID),
+				zap.String("method", method),
+				zap.String("path", path),
+				zap.Duration("timeout", timeout))
+
+			<-done
+			if panicked != nil {
+				panic(panicked)
+			}
+		}
+	}
+}
+
+// requestTimeout 返回请求路径对应的超时时间，routes 中最长的匹配前缀优先，
+// 前缀需按路径段匹配，如 /api/tasks 匹配 /api/tasks/1，不匹配 /api/tasksx
+func requestTimeout(cfg config.RequestTimeoutConfig, path string) time.Duration {
+	matched := -1
+	var routeTimeout time.Duration
+	for prefix, d := range cfg.Routes {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+			continue
+		}
+		if len(prefix) > matched {
+			matched = len(prefix)
+			routeTimeout = d
+		}
+	}
+
+	switch {
+	case routeTimeout != 0:
+		return routeTimeout
+	case cfg.Default != 0:
+		return cfg.Default
+	default:
+		return DefaultRequestTimeout
+	}
+}
+
+// timeoutWriter 缓存处理器写入的响应，处理器按时返回后再写出。
+// 超时后丢弃之后的所有写入
+type timeoutWriter struct {
+	gin.ResponseWriter
+	mu       sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	status   int
+	written  bool // 处理器是否已写入状态码
+	timedOut bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.written {
+		return
+	}
+	w.status = code
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.written = true
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.written = true
+	return w.buf.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.written {
+		return -1
+	}
+	return w.buf.Len()
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.written
+}
+
+// Flush 响应在处理器返回后才写出，缓存期间不向客户端刷新
+func (w *timeoutWriter) Flush() {}
+
+// flushTo 将缓存的响应头、状态码和响应体写入 dst
+func (w *timeoutWriter) flushTo(dst gin.ResponseWriter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	header := dst.Header()
+	for key := range header {
+		if _, ok := w.header[key]; !ok {
+			header.Del(key)
+		}
+	}
+	for key, values := range w.header {
+		header[key] = values
+	}
+	dst.WriteHeader(w.status)
+	if w.buf.Len() > 0 {
+		_, _ = dst.Write(w.buf.Bytes())
+	} else {
+		dst.WriteHeaderNow()
+	}
+}
+
+// timeout 标记超时并向 dst 写出 503 响应，立即刷新使客户端不必等待处理器返回
+func (w *timeoutWriter) timeout(dst gin.ResponseWriter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timedOut = true
+
+	body, _ := json.Marshal(types.CommonResponse{
+		Code:    http.StatusServiceUnavailable,
+		Message: "Request timed out",
+	})
+	header := dst.Header()
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	dst.WriteHeader(http.StatusServiceUnavailable)
+	_, _ = dst.Write(body)
+	dst.Flush()
+}
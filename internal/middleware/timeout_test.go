@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/igwen6w/syt-go-queue/internal/types"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(Recovery())
+	router.Use(Timeout(config.RequestTimeoutConfig{Default: 50 * time.Millisecond}))
+	router.GET("/fast", func(c *gin.Context) {
+		c.Header("X-Test", "fast")
+		c.JSON(http.StatusCreated, types.CommonResponse{Code: 201, Message: "Created"})
+	})
+	router.GET("/slow", func(c *gin.Context) {
+		// 遵守请求上下文的处理器在超时后返回，写入的响应被丢弃
+		<-c.Request.Context().Done()
+		c.JSON(http.StatusInternalServerError, types.CommonResponse{Code: 500, Message: "context canceled"})
+	})
+	router.GET("/panic", func(c *gin.Context) {
+		panic("something went wrong")
+	})
+
+	t.Run("fast handler response kept", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/fast", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusCreated, resp.Code)
+		assert.Equal(t, "fast", resp.Header().Get("X-Test"))
+
+		var response types.CommonResponse
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+		assert.Equal(t, "Created", response.Message)
+	})
+
+	t.Run("slow handler returns 503", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/slow", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+		assert.Equal(t, "application/json; charset=utf-8", resp.Header().Get("Content-Type"))
+
+		var response types.CommonResponse
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+		assert.Equal(t, 503, response.Code)
+		assert.Equal(t, "Request timed out", response.Message)
+	})
+
+	t.Run("panic reaches recovery", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/panic", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusInternalServerError, resp.Code)
+
+		var response types.CommonResponse
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+		assert.Equal(t, "Internal error", response.Message)
+	})
+}
+
+func TestTimeout_BlockingHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Go 的 HTTP 客户端默认声明 Accept-Encoding: gzip，同时验证压缩中间件不会缓存 503 响应
+	for _, compression := range []bool{false, true} {
+		t.Run(fmt.Sprintf("compression=%v", compression), func(t *testing.T) {
+			// 模拟阻塞在不接受上下文的调用上的处理器
+			release := make(chan struct{})
+			router := gin.New()
+			router.Use(Compression(config.CompressionConfig{Enabled: compression}))
+			router.Use(Timeout(config.RequestTimeoutConfig{Default: 50 * time.Millisecond}))
+			router.GET("/blocked", func(c *gin.Context) {
+				<-release
+				c.String(http.StatusOK, "too late")
+			})
+
+			server := httptest.NewServer(router)
+			defer server.Close()
+			defer close(release)
+
+			// 客户端在处理器返回前就收到完整的 503 响应
+			client := &http.Client{Timeout: 5 * time.Second}
+			resp, err := client.Get(server.URL + "/blocked")
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+			body, err := io.ReadAll(resp.Body)
+			assert.NoError(t, err)
+
+			var response types.CommonResponse
+			assert.NoError(t, json.Unmarshal(body, &response))
+			assert.Equal(t, "Request timed out", response.Message)
+		})
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	cfg := config.RequestTimeoutConfig{
+		Default: 10 * time.Second,
+		Routes: map[string]time.Duration{
+			"/api/queues":        2 * time.Minute,
+			"/api/queues/stuck/": -1,
+			"/api/tasks":         0,
+		},
+	}
+
+	tests := []struct {
+		name string
+		cfg  config.RequestTimeoutConfig
+		path string
+		want time.Duration
+	}{
+		{name: "default", cfg: cfg, path: "/api/stats", want: 10 * time.Second},
+		{name: "route override", cfg: cfg, path: "/api/queues/default/pause", want: 2 * time.Minute},
+		{name: "exact route", cfg: cfg, path: "/api/queues", want: 2 * time.Minute},
+		{name: "longest prefix wins", cfg: cfg, path: "/api/queues/stuck/pause", want: -1},
+		{name: "prefix matches whole segments", cfg: cfg, path: "/api/queuesx", want: 10 * time.Second},
+		{name: "zero route uses default", cfg: cfg, path: "/api/tasks/1", want: 10 * time.Second},
+		{name: "unset default", cfg: config.RequestTimeoutConfig{}, path: "/api/stats", want: DefaultRequestTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, requestTimeout(tt.cfg, tt.path))
+		})
+	}
+}
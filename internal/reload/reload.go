@@ -0,0 +1,98 @@
+// Package reload 提供配置文件的热加载功能。
+package reload
+
+import (
+	"github.com/fsnotify/fsnotify"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/igwen6w/syt-go-queue/internal/logger"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"reflect"
+	"sync"
+)
+
+// Watch 监听配置文件变化，并在变化时应用可热加载的配置
+// 可热加载的字段为 logger.level 和 deepseek.circuit_breaker 下除 enabled 外的参数，
+// 由 apply 回调负责实际生效；其他字段的变化仅记录为需要重启。
+// 新配置无法解析或未通过校验时忽略本次变化。
+//
+// 参数:
+//   - current: 启动时加载的配置，作为比较基准
+//   - apply: 新配置通过校验后调用，用于应用可热加载的字段
+func Watch(current *config.Config, apply func(cfg *config.Config)) {
+	var mu sync.Mutex
+	prev := *current
+
+	viper.OnConfigChange(func(e fsnotify.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		logger.Info("Config file changed", zap.String("file", e.Name))
+
+		var next config.Config
+		if err := viper.Unmarshal(&next); err != nil {
+			logger.Error("Failed to unmarshal reloaded config, ignoring change", zap.Error(err))
+			return
+		}
+		if err := config.ValidateConfig(&next); err != nil {
+			logger.Error("Reloaded config is invalid, ignoring change", zap.Error(err))
+			return
+		}
+
+		for _, field := range restartRequiredChanges(&prev, &next) {
+			logger.Warn("Config change requires restart to take effect", zap.String("field", field))
+		}
+
+		apply(&next)
+		prev = next
+
+		logger.Info("Config reloaded",
+			zap.String("log_level", next.Logger.Level),
+			zap.Float64("circuit_breaker_fail_threshold", next.Deepseek.CircuitBreaker.FailThreshold),
+			zap.Int("circuit_breaker_max_requests", next.Deepseek.CircuitBreaker.MaxRequests),
+			zap.Duration("circuit_breaker_interval", next.Deepseek.CircuitBreaker.Interval),
+			zap.Duration("circuit_breaker_timeout", next.Deepseek.CircuitBreaker.Timeout))
+	})
+	viper.WatchConfig()
+}
+
+// HotReloadableFields 修改配置文件后无需重启即可生效的字段，与 restartRequiredChanges 中清除的字段一致
+var HotReloadableFields = []string{
+	"logger.level",
+	"deepseek.circuit_breaker.fail_threshold",
+	"deepseek.circuit_breaker.max_requests",
+	"deepseek.circuit_breaker.interval",
+	"deepseek.circuit_breaker.timeout",
+}
+
+// restartRequiredChanges 返回发生变化但无法热加载的配置部分
+func restartRequiredChanges(prev, next *config.Config) []string {
+	// 清除可热加载的字段后再比较
+	a, b := *prev, *next
+	a.Logger.Level, b.Logger.Level = "", ""
+	a.Deepseek.CircuitBreaker.FailThreshold, b.Deepseek.CircuitBreaker.FailThreshold = 0, 0
+	a.Deepseek.CircuitBreaker.MaxRequests, b.Deepseek.CircuitBreaker.MaxRequests = 0, 0
+	a.Deepseek.CircuitBreaker.Interval, b.Deepseek.CircuitBreaker.Interval = 0, 0
+	a.Deepseek.CircuitBreaker.Timeout, b.Deepseek.CircuitBreaker.Timeout = 0, 0
+
+	sections := []struct {
+		name       string
+		prev, next interface{}
+	}{
+		{"app", a.App, b.App},
+		{"redis", a.Redis, b.Redis},
+		{"mysql", a.MySQL, b.MySQL},
+		{"deepseek", a.Deepseek, b.Deepseek},
+		{"queue", a.Queue, b.Queue},
+		{"logger", a.Logger, b.Logger},
+		{"auth", a.Auth, b.Auth},
+	}
+
+	var changed []string
+	for _, s := range sections {
+		if !reflect.DeepEqual(s.prev, s.next) {
+			changed = append(changed, s.name)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,74 @@
+package reload
+
+import (
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRestartRequiredChanges(t *testing.T) {
+	base := config.Config{
+		Redis:  config.RedisConfig{Addr: "localhost:6379"},
+		Queue:  config.QueueConfig{Concurrency: 10},
+		Logger: config.LoggerConfig{Level: "info"},
+		Deepseek: config.DeepseekConfig{
+			CircuitBreaker: config.CircuitBreakerConfig{FailThreshold: 0.5},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		modifyFn func(*config.Config)
+		want     []string
+	}{
+		{
+			name:     "no changes",
+			modifyFn: func(c *config.Config) {},
+			want:     nil,
+		},
+		{
+			name: "hot reloadable fields only",
+			modifyFn: func(c *config.Config) {
+				c.Logger.Level = "debug"
+				c.Deepseek.CircuitBreaker.FailThreshold = 0.8
+			},
+			want: nil,
+		},
+		{
+			name: "circuit breaker thresholds",
+			modifyFn: func(c *config.Config) {
+				c.Deepseek.CircuitBreaker.MaxRequests = 5
+				c.Deepseek.CircuitBreaker.Interval = time.Minute
+				c.Deepseek.CircuitBreaker.Timeout = time.Minute
+			},
+			want: nil,
+		},
+		{
+			name: "redis addr and concurrency",
+			modifyFn: func(c *config.Config) {
+				c.Redis.Addr = "redis:6379"
+				c.Queue.Concurrency = 20
+			},
+			want: []string{"redis", "queue"},
+		},
+		{
+			name: "circuit breaker enabled",
+			modifyFn: func(c *config.Config) {
+				c.Deepseek.CircuitBreaker.Enabled = true
+			},
+			want: []string{"deepseek"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := base
+			tt.modifyFn(&next)
+			got := restartRequiredChanges(&base, &next)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("restartRequiredChanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package resultstore
+
+import (
+	"context"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+)
+
+// 报告存储类型
+const (
+	TypeDB = "db" // 报告直接写入记录的 report 列
+	TypeS3 = "s3" // 报告写入 S3 兼容的对象存储，report 列只保存对象地址
+)
+
+// ResultStore 保存任务生成的报告，返回写入记录 report 列的内容
+type ResultStore interface {
+	// Save 以 key 保存报告，返回写入 report 列的值：
+	// 直接写入数据库时为报告本身，写入对象存储时为对象地址
+	Save(ctx context.Context, key, report string) (string, error)
+}
+
+// DBStore 直接把报告写入数据库的默认实现，原样返回报告
+type DBStore struct{}
+
+// Save 原样返回报告，由调用方写入 report 列
+func (DBStore) Save(_ context.Context, _, report string) (string, error) {
+	return report, nil
+}
+
+// New 按配置创建报告存储，未配置或配置为 db 时返回 DBStore。
+// 配置需先经过 config.ValidateConfig 验证
+func New(cfg config.ResultStoreConfig) ResultStore {
+	switch cfg.Type {
+	case TypeS3:
+		return NewS3Store(cfg.S3, cfg.MinSize)
+	default:
+		return DBStore{}
+	}
+}
@@ -0,0 +1,173 @@
+package resultstore
+
+import (
+	"context"
+	"encoding/hex"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	if _, ok := New(config.ResultStoreConfig{}).(DBStore); !ok {
+		t.Errorf("New() with empty type should return DBStore")
+	}
+
+	if _, ok := New(config.ResultStoreConfig{Type: TypeS3}).(*S3Store); !ok {
+		t.Errorf("New() with type s3 should return *S3Store")
+	}
+}
+
+func TestDBStore_Save(t *testing.T) {
+	got, err := DBStore{}.Save(context.Background(), "valuation/1/1.txt", "report")
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if got != "report" {
+		t.Errorf("Save() = %q, want the report unchanged", got)
+	}
+}
+
+func TestSigningKey(t *testing.T) {
+	// AWS Signature V4 文档中派生签名密钥的示例
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	want := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if got := hex.EncodeToString(key); got != want {
+		t.Errorf("signingKey() = %s, want %s", got, want)
+	}
+}
+
+func TestS3Store_Save(t *testing.T) {
+	var gotMethod, gotPath, gotAuth, gotDate, gotHash, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotPath = r.URL.EscapedPath()
+		gotAuth = r.Header.Get("Authorization")
+		gotDate = r.Header.Get("X-Amz-Date")
+		gotHash = r.Header.Get("X-Amz-Content-Sha256")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	store := NewS3Store(config.S3Config{
+		Endpoint:        server.URL + "/",
+		Region:          "us-east-1",
+		Bucket:          "reports",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+		Prefix:          "llm/",
+	}, 0)
+	store.now = func() time.Time { return time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC) }
+
+	got, err := store.Save(context.Background(), "valuation/1/2.txt", "a long report")
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	if got != "s3://reports/llm/valuation/1/2.txt" {
+		t.Errorf("Save() = %q, want s3://reports/llm/valuation/1/2.txt", got)
+	}
+	if gotMethod != http.MethodPut {
+		t.Errorf("method = %s, want PUT", gotMethod)
+	}
+	if gotPath != "/reports/llm/valuation/1/2.txt" {
+		t.Errorf("path = %s, want /reports/llm/valuation/1/2.txt", gotPath)
+	}
+	if gotBody != "a long report" {
+		t.Errorf("body = %q, want the report", gotBody)
+	}
+	if gotDate != "20240501T083000Z" {
+		t.Errorf("X-Amz-Date = %s, want 20240501T083000Z", gotDate)
+	}
+	if gotHash != hashHex([]byte("a long report")) {
+		t.Errorf("X-Amz-Content-Sha256 = %s, want the payload hash", gotHash)
+	}
+	wantAuth := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20240501/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="
+	if !strings.HasPrefix(gotAuth, wantAuth) {
+		t.Errorf("Authorization = %s, want prefix %s", gotAuth, wantAuth)
+	}
+}
+
+func TestS3Store_SavePublicBaseURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	store := NewS3Store(config.S3Config{
+		Endpoint:      server.URL,
+		Region:        "us-east-1",
+		Bucket:        "reports",
+		PublicBaseURL: "https://cdn.example.com/reports/",
+	}, 0)
+
+	got, err := store.Save(context.Background(), "valuation/1/2.txt", "report")
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if got != "https://cdn.example.com/reports/valuation/1/2.txt" {
+		t.Errorf("Save() = %q, want the public URL", got)
+	}
+}
+
+func TestS3Store_SaveBelowMinSize(t *testing.T) {
+	uploaded := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uploaded = true
+	}))
+	defer server.Close()
+
+	store := NewS3Store(config.S3Config{Endpoint: server.URL, Region: "us-east-1", Bucket: "reports"}, 100)
+
+	got, err := store.Save(context.Background(), "valuation/1/2.txt", "short report")
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if got != "short report" {
+		t.Errorf("Save() = %q, want the report unchanged", got)
+	}
+	if uploaded {
+		t.Errorf("report below min_size should not be uploaded")
+	}
+}
+
+func TestS3Store_SaveError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("<Error><Code>AccessDenied</Code></Error>"))
+	}))
+	defer server.Close()
+
+	store := NewS3Store(config.S3Config{Endpoint: server.URL, Region: "us-east-1", Bucket: "reports"}, 0)
+
+	_, err := store.Save(context.Background(), "valuation/1/2.txt", "report")
+	if err == nil {
+		t.Fatal("Save() should fail when the upload is rejected")
+	}
+	if !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("Save() error = %v, want status and error code", err)
+	}
+}
+
+func TestEscapePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"valuation/1/2.txt", "valuation/1/2.txt"},
+		{"a b/c+d", "a%20b/c%2Bd"},
+		{"报告", "%E6%8A%A5%E5%91%8A"},
+	}
+
+	for _, tt := range tests {
+		if got := escapePath(tt.path); got != tt.want {
+			t.Errorf("escapePath(%q) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
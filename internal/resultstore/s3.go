@@ -0,0 +1,160 @@
+package resultstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultS3Timeout 未配置时单次上传请求的超时时间
+const DefaultS3Timeout = 30 * time.Second
+
+// S3Store 将报告写入 S3 兼容的对象存储（AWS S3、MinIO 等），
+// 使用路径风格的地址 {endpoint}/{bucket}/{key} 和 AWS Signature V4 签名
+type S3Store struct {
+	cfg     config.S3Config
+	minSize int // 报告不超过该字节数时仍直接写入数据库
+	client  *http.Client
+	now     func() time.Time // 签名使用的当前时间，测试时替换
+}
+
+// NewS3Store 创建 S3 报告存储，minSize 为 0 时所有报告都写入对象存储
+func NewS3Store(cfg config.S3Config, minSize int) *S3Store {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultS3Timeout
+	}
+
+	return &S3Store{
+		cfg:     cfg,
+		minSize: minSize,
+		client:  &http.Client{Timeout: timeout},
+		now:     time.Now,
+	}
+}
+
+// Save 上传报告并返回对象地址：配置了 public_base_url 时为 {public_base_url}/{key}，
+// 否则为 s3://{bucket}/{key}。报告不超过 min_size 时原样返回，不上传
+func (s *S3Store) Save(ctx context.Context, key, report string) (string, error) {
+	if len(report) <= s.minSize {
+		return report, nil
+	}
+
+	key = s.cfg.Prefix + key
+	if err := s.put(ctx, key, []byte(report)); err != nil {
+		return "", err
+	}
+
+	if s.cfg.PublicBaseURL != "" {
+		return strings.TrimSuffix(s.cfg.PublicBaseURL, "/") + "/" + key, nil
+	}
+	return fmt.Sprintf("s3://%s/%s", s.cfg.Bucket, key), nil
+}
+
+// put 以 PUT 请求上传对象
+func (s *S3Store) put(ctx context.Context, key string, body []byte) error {
+	path := "/" + escapePath(s.cfg.Bucket) + "/" + escapePath(key)
+	endpoint := strings.TrimSuffix(s.cfg.Endpoint, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create upload request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	s.sign(req, path, body)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload report: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// 错误响应体为 XML，只截取开头部分用于排查
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to upload report: status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	return nil
+}
+
+// sign 为请求添加 AWS Signature V4 签名，签名的请求头为 host、x-amz-content-sha256 和 x-amz-date
+func (s *S3Store) sign(req *http.Request, path string, body []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := hashHex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		"", // 无查询参数
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := signingKey(s.cfg.SecretAccessKey, date, s.cfg.Region, "s3")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// signingKey 按 Signature V4 的规则从密钥、日期、区域和服务名称逐级派生签名密钥
+func signingKey(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// escapePath 按 Signature V4 的规则编码对象路径：保留字母、数字、'-'、'_'、'.'、'~' 和 '/'，
+// 其他字节编码为 %XX
+func escapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
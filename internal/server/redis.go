@@ -0,0 +1,35 @@
+package server
+
+import (
+	"fmt"
+	"github.com/hibiken/asynq"
+	"sync"
+)
+
+// redisClient 包装 asynq 客户端，为就绪检查提供 Redis 连通性检测
+type redisClient struct {
+	*asynq.Client
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// newRedisClient 创建 asynq 客户端包装
+func newRedisClient(opt asynq.RedisConnOpt) *redisClient {
+	return &redisClient{Client: asynq.NewClient(opt)}
+}
+
+// Ping 向 Redis 发送 PING，检查连接是否可用
+func (r *redisClient) Ping() error {
+	if err := r.Client.Ping(); err != nil {
+		return fmt.Errorf("redis ping failed: %w", err)
+	}
+	return nil
+}
+
+// Close 关闭底层连接，可以安全地重复调用
+func (r *redisClient) Close() error {
+	r.closeOnce.Do(func() {
+		r.closeErr = r.Client.Close()
+	})
+	return r.closeErr
+}
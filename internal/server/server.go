@@ -9,115 +9,176 @@ import (
 	"github.com/igwen6w/syt-go-queue/internal/handler"
 	"github.com/igwen6w/syt-go-queue/internal/logger"
 	"github.com/igwen6w/syt-go-queue/internal/middleware"
-	"github.com/jmoiron/sqlx"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
-	"net/http"
 )
 
 type Server struct {
-	engine *gin.Engine
-	cfg    *config.Config
-	client *asynq.Client
-	db     *database.Database
+	engine   *gin.Engine
+	cfg      *config.Config
+	client   *redisClient
+	redisOpt asynq.RedisConnOpt
+	db       *database.Database
 }
 
-func NewServer(cfg *config.Config) *Server {
+func NewServer(cfg *config.Config) (*Server, error) {
 	// 初始化 Redis 客户端
-	client := asynq.NewClient(asynq.RedisClientOpt{
-		Addr:     cfg.Redis.Addr,
-		Password: cfg.Redis.Password,
-		DB:       cfg.Redis.DB,
-	})
+	redisOpt, err := cfg.Redis.ConnOpt()
+	if err != nil {
+		return nil, err
+	}
+	client := newRedisClient(redisOpt)
+
+	// 初始化 MySQL 连接，失败时按指数退避重试
+	db, err := database.Connect(cfg.MySQL, cfg.MySQL.ConnectRetries, cfg.MySQL.ConnectBackoff)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	// 配置了只读副本时，记录查询使用副本
+	replica, err := database.ConnectReplica(cfg.MySQL, cfg.MySQL.ConnectRetries, cfg.MySQL.ConnectBackoff)
+	if err != nil {
+		_ = db.Close()
+		_ = client.Close()
+		return nil, err
+	}
+
+	// 初始化数据库实例，应用列名映射
+	newDatabase, err := database.NewDatabaseWithReplica(db, replica).
+		WithQueryTimeout(cfg.MySQL.QueryTimeout).
+		WithColumns(cfg.MySQL.Columns)
+	if err != nil {
+		_ = db.Close()
+		if replica != nil {
+			_ = replica.Close()
+		}
+		_ = client.Close()
+		return nil, fmt.Errorf("invalid mysql columns: %w", err)
+	}
+
+	// 初始化 Gin 引擎，使用自定义的恢复中间件返回 JSON 格式的 500 响应
+	engine := gin.New()
+	engine.Use(middleware.Recovery())
+	engine.Use(gin.Logger())
 
-	// 初始化 MySQL 连接
-	db := sqlx.MustConnect("mysql", cfg.MySQL.DSN)
-	db.SetMaxIdleConns(cfg.MySQL.MaxIdleConns)
-	db.SetMaxOpenConns(cfg.MySQL.MaxOpenConns)
+	// 限制请求体大小，避免超大请求占用内存
+	engine.Use(middleware.BodyLimit(cfg.App.MaxBodyBytes))
 
-	// 初始化数据库实例
-	newDatabase := database.NewDatabase(db)
+	// 添加 CORS 中间件，未配置允许的来源时不做处理
+	engine.Use(middleware.CORS(cfg.CORS))
 
-	// 初始化 Gin 引擎
-	engine := gin.Default()
+	// 添加请求ID中间件，用于关联 API 和 worker 日志
+	engine.Use(middleware.RequestID())
 
 	// 添加指标收集中间件
 	engine.Use(middleware.MetricsMiddleware())
 
-	// 设置认证
-	if cfg.Auth.Enabled {
-		logger.Info("Enabling authentication", zap.String("realm", cfg.Auth.Realm))
-		// 创建认证中间件
-		authorized := gin.BasicAuth(cfg.Auth.Users)
-		// 全局应用认证中间件
-		engine.Use(authorized)
-	} else {
-		logger.Warn("Authentication is disabled")
-	}
+	// 压缩响应体，放在指标中间件之后，未启用时不做处理
+	engine.Use(middleware.Compression(cfg.Compression))
 
 	server := &Server{
-		engine: engine,
-		cfg:    cfg,
-		client: client,
-		db:     newDatabase,
+		engine:   engine,
+		cfg:      cfg,
+		client:   client,
+		redisOpt: redisOpt,
+		db:       newDatabase,
 	}
 
 	server.setupRoutes()
-	return server
+	return server, nil
 }
 
 func (s *Server) setupRoutes() {
-	// 创建 Redis 客户端配置，用于任务检查器
-	redisOpt := asynq.RedisClientOpt{
-		Addr:     s.cfg.Redis.Addr,
-		Password: s.cfg.Redis.Password,
-		DB:       s.cfg.Redis.DB,
+	// 创建任务处理器，任务检查器与客户端使用相同的 Redis 连接配置
+	taskHandler := handler.NewTaskHandler(s.client.Client, s.db, s.redisOpt, s.cfg.Queue, s.cfg.Deepseek)
+
+	// 创建健康检查处理器
+	healthHandler := handler.NewHealthHandler(s.db, s.client, s.cfg.App.HealthCheckTimeout)
+
+	// 健康检查路由 - 不需要认证，供负载均衡和 Kubernetes 探针使用
+	s.engine.GET("/health", healthHandler.HealthCheck)
+	healthz := s.engine.Group("/healthz")
+	{
+		healthz.GET("/live", healthHandler.LivenessCheck)
+		healthz.GET("/ready", healthHandler.ReadinessCheck)
+		healthz.GET("/startup", healthHandler.StartupCheck)
 	}
 
-	// 创建任务处理器
-	taskHandler := handler.NewTaskHandler(s.client, s.db, redisOpt)
+	// 版本信息路由 - 不需要认证，用于确认部署的版本
+	s.engine.GET("/version", handler.Version)
 
-	// 创建健康检查处理器
-	healthHandler := handler.NewHealthHandler(s.db, s.client)
-
-	// 健康检查路由 - 不需要认证
-	// 如果启用了全局认证，需要先禁用这些路由的认证
-	s.engine.Group("/health", func(c *gin.Context) {
-		// 跳过认证中间件
-		c.Next()
-	}).GET("", healthHandler.HealthCheck)
-
-	s.engine.Group("/healthz", func(c *gin.Context) {
-		c.Next()
-	}).GET("/live", healthHandler.LivenessCheck)
-
-	s.engine.Group("/healthz", func(c *gin.Context) {
-		c.Next()
-	}).GET("/ready", healthHandler.ReadinessCheck)
-
-	// 指标端点 - 不需要认证
-	s.engine.GET("/metrics", func(c *gin.Context) {
-		// 跳过认证中间件
-		c.Next()
-		// 使用 promhttp 处理指标请求
-		promhttp.Handler().ServeHTTP(c.Writer, c.Request)
-	})
-
-	// API 路由
+	// 指标端点 - 不使用 API 认证，配置了 metrics_users 时使用独立的凭据
+	metricsGroup := s.engine.Group("/metrics")
+	if len(s.cfg.Auth.MetricsUsers) > 0 {
+		logger.Info("Enabling metrics authentication")
+		metricsGroup.Use(gin.BasicAuthForRealm(s.cfg.Auth.MetricsUsers, s.cfg.Auth.Realm))
+	}
+	metricsGroup.GET("", gin.WrapH(promhttp.Handler()))
+
+	// API 路由 - 认证只应用于该分组
 	api := s.engine.Group("/api")
+	if s.cfg.Auth.Enabled {
+		logger.Info("Enabling authentication", zap.String("realm", s.cfg.Auth.Realm))
+		api.Use(gin.BasicAuthForRealm(s.cfg.Auth.Users, s.cfg.Auth.Realm))
+	} else {
+		logger.Warn("Authentication is disabled")
+	}
+	// 限制处理时间，避免阻塞在 Inspector 或数据库上的请求一直占用连接
+	api.Use(middleware.Timeout(s.cfg.RequestTimeout))
 	{
 		// 任务创建路由
 		api.POST("/tasks/llm", taskHandler.CreateLLMTask)
+		api.POST("/tasks/llm/batch", taskHandler.CreateBatchLLMTask)
+		api.POST("/tasks/llm/retry", taskHandler.RetryLLMTask)
 
 		// 任务管理路由
 		tasks := api.Group("/tasks")
 		{
+			// 列出支持的任务类型
+			tasks.GET("/types", taskHandler.ListTaskTypes)
+
+			// 按表名和记录ID查找任务
+			tasks.GET("/search", taskHandler.SearchTasks)
+
 			// 获取任务状态
 			tasks.GET("/:id", taskHandler.GetTaskStatus)
 
+			// 取消处理中的任务
+			tasks.POST("/:id/cancel", taskHandler.CancelTask)
+
+			// 立即处理或修改计划中、等待重试的任务的执行时间
+			tasks.POST("/:id/run", taskHandler.RunTask)
+			tasks.POST("/:id/reschedule", taskHandler.RescheduleTask)
+
 			// 列出任务
 			tasks.GET("", taskHandler.ListTasks)
 		}
+
+		// 队列统计
+		api.GET("/stats", taskHandler.GetStats)
+
+		// 队列管理路由
+		queues := api.Group("/queues")
+		{
+			// 暂停和恢复队列
+			queues.POST("/:name/pause", taskHandler.PauseQueue)
+			queues.POST("/:name/resume", taskHandler.ResumeQueue)
+
+			// 批量归档或删除某一状态的任务，需要 confirm=true
+			queues.POST("/:name/:state/:action", taskHandler.QueueBulkAction)
+		}
+
+		// 管理路由
+		admin := api.Group("/admin")
+		{
+			// 维护模式开关，开启后拒绝创建新任务
+			admin.GET("/maintenance", taskHandler.GetMaintenance)
+			admin.POST("/maintenance", taskHandler.SetMaintenance)
+
+			// 当前生效的队列配置和运行中 worker 的并发、队列权重
+			admin.GET("/config", taskHandler.GetConfig)
+		}
 	}
 }
 
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/igwen6w/syt-go-queue/internal/database"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestServer 创建不依赖真实 MySQL 的测试服务器。
+// 数据库连接指向不可用的地址，就绪检查会返回 503。
+func newTestServer(t *testing.T, auth config.AuthConfig) *Server {
+	db, err := sqlx.Open("mysql", "root:password@tcp(127.0.0.1:1)/test?timeout=1s")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	redisOpt := asynq.RedisClientOpt{Addr: "127.0.0.1:1"}
+	client := newRedisClient(redisOpt)
+	t.Cleanup(func() { _ = client.Close() })
+
+	s := &Server{
+		engine: gin.New(),
+		cfg: &config.Config{
+			Redis: config.RedisConfig{Addr: "127.0.0.1:1"},
+			Auth:  auth,
+		},
+		client:   client,
+		redisOpt: redisOpt,
+		db:       database.NewDatabase(db),
+	}
+	s.setupRoutes()
+	return s
+}
+
+func TestMetricsAuth(t *testing.T) {
+	apiAuth := config.AuthConfig{
+		Enabled: true,
+		Realm:   "Test Realm",
+		Users:   map[string]string{"admin": "admin123"},
+	}
+	metricsAuth := apiAuth
+	metricsAuth.MetricsUsers = map[string]string{"prometheus": "secret"}
+
+	tests := []struct {
+		name           string
+		auth           config.AuthConfig
+		path           string
+		username       string
+		password       string
+		expectedStatus int
+	}{
+		{
+			name:           "metrics open without api credentials",
+			auth:           apiAuth,
+			path:           "/metrics",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "api still requires credentials",
+			auth:           apiAuth,
+			path:           "/api/stats",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "metrics protected without credentials",
+			auth:           metricsAuth,
+			path:           "/metrics",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "metrics rejects api credentials",
+			auth:           metricsAuth,
+			path:           "/metrics",
+			username:       "admin",
+			password:       "admin123",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "metrics accepts metrics credentials",
+			auth:           metricsAuth,
+			path:           "/metrics",
+			username:       "prometheus",
+			password:       "secret",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, tt.auth)
+
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			if tt.username != "" {
+				req.SetBasicAuth(tt.username, tt.password)
+			}
+			resp := httptest.NewRecorder()
+
+			s.engine.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedStatus, resp.Code)
+		})
+	}
+}
+
+func TestHealthEndpointsSkipAuth(t *testing.T) {
+	s := newTestServer(t, config.AuthConfig{
+		Enabled: true,
+		Realm:   "Test Realm",
+		Users:   map[string]string{"admin": "admin123"},
+	})
+
+	tests := []struct {
+		path             string
+		expectedStatuses []int
+	}{
+		{"/health", []int{http.StatusOK}},
+		{"/healthz/live", []int{http.StatusOK}},
+		// 测试数据库不可用，就绪检查返回 503，但不能是 401
+		{"/healthz/ready", []int{http.StatusOK, http.StatusServiceUnavailable}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			resp := httptest.NewRecorder()
+
+			s.engine.ServeHTTP(resp, req)
+
+			assert.NotEqual(t, http.StatusUnauthorized, resp.Code)
+			assert.Contains(t, tt.expectedStatuses, resp.Code)
+		})
+	}
+}
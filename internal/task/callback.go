@@ -0,0 +1,43 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hibiken/asynq"
+)
+
+const TypeCallback = "callback:send"
+
+func init() {
+	Register(Definition{
+		Type:        TypeCallback,
+		Description: "将处理结果以 JSON POST 到回调地址，失败时按队列的重试策略重新发送",
+		Schema: map[string]interface{}{
+			"type":     "object",
+			"required": []string{"url", "body"},
+			"properties": map[string]interface{}{
+				"url":  map[string]interface{}{"type": "string", "description": "回调地址"},
+				"body": map[string]interface{}{"type": "object", "description": "回调请求体"},
+			},
+		},
+	})
+}
+
+type CallbackPayload struct {
+	URL  string          `json:"url"`  // 回调地址
+	Body json.RawMessage `json:"body"` // 回调请求体，原样发送
+}
+
+// NewCallbackTask 创建回调任务，result 编码为 JSON 后作为回调请求体
+func NewCallbackTask(url string, result interface{}) (*asynq.Task, error) {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal callback body: %w", err)
+	}
+
+	payload, err := json.Marshal(CallbackPayload{URL: url, Body: body})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal callback task payload: %w", err)
+	}
+	return asynq.NewTask(TypeCallback, payload), nil
+}
@@ -6,20 +6,72 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/hibiken/asynq"
+	"time"
 )
 
 const TypeLLM = "llm:process"
 
+func init() {
+	Register(Definition{
+		Type:        TypeLLM,
+		Description: "使用记录中的系统消息和用户消息调用 LLM，并将结果写回记录",
+		Schema: map[string]interface{}{
+			"type":     "object",
+			"required": []string{"table_name", "id"},
+			"properties": map[string]interface{}{
+				"table_name": map[string]interface{}{"type": "string", "description": "数据表名"},
+				"id":         map[string]interface{}{"type": "integer", "description": "记录ID"},
+				"model":      map[string]interface{}{"type": "string", "description": "任务级模型，为空时使用配置"},
+				"max_tokens": map[string]interface{}{"type": "integer", "description": "任务级最大 token 数，为 0 时使用配置"},
+			},
+		},
+	})
+}
+
 type LLMPayload struct {
-	TableName string `json:"table_name"` // 数据表名
-	ID        int64  `json:"id"`         // 记录ID
+	TableName string `json:"table_name"`           // 数据表名
+	ID        int64  `json:"id"`                   // 记录ID
+	RequestID string `json:"request_id,omitempty"` // 创建任务的请求ID，用于关联 API 和 worker 日志
+	Model     string `json:"model,omitempty"`      // 任务级模型，为空时使用配置
+	MaxTokens int    `json:"max_tokens,omitempty"` // 任务级最大 token 数，为 0 时使用配置
+	// 入队时间（Unix 毫秒），用于统计任务在队列中的等待时间
+	EnqueuedAt int64 `json:"enqueued_at,omitempty"`
 }
 
-func NewLLMTask(tableName string, id int64) (*asynq.Task, error) {
-	payload, err := json.Marshal(LLMPayload{
-		TableName: tableName,
-		ID:        id,
-	})
+// LLMResult 是 LLM 任务成功后写入 asynq 任务结果的内容，
+// 查询任务状态时无需访问数据库即可返回报告
+type LLMResult struct {
+	Report    string `json:"report"`              // 报告，超过 queue.task_result_max_bytes 时截断
+	Truncated bool   `json:"truncated,omitempty"` // 报告是否被截断
+}
+
+// Message 是发送给 LLM 的一条对话消息
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// BuildMessages 使用记录中的系统消息和用户消息构建 LLM 对话消息，
+// examples 按顺序插入到系统消息和用户消息之间
+func BuildMessages(sysMessage, userMessage string, examples ...Message) []Message {
+	messages := make([]Message, 0, len(examples)+2)
+	messages = append(messages, Message{Role: "system", Content: sysMessage})
+	messages = append(messages, examples...)
+	return append(messages, Message{Role: "user", Content: userMessage})
+}
+
+// LLMTaskID 返回记录对应的确定性任务ID，用于去重入队
+func LLMTaskID(tableName string, id int64) string {
+	return fmt.Sprintf("%s:%s:%d", TypeLLM, tableName, id)
+}
+
+// NewLLMTask 创建 LLM 任务，未设置入队时间时使用当前时间
+func NewLLMTask(p LLMPayload) (*asynq.Task, error) {
+	if p.EnqueuedAt == 0 {
+		p.EnqueuedAt = time.Now().UnixMilli()
+	}
+
+	payload, err := json.Marshal(p)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal LLM task payload: %w", err)
 	}
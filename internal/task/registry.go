@@ -0,0 +1,55 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Definition 描述一个已注册的任务类型，用于 API 的任务类型发现
+type Definition struct {
+	Type        string                 `json:"type"`        // 任务类型名称
+	Description string                 `json:"description"` // 任务说明
+	Schema      map[string]interface{} `json:"schema"`      // 载荷的 JSON Schema
+}
+
+var (
+	registryMu  sync.RWMutex
+	definitions = make(map[string]Definition)
+)
+
+// Register 注册任务类型，重复注册同一类型会 panic
+func Register(def Definition) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if def.Type == "" {
+		panic("task: Register called with empty type")
+	}
+	if _, exists := definitions[def.Type]; exists {
+		panic(fmt.Sprintf("task: Register called twice for type %q", def.Type))
+	}
+	definitions[def.Type] = def
+}
+
+// Definitions 返回所有已注册的任务类型，按类型名称排序
+func Definitions() []Definition {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	defs := make([]Definition, 0, len(definitions))
+	for _, def := range definitions {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Type < defs[j].Type })
+	return defs
+}
+
+// Lookup 返回指定类型的任务定义
+func Lookup(taskType string) (Definition, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	def, ok := definitions[taskType]
+	return def, ok
+}
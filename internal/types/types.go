@@ -1,8 +1,30 @@
 package types
 
+import "github.com/igwen6w/syt-go-queue/internal/task"
+
 type CreateTaskRequest struct {
-	TableName string `json:"table_name" binding:"required"`
-	ID        int64  `json:"id" binding:"required"`
+	TableName  string `json:"table_name" form:"table_name" binding:"required,table_name"`
+	ID         int64  `json:"id" form:"id" binding:"required"`
+	Idempotent bool   `json:"idempotent" form:"idempotent"`                           // 为 true 时，同一记录已存在任务则返回已有任务ID而不重复入队
+	Model      string `json:"model" form:"model"`                                     // 可选，覆盖配置中的模型
+	MaxTokens  int    `json:"max_tokens" form:"max_tokens" binding:"omitempty,min=1"` // 可选，覆盖配置中的最大 token 数
+	// 可选，任务完成后结果保留的秒数，覆盖配置中的 retention
+	RetentionSeconds int `json:"retention_seconds" form:"retention_seconds" binding:"omitempty,min=1"`
+	// 为 true 时只校验记录和回调地址并返回组装好的消息，不入队也不调用 LLM
+	DryRun bool `json:"dry_run" form:"dry_run"`
+	// 可选，任务的计划执行时间（RFC3339），为空时立即入队
+	ProcessAt string `json:"process_at" form:"process_at"`
+	// 可选，任务优先级，对应 queue.priority_queues 中配置的队列，默认 normal
+	Priority string `json:"priority" form:"priority" binding:"omitempty,oneof=high normal low"`
+}
+
+// DryRunResponse 预演模式下返回的 LLM 请求内容
+type DryRunResponse struct {
+	TableName string         `json:"table_name"`
+	ID        int64          `json:"id"`
+	Model     string         `json:"model,omitempty"`      // 任务级模型，为空时 worker 使用配置
+	MaxTokens int            `json:"max_tokens,omitempty"` // 任务级最大 token 数，为 0 时 worker 使用配置
+	Messages  []task.Message `json:"messages"`
 }
 
 type CreateTaskResponse struct {
@@ -10,6 +32,29 @@ type CreateTaskResponse struct {
 	Status string `json:"status"`
 }
 
+type RetryTaskRequest struct {
+	TableName string `json:"table_name" binding:"required,table_name"`
+	ID        int64  `json:"id" binding:"required"`
+}
+
+type CreateBatchTaskRequest struct {
+	TableName string  `json:"table_name" binding:"required,table_name"`
+	IDs       []int64 `json:"ids" binding:"required,min=1"`
+}
+
+type BatchTaskResult struct {
+	ID     int64  `json:"id"`
+	TaskID string `json:"task_id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type CreateBatchTaskResponse struct {
+	Results  []BatchTaskResult `json:"results"`
+	Enqueued int               `json:"enqueued"`
+	Failed   int               `json:"failed"`
+}
+
 type GetTaskStatusRequest struct {
 	TaskID string `json:"task_id" binding:"required"`
 }
@@ -20,6 +65,20 @@ type GetTaskStatusResponse struct {
 	QueueName  string `json:"queue_name"`
 	CreatedAt  int64  `json:"created_at"`
 	RetryCount int    `json:"retry_count"`
+	MaxRetry   int    `json:"max_retry"`
+	// 最近一次失败的错误信息和时间（Unix 秒），任务未失败过时为空
+	LastError    string `json:"last_error,omitempty"`
+	LastFailedAt int64  `json:"last_failed_at,omitempty"`
+	TableName    string `json:"table_name,omitempty"`
+	RecordID     int64  `json:"record_id,omitempty"`
+	// 已完成任务写入的报告，超过 queue.task_result_max_bytes 时截断，result_truncated 为 true
+	Result          string `json:"result,omitempty"`
+	ResultTruncated bool   `json:"result_truncated,omitempty"`
+}
+
+// RescheduleTaskRequest 修改任务执行时间的请求
+type RescheduleTaskRequest struct {
+	ProcessAt string `json:"process_at" form:"process_at" binding:"required"` // 新的计划执行时间（RFC3339）
 }
 
 type ListTasksRequest struct {
@@ -29,6 +88,19 @@ type ListTasksRequest struct {
 	Offset    int    `form:"offset" json:"offset"`
 }
 
+// SearchTasksRequest 按记录查找任务的查询参数
+type SearchTasksRequest struct {
+	TableName string `form:"table_name" binding:"required"`
+	ID        int64  `form:"id" binding:"required"`
+	QueueName string `form:"queue_name"`
+}
+
+// SearchTasksResponse 按记录查找任务的结果
+type SearchTasksResponse struct {
+	Tasks    []TaskInfo `json:"tasks"`
+	PageSize int        `json:"page_size"` // 每种状态最多扫描的任务数量，更早的任务不会被找到
+}
+
 type ListTasksResponse struct {
 	Tasks      []TaskInfo `json:"tasks"`
 	TotalCount int        `json:"total_count"`
@@ -45,8 +117,122 @@ type TaskInfo struct {
 	Type       string `json:"type"`
 }
 
+type QueueStats struct {
+	Queue          string `json:"queue"`
+	Size           int    `json:"size"`
+	Pending        int    `json:"pending"`
+	Active         int    `json:"active"`
+	Scheduled      int    `json:"scheduled"`
+	Retry          int    `json:"retry"`
+	Archived       int    `json:"archived"`
+	Completed      int    `json:"completed"`
+	Processed      int    `json:"processed"`
+	Failed         int    `json:"failed"`
+	ProcessedTotal int    `json:"processed_total"`
+	FailedTotal    int    `json:"failed_total"`
+	Paused         bool   `json:"paused"`
+	LatencyMs      int64  `json:"latency_ms"`
+}
+
+type StatsResponse struct {
+	Queues         []QueueStats `json:"queues"`
+	ProcessedTotal int          `json:"processed_total"`
+	FailedTotal    int          `json:"failed_total"`
+	Timestamp      int64        `json:"timestamp"`
+}
+
+// AdminConfigResponse API 服务当前生效的队列配置，以及正在运行的 worker 上报的并发和队列权重
+type AdminConfigResponse struct {
+	Queue   QueueConfigInfo  `json:"queue"`
+	Queues  []QueueRouteInfo `json:"queues"`  // 配置的所有队列及其权重
+	Servers []WorkerServer   `json:"servers"` // 正在运行的 worker 进程，来自 Redis 中的心跳
+	// 修改配置文件后无需重启即可生效的字段，其他字段需要重启。
+	// 暂停和恢复队列（POST /api/queues/:name/pause、resume）也可以在运行时限制处理
+	HotReloadable []string `json:"hot_reloadable"`
+}
+
+// QueueConfigInfo 队列配置中影响处理和重试的字段，时长为 Go duration 格式，如 1m30s
+type QueueConfigInfo struct {
+	Concurrency         int    `json:"concurrency"`
+	Retry               int    `json:"retry"`
+	Retention           string `json:"retention"`
+	DefaultQueue        string `json:"default_queue"`
+	TaskTimeout         string `json:"task_timeout"`
+	DrainTimeout        string `json:"drain_timeout"`
+	RetryBaseDelay      string `json:"retry_base_delay"`
+	RetryMaxDelay       string `json:"retry_max_delay"`
+	RetryTransientDelay string `json:"retry_transient_delay"`
+	RetryRateLimitDelay string `json:"retry_rate_limit_delay"`
+	MaxBatchSize        int    `json:"max_batch_size"`
+	MaxFailedTimes      int    `json:"max_failed_times"`
+}
+
+// QueueRouteInfo 一个配置的队列
+type QueueRouteInfo struct {
+	Name      string   `json:"name"`
+	Weight    int      `json:"weight"`
+	TaskTypes []string `json:"task_types,omitempty"` // 路由到该队列的任务类型
+	Priority  string   `json:"priority,omitempty"`   // 优先级队列对应的优先级
+}
+
+// WorkerServer 一个正在运行的 worker 进程
+type WorkerServer struct {
+	ID             string         `json:"id"`
+	Host           string         `json:"host"`
+	PID            int            `json:"pid"`
+	Concurrency    int            `json:"concurrency"`
+	Queues         map[string]int `json:"queues"` // 该进程使用的队列权重
+	StrictPriority bool           `json:"strict_priority"`
+	Status         string         `json:"status"`
+	ActiveWorkers  int            `json:"active_workers"`
+	Started        int64          `json:"started"` // 启动时间（Unix 秒）
+}
+
+// MaintenanceRequest 切换维护模式的请求
+type MaintenanceRequest struct {
+	Enabled *bool  `json:"enabled" binding:"required"`
+	Message string `json:"message"` // 开启时返回给调用方的说明，为空时使用默认提示
+}
+
+// MaintenanceStatus 维护模式的当前状态
+type MaintenanceStatus struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message,omitempty"`
+	Since   int64  `json:"since,omitempty"` // 最近一次切换的时间（Unix 秒），启动后未切换过时为空
+}
+
+type QueueStateResponse struct {
+	Queue  string `json:"queue"`
+	Paused bool   `json:"paused"`
+}
+
+// TaskTypesResponse 列出已注册的任务类型及其载荷结构
+type TaskTypesResponse struct {
+	Types []task.Definition `json:"types"`
+}
+
+// QueueBulkActionResponse 批量归档或删除队列中某一状态任务的结果
+type QueueBulkActionResponse struct {
+	Queue    string `json:"queue"`
+	State    string `json:"state"`
+	Action   string `json:"action"`
+	Affected int    `json:"affected"` // 受影响的任务数量
+}
+
 type CommonResponse struct {
 	Code    int         `json:"code"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
 }
+
+// FieldError 描述单个字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`   // 请求中的字段名
+	Rule    string `json:"rule"`    // 未通过的校验规则，如 required、min
+	Message string `json:"message"` // 错误说明
+}
+
+// ValidationErrorData 请求校验失败时 CommonResponse.Data 的内容
+type ValidationErrorData struct {
+	Fields []FieldError `json:"fields"`
+}
@@ -0,0 +1,51 @@
+package utils
+
+import "strings"
+
+// maskPlaceholder 替换敏感信息时使用的占位符
+const maskPlaceholder = "****"
+
+// MaskSecret 隐藏密钥类敏感信息，只保留前4个字符便于识别
+// 长度不超过8的值完全隐藏
+func MaskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	if len(secret) <= 8 {
+		return maskPlaceholder
+	}
+	return secret[:4] + maskPlaceholder
+}
+
+// MaskDSN 隐藏 MySQL DSN 中的密码，保留用户名、主机和数据库名
+// DSN 格式: [user[:password]@][net[(addr)]]/dbname[?params]
+func MaskDSN(dsn string) string {
+	// 与驱动的解析规则一致：最后一个 '/' 之前的最后一个 '@' 分隔凭据
+	slash := strings.LastIndex(dsn, "/")
+	if slash < 0 {
+		return dsn
+	}
+	at := strings.LastIndex(dsn[:slash], "@")
+	if at < 0 {
+		// 没有凭据
+		return dsn
+	}
+
+	credentials := dsn[:at]
+	colon := strings.Index(credentials, ":")
+	if colon < 0 {
+		// 只有用户名，没有密码
+		return dsn
+	}
+
+	return credentials[:colon+1] + maskPlaceholder + dsn[at:]
+}
+
+// RedactSecret 将文本中出现的密钥替换为脱敏后的值，
+// 用于防止外部响应或错误信息回显密钥
+func RedactSecret(text, secret string) string {
+	if secret == "" {
+		return text
+	}
+	return strings.ReplaceAll(text, secret, MaskSecret(secret))
+}
@@ -0,0 +1,75 @@
+package utils
+
+import "testing"
+
+func TestMaskDSN(t *testing.T) {
+	tests := []struct {
+		name string
+		dsn  string
+		want string
+	}{
+		{
+			name: "with password",
+			dsn:  "root:password@tcp(localhost:3306)/syt_queue?charset=utf8mb4&parseTime=True",
+			want: "root:****@tcp(localhost:3306)/syt_queue?charset=utf8mb4&parseTime=True",
+		},
+		{
+			name: "password containing @ and /",
+			dsn:  "root:p@ss/word@tcp(db:3306)/syt_queue",
+			want: "root:****@tcp(db:3306)/syt_queue",
+		},
+		{
+			name: "without password",
+			dsn:  "root@tcp(localhost:3306)/syt_queue",
+			want: "root@tcp(localhost:3306)/syt_queue",
+		},
+		{
+			name: "without credentials",
+			dsn:  "tcp(localhost:3306)/syt_queue",
+			want: "tcp(localhost:3306)/syt_queue",
+		},
+		{
+			name: "empty password",
+			dsn:  "root:@tcp(localhost:3306)/syt_queue",
+			want: "root:****@tcp(localhost:3306)/syt_queue",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaskDSN(tt.dsn); got != tt.want {
+				t.Errorf("MaskDSN(%q) = %q, want %q", tt.dsn, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskSecret(t *testing.T) {
+	tests := []struct {
+		secret string
+		want   string
+	}{
+		{"", ""},
+		{"short", "****"},
+		{"sk-1234567890abcdef", "sk-1****"},
+	}
+
+	for _, tt := range tests {
+		if got := MaskSecret(tt.secret); got != tt.want {
+			t.Errorf("MaskSecret(%q) = %q, want %q", tt.secret, got, tt.want)
+		}
+	}
+}
+
+func TestRedactSecret(t *testing.T) {
+	text := `{"error":"invalid api key sk-1234567890abcdef"}`
+	got := RedactSecret(text, "sk-1234567890abcdef")
+	want := `{"error":"invalid api key sk-1****"}`
+	if got != want {
+		t.Errorf("RedactSecret() = %q, want %q", got, want)
+	}
+
+	if got := RedactSecret(text, ""); got != text {
+		t.Errorf("RedactSecret() with empty secret = %q, want %q", got, text)
+	}
+}
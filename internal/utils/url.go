@@ -3,8 +3,12 @@ package utils
 import (
 	"fmt"
 	"net"
+	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
 // 允许的回调URL方案
@@ -13,30 +17,71 @@ var allowedSchemes = map[string]bool{
 	"https": true,
 }
 
-// 禁止的IP范围
-var forbiddenIPRanges = []struct {
-	start net.IP
-	end   net.IP
-}{
-	{net.ParseIP("10.0.0.0"), net.ParseIP("10.255.255.255")},                           // 10.0.0.0/8
-	{net.ParseIP("172.16.0.0"), net.ParseIP("172.31.255.255")},                         // 172.16.0.0/12
-	{net.ParseIP("192.168.0.0"), net.ParseIP("192.168.255.255")},                       // 192.168.0.0/16
-	{net.ParseIP("127.0.0.0"), net.ParseIP("127.255.255.255")},                         // 127.0.0.0/8
-	{net.ParseIP("0.0.0.0"), net.ParseIP("0.255.255.255")},                             // 0.0.0.0/8
-	{net.ParseIP("169.254.0.0"), net.ParseIP("169.254.255.255")},                       // 169.254.0.0/16
-	{net.ParseIP("192.0.2.0"), net.ParseIP("192.0.2.255")},                             // 192.0.2.0/24
-	{net.ParseIP("198.51.100.0"), net.ParseIP("198.51.100.255")},                       // 198.51.100.0/24
-	{net.ParseIP("203.0.113.0"), net.ParseIP("203.0.113.255")},                         // 203.0.113.0/24
-	{net.ParseIP("224.0.0.0"), net.ParseIP("239.255.255.255")},                         // 224.0.0.0/4
-	{net.ParseIP("240.0.0.0"), net.ParseIP("255.255.255.255")},                         // 240.0.0.0/4
-	{net.ParseIP("100.64.0.0"), net.ParseIP("100.127.255.255")},                        // 100.64.0.0/10
-	{net.ParseIP("192.0.0.0"), net.ParseIP("192.0.0.255")},                             // 192.0.0.0/24
-	{net.ParseIP("198.18.0.0"), net.ParseIP("198.19.255.255")},                         // 198.18.0.0/15
-	{net.ParseIP("2001:db8::"), net.ParseIP("2001:db8:ffff:ffff:ffff:ffff:ffff:ffff")}, // 2001:db8::/32
-	{net.ParseIP("fc00::"), net.ParseIP("fdff:ffff:ffff:ffff:ffff:ffff:ffff:ffff")},    // fc00::/7
-	{net.ParseIP("fe80::"), net.ParseIP("febf:ffff:ffff:ffff:ffff:ffff:ffff:ffff")},    // fe80::/10
-	{net.ParseIP("::1"), net.ParseIP("::1")},                                           // ::1/128
-	{net.ParseIP("::"), net.ParseIP("::")},                                             // ::/128
+// defaultForbiddenCIDRs 默认禁止的IP范围
+var defaultForbiddenCIDRs = []string{
+	"10.0.0.0/8",      // 私有网络
+	"172.16.0.0/12",   // 私有网络
+	"192.168.0.0/16",  // 私有网络
+	"127.0.0.0/8",     // 回环地址
+	"0.0.0.0/8",       // 本网络
+	"169.254.0.0/16",  // 链路本地地址
+	"192.0.2.0/24",    // 文档地址 TEST-NET-1
+	"198.51.100.0/24", // 文档地址 TEST-NET-2
+	"203.0.113.0/24",  // 文档地址 TEST-NET-3
+	"224.0.0.0/4",     // 组播地址
+	"240.0.0.0/4",     // 保留地址，包括广播地址
+	"100.64.0.0/10",   // 运营商级 NAT
+	"192.0.0.0/24",    // IETF 协议分配
+	"198.18.0.0/15",   // 基准测试
+	"2001:db8::/32",   // IPv6 文档地址
+	"fc00::/7",        // IPv6 唯一本地地址
+	"fe80::/10",       // IPv6 链路本地地址
+	"::1/128",         // IPv6 回环地址
+	"::/128",          // IPv6 未指定地址
+}
+
+// 禁止的IP范围，由默认范围和配置的自定义范围组成
+var (
+	forbiddenNetsMu sync.RWMutex
+	forbiddenNets   = mustParseCIDRs(defaultForbiddenCIDRs)
+)
+
+// mustParseCIDRs 解析 CIDR 列表，解析失败时 panic，只用于内置常量
+func mustParseCIDRs(cidrs []string) []*net.IPNet {
+	nets, err := parseCIDRs(cidrs)
+	if err != nil {
+		panic(err)
+	}
+	return nets
+}
+
+// parseCIDRs 解析 CIDR 列表
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// SetCallbackBlockedCIDRs 在默认禁止范围之外追加自定义禁止的IP范围。
+// 任一 CIDR 无法解析时返回错误，且不修改当前配置。
+func SetCallbackBlockedCIDRs(cidrs []string) error {
+	custom, err := parseCIDRs(cidrs)
+	if err != nil {
+		return err
+	}
+
+	nets := append(mustParseCIDRs(defaultForbiddenCIDRs), custom...)
+
+	forbiddenNetsMu.Lock()
+	defer forbiddenNetsMu.Unlock()
+	forbiddenNets = nets
+	return nil
 }
 
 // 禁止的主机名
@@ -49,27 +94,44 @@ var forbiddenHostnames = map[string]bool{
 	"[::0]":     true,
 }
 
-// ipInRange 检查IP是否在指定范围内
-func ipInRange(ip net.IP, start net.IP, end net.IP) bool {
-	if ip.To4() != nil {
-		ip = ip.To4()
-		start = start.To4()
-		end = end.To4()
+// 回调主机白名单，为空时只进行 SSRF 检查
+var (
+	allowedHostsMu sync.RWMutex
+	allowedHosts   []string
+)
+
+// SetCallbackAllowedHosts 设置回调主机白名单。
+// 白名单不为空时，只有主机名与某一项完全相同或是其子域名的URL才会被接受。
+func SetCallbackAllowedHosts(hosts []string) {
+	normalized := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		host = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(host)), ".")
+		if host != "" {
+			normalized = append(normalized, host)
+		}
 	}
 
-	if ip == nil || start == nil || end == nil {
-		return false
+	allowedHostsMu.Lock()
+	defer allowedHostsMu.Unlock()
+	allowedHosts = normalized
+}
+
+// hostAllowed 检查主机名是否在白名单中，白名单为空时允许所有主机
+func hostAllowed(hostname string) bool {
+	allowedHostsMu.RLock()
+	defer allowedHostsMu.RUnlock()
+
+	if len(allowedHosts) == 0 {
+		return true
 	}
 
-	for i := 0; i < len(ip); i++ {
-		if ip[i] < start[i] || ip[i] > end[i] {
-			return false
-		}
-		if ip[i] != start[i] && ip[i] != end[i] {
-			break
+	hostname = strings.ToLower(hostname)
+	for _, allowed := range allowedHosts {
+		if hostname == allowed || strings.HasSuffix(hostname, "."+allowed) {
+			return true
 		}
 	}
-	return true
+	return false
 }
 
 // ValidateCallbackURL 验证回调URL是否安全
@@ -92,6 +154,11 @@ func ValidateCallbackURL(callbackURL string) error {
 		return fmt.Errorf("hostname not allowed: %s", hostname)
 	}
 
+	// 检查主机白名单
+	if !hostAllowed(hostname) {
+		return fmt.Errorf("hostname not in allowlist: %s", hostname)
+	}
+
 	// 解析IP地址
 	ips, err := net.LookupIP(hostname)
 	if err != nil {
@@ -100,12 +167,69 @@ func ValidateCallbackURL(callbackURL string) error {
 
 	// 检查每个IP是否在禁止范围内
 	for _, ip := range ips {
-		for _, ipRange := range forbiddenIPRanges {
-			if ipInRange(ip, ipRange.start, ipRange.end) {
-				return fmt.Errorf("IP address in forbidden range: %s", ip.String())
-			}
+		if err := checkIP(ip); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
+
+// CheckPrivateHost 检查主机名是否为本地主机名，或是禁止范围内的IP地址。
+// 与 ValidateCallbackURL 不同，不解析域名，适合在加载配置时校验服务地址
+func CheckPrivateHost(hostname string) error {
+	if forbiddenHostnames[strings.ToLower(hostname)] {
+		return fmt.Errorf("hostname not allowed: %s", hostname)
+	}
+	if ip := net.ParseIP(hostname); ip != nil {
+		return checkIP(ip)
+	}
+	return nil
+}
+
+// checkIP 检查IP是否在禁止范围内
+func checkIP(ip net.IP) error {
+	forbiddenNetsMu.RLock()
+	defer forbiddenNetsMu.RUnlock()
+
+	for _, ipNet := range forbiddenNets {
+		if ipNet.Contains(ip) {
+			return fmt.Errorf("IP address in forbidden range: %s", ip.String())
+		}
+	}
+	return nil
+}
+
+// checkDialAddress 在建立连接前检查实际连接的地址，
+// 作为 net.Dialer 的 Control 函数使用
+func checkDialAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid dial address %s: %w", address, err)
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("invalid dial address: %s", address)
+	}
+
+	return checkIP(ip)
+}
+
+// NewCallbackTransport 创建用于回调请求的 HTTP Transport。
+// ValidateCallbackURL 解析域名后，发起连接时会再次解析，
+// 攻击者控制的 DNS 可以在两次解析之间返回不同的IP（DNS 重绑定），
+// 因此在实际建立连接前再次检查目标IP。
+// 回调请求不使用代理，否则连接目标是代理地址，无法检查真实目标。
+func NewCallbackTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   checkDialAddress,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
@@ -0,0 +1,230 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateCallbackURL_AllowedHosts(t *testing.T) {
+	SetCallbackAllowedHosts([]string{"example.com", "93.184.216.34"})
+	defer SetCallbackAllowedHosts(nil)
+
+	tests := []struct {
+		name      string
+		url       string
+		wantError string
+	}{
+		{
+			name: "exact match",
+			url:  "https://93.184.216.34/callback",
+		},
+		{
+			name:      "host not in allowlist",
+			url:       "https://evil.com/callback",
+			wantError: "not in allowlist",
+		},
+		{
+			name:      "suffix without dot boundary",
+			url:       "https://notexample.com/callback",
+			wantError: "not in allowlist",
+		},
+		{
+			name:      "private address still blocked",
+			url:       "http://localhost/callback",
+			wantError: "hostname not allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCallbackURL(tt.url)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Errorf("ValidateCallbackURL(%q) returned error: %v", tt.url, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("ValidateCallbackURL(%q) error = %v, want error containing %q", tt.url, err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestHostAllowed(t *testing.T) {
+	// 白名单为空时允许所有主机
+	SetCallbackAllowedHosts(nil)
+	if !hostAllowed("anything.com") {
+		t.Errorf("Expected all hosts to be allowed with empty allowlist")
+	}
+
+	SetCallbackAllowedHosts([]string{"Example.com"})
+	defer SetCallbackAllowedHosts(nil)
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", true},
+		{"EXAMPLE.COM", true},
+		{"api.example.com", true},
+		{"notexample.com", false},
+		{"example.com.evil.com", false},
+	}
+
+	for _, tt := range tests {
+		if got := hostAllowed(tt.host); got != tt.want {
+			t.Errorf("hostAllowed(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestCheckIP_MixedResolution(t *testing.T) {
+	// 模拟同一主机同时解析到公网和内网地址，只要有一个内网地址就应拒绝
+	ips := []net.IP{
+		net.ParseIP("93.184.216.34"),
+		net.ParseIP("10.0.0.5"),
+	}
+
+	var err error
+	for _, ip := range ips {
+		if err = checkIP(ip); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		t.Errorf("Expected mixed public and private resolution to be rejected")
+	}
+}
+
+func TestNewCallbackTransport_RejectsPrivateAddressAtDial(t *testing.T) {
+	// 模拟 DNS 重绑定：URL 已通过校验，但连接时目标是内网地址
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewCallbackTransport()}
+	resp, err := client.Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatalf("Expected connection to loopback address to be rejected")
+	}
+	if !strings.Contains(err.Error(), "forbidden range") {
+		t.Errorf("Expected forbidden range error, got %v", err)
+	}
+}
+
+func TestCheckDialAddress(t *testing.T) {
+	tests := []struct {
+		address   string
+		wantError bool
+	}{
+		{"93.184.216.34:443", false},
+		{"127.0.0.1:80", true},
+		{"[::1]:80", true},
+		{"192.168.1.1:8080", true},
+		{"invalid", true},
+	}
+
+	for _, tt := range tests {
+		err := checkDialAddress("tcp", tt.address, nil)
+		if (err != nil) != tt.wantError {
+			t.Errorf("checkDialAddress(%q) error = %v, wantError %v", tt.address, err, tt.wantError)
+		}
+	}
+}
+
+func TestCheckPrivateHost(t *testing.T) {
+	tests := []struct {
+		hostname  string
+		wantError bool
+	}{
+		{"api.deepseek.com", false},
+		{"93.184.216.34", false},
+		{"localhost", true},
+		{"LOCALHOST", true},
+		{"127.0.0.1", true},
+		{"10.0.0.5", true},
+		{"::1", true},
+		{"169.254.169.254", true},
+	}
+
+	for _, tt := range tests {
+		err := CheckPrivateHost(tt.hostname)
+		if (err != nil) != tt.wantError {
+			t.Errorf("CheckPrivateHost(%q) error = %v, wantError %v", tt.hostname, err, tt.wantError)
+		}
+	}
+}
+
+func TestCheckIP_Boundaries(t *testing.T) {
+	tests := []struct {
+		ip        string
+		wantError bool
+	}{
+		{"9.255.255.255", false},
+		{"10.0.0.0", true},
+		{"10.255.255.255", true},
+		{"11.0.0.0", false},
+		{"172.15.255.255", false},
+		{"172.16.0.0", true},
+		{"172.31.255.255", true},
+		{"172.32.0.0", false},
+		{"100.63.255.255", false},
+		{"100.64.0.0", true},
+		{"100.127.255.255", true},
+		{"100.128.0.0", false},
+		{"255.255.255.255", true},
+		{"::ffff:192.168.0.1", true},
+		{"2001:db7:ffff:ffff:ffff:ffff:ffff:ffff", false},
+		{"2001:db8::", true},
+		{"2001:db8:ffff:ffff:ffff:ffff:ffff:ffff", true},
+		{"fbff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", false},
+		{"fc00::", true},
+		{"fe80::1", true},
+		{"::1", true},
+		{"::", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", false},
+	}
+
+	for _, tt := range tests {
+		err := checkIP(net.ParseIP(tt.ip))
+		if (err != nil) != tt.wantError {
+			t.Errorf("checkIP(%q) error = %v, wantError %v", tt.ip, err, tt.wantError)
+		}
+	}
+}
+
+func TestSetCallbackBlockedCIDRs(t *testing.T) {
+	defer func() {
+		if err := SetCallbackBlockedCIDRs(nil); err != nil {
+			t.Fatalf("Failed to reset blocked CIDRs: %v", err)
+		}
+	}()
+
+	if err := SetCallbackBlockedCIDRs([]string{"93.184.216.0/24", "2606:2800::/32"}); err != nil {
+		t.Fatalf("SetCallbackBlockedCIDRs() returned error: %v", err)
+	}
+
+	// 自定义范围生效，默认范围仍然保留
+	for _, ip := range []string{"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946", "10.0.0.1"} {
+		if err := checkIP(net.ParseIP(ip)); err == nil {
+			t.Errorf("Expected %s to be blocked", ip)
+		}
+	}
+	if err := checkIP(net.ParseIP("93.184.217.1")); err != nil {
+		t.Errorf("Expected 93.184.217.1 to be allowed, got %v", err)
+	}
+
+	// 无效 CIDR 返回错误，且不修改当前配置
+	if err := SetCallbackBlockedCIDRs([]string{"not-a-cidr"}); err == nil {
+		t.Errorf("Expected error for invalid CIDR")
+	}
+	if err := checkIP(net.ParseIP("93.184.216.34")); err == nil {
+		t.Errorf("Expected previous blocked CIDRs to be kept after invalid update")
+	}
+}
@@ -0,0 +1,35 @@
+// Package version 记录构建时注入的版本信息。
+//
+// 构建时通过 -ldflags 设置，例如：
+//
+//	go build -ldflags "-X github.com/igwen6w/syt-go-queue/internal/version.Version=v1.2.0 \
+//	  -X github.com/igwen6w/syt-go-queue/internal/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X github.com/igwen6w/syt-go-queue/internal/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/api
+package version
+
+import "runtime"
+
+// 构建时注入的变量，未注入时为默认值
+var (
+	Version   = "dev"     // 版本号
+	Commit    = "unknown" // git 提交
+	BuildTime = "unknown" // 构建时间
+)
+
+// Info 二进制的版本信息
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get 返回当前二进制的版本信息
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+}
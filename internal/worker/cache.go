@@ -0,0 +1,116 @@
+package worker
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultPromptCacheSize 未配置 prompt_cache.size 时最多缓存的回复数
+	defaultPromptCacheSize = 1000
+	// defaultPromptCacheTTL 未配置 prompt_cache.ttl 时回复的缓存时间
+	defaultPromptCacheTTL = time.Hour
+)
+
+// promptCache 缓存 LLM 回复的 LRU 缓存，以请求体的哈希为键。
+// 同一工作者进程内的所有任务协程共享一个缓存，所有方法都可以并发调用
+type promptCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	order   *list.List       // 按最近使用排序，最近使用的在前
+	now     func() time.Time // 当前时间，测试中可以替换
+}
+
+type promptCacheEntry struct {
+	key       string
+	content   string
+	usage     *llmUsage // 生成回复时的 token 用量，响应中没有时为 nil
+	expiresAt time.Time
+}
+
+// newPromptCache 按配置创建缓存，未启用时返回 nil
+func newPromptCache(cfg config.PromptCacheConfig) *promptCache {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	size := cfg.Size
+	if size <= 0 {
+		size = defaultPromptCacheSize
+	}
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = defaultPromptCacheTTL
+	}
+
+	return &promptCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// get 返回缓存的回复，Cached 为 true，Usage 为生成该回复时的用量。
+// 未缓存或已过期时返回 false，过期的回复同时被移除
+func (c *promptCache) get(key string) (llmResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return llmResult{}, false
+	}
+	entry := elem.Value.(*promptCacheEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return llmResult{}, false
+	}
+
+	c.order.MoveToFront(elem)
+	return llmResult{Content: entry.content, Usage: entry.usage, Cached: true}, true
+}
+
+// add 缓存回复的内容和 token 用量，已缓存时更新内容和过期时间，超出容量时淘汰最久未使用的回复
+func (c *promptCache) add(key string, result llmResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*promptCacheEntry)
+		entry.content = result.Content
+		entry.usage = result.Usage
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&promptCacheEntry{
+		key:       key,
+		content:   result.Content,
+		usage:     result.Usage,
+		expiresAt: expiresAt,
+	})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*promptCacheEntry).key)
+	}
+}
+
+// promptCacheKey 返回序列化后请求体的 SHA-256 哈希，作为回复缓存的键。
+// 请求体包含模型、消息以及 max_tokens、temperature 等所有生成参数，任一参数不同都不会命中。
+// 请求体由 map 序列化，键按字母顺序排列，相同的请求得到相同的键
+func promptCacheKey(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
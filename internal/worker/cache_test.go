@@ -0,0 +1,210 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/igwen6w/syt-go-queue/internal/circuitbreaker"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/igwen6w/syt-go-queue/internal/database"
+	"github.com/igwen6w/syt-go-queue/internal/metrics"
+	"github.com/igwen6w/syt-go-queue/internal/task"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewPromptCache(t *testing.T) {
+	if c := newPromptCache(config.PromptCacheConfig{Size: 10}); c != nil {
+		t.Errorf("Expected nil cache when disabled")
+	}
+
+	c := newPromptCache(config.PromptCacheConfig{Enabled: true})
+	if c.size != defaultPromptCacheSize {
+		t.Errorf("Expected default size %d, got %d", defaultPromptCacheSize, c.size)
+	}
+	if c.ttl != defaultPromptCacheTTL {
+		t.Errorf("Expected default ttl %v, got %v", defaultPromptCacheTTL, c.ttl)
+	}
+}
+
+func TestPromptCache_Evict(t *testing.T) {
+	c := newPromptCache(config.PromptCacheConfig{Enabled: true, Size: 2})
+	c.add("a", llmResult{Content: "A"})
+	c.add("b", llmResult{Content: "B"})
+
+	// 读取 a 后 b 成为最久未使用的，超出容量时被淘汰
+	if _, ok := c.get("a"); !ok {
+		t.Fatalf("Expected a to be cached")
+	}
+	c.add("c", llmResult{Content: "C"})
+
+	if _, ok := c.get("b"); ok {
+		t.Errorf("Expected b to be evicted")
+	}
+	for key, want := range map[string]string{"a": "A", "c": "C"} {
+		if got, ok := c.get(key); !ok || got.Content != want {
+			t.Errorf("Expected %s to be %q, got %q (cached: %v)", key, want, got.Content, ok)
+		}
+	}
+}
+
+func TestPromptCache_TTL(t *testing.T) {
+	now := time.Now()
+	c := newPromptCache(config.PromptCacheConfig{Enabled: true, TTL: time.Minute})
+	c.now = func() time.Time { return now }
+
+	c.add("a", llmResult{Content: "A"})
+	now = now.Add(59 * time.Second)
+	if _, ok := c.get("a"); !ok {
+		t.Errorf("Expected a to be cached before ttl")
+	}
+
+	now = now.Add(time.Second)
+	if _, ok := c.get("a"); ok {
+		t.Errorf("Expected a to expire after ttl")
+	}
+	if _, ok := c.entries["a"]; ok {
+		t.Errorf("Expected expired entry to be removed")
+	}
+}
+
+func TestPromptCache_Concurrent(t *testing.T) {
+	c := newPromptCache(config.PromptCacheConfig{Enabled: true, Size: 10})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprintf("key-%d", (i+j)%20)
+				c.add(key, llmResult{Content: key})
+				if got, ok := c.get(key); ok && got.Content != key {
+					t.Errorf("Expected %q, got %q", key, got.Content)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := c.order.Len(); n > 10 || n != len(c.entries) {
+		t.Errorf("Expected at most 10 consistent entries, got %d in list and %d in map", n, len(c.entries))
+	}
+}
+
+func TestPromptCacheKey(t *testing.T) {
+	key := func(model, user string, maxTokens int, temperature float64) string {
+		body, err := json.Marshal(map[string]interface{}{
+			"model":       model,
+			"messages":    task.BuildMessages("system", user),
+			"max_tokens":  maxTokens,
+			"temperature": temperature,
+		})
+		if err != nil {
+			t.Fatalf("Failed to marshal payload: %v", err)
+		}
+		return promptCacheKey(body)
+	}
+
+	base := key("model-a", "hello", 100, 1)
+	if got := key("model-a", "hello", 100, 1); got != base {
+		t.Errorf("Expected identical requests to have the same key")
+	}
+	tests := map[string]string{
+		"model":       key("model-b", "hello", 100, 1),
+		"messages":    key("model-a", "world", 100, 1),
+		"max_tokens":  key("model-a", "hello", 200, 1),
+		"temperature": key("model-a", "hello", 100, 0.5),
+	}
+	for name, got := range tests {
+		if got == base {
+			t.Errorf("Expected a different %s to change the key", name)
+		}
+	}
+}
+
+func TestTaskHandler_ProcessLLM_PromptCache(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"cached response"}}],"usage":{"total_tokens":10}}`))
+	}))
+	defer server.Close()
+
+	handler := &TaskHandler{
+		deepseek: config.DeepseekConfig{
+			APIKey:    "test-key",
+			BaseURL:   server.URL,
+			Timeout:   5 * time.Second,
+			Model:     "test-model",
+			MaxTokens: 100,
+		},
+		client:         &http.Client{},
+		circuitBreaker: circuitbreaker.DefaultLLMCircuitBreaker(),
+		promptCache:    newPromptCache(config.PromptCacheConfig{Enabled: true}),
+	}
+
+	counter := func(result string) float64 {
+		var m dto.Metric
+		if err := metrics.LLMCacheCounter.WithLabelValues(result).(prometheus.Metric).Write(&m); err != nil {
+			t.Fatalf("Failed to read counter: %v", err)
+		}
+		return m.GetCounter().GetValue()
+	}
+	hits, misses := counter("hit"), counter("miss")
+
+	record := &database.ValuationRecord{ID: 123, SysMessage: "system", UserMessage: "hello"}
+
+	// 第一次调用 API 并缓存回复，之后相同的提示词直接使用缓存
+	for i := 0; i < 3; i++ {
+		result, err := handler.processLLM(context.Background(), record, task.LLMPayload{})
+		if err != nil {
+			t.Fatalf("processLLM failed on call %d: %v", i+1, err)
+		}
+		if result.Content != "cached response" {
+			t.Errorf("Expected cached response, got %q", result.Content)
+		}
+		if result.Model != "test-model" {
+			t.Errorf("Expected model test-model, got %q", result.Model)
+		}
+		// 缓存的结果带有标记和生成回复时的用量
+		if result.Cached != (i > 0) {
+			t.Errorf("Expected cached %v on call %d, got %v", i > 0, i+1, result.Cached)
+		}
+		if result.Usage == nil || result.Usage.TotalTokens != 10 {
+			t.Errorf("Expected usage with 10 tokens on call %d, got %+v", i+1, result.Usage)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Expected 1 API call, got %d", got)
+	}
+
+	// 任务指定的 max_tokens 不同时不命中缓存，避免复用被截断的回复
+	if _, err := handler.processLLM(context.Background(), record, task.LLMPayload{MaxTokens: 50}); err != nil {
+		t.Fatalf("processLLM failed: %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Expected a different max_tokens to call the API, got %d calls", got)
+	}
+
+	// 任务指定的模型不同时不命中缓存
+	if _, err := handler.processLLM(context.Background(), record, task.LLMPayload{Model: "other-model"}); err != nil {
+		t.Fatalf("processLLM failed: %v", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("Expected a different model to call the API, got %d calls", got)
+	}
+
+	if got, want := counter("hit")-hits, 2.0; got != want {
+		t.Errorf("Expected %v cache hits, got %v", want, got)
+	}
+	if got, want := counter("miss")-misses, 3.0; got != want {
+		t.Errorf("Expected %v cache misses, got %v", want, got)
+	}
+}
@@ -0,0 +1,123 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/igwen6w/syt-go-queue/internal/logger"
+	"github.com/igwen6w/syt-go-queue/internal/metrics"
+	"github.com/igwen6w/syt-go-queue/internal/task"
+	"github.com/igwen6w/syt-go-queue/internal/utils"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+// deadLetterTimeout 死信回调请求的超时时间
+const deadLetterTimeout = 10 * time.Second
+
+// DeadLetterHandler 处理永久失败的任务。
+// 它实现了 asynq.ErrorHandler 接口，在任务重试次数耗尽、即将被归档时，
+// 将失败详情发送到配置的死信回调地址，便于下游系统及时处理。
+type DeadLetterHandler struct {
+	url     string       // 死信回调地址，为空时只记录日志
+	client  *http.Client // HTTP 客户端，用于发送死信回调
+	headers http.Header  // 附加到死信回调的自定义请求头
+}
+
+// NewDeadLetterHandler 创建并返回一个新的死信处理器实例。
+//
+// 参数:
+//   - cfg: 回调配置，包含死信回调地址和自定义请求头
+//
+// 返回:
+//   - 配置好的死信处理器实例
+func NewDeadLetterHandler(cfg config.CallbackConfig) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		url: cfg.DeadLetterURL,
+		client: &http.Client{
+			Timeout:   deadLetterTimeout,
+			Transport: utils.NewCallbackTransport(),
+		},
+		headers: customHeaders("dead_letter", cfg.Headers),
+	}
+}
+
+// HandleError 在任务处理失败时被 asynq 调用。
+// 只有当任务不会再重试时，才发送死信回调。
+//
+// 参数:
+//   - ctx: 任务上下文，包含任务 ID 和重试信息
+//   - t: 失败的任务
+//   - err: 任务处理返回的错误
+func (h *DeadLetterHandler) HandleError(ctx context.Context, t *asynq.Task, err error) {
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	if !isPermanentFailure(retried, maxRetry, err) {
+		return
+	}
+
+	taskID, _ := asynq.GetTaskID(ctx)
+
+	// 解析载荷，无法解析时仍然发送死信，只是缺少记录信息
+	var p task.LLMPayload
+	_ = json.Unmarshal(t.Payload(), &p)
+
+	metrics.TaskCounter.WithLabelValues(t.Type(), taskQueue(ctx), "dead_letter").Inc()
+	logger.Error("Task permanently failed",
+		zap.String("request_id", p.RequestID),
+		zap.String("task_id", taskID),
+		zap.String("task_type", t.Type()),
+		zap.String("table_name", p.TableName),
+		zap.Int64("record_id", p.ID),
+		zap.Int("retried", retried),
+		zap.Int("max_retry", maxRetry),
+		zap.Error(err))
+
+	if h.url == "" {
+		return
+	}
+
+	if err := h.send(ctx, taskID, t.Type(), p, retried, maxRetry, err); err != nil {
+		logger.Warn("Dead letter callback failed",
+			zap.String("request_id", p.RequestID),
+			zap.String("task_id", taskID),
+			zap.String("dead_letter_url", h.url),
+			zap.Error(err))
+	}
+}
+
+// send 将失败详情发送到死信回调地址。
+// 任务上下文可能已经取消或超时，因此使用独立的超时上下文。
+func (h *DeadLetterHandler) send(ctx context.Context, taskID, taskType string, p task.LLMPayload, retried, maxRetry int, taskErr error) error {
+	// 验证回调URL是否安全
+	if err := utils.ValidateCallbackURL(h.url); err != nil {
+		return errors.Wrap(err, "dead letter URL validation failed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), deadLetterTimeout)
+	defer cancel()
+
+	payload := map[string]interface{}{
+		"status":     "failed",
+		"task_id":    taskID,
+		"task_type":  taskType,
+		"table_name": p.TableName,
+		"record_id":  p.ID,
+		"request_id": p.RequestID,
+		"error":      taskErr.Error(),
+		"retried":    retried,
+		"max_retry":  maxRetry,
+		"timestamp":  time.Now().Unix(),
+	}
+
+	return postJSON(ctx, h.client, h.url, payload, h.headers)
+}
+
+// isPermanentFailure 判断任务失败后是否不会再重试。
+// 与 asynq 的归档条件保持一致：重试次数耗尽或错误包含 asynq.SkipRetry。
+func isPermanentFailure(retried, maxRetry int, err error) bool {
+	return retried >= maxRetry || errors.Is(err, asynq.SkipRetry)
+}
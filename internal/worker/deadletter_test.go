@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"fmt"
+	"github.com/hibiken/asynq"
+	"github.com/pkg/errors"
+	"testing"
+)
+
+func TestIsPermanentFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		retried  int
+		maxRetry int
+		err      error
+		want     bool
+	}{
+		{
+			name:     "retries remaining",
+			retried:  1,
+			maxRetry: 3,
+			err:      errors.New("llm error"),
+			want:     false,
+		},
+		{
+			name:     "retries exhausted",
+			retried:  3,
+			maxRetry: 3,
+			err:      errors.New("llm error"),
+			want:     true,
+		},
+		{
+			name:     "skip retry",
+			retried:  0,
+			maxRetry: 3,
+			err:      fmt.Errorf("invalid payload: %w", asynq.SkipRetry),
+			want:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPermanentFailure(tt.retried, tt.maxRetry, tt.err); got != tt.want {
+				t.Errorf("isPermanentFailure() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/igwen6w/syt-go-queue/internal/database"
 	"github.com/igwen6w/syt-go-queue/internal/logger"
 	"github.com/igwen6w/syt-go-queue/internal/metrics"
+	"github.com/igwen6w/syt-go-queue/internal/resultstore"
 	"github.com/igwen6w/syt-go-queue/internal/task"
 	"github.com/igwen6w/syt-go-queue/internal/utils"
 	"github.com/pkg/errors"
@@ -18,36 +19,121 @@ import (
 	"go.uber.org/zap"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
-// 状态常量
-const (
-	StatusProcessing = "处理中" // 处理中
-	StatusCompleted  = "已完成" // 已完成
-	StatusFailed     = "失败"  // 失败
-)
+// defaultMaxResponseBytes 未配置时 LLM API 响应体的最大字节数
+const defaultMaxResponseBytes = 4 << 20
+
+// errResponseTooLarge 响应体超过限制
+var errResponseTooLarge = errors.New("response body too large")
+
+// maxRetryAfter 限流响应 Retry-After 的上限，避免异常的响应头让断路器长时间保持打开
+const maxRetryAfter = 10 * time.Minute
+
+// rateLimitedError LLM API 返回 429 时的错误，携带响应头 Retry-After 要求的等待时间
+type rateLimitedError struct {
+	retryAfter time.Duration // 没有或无法解析 Retry-After 时为 0
+	body       string
+}
+
+func (e *rateLimitedError) Error() string {
+	return fmt.Sprintf("LLM API request failed with status: %d, body: %s", http.StatusTooManyRequests, e.body)
+}
+
+// Is 使 errors.Is(err, ErrRateLimited) 匹配限流错误
+func (e *rateLimitedError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// parseRetryAfter 解析 Retry-After 响应头，支持秒数和 HTTP 日期两种格式，
+// 结果不超过 maxRetryAfter，无法解析时返回 0
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	var d time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		d = time.Duration(seconds) * time.Second
+	} else if t, err := http.ParseTime(value); err == nil {
+		d = t.Sub(now)
+	}
+
+	if d <= 0 {
+		return 0
+	}
+	if d > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return d
+}
 
 // TaskHandler 处理异步任务的组件。
 // 它封装了处理不同类型任务的逻辑，如 LLM 请求处理。
 type TaskHandler struct {
-	db             *database.Database             // 数据库访问实例
-	deepseek       config.DeepseekConfig          // Deepseek LLM API 配置
-	client         *http.Client                   // HTTP 客户端，用于调用外部 API
-	circuitBreaker *circuitbreaker.CircuitBreaker // 断路器，用于保护外部调用
+	db              *database.Database             // 数据库访问实例
+	deepseek        config.DeepseekConfig          // Deepseek LLM API 配置
+	queue           config.QueueConfig             // 队列配置
+	client          *http.Client                   // HTTP 客户端，用于调用外部 API
+	callbackClient  *http.Client                   // HTTP 客户端，用于发送回调，拒绝连接内网地址
+	circuitBreaker  *circuitbreaker.CircuitBreaker // 断路器，用于保护外部调用
+	llmHeaders      http.Header                    // 附加到 LLM 请求的自定义请求头
+	callbackHeaders http.Header                    // 附加到回调请求的自定义请求头
+	llmSem          chan struct{}                  // 限制同时进行的 LLM 调用数，为 nil 时不限制
+	promptCache     *promptCache                   // 相同请求体的回复缓存，为 nil 时不缓存
+	callbackVerbose bool                           // 回调是否包含记录、任务和 token 用量等完整信息
+	// 回调任务的入队客户端，不为 nil 时回调作为 callback:send 任务入队发送，否则直接发送
+	callbackQueue    taskEnqueuer
+	callbackMaxRetry int                     // 回调任务的最大重试次数
+	callbackTimeout  time.Duration           // 单次回调请求的超时时间
+	results          resultstore.ResultStore // 报告存储，默认直接写入 report 列
+	// 工作者关闭的宽限期结束时取消，用于中止进行中的 LLM 调用，为 nil 时不支持中止
+	shutdownCtx    context.Context
+	cancelInFlight context.CancelFunc
+	inFlight       atomic.Int64 // 进行中的 LLM 调用数
+}
+
+// llmCircuitBreakerConfig 将配置文件中的断路器配置转换为 LLM API 断路器的配置
+func llmCircuitBreakerConfig(cfg config.CircuitBreakerConfig) circuitbreaker.CircuitBreakerConfig {
+	return circuitbreaker.CircuitBreakerConfig{
+		Name:          "llm-api",
+		MaxRequests:   uint32(cfg.MaxRequests),
+		Interval:      cfg.Interval,
+		Timeout:       cfg.Timeout,
+		FailThreshold: cfg.FailThreshold,
+	}
 }
 
 // NewTaskHandler 创建并返回一个新的任务处理器实例。
 //
 // 参数:
 //   - db: 数据库访问实例
-//   - cfg: Deepseek LLM API 的配置
+//   - appCfg: 应用程序配置，包含 Deepseek LLM API、队列和回调设置
 //
 // 返回:
 //   - 配置好的任务处理器实例
-func NewTaskHandler(db *database.Database, cfg config.DeepseekConfig) *TaskHandler {
-	// 创建 HTTP 客户端
-	client := &http.Client{Timeout: cfg.Timeout}
+func NewTaskHandler(db *database.Database, appCfg *config.Config) *TaskHandler {
+	cfg := appCfg.Deepseek
+
+	// 创建 HTTP 客户端，按配置调整连接池以复用到 LLM 服务的连接
+	client := &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: newLLMTransport(cfg.Transport),
+	}
+	callbackTimeout := appCfg.Callback.Timeout
+	if callbackTimeout <= 0 {
+		callbackTimeout = cfg.Timeout
+	}
+	callbackClient := &http.Client{
+		Timeout:   callbackTimeout,
+		Transport: utils.NewCallbackTransport(),
+	}
 
 	// 创建断路器
 	var cb *circuitbreaker.CircuitBreaker
@@ -57,63 +143,318 @@ func NewTaskHandler(db *database.Database, cfg config.DeepseekConfig) *TaskHandl
 			zap.Duration("timeout", cfg.CircuitBreaker.Timeout),
 			zap.Int("max_requests", cfg.CircuitBreaker.MaxRequests))
 
-		cb = circuitbreaker.NewCircuitBreaker(circuitbreaker.CircuitBreakerConfig{
-			Name:          "llm-api",
-			MaxRequests:   uint32(cfg.CircuitBreaker.MaxRequests),
-			Interval:      cfg.CircuitBreaker.Interval,
-			Timeout:       cfg.CircuitBreaker.Timeout,
-			FailThreshold: cfg.CircuitBreaker.FailThreshold,
-		})
+		cb = circuitbreaker.NewCircuitBreaker(llmCircuitBreakerConfig(cfg.CircuitBreaker))
 	} else {
 		logger.Warn("Circuit breaker is disabled for LLM API")
 		// 使用默认断路器
 		cb = circuitbreaker.DefaultLLMCircuitBreaker()
 	}
 
+	// 创建 LLM 调用信号量，与工作者并发数相互独立
+	var llmSem chan struct{}
+	if cfg.MaxConcurrency > 0 {
+		llmSem = make(chan struct{}, cfg.MaxConcurrency)
+	}
+
+	shutdownCtx, cancelInFlight := context.WithCancel(context.Background())
+
 	return &TaskHandler{
-		db:             db,
-		deepseek:       cfg,
-		client:         client,
-		circuitBreaker: cb,
+		db:               db,
+		deepseek:         cfg,
+		queue:            appCfg.Queue,
+		client:           client,
+		callbackClient:   callbackClient,
+		circuitBreaker:   cb,
+		llmHeaders:       customHeaders("llm", cfg.Headers),
+		callbackHeaders:  customHeaders("callback", appCfg.Callback.Headers),
+		llmSem:           llmSem,
+		promptCache:      newPromptCache(cfg.PromptCache),
+		callbackVerbose:  appCfg.Callback.Verbose,
+		callbackMaxRetry: callbackMaxRetry(appCfg),
+		callbackTimeout:  callbackTimeout,
+		results:          resultstore.New(appCfg.ResultStore),
+		shutdownCtx:      shutdownCtx,
+		cancelInFlight:   cancelInFlight,
+	}
+}
+
+// callbackMaxRetry 返回回调任务的最大重试次数，未配置时使用队列的重试次数
+func callbackMaxRetry(cfg *config.Config) int {
+	if cfg.Callback.MaxRetry > 0 {
+		return cfg.Callback.MaxRetry
+	}
+	return cfg.Queue.Retry
+}
+
+// llmContext 返回 LLM 调用使用的上下文，调用 CancelInFlight 时会被取消。
+// 返回的函数在调用结束后释放资源
+func (h *TaskHandler) llmContext(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	if h.shutdownCtx == nil {
+		return ctx, cancel
+	}
+
+	h.inFlight.Add(1)
+	stop := context.AfterFunc(h.shutdownCtx, cancel)
+	if h.shutdownCtx.Err() != nil {
+		// AfterFunc 在另一个协程中执行，已经取消时立即生效，避免开始新的调用
+		cancel()
+	}
+	return ctx, func() {
+		stop()
+		cancel()
+		h.inFlight.Add(-1)
+	}
+}
+
+// CancelInFlight 取消所有进行中和之后开始的 LLM 调用，返回被取消的进行中调用数。
+// 被取消的任务恢复记录状态后返回错误，由 asynq 重试
+func (h *TaskHandler) CancelInFlight() int {
+	if h.cancelInFlight == nil {
+		return 0
+	}
+	n := h.inFlight.Load()
+	h.cancelInFlight()
+	return int(n)
+}
+
+// newLLMTransport 基于 http.DefaultTransport 创建 LLM 请求使用的传输层，
+// 配置中为 0 的字段保留默认值。
+func newLLMTransport(cfg config.TransportConfig) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+		// 总空闲连接数不能小于单个主机的空闲连接数
+		if transport.MaxIdleConns < cfg.MaxIdleConnsPerHost {
+			transport.MaxIdleConns = cfg.MaxIdleConnsPerHost
+		}
+	}
+	if cfg.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+	if cfg.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = cfg.TLSHandshakeTimeout
 	}
+	return transport
+}
+
+// llmUsage 是 LLM 响应中的 token 用量
+type llmUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// llmResult 是一次 LLM 调用的结果
+type llmResult struct {
+	Content string    // 第一条回复的内容
+	Usage   *llmUsage // token 用量，响应中没有时为 nil
+	Model   string    // 请求使用的模型，调用备用 LLM 时为备用模型
+	Cached  bool      // 回复来自提示词缓存，没有调用 API，Usage 为生成该回复时的用量
 }
 
 // sendCallback 发送回调请求到指定的 URL。
 // 该方法将处理结果作为 JSON 发送到回调 URL。
+// 默认只包含 result、status 和 timestamp；配置 callback.verbose 时
+// 额外包含 table_name、record_id、task_id 和 LLM 的 token 用量。
 //
 // 参数:
 //   - ctx: 上下文，用于请求的生命周期管理
 //   - callbackURL: 要发送回调的 URL
+//   - p: 任务载荷，提供记录所在的表和ID
 //   - result: 要发送的处理结果
 //
 // 返回:
 //   - 如果回调请求失败，返回错误
-func (h *TaskHandler) sendCallback(ctx context.Context, callbackURL string, result string) error {
+func (h *TaskHandler) sendCallback(ctx context.Context, callbackURL string, p task.LLMPayload, result llmResult) error {
 	// 验证回调URL是否安全
 	if err := utils.ValidateCallbackURL(callbackURL); err != nil {
 		return errors.Wrap(err, "callback URL validation failed")
 	}
 
+	payload := h.callbackPayload(ctx, p, result)
+
+	ctx, cancel := h.callbackContext(ctx)
+	defer cancel()
+	return h.deliverCallback(ctx, callbackURL, payload)
+}
+
+// callbackContext 返回发送回调使用的上下文。
+// 长时间的 LLM 调用可能已经耗尽任务的截止时间，因此回调使用从任务上下文分离的新上下文，
+// 保留请求ID等值，超时时间为 callback.timeout，关闭时调用 CancelInFlight 仍会取消回调。
+// 返回的函数在回调结束后释放资源
+func (h *TaskHandler) callbackContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = context.WithoutCancel(ctx)
+	var cancel context.CancelFunc
+	if h.callbackTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, h.callbackTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	if h.shutdownCtx == nil {
+		return ctx, cancel
+	}
+
+	stop := context.AfterFunc(h.shutdownCtx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// deliverCallback 发送回调载荷。配置了 callback.async 时将回调作为 callback:send 任务入队，
+// 由 HandleCallbackTask 发送并在失败时重试；否则直接发送
+func (h *TaskHandler) deliverCallback(ctx context.Context, callbackURL string, payload map[string]interface{}) error {
+	if h.callbackQueue == nil {
+		return postJSON(ctx, h.callbackClient, callbackURL, payload, h.callbackHeaders)
+	}
+
+	t, err := task.NewCallbackTask(callbackURL, payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to create callback task")
+	}
+	info, err := h.callbackQueue.Enqueue(t, asynq.Queue(h.queue.QueueFor(task.TypeCallback)), asynq.MaxRetry(h.callbackMaxRetry))
+	if err != nil {
+		return errors.Wrap(err, "failed to enqueue callback task")
+	}
+	logger.Debug("Callback task enqueued",
+		logger.RequestIDField(ctx),
+		zap.String("task_id", info.ID),
+		zap.String("callback_url", callbackURL))
+	return nil
+}
+
+// HandleCallbackTask 处理 callback:send 任务，将载荷中的请求体 POST 到回调地址。
+// 回调地址未通过安全校验时不再重试，其他失败返回错误，由 asynq 按重试策略重新发送
+//
+// 参数:
+//   - ctx: 上下文，用于请求的生命周期管理
+//   - t: 回调任务，包含回调地址和请求体
+//
+// 返回:
+//   - 如果回调发送失败，返回错误
+func (h *TaskHandler) HandleCallbackTask(ctx context.Context, t *asynq.Task) error {
+	queue := taskQueue(ctx)
+
+	var p task.CallbackPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		metrics.TaskCounter.WithLabelValues(task.TypeCallback, queue, "unmarshal_error").Inc()
+		return errors.Wrap(asynq.SkipRetry, "failed to unmarshal callback payload: "+err.Error())
+	}
+
+	// 入队后域名解析结果可能变化，发送前再次校验
+	if err := utils.ValidateCallbackURL(p.URL); err != nil {
+		metrics.TaskCounter.WithLabelValues(task.TypeCallback, queue, "invalid_url").Inc()
+		return errors.Wrap(asynq.SkipRetry, "callback URL validation failed: "+err.Error())
+	}
+
+	if err := postJSON(ctx, h.callbackClient, p.URL, p.Body, h.callbackHeaders); err != nil {
+		metrics.TaskCounter.WithLabelValues(task.TypeCallback, queue, "error").Inc()
+		retried, _ := asynq.GetRetryCount(ctx)
+		logger.Warn("Callback delivery failed",
+			zap.String("callback_url", p.URL),
+			zap.Int("retried", retried),
+			zap.Error(err))
+		return err
+	}
+
+	metrics.TaskCounter.WithLabelValues(task.TypeCallback, queue, "success").Inc()
+	return nil
+}
+
+// callbackPayload 构建结果回调的载荷
+func (h *TaskHandler) callbackPayload(ctx context.Context, p task.LLMPayload, result llmResult) map[string]interface{} {
 	payload := map[string]interface{}{
-		"result":    result,
+		"result":    result.Content,
 		"status":    "success",
 		"timestamp": time.Now().Unix(),
 	}
 
+	if h.callbackVerbose {
+		taskID, _ := asynq.GetTaskID(ctx)
+		payload["table_name"] = p.TableName
+		payload["record_id"] = p.ID
+		payload["task_id"] = taskID
+		if result.Usage != nil {
+			payload["usage"] = result.Usage
+		}
+		if result.Cached {
+			payload["cached"] = true
+		}
+	}
+
+	return payload
+}
+
+// sendFailureCallback 在任务最后一次尝试失败后通知记录的回调地址，
+// 载荷的 status 为 failed，error 为失败原因。
+// 任务上下文可能已经超时，因此与结果回调一样使用 callbackContext 返回的上下文
+func (h *TaskHandler) sendFailureCallback(ctx context.Context, callbackURL string, p task.LLMPayload, taskErr error) error {
+	if err := utils.ValidateCallbackURL(callbackURL); err != nil {
+		return errors.Wrap(err, "callback URL validation failed")
+	}
+
+	payload := h.failureCallbackPayload(ctx, p, taskErr)
+
+	ctx, cancel := h.callbackContext(ctx)
+	defer cancel()
+	return h.deliverCallback(ctx, callbackURL, payload)
+}
+
+// failureCallbackPayload 构建失败回调的载荷，verbose 模式下额外包含记录、任务和重试次数
+func (h *TaskHandler) failureCallbackPayload(ctx context.Context, p task.LLMPayload, taskErr error) map[string]interface{} {
+	payload := map[string]interface{}{
+		"error":     taskErr.Error(),
+		"status":    "failed",
+		"timestamp": time.Now().Unix(),
+	}
+
+	if h.callbackVerbose {
+		taskID, _ := asynq.GetTaskID(ctx)
+		retried, _ := asynq.GetRetryCount(ctx)
+		maxRetry, _ := asynq.GetMaxRetry(ctx)
+		payload["table_name"] = p.TableName
+		payload["record_id"] = p.ID
+		payload["task_id"] = taskID
+		payload["retried"] = retried
+		payload["max_retry"] = maxRetry
+	}
+
+	return payload
+}
+
+// isFinalAttempt 判断本次失败后任务是否不会再重试。
+// 上下文中没有 asynq 的重试信息时（如直接调用处理器）返回 false
+func isFinalAttempt(ctx context.Context, err error) bool {
+	retried, ok := asynq.GetRetryCount(ctx)
+	if !ok {
+		return false
+	}
+	maxRetry, ok := asynq.GetMaxRetry(ctx)
+	if !ok {
+		return false
+	}
+	return isPermanentFailure(retried, maxRetry, err)
+}
+
+// postJSON 将载荷以 JSON 格式 POST 到指定 URL，
+// 附加自定义请求头，响应状态码不是 200 时返回错误。
+func postJSON(ctx context.Context, client *http.Client, targetURL string, payload interface{}, headers http.Header) error {
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return errors.Wrap(err, "failed to marshal callback payload")
 	}
 
 	// 使用传入的上下文创建请求
-	req, err := http.NewRequestWithContext(ctx, "POST", callbackURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", targetURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return errors.Wrap(err, "failed to create callback request")
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	applyHeaders(req, headers)
 
-	resp, err := h.client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return errors.Wrap(err, "failed to send callback request")
 	}
@@ -135,74 +476,271 @@ func (h *TaskHandler) sendCallback(ctx context.Context, callbackURL string, resu
 //
 // 返回:
 //   - 如果任务处理失败，返回错误
-func (h *TaskHandler) HandleLLMTask(ctx context.Context, t *asynq.Task) error {
+//
+// 处理过程中发生 panic 时，已认领的记录标记为失败并写入 panic 信息，
+// 返回的错误使任务按重试策略重试。认领记录后其他原因导致返回错误时，
+// 记录恢复为认领前的状态，重试时可以重新认领
+func (h *TaskHandler) HandleLLMTask(ctx context.Context, t *asynq.Task) (err error) {
 	// 开始计时并记录指标
-	defer metrics.MeasureTaskDuration(task.TypeLLM)()
+	start := time.Now()
+	queue := taskQueue(ctx)
+	defer metrics.MeasureTaskDuration(task.TypeLLM, queue)()
 
 	var p task.LLMPayload
+	// 已认领、结果尚未写入的记录，panic 时需要标记为失败，返回错误时需要释放认领
+	var pending *database.ValuationRecord
+	defer func() {
+		if r := recover(); r != nil {
+			err = h.recoverLLMTask(ctx, queue, p, pending, r)
+			return
+		}
+		if err != nil && pending != nil {
+			h.releaseClaim(ctx, p, pending)
+		}
+	}()
+
 	if err := json.Unmarshal(t.Payload(), &p); err != nil {
 		// 记录解析失败指标
-		metrics.TaskCounter.WithLabelValues(task.TypeLLM, "unmarshal_error").Inc()
-		return errors.Wrap(err, "failed to unmarshal payload")
+		metrics.TaskCounter.WithLabelValues(task.TypeLLM, queue, "unmarshal_error").Inc()
+		return errors.Wrap(asynq.SkipRetry, "failed to unmarshal payload: "+err.Error())
 	}
 
-	// 获取任务记录
-	record, err := h.db.GetValuationRecord(ctx, p.TableName, p.ID)
+	// 将请求ID存入上下文，关联 API 和 worker 日志
+	ctx = logger.WithRequestID(ctx, p.RequestID)
+
+	// 任务级模型不在 allowed_models 中时重试也不会成功，直接归档
+	if p.Model != "" && !h.deepseek.ModelAllowed(p.Model) {
+		metrics.TaskCounter.WithLabelValues(task.TypeLLM, queue, "validation_error").Inc()
+		return errors.Wrapf(asynq.SkipRetry, "model %s is not in allowed_models", p.Model)
+	}
+
+	// 记录首次处理前在队列中的等待时间，重试的等待包含重试延迟，不计入
+	observeTaskWait(ctx, queue, p)
+
+	// 限制任务的总处理时间，LLM 调用自身的超时由 deepseek.timeout 控制
+	if h.queue.TaskTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.queue.TaskTimeout)
+		defer cancel()
+	}
+
+	// 认领任务记录，并将状态更新为处理中。
+	// 认领在独立事务中完成并立即提交，LLM 调用期间不持有行锁，
+	// 这样进度心跳可以写入记录，外部也能看到处理中状态。
+	// 未开启 reprocess_completed 时，已完成的记录不再认领
+	statuses := h.queue.RecordStatuses()
+	completedStatus := statuses.Completed
+	if h.queue.ReprocessCompleted {
+		completedStatus = ""
+	}
+	record, err := h.db.ClaimRecord(ctx, p.TableName, p.ID, statuses.Processing, completedStatus)
 	if err != nil {
+		// 记录已处理完成（重复或重试的任务），确认任务并跳过，避免重复调用 LLM
+		if errors.Is(err, database.ErrRecordCompleted) {
+			metrics.TaskCounter.WithLabelValues(task.TypeLLM, queue, "skipped_completed").Inc()
+			logger.Info("Record already completed, skipping",
+				logger.RequestIDField(ctx),
+				zap.Int64("record_id", p.ID),
+				zap.String("table_name", p.TableName))
+			return nil
+		}
+		// 记录已被其他工作者认领，确认任务并跳过，避免重复调用 LLM
+		if errors.Is(err, database.ErrRecordAlreadyClaimed) {
+			metrics.TaskCounter.WithLabelValues(task.TypeLLM, queue, "skipped_claimed").Inc()
+			logger.Info("Record already claimed by another worker, skipping",
+				logger.RequestIDField(ctx),
+				zap.Int64("record_id", p.ID),
+				zap.String("table_name", p.TableName))
+			return nil
+		}
+		// 表名不合法时重试也不会成功，直接归档
+		if errors.Is(err, database.ErrInvalidTableName) {
+			metrics.TaskCounter.WithLabelValues(task.TypeLLM, queue, "validation_error").Inc()
+			return errors.Wrap(asynq.SkipRetry, err.Error())
+		}
 		// 记录获取记录失败指标
-		metrics.TaskCounter.WithLabelValues(task.TypeLLM, "db_error").Inc()
-		return errors.Wrap(err, "failed to get valuation record")
+		metrics.TaskCounter.WithLabelValues(task.TypeLLM, queue, "db_error").Inc()
+		return errors.Wrap(err, "failed to claim valuation record")
 	}
+	pending = record
 
-	// 更新状态为处理中
-	if err := h.db.UpdateStatus(ctx, p.TableName, p.ID, StatusProcessing); err != nil {
-		// 记录更新状态失败指标
-		metrics.TaskCounter.WithLabelValues(task.TypeLLM, "update_error").Inc()
-		return errors.Wrap(err, "failed to update status")
+	// 失败次数已达到 max_failed_times 的记录不再调用 LLM，恢复失败状态并归档任务
+	if h.reachedMaxFailedTimes(record.FailedTimes) {
+		metrics.TaskCounter.WithLabelValues(task.TypeLLM, queue, "poison_record").Inc()
+		logger.Warn("Record reached max_failed_times, giving up",
+			logger.RequestIDField(ctx),
+			zap.Int64("record_id", p.ID),
+			zap.String("table_name", p.TableName),
+			zap.Int("failed_times", record.FailedTimes),
+			zap.Int("max_failed_times", h.queue.MaxFailedTimes))
+		if err := h.db.UpdateStatus(ctx, p.TableName, p.ID, h.failureStatus(record)); err != nil {
+			return errors.Wrap(err, "failed to mark record as failed")
+		}
+		pending = nil
+		return errors.Wrapf(asynq.SkipRetry, "record failed %d times, reaching max_failed_times %d",
+			record.FailedTimes, h.queue.MaxFailedTimes)
 	}
 
-	// 调用 LLM API
-	result, err := h.processLLM(ctx, record)
-	if err != nil {
-		// 记录LLM处理失败指标
-		metrics.TaskCounter.WithLabelValues(task.TypeLLM, "llm_error").Inc()
+	// 调用 LLM API，期间定期写入进度。工作者关闭的宽限期结束时调用会被取消
+	llmCtx, stopLLM := h.llmContext(ctx)
+	defer stopLLM()
+	stopHeartbeat := h.startHeartbeat(llmCtx, p, "calling LLM")
+	result, llmErr := h.processLLM(llmCtx, record, p)
+	stopHeartbeat()
+
+	// 配置了 response_json_path 时从回复中取出指定字段，回复不符合要求时按失败处理
+	if llmErr == nil && h.deepseek.ResponseJSONPath != "" {
+		result.Content, llmErr = extractJSONPath(result.Content, h.deepseek.ResponseJSONPath)
+	}
+
+	if llmErr != nil && isCancelled(llmCtx) {
+		// 任务被取消（如工作者关闭），不计为失败，返回后恢复认领前的状态以便重试
+		metrics.TaskCounter.WithLabelValues(task.TypeLLM, queue, "cancelled").Inc()
+		logger.Info("Task cancelled, record left for retry",
+			logger.RequestIDField(ctx),
+			zap.Int64("record_id", p.ID),
+			zap.String("table_name", p.TableName))
+		return errors.Wrap(llmCtx.Err(), "task cancelled")
+	}
+
+	// 任务总时间已用完时，使用不带截止时间的上下文写入失败信息，避免记录停留在处理中
+	writeCtx := ctx
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		writeCtx = context.WithoutCancel(ctx)
+	}
+
+	// 事务开始前保存报告，配置了对象存储时 report 列只写入对象地址。
+	// 对象键包含本次处理后的 current_task_node，重新处理不会覆盖之前的报告
+	report := ""
+	if llmErr == nil {
+		key := fmt.Sprintf("%s/%d/%d.txt", p.TableName, p.ID, record.CurrentTaskNode+1)
+		if report, llmErr = h.results.Save(writeCtx, key, result.Content); llmErr != nil {
+			llmErr = classify(ErrTransient, errors.Wrap(llmErr, "failed to store report"))
+		}
+	}
 
-		// 更新失败信息
-		failedTimes := record.FailedTimes + 1
+	// 在单个事务中写入处理结果，避免部分字段写入成功
+	var staleErr error
+	err = h.db.WithTx(writeCtx, func(tx *database.Database) error {
+		if llmErr != nil {
+			// 记录LLM处理失败指标
+			metrics.TaskCounter.WithLabelValues(task.TypeLLM, queue, "llm_error").Inc()
 
-		// 更新状态和失败信息
+			// 更新状态和失败信息，报告列不写入，已有的报告保持不变
+			updates := map[string]interface{}{
+				"status":       h.failureStatus(record),
+				"failed_times": record.FailedTimes + 1,
+				"failed_info":  llmErr.Error(),
+			}
+			if updateErr := tx.UpdateRecord(writeCtx, p.TableName, p.ID, updates); updateErr != nil {
+				return errors.Wrap(updateErr, "failed to update failure information")
+			}
+
+			// 提交失败信息，LLM 错误在事务结束后返回
+			return nil
+		}
+
+		// 更新处理结果
 		updates := map[string]interface{}{
-			"status":       StatusFailed,
-			"failed_times": failedTimes,
-			"failed_info":  err.Error(),
+			"status":            statuses.Completed,
+			"report":            report,
+			"current_task_node": record.CurrentTaskNode + 1,
 		}
-		if updateErr := h.db.UpdateRecord(ctx, p.TableName, p.ID, updates); updateErr != nil {
-			return errors.Wrap(updateErr, "failed to update failure information")
+		// 以认领时读取的 current_task_node 作为乐观锁条件，避免覆盖 LLM 调用期间的并发修改
+		conditions := map[string]interface{}{
+			"current_task_node": record.CurrentTaskNode,
 		}
+		rows, err := tx.UpdateRecordIf(writeCtx, p.TableName, p.ID, conditions, updates)
+		if err != nil {
+			// 记录更新结果失败指标
+			metrics.TaskCounter.WithLabelValues(task.TypeLLM, queue, "update_result_error").Inc()
+			return errors.Wrap(err, "failed to update record")
+		}
+
+		if rows == 0 {
+			// 记录已被并发修改，丢弃本次结果
+			metrics.TaskCounter.WithLabelValues(task.TypeLLM, queue, "stale_record").Inc()
+			staleErr = errors.Wrapf(database.ErrStaleRecord, "current_task_node is no longer %d", record.CurrentTaskNode)
 
-		return errors.Wrap(err, "failed to process LLM")
+			// 释放认领，以并发写入后的 current_task_node 为条件，只恢复状态，保留并发写入的其他值
+			current, err := tx.GetValuationRecord(writeCtx, p.TableName, p.ID)
+			if err != nil {
+				return errors.Wrap(err, "failed to read modified record")
+			}
+			conditions := map[string]interface{}{
+				"status":            statuses.Processing,
+				"current_task_node": current.CurrentTaskNode,
+			}
+			updates := map[string]interface{}{
+				"status": record.Status,
+			}
+			if _, err := tx.UpdateRecordIf(writeCtx, p.TableName, p.ID, conditions, updates); err != nil {
+				return errors.Wrap(err, "failed to release modified record")
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	pending = nil
 
-	// 更新处理结果
-	updates := map[string]interface{}{
-		"status":            StatusCompleted,
-		"report":            result,
-		"current_task_node": record.CurrentTaskNode + 1,
+	if llmErr != nil {
+		// 本次失败使失败次数达到 max_failed_times 时不再重试，任务直接归档
+		if h.reachedMaxFailedTimes(record.FailedTimes + 1) {
+			metrics.TaskCounter.WithLabelValues(task.TypeLLM, queue, "poison_record").Inc()
+			llmErr = errors.Wrapf(asynq.SkipRetry, "%s, record reached max_failed_times %d",
+				llmErr.Error(), h.queue.MaxFailedTimes)
+		}
+
+		// final_attempt 区分重试耗尽的失败和之后还会重试的失败，告警只需关注前者
+		final := isFinalAttempt(ctx, llmErr)
+		logger.Warn("Task failed",
+			append(taskLogFields(ctx, p, h.taskModel(p), start, nil),
+				zap.Bool("final_attempt", final),
+				zap.Error(llmErr))...)
+
+		if final {
+			metrics.TaskCounter.WithLabelValues(task.TypeLLM, queue, "retries_exhausted").Inc()
+		}
+
+		// 最后一次尝试失败时通知记录的回调地址，之后还会重试的失败不通知
+		if final && record.CallbackURL != "" {
+			if err := h.sendFailureCallback(ctx, record.CallbackURL, p, llmErr); err != nil {
+				logger.Warn("Failure callback failed",
+					logger.RequestIDField(ctx),
+					zap.String("callback_url", record.CallbackURL),
+					zap.Int64("record_id", record.ID),
+					zap.String("table_name", p.TableName),
+					zap.Error(err))
+			}
+		}
+		return errors.Wrap(llmErr, "failed to process LLM")
 	}
-	if err := h.db.UpdateRecord(ctx, p.TableName, p.ID, updates); err != nil {
-		// 记录更新结果失败指标
-		metrics.TaskCounter.WithLabelValues(task.TypeLLM, "update_result_error").Inc()
-		return errors.Wrap(err, "failed to update record")
+
+	if staleErr != nil {
+		logger.Warn("Record modified during processing, result discarded",
+			logger.RequestIDField(ctx),
+			zap.Int64("record_id", p.ID),
+			zap.String("table_name", p.TableName),
+			zap.Int("current_task_node", record.CurrentTaskNode))
+		// 记录已由其他写入者更新，本次任务的结果已经过时，不再重试，直接归档
+		return classify(asynq.SkipRetry, staleErr)
 	}
 
 	// 记录任务成功指标
-	metrics.TaskCounter.WithLabelValues(task.TypeLLM, "success").Inc()
+	metrics.TaskCounter.WithLabelValues(task.TypeLLM, queue, "success").Inc()
+
+	// 报告写入任务结果，查询任务状态时无需访问数据库
+	h.writeTaskResult(ctx, t, result.Content)
 
 	// 如果有回调URL，发送回调请求
 	if record.CallbackURL != "" {
-		if err := h.sendCallback(ctx, record.CallbackURL, result); err != nil {
+		if err := h.sendCallback(ctx, record.CallbackURL, p, result); err != nil {
 			// 回调失败不应该影响任务完成，只记录错误
 			logger.Warn("Callback failed",
+				logger.RequestIDField(ctx),
 				zap.String("callback_url", record.CallbackURL),
 				zap.Int64("record_id", record.ID),
 				zap.String("table_name", p.TableName),
@@ -210,9 +748,85 @@ func (h *TaskHandler) HandleLLMTask(ctx context.Context, t *asynq.Task) error {
 		}
 	}
 
+	logger.Info("Task completed",
+		append(taskLogFields(ctx, p, result.Model, start, result.Usage),
+			zap.Bool("cached", result.Cached))...)
 	return nil
 }
 
+// reachedMaxFailedTimes 判断失败次数是否已达到 max_failed_times，未配置上限时始终返回 false
+func (h *TaskHandler) reachedMaxFailedTimes(failedTimes int) bool {
+	return h.queue.MaxFailedTimes > 0 && failedTimes >= h.queue.MaxFailedTimes
+}
+
+// failureStatus 返回 LLM 调用失败时记录的状态。
+// 开启 keep_stale_report 且记录已有报告时返回已过期状态，调用方仍可使用之前的报告
+func (h *TaskHandler) failureStatus(record *database.ValuationRecord) string {
+	if h.queue.KeepStaleReport && record.Report != "" {
+		return h.queue.RecordStatuses().Stale
+	}
+	return h.queue.RecordStatuses().Failed
+}
+
+// releaseClaim 将已认领的记录恢复为认领前的状态，使任务重试时可以重新认领。
+// 只在记录仍处于处理中时更新，不覆盖处理期间其他写入者修改的状态
+func (h *TaskHandler) releaseClaim(ctx context.Context, p task.LLMPayload, record *database.ValuationRecord) {
+	conditions := map[string]interface{}{
+		"status": h.queue.RecordStatuses().Processing,
+	}
+	updates := map[string]interface{}{
+		"status": record.Status,
+	}
+	// 任务上下文可能已经取消，使用不带取消信号的上下文恢复状态
+	if _, err := h.db.UpdateRecordIf(context.WithoutCancel(ctx), p.TableName, p.ID, conditions, updates); err != nil {
+		logger.Warn("Failed to release claimed record",
+			logger.RequestIDField(ctx),
+			zap.Int64("record_id", p.ID),
+			zap.String("table_name", p.TableName),
+			zap.Error(err))
+	}
+}
+
+// recoverLLMTask 处理 HandleLLMTask 中恢复的 panic，记录 panic 指标和调用栈。
+// 记录已认领但结果尚未写入时，将其标记为失败并写入 panic 信息，避免记录停留在处理中。
+// 返回普通错误使 asynq 按重试策略重试，本次失败使失败次数达到 max_failed_times 时不再重试
+func (h *TaskHandler) recoverLLMTask(ctx context.Context, queue string, p task.LLMPayload, record *database.ValuationRecord, recovered interface{}) error {
+	metrics.TaskCounter.WithLabelValues(task.TypeLLM, queue, "panic").Inc()
+	logger.Error("Task panicked",
+		logger.RequestIDField(ctx),
+		zap.Int64("record_id", p.ID),
+		zap.String("table_name", p.TableName),
+		zap.Any("panic", recovered),
+		zap.Stack("stack"))
+
+	panicErr := errors.Errorf("panic while processing task: %v", recovered)
+	if record == nil {
+		return panicErr
+	}
+
+	// 任务上下文可能已经取消，使用不带取消信号的上下文写入失败信息
+	updates := map[string]interface{}{
+		"status":       h.failureStatus(record),
+		"failed_times": record.FailedTimes + 1,
+		"failed_info":  panicErr.Error(),
+	}
+	if err := h.db.UpdateRecord(context.WithoutCancel(ctx), p.TableName, p.ID, updates); err != nil {
+		logger.Error("Failed to mark record as failed after panic",
+			logger.RequestIDField(ctx),
+			zap.Int64("record_id", p.ID),
+			zap.String("table_name", p.TableName),
+			zap.Error(err))
+		return panicErr
+	}
+
+	if h.reachedMaxFailedTimes(record.FailedTimes + 1) {
+		metrics.TaskCounter.WithLabelValues(task.TypeLLM, queue, "poison_record").Inc()
+		return errors.Wrapf(asynq.SkipRetry, "%s, record reached max_failed_times %d",
+			panicErr.Error(), h.queue.MaxFailedTimes)
+	}
+	return panicErr
+}
+
 // processLLM 调用 LLM API 处理记录中的消息。
 // 该方法使用记录中的系统消息和用户消息构建请求，
 // 并调用 Deepseek API 获取响应。
@@ -220,11 +834,65 @@ func (h *TaskHandler) HandleLLMTask(ctx context.Context, t *asynq.Task) error {
 // 参数:
 //   - ctx: 上下文，用于请求的生命周期管理
 //   - record: 包含要处理的消息的记录
+//   - p: 任务载荷，其中的模型参数会覆盖配置
 //
 // 返回:
 //   - 处理结果字符串
 //   - 如果处理失败，返回错误
-func (h *TaskHandler) processLLM(ctx context.Context, record *database.ValuationRecord) (string, error) {
+func (h *TaskHandler) processLLM(ctx context.Context, record *database.ValuationRecord, p task.LLMPayload) (llmResult, error) {
+	// 配置了截断方式时先截断用户消息，使提示词不超过上限
+	if trimmed, original := h.trimPrompt(record); trimmed != record {
+		logger.Info("Prompt trimmed to fit max_prompt_chars",
+			logger.RequestIDField(ctx),
+			zap.Int64("record_id", record.ID),
+			zap.String("trim_strategy", h.deepseek.TrimStrategy),
+			zap.Int("original_chars", original),
+			zap.Int("max_prompt_chars", h.deepseek.MaxPromptChars))
+		record = trimmed
+	}
+
+	// 提示词超过上限时不调用 API，重试也不会成功，直接跳过重试
+	if err := h.checkPromptSize(record); err != nil {
+		metrics.LLMAPICounter.WithLabelValues("prompt_too_large").Inc()
+		return llmResult{}, err
+	}
+
+	// 构建请求体，请求不合法时不占用调用名额，也不调用 API
+	payload := h.buildLLMRequest(record, p)
+	if err := h.validateLLMRequest(payload); err != nil {
+		metrics.LLMAPICounter.WithLabelValues("invalid_request").Inc()
+		return llmResult{}, err
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		// 请求体无法序列化时重试也不会成功
+		metrics.LLMAPICounter.WithLabelValues("marshal_error").Inc()
+		return llmResult{}, errors.Wrap(asynq.SkipRetry, "failed to marshal LLM request payload: "+err.Error())
+	}
+
+	// 相同请求体的回复已缓存时直接使用，不占用调用名额，也不调用 API
+	var cacheKey string
+	if h.promptCache != nil {
+		cacheKey = promptCacheKey(jsonData)
+		if cached, ok := h.promptCache.get(cacheKey); ok {
+			metrics.LLMCacheCounter.WithLabelValues("hit").Inc()
+			logger.Info("LLM response served from prompt cache",
+				logger.RequestIDField(ctx),
+				zap.Int64("record_id", record.ID))
+			cached.Model, _ = payload["model"].(string)
+			return cached, nil
+		}
+		metrics.LLMCacheCounter.WithLabelValues("miss").Inc()
+	}
+
+	// 获取 LLM 调用名额，等待期间任务被取消时直接返回
+	release, err := h.acquireLLMSlot(ctx)
+	if err != nil {
+		return llmResult{}, errors.Wrap(err, "failed to acquire LLM concurrency slot")
+	}
+	defer release()
+
 	// 记录LLM API调用指标并计时
 	defer metrics.MeasureLLMAPIDuration()()
 
@@ -233,99 +901,399 @@ func (h *TaskHandler) processLLM(ctx context.Context, record *database.Valuation
 	ctx, cancel := context.WithTimeout(ctx, h.deepseek.Timeout)
 	defer cancel() // 确保在函数返回前释放资源
 
-	// 构建请求体
-	payload := map[string]interface{}{
-		"model": h.deepseek.Model,
-		"messages": []map[string]string{
-			{
-				"role":    "system",
-				"content": record.SysMessage,
-			},
-			{
-				"role":    "user",
-				"content": record.UserMessage,
-			},
-		},
-		"max_tokens": h.deepseek.MaxTokens,
+	// 使用断路器执行请求
+	result, err := h.circuitBreaker.Execute(func() (interface{}, error) {
+		return h.callLLM(ctx, h.deepseek.BaseURL, h.deepseek.APIKey, jsonData)
+	})
+
+	// 处理断路器错误
+	if err != nil {
+		// 限流时按 Retry-After 主动打开断路器，在此期间不再请求主 LLM
+		var rateLimited *rateLimitedError
+		if errors.As(err, &rateLimited) && rateLimited.retryAfter > 0 {
+			h.circuitBreaker.ForceOpen(rateLimited.retryAfter)
+			metrics.CircuitBreakerForcedTrips.Inc()
+			logger.Warn("LLM API rate limited, circuit breaker forced open",
+				logger.RequestIDField(ctx),
+				zap.Int64("record_id", record.ID),
+				zap.Duration("retry_after", rateLimited.retryAfter))
+		}
+		if errors.Is(err, gobreaker.ErrOpenState) {
+			logger.Warn("Circuit breaker is open, too many failures",
+				logger.RequestIDField(ctx),
+				zap.String("record_id", fmt.Sprintf("%d", record.ID)))
+			if h.deepseek.FallbackBaseURL != "" {
+				return h.callFallbackLLM(ctx, payload)
+			}
+			return llmResult{}, errors.New("service temporarily unavailable: circuit breaker is open")
+		}
+		return llmResult{}, err
+	}
+
+	// 转换结果类型
+	content, ok := result.(llmResult)
+	if !ok {
+		return llmResult{}, errors.New("unexpected result type from LLM API")
+	}
+	content.Model, _ = payload["model"].(string)
+
+	// 只缓存主 LLM 的回复，备用模型的回复不能作为主模型的结果
+	if h.promptCache != nil {
+		h.promptCache.add(cacheKey, content)
+	}
+
+	return content, nil
+}
+
+// callFallbackLLM 在主 LLM 断路器打开时调用备用 LLM。
+// 备用调用不经过主断路器，失败不会计入主断路器的统计。
+func (h *TaskHandler) callFallbackLLM(ctx context.Context, payload map[string]interface{}) (llmResult, error) {
+	if h.deepseek.FallbackModel != "" {
+		payload["model"] = h.deepseek.FallbackModel
+		// 备用模型的 max_tokens 上限可能与主模型不同
+		if err := h.validateLLMRequest(payload); err != nil {
+			metrics.LLMFallbackCounter.WithLabelValues("invalid_request").Inc()
+			return llmResult{}, err
+		}
+	}
+
+	apiKey := h.deepseek.FallbackAPIKey
+	if apiKey == "" {
+		apiKey = h.deepseek.APIKey
 	}
 
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
-		metrics.LLMAPICounter.WithLabelValues("marshal_error").Inc()
-		return "", errors.Wrap(err, "failed to marshal LLM request payload")
+		metrics.LLMFallbackCounter.WithLabelValues("marshal_error").Inc()
+		return llmResult{}, errors.Wrap(err, "failed to marshal fallback LLM request payload")
 	}
 
-	// 使用断路器执行请求
-	result, err := h.circuitBreaker.Execute(func() (interface{}, error) {
-		// 创建请求
-		req, err := http.NewRequestWithContext(ctx, "POST", h.deepseek.BaseURL, bytes.NewBuffer(jsonData))
-		if err != nil {
-			metrics.LLMAPICounter.WithLabelValues("request_error").Inc()
-			return nil, errors.Wrap(err, "failed to create LLM API request")
-		}
+	logger.Info("Calling fallback LLM",
+		logger.RequestIDField(ctx),
+		zap.Any("model", payload["model"]))
 
-		// 设置请求头
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", "Bearer "+h.deepseek.APIKey)
+	content, err := h.callLLM(ctx, h.deepseek.FallbackBaseURL, apiKey, jsonData)
+	if err != nil {
+		metrics.LLMFallbackCounter.WithLabelValues("error").Inc()
+		return llmResult{}, errors.Wrap(err, "fallback LLM request failed")
+	}
 
-		// 发送请求
-		resp, err := h.client.Do(req)
-		if err != nil {
-			metrics.LLMAPICounter.WithLabelValues("network_error").Inc()
-			return nil, errors.Wrap(err, "failed to send LLM API request")
+	metrics.LLMFallbackCounter.WithLabelValues("success").Inc()
+	content.Model, _ = payload["model"].(string)
+	return content, nil
+}
+
+// callLLM 向指定地址发送 LLM 请求并返回第一条回复的内容和 token 用量。
+func (h *TaskHandler) callLLM(ctx context.Context, baseURL, apiKey string, jsonData []byte) (llmResult, error) {
+	// 创建请求
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		metrics.LLMAPICounter.WithLabelValues("request_error").Inc()
+		return llmResult{}, errors.Wrap(err, "failed to create LLM API request")
+	}
+
+	// 设置请求头
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	applyHeaders(req, h.llmHeaders)
+
+	// 发送请求
+	resp, err := h.client.Do(req)
+	if err != nil {
+		if isCancelled(ctx) {
+			metrics.LLMAPICounter.WithLabelValues("cancelled").Inc()
+			return llmResult{}, errors.Wrap(err, "failed to send LLM API request")
 		}
-		defer resp.Body.Close()
+		// 网络错误和超时通常很快恢复，使用较短的重试延迟
+		metrics.LLMAPICounter.WithLabelValues("network_error").Inc()
+		return llmResult{}, classify(ErrTransient, errors.Wrap(err, "failed to send LLM API request"))
+	}
+	defer resp.Body.Close()
 
-		// 检查响应状态
-		if resp.StatusCode != http.StatusOK {
+	// 检查响应状态
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusTooManyRequests {
+			metrics.LLMAPICounter.WithLabelValues("rate_limited").Inc()
+		} else {
 			metrics.LLMAPICounter.WithLabelValues("status_error").Inc()
-			bodyBytes, readErr := io.ReadAll(resp.Body)
-			if readErr != nil {
-				return nil, errors.Wrap(readErr, "failed to read error response body")
+		}
+		// 错误响应只读取限制内的部分，超出部分截断
+		bodyBytes, readErr := io.ReadAll(io.LimitReader(resp.Body, h.maxResponseBytes()))
+		if readErr != nil {
+			return llmResult{}, errors.Wrap(readErr, "failed to read error response body")
+		}
+		// 响应体可能回显请求内容，脱敏后再写入错误信息
+		body := utils.RedactSecret(string(bodyBytes), apiKey)
+		// 不在 retryable_status_codes 中的状态码（如 400 请求不合法、401 密钥错误）重试也不会成功，直接归档
+		if !h.deepseek.StatusRetryable(resp.StatusCode) {
+			return llmResult{}, errors.Wrapf(asynq.SkipRetry, "LLM API request failed with non-retryable status: %d, body: %s", resp.StatusCode, body)
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return llmResult{}, &rateLimitedError{
+				retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+				body:       body,
 			}
-			return nil, errors.Errorf("LLM API request failed with status: %d, body: %s", resp.StatusCode, string(bodyBytes))
 		}
+		return llmResult{}, errors.Errorf("LLM API request failed with status: %d, body: %s", resp.StatusCode, body)
+	}
+
+	// 解析响应
+	var response struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage *llmUsage `json:"usage"`
+	}
+
+	body, err := readLimited(resp.Body, h.maxResponseBytes())
+	if err != nil {
+		if errors.Is(err, errResponseTooLarge) {
+			metrics.LLMAPICounter.WithLabelValues("response_too_large").Inc()
+		} else {
+			metrics.LLMAPICounter.WithLabelValues("read_error").Inc()
+			return llmResult{}, classify(ErrTransient, errors.Wrap(err, "failed to read LLM API response"))
+		}
+		return llmResult{}, errors.Wrap(err, "failed to read LLM API response")
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		metrics.LLMAPICounter.WithLabelValues("decode_error").Inc()
+		return llmResult{}, errors.Wrap(err, "failed to decode LLM API response")
+	}
 
-		// 解析响应
-		var response struct {
-			Choices []struct {
-				Message struct {
-					Content string `json:"content"`
-				} `json:"message"`
-			} `json:"choices"`
+	// 检查是否有响应内容
+	if len(response.Choices) == 0 || response.Choices[0].Message.Content == "" {
+		metrics.LLMAPICounter.WithLabelValues("empty_response").Inc()
+		return llmResult{}, errors.New("empty response from LLM API")
+	}
+
+	// 记录成功调用
+	metrics.LLMAPICounter.WithLabelValues("success").Inc()
+	return llmResult{Content: response.Choices[0].Message.Content, Usage: response.Usage}, nil
+}
+
+// startHeartbeat 按配置的间隔将处理进度写入记录的 progress 和 progress_info 字段，
+// 便于外部看板判断长时间的 LLM 调用是否仍在进行。
+// 未配置间隔时不启动心跳。返回的停止函数会等待心跳协程退出，
+// 确保之后的结果写入不会被心跳覆盖。
+func (h *TaskHandler) startHeartbeat(ctx context.Context, p task.LLMPayload, stage string) func() {
+	if h.queue.ProgressInterval <= 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	start := time.Now()
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(h.queue.ProgressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				updates := map[string]interface{}{
+					"progress":      stage,
+					"progress_info": fmt.Sprintf("%s, elapsed %s", stage, time.Since(start).Round(time.Second)),
+				}
+				if err := h.db.UpdateRecord(ctx, p.TableName, p.ID, updates); err != nil && ctx.Err() == nil {
+					logger.Warn("Failed to update progress",
+						logger.RequestIDField(ctx),
+						zap.Int64("record_id", p.ID),
+						zap.String("table_name", p.TableName),
+						zap.Error(err))
+				}
+			}
 		}
+	}()
 
-		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-			metrics.LLMAPICounter.WithLabelValues("decode_error").Inc()
-			return nil, errors.Wrap(err, "failed to decode LLM API response")
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// acquireLLMSlot 获取一个 LLM 调用名额，返回释放函数。
+// 未配置并发上限时立即返回；等待期间上下文被取消时返回上下文错误。
+func (h *TaskHandler) acquireLLMSlot(ctx context.Context) (func(), error) {
+	if h.llmSem != nil {
+		select {
+		case h.llmSem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
+	}
 
-		// 检查是否有响应内容
-		if len(response.Choices) == 0 || response.Choices[0].Message.Content == "" {
-			metrics.LLMAPICounter.WithLabelValues("empty_response").Inc()
-			return nil, errors.New("empty response from LLM API")
+	metrics.LLMInFlight.Inc()
+	return func() {
+		metrics.LLMInFlight.Dec()
+		if h.llmSem != nil {
+			<-h.llmSem
 		}
+	}, nil
+}
 
-		// 记录成功调用
-		metrics.LLMAPICounter.WithLabelValues("success").Inc()
-		return response.Choices[0].Message.Content, nil
-	})
+// buildLLMRequest 构建 LLM API 请求体。
+// 任务载荷中设置了模型或最大 token 数时优先使用，否则回退到配置值。
+func (h *TaskHandler) buildLLMRequest(record *database.ValuationRecord, p task.LLMPayload) map[string]interface{} {
+	model := h.taskModel(p)
 
-	// 处理断路器错误
+	maxTokens := h.deepseek.MaxTokens
+	if p.MaxTokens > 0 {
+		maxTokens = p.MaxTokens
+	}
+
+	payload := map[string]interface{}{
+		"model":      model,
+		"messages":   task.BuildMessages(record.SysMessage, record.UserMessage, h.exampleMessages()...),
+		"max_tokens": maxTokens,
+	}
+
+	// 未配置时不发送，使用服务端默认值
+	if h.deepseek.Temperature != nil {
+		payload["temperature"] = *h.deepseek.Temperature
+	}
+	if h.deepseek.TopP != nil {
+		payload["top_p"] = *h.deepseek.TopP
+	}
+
+	return payload
+}
+
+// exampleMessages 返回配置的示例消息，未配置时返回 nil
+func (h *TaskHandler) exampleMessages() []task.Message {
+	if len(h.deepseek.Examples) == 0 {
+		return nil
+	}
+	messages := make([]task.Message, len(h.deepseek.Examples))
+	for i, example := range h.deepseek.Examples {
+		messages[i] = task.Message{Role: example.Role, Content: example.Content}
+	}
+	return messages
+}
+
+// taskModel 返回任务使用的模型，任务未指定时使用配置
+func (h *TaskHandler) taskModel(p task.LLMPayload) string {
+	if p.Model != "" {
+		return p.Model
+	}
+	return h.deepseek.Model
+}
+
+// taskLogFields 返回任务结果日志的公共字段，不包含提示词和回复内容
+func taskLogFields(ctx context.Context, p task.LLMPayload, model string, start time.Time, usage *llmUsage) []zap.Field {
+	fields := []zap.Field{
+		logger.RequestIDField(ctx),
+		zap.String("model", model),
+		zap.String("table_name", p.TableName),
+		zap.Int64("record_id", p.ID),
+		zap.Int64("duration_ms", time.Since(start).Milliseconds()),
+	}
+	if usage != nil {
+		fields = append(fields, zap.Int("tokens", usage.TotalTokens))
+	}
+	return fields
+}
+
+// checkPromptSize 检查系统消息和用户消息的总字符数是否超过配置的上限，
+// 超过时返回包含 asynq.SkipRetry 的错误。未配置上限时不检查
+func (h *TaskHandler) checkPromptSize(record *database.ValuationRecord) error {
+	if h.deepseek.MaxPromptChars <= 0 {
+		return nil
+	}
+
+	chars := utf8.RuneCountInString(record.SysMessage) + utf8.RuneCountInString(record.UserMessage)
+	if chars > h.deepseek.MaxPromptChars {
+		return errors.Wrapf(asynq.SkipRetry, "prompt has %d characters, exceeding the limit of %d", chars, h.deepseek.MaxPromptChars)
+	}
+	return nil
+}
+
+// trimPrompt 按配置的截断方式截断用户消息，使系统消息和用户消息的总字符数不超过上限。
+// 需要截断时返回截断后的记录副本和截断前的总字符数，否则返回原记录。
+// 系统消息本身已超过上限时不截断，由 checkPromptSize 报告失败
+func (h *TaskHandler) trimPrompt(record *database.ValuationRecord) (*database.ValuationRecord, int) {
+	strategy := h.deepseek.TrimStrategy
+	if h.deepseek.MaxPromptChars <= 0 || (strategy != config.TrimHead && strategy != config.TrimTail) {
+		return record, 0
+	}
+
+	sysChars := utf8.RuneCountInString(record.SysMessage)
+	userChars := utf8.RuneCountInString(record.UserMessage)
+	budget := h.deepseek.MaxPromptChars - sysChars
+	if sysChars+userChars <= h.deepseek.MaxPromptChars || budget <= 0 {
+		return record, 0
+	}
+
+	// 按字符截断，避免截断多字节字符
+	user := []rune(record.UserMessage)
+	if strategy == config.TrimHead {
+		user = user[:budget]
+	} else {
+		user = user[len(user)-budget:]
+	}
+
+	trimmed := *record
+	trimmed.UserMessage = string(user)
+	return &trimmed, sysChars + userChars
+}
+
+// maxResponseBytes 返回 LLM API 响应体的最大字节数，未配置时使用默认值
+func (h *TaskHandler) maxResponseBytes() int64 {
+	if h.deepseek.MaxResponseBytes > 0 {
+		return h.deepseek.MaxResponseBytes
+	}
+	return defaultMaxResponseBytes
+}
+
+// readLimited 读取最多 limit 字节，超过限制时返回 errResponseTooLarge，
+// 防止异常的服务端返回超大响应体耗尽内存
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
 	if err != nil {
-		if errors.Is(err, gobreaker.ErrOpenState) {
-			logger.Warn("Circuit breaker is open, too many failures",
-				zap.String("record_id", fmt.Sprintf("%d", record.ID)))
-			return "", errors.New("service temporarily unavailable: circuit breaker is open")
-		}
-		return "", err
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errors.Wrapf(errResponseTooLarge, "exceeds %d bytes", limit)
+	}
+	return data, nil
+}
+
+// observeTaskWait 记录任务从入队到开始处理的等待时间。
+// 只统计首次处理，旧版本入队的任务没有入队时间，跳过统计
+func observeTaskWait(ctx context.Context, queue string, p task.LLMPayload) {
+	if p.EnqueuedAt <= 0 {
+		return
+	}
+	if retried, ok := asynq.GetRetryCount(ctx); ok && retried > 0 {
+		return
 	}
 
-	// 转换结果类型
-	content, ok := result.(string)
-	if !ok {
-		return "", errors.New("unexpected result type from LLM API")
+	wait := time.Since(time.UnixMilli(p.EnqueuedAt))
+	if wait < 0 {
+		// 主机间时钟偏差可能导致负值
+		wait = 0
 	}
+	metrics.TaskWaitDuration.WithLabelValues(queue, task.TypeLLM).Observe(wait.Seconds())
+}
 
-	return content, nil
+// taskQueue 返回任务所在的队列名称，用作指标标签。
+// 不在 asynq 处理上下文中（如直接调用处理器的测试）时返回 "unknown"。
+func taskQueue(ctx context.Context) string {
+	if queue, ok := asynq.GetQueueName(ctx); ok {
+		return queue
+	}
+	return "unknown"
+}
+
+// isCancelled 判断上下文是否被主动取消。
+// 超时（context.DeadlineExceeded）不属于取消，仍按失败处理。
+func isCancelled(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.Canceled)
 }
@@ -2,16 +2,27 @@ package worker
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/circuitbreaker"
 	"github.com/igwen6w/syt-go-queue/internal/config"
 	"github.com/igwen6w/syt-go-queue/internal/database"
+	"github.com/igwen6w/syt-go-queue/internal/metrics"
 	"github.com/igwen6w/syt-go-queue/internal/task"
 	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sony/gobreaker"
 	"github.com/spf13/viper"
+	"go.uber.org/zap"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 )
 
 var testConfig *config.Config
@@ -50,6 +61,10 @@ func setupTestData(t *testing.T, db *sqlx.DB) {
 			report TEXT,
 			current_task_node INT,
 			failed_times INT,
+			failed_info TEXT,
+			progress VARCHAR(50),
+			progress_info TEXT,
+			callback_url VARCHAR(255),
 			user_message TEXT,
 			sys_message TEXT
 		)
@@ -92,7 +107,7 @@ func TestTaskHandler_HandleLLMTask(t *testing.T) {
 	defer callbackServer.Close()
 
 	// 创建测试任务处理器
-	handler := NewTaskHandler(testDB, testConfig.Deepseek)
+	handler := NewTaskHandler(testDB, testConfig)
 
 	// 创建测试任务
 	payload := task.LLMPayload{
@@ -113,6 +128,377 @@ func TestTaskHandler_HandleLLMTask(t *testing.T) {
 	}
 }
 
+func TestTaskHandler_HandleLLMTask_StaleRecord(t *testing.T) {
+	// 创建测试数据库
+	testDB, db := setupTestDB(t)
+	defer db.Close()
+
+	// 设置测试数据，current_task_node 初始为 1
+	setupTestData(t, db)
+
+	// 模拟 LLM 调用期间其他进程写入了记录并推进了 current_task_node，使认领时读取的值过期
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := db.Exec(`UPDATE test_table SET current_task_node = current_task_node + 1,
+			status = '已完成', report = 'concurrent report', failed_times = 5, failed_info = 'concurrent info'
+			WHERE id = 123`); err != nil {
+			t.Errorf("Failed to simulate concurrent update: %v", err)
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"stale result"}}]}`))
+	}))
+	defer server.Close()
+
+	cfg := *testConfig
+	cfg.Deepseek.BaseURL = server.URL
+	handler := NewTaskHandler(testDB, &cfg)
+
+	jsonPayload, err := json.Marshal(task.LLMPayload{TableName: "test_table", ID: 123})
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+
+	err = handler.HandleLLMTask(context.Background(), asynq.NewTask(task.TypeLLM, jsonPayload))
+	if !errors.Is(err, database.ErrStaleRecord) {
+		t.Fatalf("Expected ErrStaleRecord, got %v", err)
+	}
+	if !errors.Is(err, asynq.SkipRetry) {
+		t.Errorf("Expected stale record not to be retried, got %v", err)
+	}
+
+	// 结果被丢弃，并发写入的值全部保留
+	var record struct {
+		Status          string         `db:"status"`
+		Report          sql.NullString `db:"report"`
+		CurrentTaskNode int            `db:"current_task_node"`
+		FailedTimes     int            `db:"failed_times"`
+		FailedInfo      sql.NullString `db:"failed_info"`
+	}
+	if err := db.Get(&record, "SELECT status, report, current_task_node, failed_times, failed_info FROM test_table WHERE id = 123"); err != nil {
+		t.Fatalf("Failed to read record: %v", err)
+	}
+	if record.CurrentTaskNode != 2 {
+		t.Errorf("Expected current_task_node 2, got %d", record.CurrentTaskNode)
+	}
+	if record.Status != config.DefaultStatusCompleted {
+		t.Errorf("Expected status %s, got %s", config.DefaultStatusCompleted, record.Status)
+	}
+	if record.Report.String != "concurrent report" {
+		t.Errorf("Expected concurrent report to be kept, got %q", record.Report.String)
+	}
+	if record.FailedTimes != 5 {
+		t.Errorf("Expected failed_times 5, got %d", record.FailedTimes)
+	}
+	if record.FailedInfo.String != "concurrent info" {
+		t.Errorf("Expected concurrent failed_info to be kept, got %q", record.FailedInfo.String)
+	}
+}
+
+func TestTaskHandler_HandleLLMTask_StaleRecordReleased(t *testing.T) {
+	// 创建测试数据库
+	testDB, db := setupTestDB(t)
+	defer db.Close()
+
+	// 设置测试数据，current_task_node 初始为 1
+	setupTestData(t, db)
+
+	// 模拟 LLM 调用期间其他进程只推进了 current_task_node，记录仍处于处理中
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := db.Exec("UPDATE test_table SET current_task_node = current_task_node + 1 WHERE id = 123"); err != nil {
+			t.Errorf("Failed to simulate concurrent update: %v", err)
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"stale result"}}]}`))
+	}))
+	defer server.Close()
+
+	cfg := *testConfig
+	cfg.Deepseek.BaseURL = server.URL
+	handler := NewTaskHandler(testDB, &cfg)
+
+	jsonPayload, err := json.Marshal(task.LLMPayload{TableName: "test_table", ID: 123})
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+
+	err = handler.HandleLLMTask(context.Background(), asynq.NewTask(task.TypeLLM, jsonPayload))
+	if !errors.Is(err, database.ErrStaleRecord) {
+		t.Fatalf("Expected ErrStaleRecord, got %v", err)
+	}
+
+	// 认领被释放，状态恢复为认领前的值，并发写入的 current_task_node 保留
+	var record struct {
+		Status          string `db:"status"`
+		CurrentTaskNode int    `db:"current_task_node"`
+	}
+	if err := db.Get(&record, "SELECT status, current_task_node FROM test_table WHERE id = 123"); err != nil {
+		t.Fatalf("Failed to read record: %v", err)
+	}
+	if record.Status != "待处理" {
+		t.Errorf("Expected status 待处理, got %s", record.Status)
+	}
+	if record.CurrentTaskNode != 2 {
+		t.Errorf("Expected current_task_node 2, got %d", record.CurrentTaskNode)
+	}
+}
+
+func TestTaskHandler_HandleLLMTask_ClaimCommittedBeforeLLMCall(t *testing.T) {
+	// 创建测试数据库
+	testDB, db := setupTestDB(t)
+	defer db.Close()
+
+	// 设置测试数据
+	setupTestData(t, db)
+
+	// LLM 调用期间从另一个连接读取记录：认领已提交，状态可见，且不持有行锁
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tx, err := db.Beginx()
+		if err != nil {
+			t.Errorf("Failed to begin transaction: %v", err)
+		} else {
+			var status string
+			if err := tx.Get(&status, "SELECT status FROM test_table WHERE id = 123 FOR UPDATE NOWAIT"); err != nil {
+				t.Errorf("Expected record not to be locked during the LLM call: %v", err)
+			} else if status != config.DefaultStatusProcessing {
+				t.Errorf("Expected committed status %s during the LLM call, got %s", config.DefaultStatusProcessing, status)
+			}
+			_ = tx.Rollback()
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"result"}}]}`))
+	}))
+	defer server.Close()
+
+	cfg := *testConfig
+	cfg.Deepseek.BaseURL = server.URL
+	handler := NewTaskHandler(testDB, &cfg)
+
+	jsonPayload, err := json.Marshal(task.LLMPayload{TableName: "test_table", ID: 123})
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+
+	if err := handler.HandleLLMTask(context.Background(), asynq.NewTask(task.TypeLLM, jsonPayload)); err != nil {
+		t.Errorf("HandleLLMTask failed: %v", err)
+	}
+}
+
+func TestTaskHandler_HandleLLMTask_TaskTimeout(t *testing.T) {
+	// 创建测试数据库
+	testDB, db := setupTestDB(t)
+	defer db.Close()
+
+	// 设置测试数据
+	setupTestData(t, db)
+
+	// LLM 响应慢于任务总超时，但在 LLM 调用超时之内
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"late result"}}]}`))
+	}))
+	defer server.Close()
+
+	cfg := *testConfig
+	cfg.Deepseek.BaseURL = server.URL
+	cfg.Deepseek.Timeout = 5 * time.Second
+	cfg.Queue.TaskTimeout = 100 * time.Millisecond
+	handler := NewTaskHandler(testDB, &cfg)
+
+	jsonPayload, err := json.Marshal(task.LLMPayload{TableName: "test_table", ID: 123})
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+
+	start := time.Now()
+	err = handler.HandleLLMTask(context.Background(), asynq.NewTask(task.TypeLLM, jsonPayload))
+	if err == nil {
+		t.Fatalf("Expected error when task timeout is exceeded")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected task to stop at task timeout, took %v", elapsed)
+	}
+
+	// 超时后仍然写入失败信息，记录不会停留在处理中
+	var status string
+	if err := db.Get(&status, "SELECT status FROM test_table WHERE id = 123"); err != nil {
+		t.Fatalf("Failed to read record: %v", err)
+	}
+	if status != config.DefaultStatusFailed {
+		t.Errorf("Expected status %s, got %s", config.DefaultStatusFailed, status)
+	}
+}
+
+// panicStore 保存报告时 panic，模拟处理器在认领记录后出现的意外错误
+type panicStore struct{}
+
+func (panicStore) Save(context.Context, string, string) (string, error) {
+	panic("unexpected response shape")
+}
+
+func TestTaskHandler_HandleLLMTask_Panic(t *testing.T) {
+	// 创建测试数据库
+	testDB, db := setupTestDB(t)
+	defer db.Close()
+
+	// 设置测试数据
+	setupTestData(t, db)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"result"}}]}`))
+	}))
+	defer server.Close()
+
+	cfg := *testConfig
+	cfg.Deepseek.BaseURL = server.URL
+	handler := NewTaskHandler(testDB, &cfg)
+	handler.results = panicStore{}
+
+	jsonPayload, err := json.Marshal(task.LLMPayload{TableName: "test_table", ID: 123})
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+
+	// panic 被恢复为普通错误，任务会被重试
+	err = handler.HandleLLMTask(context.Background(), asynq.NewTask(task.TypeLLM, jsonPayload))
+	if err == nil {
+		t.Fatalf("Expected error after panic")
+	}
+	if errors.Is(err, asynq.SkipRetry) {
+		t.Errorf("Expected panic error to be retried, got %v", err)
+	}
+
+	// 记录被标记为失败，而不是停留在处理中
+	var record struct {
+		Status      string         `db:"status"`
+		FailedTimes int            `db:"failed_times"`
+		FailedInfo  sql.NullString `db:"failed_info"`
+	}
+	if err := db.Get(&record, "SELECT status, failed_times, failed_info FROM test_table WHERE id = 123"); err != nil {
+		t.Fatalf("Failed to read record: %v", err)
+	}
+	if record.Status != config.DefaultStatusFailed {
+		t.Errorf("Expected status %s, got %s", config.DefaultStatusFailed, record.Status)
+	}
+	if record.FailedTimes != 1 {
+		t.Errorf("Expected failed_times 1, got %d", record.FailedTimes)
+	}
+	if !strings.Contains(record.FailedInfo.String, "unexpected response shape") {
+		t.Errorf("Expected failed_info to contain panic message, got %q", record.FailedInfo.String)
+	}
+}
+
+func TestTaskHandler_RecoverLLMTask_Unclaimed(t *testing.T) {
+	const queue = "panic_test"
+	handler := &TaskHandler{}
+
+	// 记录尚未认领时不写入数据库，只记录指标并返回可重试的错误
+	err := handler.recoverLLMTask(context.Background(), queue, task.LLMPayload{TableName: "test_table", ID: 123}, nil, "boom")
+	if err == nil || !strings.Contains(err.Error(), "panic while processing task: boom") {
+		t.Errorf("Expected panic error, got %v", err)
+	}
+	if errors.Is(err, asynq.SkipRetry) {
+		t.Errorf("Expected panic error to be retried, got %v", err)
+	}
+
+	var m dto.Metric
+	counter := metrics.TaskCounter.WithLabelValues(task.TypeLLM, queue, "panic").(prometheus.Metric)
+	if err := counter.Write(&m); err != nil {
+		t.Fatalf("Failed to read counter: %v", err)
+	}
+	if got := m.GetCounter().GetValue(); got != 1 {
+		t.Errorf("Expected panic counter 1, got %v", got)
+	}
+}
+
+func TestTaskHandler_ReleaseClaim(t *testing.T) {
+	// 创建测试数据库
+	testDB, db := setupTestDB(t)
+	defer db.Close()
+
+	handler := NewTaskHandler(testDB, testConfig)
+	p := task.LLMPayload{TableName: "test_table", ID: 123}
+
+	readStatus := func() string {
+		var status string
+		if err := db.Get(&status, "SELECT status FROM test_table WHERE id = 123"); err != nil {
+			t.Fatalf("Failed to read record: %v", err)
+		}
+		return status
+	}
+
+	// 释放认领后恢复认领前的状态，重试的任务可以重新认领
+	setupTestData(t, db)
+	record, err := testDB.ClaimRecord(context.Background(), "test_table", 123, config.DefaultStatusProcessing, config.DefaultStatusCompleted)
+	if err != nil {
+		t.Fatalf("Failed to claim record: %v", err)
+	}
+	handler.releaseClaim(context.Background(), p, record)
+	if got := readStatus(); got != "待处理" {
+		t.Errorf("Expected status 待处理 after release, got %s", got)
+	}
+	if _, err := testDB.ClaimRecord(context.Background(), "test_table", 123, config.DefaultStatusProcessing, config.DefaultStatusCompleted); err != nil {
+		t.Errorf("Expected released record to be claimed again, got %v", err)
+	}
+
+	// 处理期间状态已被其他写入者修改时保留修改后的状态
+	setupTestData(t, db)
+	record, err = testDB.ClaimRecord(context.Background(), "test_table", 123, config.DefaultStatusProcessing, config.DefaultStatusCompleted)
+	if err != nil {
+		t.Fatalf("Failed to claim record: %v", err)
+	}
+	if _, err := db.Exec("UPDATE test_table SET status = '已完成' WHERE id = 123"); err != nil {
+		t.Fatalf("Failed to simulate concurrent update: %v", err)
+	}
+	handler.releaseClaim(context.Background(), p, record)
+	if got := readStatus(); got != config.DefaultStatusCompleted {
+		t.Errorf("Expected concurrent status %s to be kept, got %s", config.DefaultStatusCompleted, got)
+	}
+}
+
+func TestTaskHandler_HandleLLMTask_SkipCompleted(t *testing.T) {
+	// 创建测试数据库
+	testDB, db := setupTestDB(t)
+	defer db.Close()
+
+	// 设置测试数据，记录已处理完成
+	setupTestData(t, db)
+	if _, err := db.Exec("UPDATE test_table SET status = ?, current_task_node = 2 WHERE id = 123", config.DefaultStatusCompleted); err != nil {
+		t.Fatalf("Failed to update test data: %v", err)
+	}
+
+	var llmCalls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		llmCalls++
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"reprocessed"}}]}`))
+	}))
+	defer server.Close()
+
+	jsonPayload, err := json.Marshal(task.LLMPayload{TableName: "test_table", ID: 123})
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+
+	// 默认跳过已完成的记录，不调用 LLM
+	cfg := *testConfig
+	cfg.Deepseek.BaseURL = server.URL
+	handler := NewTaskHandler(testDB, &cfg)
+	if err := handler.HandleLLMTask(context.Background(), asynq.NewTask(task.TypeLLM, jsonPayload)); err != nil {
+		t.Fatalf("Expected completed record to be skipped, got error: %v", err)
+	}
+	if llmCalls != 0 {
+		t.Errorf("Expected LLM not to be called for completed record, got %d calls", llmCalls)
+	}
+
+	// 开启 reprocess_completed 时重新处理
+	cfg.Queue.ReprocessCompleted = true
+	handler = NewTaskHandler(testDB, &cfg)
+	if err := handler.HandleLLMTask(context.Background(), asynq.NewTask(task.TypeLLM, jsonPayload)); err != nil {
+		t.Fatalf("HandleLLMTask failed: %v", err)
+	}
+	if llmCalls != 1 {
+		t.Errorf("Expected LLM to be called once when reprocessing, got %d calls", llmCalls)
+	}
+}
+
 func TestTaskHandler_SendCallback(t *testing.T) {
 	// 创建测试数据库
 	testDB, db := setupTestDB(t)
@@ -130,14 +516,183 @@ func TestTaskHandler_SendCallback(t *testing.T) {
 	}))
 	defer server.Close()
 
-	handler := NewTaskHandler(testDB, testConfig.Deepseek)
+	handler := NewTaskHandler(testDB, testConfig)
 
-	err := handler.sendCallback(context.Background(), server.URL, "test result")
+	err := handler.sendCallback(context.Background(), server.URL, task.LLMPayload{TableName: "test_table", ID: 123}, llmResult{Content: "test result"})
 	if err != nil {
 		t.Errorf("sendCallback failed: %v", err)
 	}
 }
 
+func TestTaskHandler_CallbackPayload(t *testing.T) {
+	p := task.LLMPayload{TableName: "test_table", ID: 123}
+	result := llmResult{
+		Content: "test result",
+		Usage:   &llmUsage{PromptTokens: 10, CompletionTokens: 20, TotalTokens: 30},
+	}
+
+	// 默认只包含 result、status 和 timestamp
+	handler := &TaskHandler{}
+	payload := handler.callbackPayload(context.Background(), p, result)
+	if len(payload) != 3 || payload["result"] != "test result" || payload["status"] != "success" {
+		t.Errorf("Unexpected minimal payload: %v", payload)
+	}
+
+	// verbose 模式包含记录、任务和 token 用量
+	handler = &TaskHandler{callbackVerbose: true}
+	payload = handler.callbackPayload(context.Background(), p, result)
+	for _, key := range []string{"result", "status", "timestamp", "table_name", "record_id", "task_id", "usage"} {
+		if _, ok := payload[key]; !ok {
+			t.Errorf("Expected verbose payload to contain %q, got %v", key, payload)
+		}
+	}
+	if payload["table_name"] != "test_table" || payload["record_id"] != int64(123) {
+		t.Errorf("Unexpected record fields: %v", payload)
+	}
+
+	if _, ok := payload["cached"]; ok {
+		t.Errorf("Expected no cached flag for a fresh reply, got %v", payload["cached"])
+	}
+
+	// 响应中没有用量时不包含 usage
+	payload = handler.callbackPayload(context.Background(), p, llmResult{Content: "test result"})
+	if _, ok := payload["usage"]; ok {
+		t.Errorf("Expected no usage when LLM response has none, got %v", payload["usage"])
+	}
+
+	// 来自提示词缓存的回复带有 cached 标记
+	result.Cached = true
+	payload = handler.callbackPayload(context.Background(), p, result)
+	if payload["cached"] != true || payload["usage"] == nil {
+		t.Errorf("Expected cached flag and usage for a cached reply, got %v", payload)
+	}
+}
+
+func TestTaskHandler_FailureCallbackPayload(t *testing.T) {
+	p := task.LLMPayload{TableName: "test_table", ID: 123}
+	taskErr := errors.New("LLM API request failed with status: 500")
+
+	// 默认只包含 error、status 和 timestamp
+	handler := &TaskHandler{}
+	payload := handler.failureCallbackPayload(context.Background(), p, taskErr)
+	if len(payload) != 3 || payload["status"] != "failed" || payload["error"] != taskErr.Error() {
+		t.Errorf("Unexpected minimal payload: %v", payload)
+	}
+
+	// verbose 模式包含记录、任务和重试次数
+	handler = &TaskHandler{callbackVerbose: true}
+	payload = handler.failureCallbackPayload(context.Background(), p, taskErr)
+	for _, key := range []string{"error", "status", "timestamp", "table_name", "record_id", "task_id", "retried", "max_retry"} {
+		if _, ok := payload[key]; !ok {
+			t.Errorf("Expected verbose payload to contain %q, got %v", key, payload)
+		}
+	}
+}
+
+func TestTaskHandler_DeliverCallback_Async(t *testing.T) {
+	queue := &fakeEnqueuer{}
+	handler := NewTaskHandler(nil, testConfig)
+	handler.callbackQueue = queue
+
+	payload := map[string]interface{}{"result": "test result", "status": "success"}
+	if err := handler.deliverCallback(context.Background(), "https://example.com/callback", payload); err != nil {
+		t.Fatalf("deliverCallback failed: %v", err)
+	}
+	if len(queue.tasks) != 1 || queue.tasks[0].Type() != task.TypeCallback {
+		t.Fatalf("Expected one %s task enqueued, got %v", task.TypeCallback, queue.tasks)
+	}
+
+	var p task.CallbackPayload
+	if err := json.Unmarshal(queue.tasks[0].Payload(), &p); err != nil {
+		t.Fatalf("Failed to unmarshal callback payload: %v", err)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(p.Body, &body); err != nil {
+		t.Fatalf("Failed to unmarshal callback body: %v", err)
+	}
+	if p.URL != "https://example.com/callback" || body["result"] != "test result" {
+		t.Errorf("Unexpected callback payload: url=%s body=%v", p.URL, body)
+	}
+
+	// 入队失败时返回错误
+	handler.callbackQueue = &fakeEnqueuer{err: errors.New("redis unavailable")}
+	if err := handler.deliverCallback(context.Background(), "https://example.com/callback", payload); err == nil {
+		t.Error("Expected error when callback task cannot be enqueued")
+	}
+}
+
+func TestTaskHandler_HandleCallbackTask_SkipRetry(t *testing.T) {
+	handler := NewTaskHandler(nil, testConfig)
+
+	invalidURL, err := task.NewCallbackTask("http://localhost/callback", map[string]string{"status": "success"})
+	if err != nil {
+		t.Fatalf("NewCallbackTask failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		task *asynq.Task
+	}{
+		{"invalid payload", asynq.NewTask(task.TypeCallback, []byte("not json"))},
+		{"forbidden callback URL", invalidURL},
+	}
+	for _, tt := range tests {
+		err := handler.HandleCallbackTask(context.Background(), tt.task)
+		if !errors.Is(err, asynq.SkipRetry) {
+			t.Errorf("%s: expected SkipRetry error, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestTaskHandler_HandleLLMTask_ModelNotAllowed(t *testing.T) {
+	// 模型校验在认领记录之前进行，不访问数据库
+	handler := &TaskHandler{deepseek: config.DeepseekConfig{Model: "deepseek-chat", AllowedModels: []string{"deepseek-chat"}}}
+
+	disallowed, err := task.NewLLMTask(task.LLMPayload{TableName: "valuation_records", ID: 1, Model: "gpt-4"})
+	if err != nil {
+		t.Fatalf("NewLLMTask failed: %v", err)
+	}
+	if err := handler.HandleLLMTask(context.Background(), disallowed); !errors.Is(err, asynq.SkipRetry) {
+		t.Errorf("Expected SkipRetry error for a model outside allowed_models, got %v", err)
+	}
+}
+
+func TestTaskHandler_CallbackContext(t *testing.T) {
+	shutdownCtx, cancelInFlight := context.WithCancel(context.Background())
+	defer cancelInFlight()
+	handler := &TaskHandler{callbackTimeout: time.Minute, shutdownCtx: shutdownCtx}
+
+	// 任务上下文已经超时，回调上下文仍然可用，并有自己的截止时间
+	taskCtx, cancelTask := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancelTask()
+	<-taskCtx.Done()
+
+	ctx, cancel := handler.callbackContext(taskCtx)
+	defer cancel()
+	if ctx.Err() != nil {
+		t.Fatalf("Expected callback context to outlive the task deadline, got %v", ctx.Err())
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok || time.Until(deadline) < 50*time.Second {
+		t.Errorf("Expected callback deadline about one minute away, got %v (ok=%v)", time.Until(deadline), ok)
+	}
+
+	// 关闭时取消进行中的回调
+	cancelInFlight()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Error("Expected callback context to be cancelled on shutdown")
+	}
+}
+
+func TestIsFinalAttempt(t *testing.T) {
+	// 不在 asynq 任务上下文中时无法判断，视为还会重试
+	if isFinalAttempt(context.Background(), errors.Wrap(asynq.SkipRetry, "prompt too large")) {
+		t.Error("Expected isFinalAttempt to be false without asynq retry information")
+	}
+}
+
 func TestTaskHandler_ProcessLLM(t *testing.T) {
 	// 创建测试数据库
 	testDB, db := setupTestDB(t)
@@ -181,9 +736,9 @@ func TestTaskHandler_ProcessLLM(t *testing.T) {
 	defer server.Close()
 
 	// 创建带有测试配置的处理器
-	cfg := testConfig.Deepseek
-	cfg.BaseURL = server.URL // 使用测试服务器的 URL
-	handler := NewTaskHandler(testDB, cfg)
+	cfg := *testConfig
+	cfg.Deepseek.BaseURL = server.URL // 使用测试服务器的 URL
+	handler := NewTaskHandler(testDB, &cfg)
 
 	// 创建测试记录
 	record := &database.ValuationRecord{
@@ -193,14 +748,652 @@ func TestTaskHandler_ProcessLLM(t *testing.T) {
 	}
 
 	// 测试 processLLM 方法
-	result, err := handler.processLLM(context.Background(), record)
+	result, err := handler.processLLM(context.Background(), record, task.LLMPayload{})
 	if err != nil {
 		t.Errorf("processLLM failed: %v", err)
 	}
 
 	// 验证结果
 	expected := "This is a test response from the LLM API."
-	if result != expected {
-		t.Errorf("Expected result %q, got %q", expected, result)
+	if result.Content != expected {
+		t.Errorf("Expected result %q, got %q", expected, result.Content)
+	}
+}
+
+func TestTaskHandler_BuildLLMRequest(t *testing.T) {
+	handler := &TaskHandler{
+		deepseek: config.DeepseekConfig{
+			Model:     "deepseek-chat",
+			MaxTokens: 2000,
+		},
+	}
+	record := &database.ValuationRecord{
+		ID:          123,
+		UserMessage: "user",
+		SysMessage:  "system",
+	}
+
+	tests := []struct {
+		name          string
+		payload       task.LLMPayload
+		wantModel     string
+		wantMaxTokens int
+	}{
+		{
+			name:          "使用配置默认值",
+			payload:       task.LLMPayload{},
+			wantModel:     "deepseek-chat",
+			wantMaxTokens: 2000,
+		},
+		{
+			name:          "任务级覆盖",
+			payload:       task.LLMPayload{Model: "deepseek-reasoner", MaxTokens: 500},
+			wantModel:     "deepseek-reasoner",
+			wantMaxTokens: 500,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := handler.buildLLMRequest(record, tt.payload)
+			if req["model"] != tt.wantModel {
+				t.Errorf("Expected model %q, got %v", tt.wantModel, req["model"])
+			}
+			if req["max_tokens"] != tt.wantMaxTokens {
+				t.Errorf("Expected max_tokens %d, got %v", tt.wantMaxTokens, req["max_tokens"])
+			}
+		})
+	}
+}
+
+func TestTaskHandler_BuildLLMRequest_SamplingParams(t *testing.T) {
+	record := &database.ValuationRecord{ID: 123}
+
+	// 未配置时不应包含采样参数
+	handler := &TaskHandler{deepseek: config.DeepseekConfig{Model: "deepseek-chat", MaxTokens: 2000}}
+	req := handler.buildLLMRequest(record, task.LLMPayload{})
+	if _, ok := req["temperature"]; ok {
+		t.Errorf("Expected temperature to be omitted")
+	}
+	if _, ok := req["top_p"]; ok {
+		t.Errorf("Expected top_p to be omitted")
+	}
+
+	// 配置后应包含采样参数，包括零值
+	temperature, topP := 0.0, 0.9
+	handler.deepseek.Temperature = &temperature
+	handler.deepseek.TopP = &topP
+	req = handler.buildLLMRequest(record, task.LLMPayload{})
+	if req["temperature"] != 0.0 {
+		t.Errorf("Expected temperature 0, got %v", req["temperature"])
+	}
+	if req["top_p"] != 0.9 {
+		t.Errorf("Expected top_p 0.9, got %v", req["top_p"])
+	}
+}
+
+func TestTaskHandler_BuildLLMRequest_Examples(t *testing.T) {
+	record := &database.ValuationRecord{ID: 123, SysMessage: "system", UserMessage: "user"}
+
+	// 未配置示例时只有系统消息和用户消息
+	handler := &TaskHandler{deepseek: config.DeepseekConfig{Model: "deepseek-chat", MaxTokens: 2000}}
+	messages := handler.buildLLMRequest(record, task.LLMPayload{})["messages"].([]task.Message)
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(messages))
+	}
+
+	// 示例按顺序插入到系统消息和用户消息之间
+	handler.deepseek.Examples = []config.ExampleMessage{
+		{Role: "user", Content: "example question"},
+		{Role: "assistant", Content: "example answer"},
+	}
+	messages = handler.buildLLMRequest(record, task.LLMPayload{})["messages"].([]task.Message)
+	expected := []task.Message{
+		{Role: "system", Content: "system"},
+		{Role: "user", Content: "example question"},
+		{Role: "assistant", Content: "example answer"},
+		{Role: "user", Content: "user"},
+	}
+	if len(messages) != len(expected) {
+		t.Fatalf("Expected %d messages, got %d", len(expected), len(messages))
+	}
+	for i, m := range expected {
+		if messages[i] != m {
+			t.Errorf("Message %d: expected %+v, got %+v", i, m, messages[i])
+		}
+	}
+}
+
+func TestReadLimited(t *testing.T) {
+	data, err := readLimited(strings.NewReader("12345"), 5)
+	if err != nil {
+		t.Errorf("readLimited() at limit returned error: %v", err)
+	}
+	if string(data) != "12345" {
+		t.Errorf("Expected %q, got %q", "12345", string(data))
+	}
+
+	_, err = readLimited(strings.NewReader("123456"), 5)
+	if !errors.Is(err, errResponseTooLarge) {
+		t.Errorf("Expected errResponseTooLarge, got %v", err)
+	}
+}
+
+func TestTaskHandler_ProcessLLM_Cancelled(t *testing.T) {
+	// 模拟响应缓慢的 LLM API，请求期间取消上下文
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	handler := &TaskHandler{
+		deepseek: config.DeepseekConfig{
+			BaseURL:   server.URL,
+			Timeout:   5 * time.Second,
+			Model:     "test-model",
+			MaxTokens: 100,
+		},
+		client:         &http.Client{},
+		circuitBreaker: circuitbreaker.DefaultLLMCircuitBreaker(),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err := handler.processLLM(ctx, &database.ValuationRecord{ID: 123}, task.LLMPayload{})
+	if err == nil {
+		t.Fatalf("Expected error when context is cancelled")
+	}
+	if !isCancelled(ctx) {
+		t.Errorf("Expected context to be reported as cancelled")
+	}
+}
+
+func TestTaskHandler_ProcessLLM_Fallback(t *testing.T) {
+	// 主 LLM 始终返回错误，使断路器打开
+	var primaryCalls int
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryCalls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer primary.Close()
+
+	// 备用 LLM 检查模型和密钥后返回正常响应
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["model"] != "fallback-model" {
+			t.Errorf("Expected fallback model, got %v", body["model"])
+		}
+		if r.Header.Get("Authorization") != "Bearer fallback-key" {
+			t.Errorf("Expected fallback API key, got %s", r.Header.Get("Authorization"))
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"fallback response"}}]}`))
+	}))
+	defer fallback.Close()
+
+	handler := &TaskHandler{
+		deepseek: config.DeepseekConfig{
+			APIKey:          "primary-key",
+			BaseURL:         primary.URL,
+			Timeout:         5 * time.Second,
+			Model:           "test-model",
+			MaxTokens:       100,
+			FallbackBaseURL: fallback.URL,
+			FallbackModel:   "fallback-model",
+			FallbackAPIKey:  "fallback-key",
+		},
+		client:         &http.Client{},
+		circuitBreaker: circuitbreaker.DefaultLLMCircuitBreaker(),
+	}
+
+	record := &database.ValuationRecord{ID: 123}
+
+	// 连续失败使断路器打开，打开前不会调用备用 LLM
+	for i := 0; i < 5; i++ {
+		if _, err := handler.processLLM(context.Background(), record, task.LLMPayload{}); err == nil {
+			t.Fatalf("Expected primary LLM error on call %d", i+1)
+		}
+	}
+	if handler.circuitBreaker.State() != gobreaker.StateOpen {
+		t.Fatalf("Expected circuit breaker to be open, got %s", handler.circuitBreaker.State())
+	}
+
+	result, err := handler.processLLM(context.Background(), record, task.LLMPayload{})
+	if err != nil {
+		t.Fatalf("Expected fallback to succeed, got %v", err)
+	}
+	if result.Content != "fallback response" {
+		t.Errorf("Expected fallback response, got %q", result.Content)
+	}
+	if primaryCalls != 5 {
+		t.Errorf("Expected primary LLM to be called 5 times, got %d", primaryCalls)
+	}
+}
+
+func TestTaskHandler_ProcessLLM_RateLimited(t *testing.T) {
+	// 主 LLM 返回 429 并要求等待 60 秒
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	handler := &TaskHandler{
+		deepseek: config.DeepseekConfig{
+			BaseURL:   server.URL,
+			Timeout:   5 * time.Second,
+			Model:     "test-model",
+			MaxTokens: 100,
+		},
+		client:         &http.Client{},
+		circuitBreaker: circuitbreaker.DefaultLLMCircuitBreaker(),
+	}
+
+	record := &database.ValuationRecord{ID: 123}
+	if _, err := handler.processLLM(context.Background(), record, task.LLMPayload{}); err == nil {
+		t.Fatal("Expected rate limit error")
+	}
+
+	// 一次限流响应就应打开断路器，而不是等待错误率达到阈值
+	if handler.circuitBreaker.State() != gobreaker.StateOpen {
+		t.Fatalf("Expected circuit breaker to be forced open, got %s", handler.circuitBreaker.State())
+	}
+
+	_, err := handler.processLLM(context.Background(), record, task.LLMPayload{})
+	if err == nil || !strings.Contains(err.Error(), "circuit breaker is open") {
+		t.Errorf("Expected circuit breaker open error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected LLM API to be called once while rate limited, got %d", calls)
+	}
+}
+
+func TestTaskHandler_CallLLM_RetryableStatusCodes(t *testing.T) {
+	tests := []struct {
+		name          string
+		retryable     []int
+		status        int
+		wantSkipRetry bool
+	}{
+		{name: "default retries 503", status: http.StatusServiceUnavailable},
+		{name: "default retries 429", status: http.StatusTooManyRequests},
+		{name: "default fails 400", status: http.StatusBadRequest, wantSkipRetry: true},
+		{name: "default fails 501", status: http.StatusNotImplemented, wantSkipRetry: true},
+		{name: "configured retries 408", retryable: []int{408, 503}, status: http.StatusRequestTimeout},
+		{name: "configured fails 500", retryable: []int{408, 503}, status: http.StatusInternalServerError, wantSkipRetry: true},
+		{name: "configured fails 429", retryable: []int{503}, status: http.StatusTooManyRequests, wantSkipRetry: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(`{"error":"failed"}`))
+			}))
+			defer server.Close()
+
+			handler := &TaskHandler{
+				deepseek: config.DeepseekConfig{RetryableStatusCodes: tt.retryable},
+				client:   &http.Client{},
+			}
+
+			_, err := handler.callLLM(context.Background(), server.URL, "key", []byte(`{}`))
+			if err == nil {
+				t.Fatal("Expected error for non-200 status")
+			}
+			if got := errors.Is(err, asynq.SkipRetry); got != tt.wantSkipRetry {
+				t.Errorf("errors.Is(err, asynq.SkipRetry) = %v, want %v (err: %v)", got, tt.wantSkipRetry, err)
+			}
+			if tt.wantSkipRetry && !strings.Contains(err.Error(), "non-retryable status") {
+				t.Errorf("Expected non-retryable status error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{"-5", 0},
+		{"invalid", 0},
+		{now.Add(2 * time.Minute).Format(http.TimeFormat), 2 * time.Minute},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"86400", maxRetryAfter},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.expected {
+			t.Errorf("parseRetryAfter(%q) = %v, expected %v", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestNewLLMTransport(t *testing.T) {
+	// 未配置时保留默认传输层的设置
+	defaults := http.DefaultTransport.(*http.Transport)
+	transport := newLLMTransport(config.TransportConfig{})
+	if transport.MaxIdleConnsPerHost != defaults.MaxIdleConnsPerHost {
+		t.Errorf("Expected default MaxIdleConnsPerHost %d, got %d", defaults.MaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+	}
+	if transport.IdleConnTimeout != defaults.IdleConnTimeout {
+		t.Errorf("Expected default IdleConnTimeout %v, got %v", defaults.IdleConnTimeout, transport.IdleConnTimeout)
+	}
+	if transport.TLSHandshakeTimeout != defaults.TLSHandshakeTimeout {
+		t.Errorf("Expected default TLSHandshakeTimeout %v, got %v", defaults.TLSHandshakeTimeout, transport.TLSHandshakeTimeout)
+	}
+
+	transport = newLLMTransport(config.TransportConfig{
+		MaxIdleConnsPerHost: 200,
+		IdleConnTimeout:     5 * time.Minute,
+		TLSHandshakeTimeout: 3 * time.Second,
+	})
+	if transport.MaxIdleConnsPerHost != 200 {
+		t.Errorf("Expected MaxIdleConnsPerHost 200, got %d", transport.MaxIdleConnsPerHost)
+	}
+	if transport.MaxIdleConns < 200 {
+		t.Errorf("Expected MaxIdleConns to be at least 200, got %d", transport.MaxIdleConns)
+	}
+	if transport.IdleConnTimeout != 5*time.Minute {
+		t.Errorf("Expected IdleConnTimeout 5m, got %v", transport.IdleConnTimeout)
+	}
+	if transport.TLSHandshakeTimeout != 3*time.Second {
+		t.Errorf("Expected TLSHandshakeTimeout 3s, got %v", transport.TLSHandshakeTimeout)
+	}
+}
+
+func TestTaskHandler_ProcessLLM_PromptTooLarge(t *testing.T) {
+	// 提示词超过上限时不应调用 LLM API
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("LLM API should not be called when the prompt is too large")
+	}))
+	defer server.Close()
+
+	handler := &TaskHandler{
+		deepseek: config.DeepseekConfig{
+			BaseURL:        server.URL,
+			Timeout:        5 * time.Second,
+			Model:          "test-model",
+			MaxTokens:      100,
+			MaxPromptChars: 10,
+		},
+		client:         &http.Client{},
+		circuitBreaker: circuitbreaker.DefaultLLMCircuitBreaker(),
+	}
+
+	// 按字符而不是字节计数，7 个中文字符加 3 个字母正好等于上限
+	record := &database.ValuationRecord{ID: 123, SysMessage: "系统提示词内容", UserMessage: "abc"}
+	if err := handler.checkPromptSize(record); err != nil {
+		t.Errorf("Expected prompt within limit, got %v", err)
+	}
+
+	record.UserMessage = "abcd"
+	_, err := handler.processLLM(context.Background(), record, task.LLMPayload{})
+	if err == nil {
+		t.Fatalf("Expected error for oversized prompt")
+	}
+	if !errors.Is(err, asynq.SkipRetry) {
+		t.Errorf("Expected oversized prompt to skip retry, got %v", err)
+	}
+}
+
+func TestTaskHandler_TrimPrompt(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		sys      string
+		user     string
+		wantUser string
+		wantTrim bool
+	}{
+		{name: "within limit", strategy: config.TrimHead, sys: "系统", user: "abcdefgh", wantUser: "abcdefgh"},
+		{name: "keep head", strategy: config.TrimHead, sys: "系统", user: "用户消息abcdefgh", wantUser: "用户消息abcd", wantTrim: true},
+		{name: "keep tail", strategy: config.TrimTail, sys: "系统", user: "abcdefgh用户消息", wantUser: "efgh用户消息", wantTrim: true},
+		{name: "no strategy", strategy: config.TrimNone, sys: "系统", user: "用户消息abcdefgh", wantUser: "用户消息abcdefgh"},
+		{name: "system message alone too long", strategy: config.TrimHead, sys: "系统提示词内容太长了吧", user: "abc", wantUser: "abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &TaskHandler{deepseek: config.DeepseekConfig{MaxPromptChars: 10, TrimStrategy: tt.strategy}}
+			record := &database.ValuationRecord{SysMessage: tt.sys, UserMessage: tt.user}
+
+			got, original := handler.trimPrompt(record)
+			if got.UserMessage != tt.wantUser {
+				t.Errorf("trimPrompt() user message = %q, want %q", got.UserMessage, tt.wantUser)
+			}
+			if (got != record) != tt.wantTrim {
+				t.Errorf("trimPrompt() trimmed = %v, want %v", got != record, tt.wantTrim)
+			}
+			if tt.wantTrim && original != utf8.RuneCountInString(tt.sys+tt.user) {
+				t.Errorf("trimPrompt() original chars = %d", original)
+			}
+			// 截断不修改原记录
+			if record.UserMessage != tt.user {
+				t.Errorf("trimPrompt() modified the original record")
+			}
+		})
+	}
+}
+
+func TestIsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if isCancelled(ctx) {
+		t.Errorf("Expected active context not to be cancelled")
+	}
+	cancel()
+	if !isCancelled(ctx) {
+		t.Errorf("Expected cancelled context to be cancelled")
+	}
+
+	// 超时不视为取消
+	timeoutCtx, timeoutCancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer timeoutCancel()
+	<-timeoutCtx.Done()
+	if isCancelled(timeoutCtx) {
+		t.Errorf("Expected deadline exceeded not to be treated as cancellation")
+	}
+}
+
+func TestTaskHandler_FailureStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		keep   bool
+		report string
+		want   string
+	}{
+		{name: "disabled", keep: false, report: "old report", want: config.DefaultStatusFailed},
+		{name: "no previous report", keep: true, report: "", want: config.DefaultStatusFailed},
+		{name: "keep previous report", keep: true, report: "old report", want: config.DefaultStatusStale},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &TaskHandler{queue: config.QueueConfig{KeepStaleReport: tt.keep}}
+			got := handler.failureStatus(&database.ValuationRecord{Report: tt.report})
+			if got != tt.want {
+				t.Errorf("failureStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// 配置了状态值时使用配置的取值
+	handler := &TaskHandler{queue: config.QueueConfig{
+		KeepStaleReport: true,
+		Statuses:        config.StatusValues{Failed: "failed", Stale: "stale"},
+	}}
+	if got := handler.failureStatus(&database.ValuationRecord{}); got != "failed" {
+		t.Errorf("failureStatus() = %q, want %q", got, "failed")
+	}
+	if got := handler.failureStatus(&database.ValuationRecord{Report: "old report"}); got != "stale" {
+		t.Errorf("failureStatus() = %q, want %q", got, "stale")
+	}
+}
+
+func TestTaskHandler_ReachedMaxFailedTimes(t *testing.T) {
+	tests := []struct {
+		name        string
+		max         int
+		failedTimes int
+		want        bool
+	}{
+		{name: "no limit", max: 0, failedTimes: 100, want: false},
+		{name: "below limit", max: 3, failedTimes: 2, want: false},
+		{name: "at limit", max: 3, failedTimes: 3, want: true},
+		{name: "above limit", max: 3, failedTimes: 5, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &TaskHandler{queue: config.QueueConfig{MaxFailedTimes: tt.max}}
+			if got := handler.reachedMaxFailedTimes(tt.failedTimes); got != tt.want {
+				t.Errorf("reachedMaxFailedTimes(%d) = %v, want %v", tt.failedTimes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskHandler_AcquireLLMSlot(t *testing.T) {
+	handler := &TaskHandler{llmSem: make(chan struct{}, 1)}
+
+	release, err := handler.acquireLLMSlot(context.Background())
+	if err != nil {
+		t.Fatalf("acquireLLMSlot() returned error: %v", err)
+	}
+
+	// 名额已满时，取消的上下文不应一直阻塞
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := handler.acquireLLMSlot(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// 释放后可以再次获取
+	release()
+	release, err = handler.acquireLLMSlot(context.Background())
+	if err != nil {
+		t.Fatalf("acquireLLMSlot() after release returned error: %v", err)
+	}
+	release()
+
+	// 未配置并发上限时不限制
+	unlimited := &TaskHandler{}
+	for i := 0; i < 3; i++ {
+		if _, err := unlimited.acquireLLMSlot(context.Background()); err != nil {
+			t.Errorf("acquireLLMSlot() without limit returned error: %v", err)
+		}
+	}
+}
+
+func TestTaskHandler_StartHeartbeat(t *testing.T) {
+	// 未配置间隔时不启动心跳
+	noop := (&TaskHandler{}).startHeartbeat(context.Background(), task.LLMPayload{}, "calling LLM")
+	noop()
+
+	// 创建测试数据库
+	testDB, db := setupTestDB(t)
+	defer db.Close()
+
+	// 设置测试数据
+	setupTestData(t, db)
+
+	cfg := *testConfig
+	cfg.Queue.ProgressInterval = 20 * time.Millisecond
+	handler := NewTaskHandler(testDB, &cfg)
+
+	stop := handler.startHeartbeat(context.Background(), task.LLMPayload{TableName: "test_table", ID: 123}, "calling LLM")
+	time.Sleep(70 * time.Millisecond)
+	stop()
+
+	var progress string
+	if err := db.Get(&progress, "SELECT progress FROM test_table WHERE id = 123"); err != nil {
+		t.Fatalf("Failed to query progress: %v", err)
+	}
+	if progress != "calling LLM" {
+		t.Errorf("Expected progress %q, got %q", "calling LLM", progress)
+	}
+}
+
+func TestObserveTaskWait(t *testing.T) {
+	const queue = "wait_test"
+
+	sampleCount := func() uint64 {
+		var m dto.Metric
+		histogram := metrics.TaskWaitDuration.WithLabelValues(queue, task.TypeLLM).(prometheus.Metric)
+		if err := histogram.Write(&m); err != nil {
+			t.Fatalf("Failed to read histogram: %v", err)
+		}
+		return m.GetHistogram().GetSampleCount()
+	}
+
+	// 首次处理时记录等待时间
+	enqueuedAt := time.Now().Add(-2 * time.Second).UnixMilli()
+	observeTaskWait(context.Background(), queue, task.LLMPayload{EnqueuedAt: enqueuedAt})
+	if got := sampleCount(); got != 1 {
+		t.Fatalf("Expected 1 observation, got %d", got)
+	}
+
+	var m dto.Metric
+	_ = metrics.TaskWaitDuration.WithLabelValues(queue, task.TypeLLM).(prometheus.Metric).Write(&m)
+	if sum := m.GetHistogram().GetSampleSum(); sum < 2 {
+		t.Errorf("Expected wait of at least 2s, got %v", sum)
+	}
+
+	// 没有入队时间的旧任务不统计
+	observeTaskWait(context.Background(), queue, task.LLMPayload{})
+	if got := sampleCount(); got != 1 {
+		t.Errorf("Expected payload without enqueue time to be skipped, got %d observations", got)
+	}
+}
+
+func TestTaskLogFields(t *testing.T) {
+	p := task.LLMPayload{TableName: "test_table", ID: 123}
+	start := time.Now().Add(-50 * time.Millisecond)
+
+	keys := func(fields []zap.Field) map[string]zap.Field {
+		m := make(map[string]zap.Field, len(fields))
+		for _, f := range fields {
+			m[f.Key] = f
+		}
+		return m
+	}
+
+	// 成功时包含 token 用量
+	fields := keys(taskLogFields(context.Background(), p, "deepseek-chat", start, &llmUsage{TotalTokens: 42}))
+	for _, key := range []string{"model", "table_name", "record_id", "duration_ms", "tokens"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("Expected field %q to be present", key)
+		}
+	}
+	if fields["model"].String != "deepseek-chat" {
+		t.Errorf("Expected model %q, got %q", "deepseek-chat", fields["model"].String)
+	}
+	if fields["tokens"].Integer != 42 {
+		t.Errorf("Expected tokens 42, got %d", fields["tokens"].Integer)
+	}
+	if fields["duration_ms"].Integer < 50 {
+		t.Errorf("Expected duration of at least 50ms, got %d", fields["duration_ms"].Integer)
+	}
+
+	// 没有用量时不输出 tokens
+	fields = keys(taskLogFields(context.Background(), p, "deepseek-chat", start, nil))
+	if _, ok := fields["tokens"]; ok {
+		t.Error("Expected tokens field to be omitted without usage")
 	}
 }
@@ -0,0 +1,44 @@
+package worker
+
+import (
+	"github.com/igwen6w/syt-go-queue/internal/logger"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// reservedHeaders 由程序设置的请求头，不允许通过配置覆盖
+var reservedHeaders = map[string]bool{
+	"Authorization": true,
+	"Content-Type":  true,
+}
+
+// customHeaders 将配置的自定义请求头转换为 http.Header。
+// 试图覆盖保留请求头的配置会被忽略并记录警告。
+//
+// 参数:
+//   - target: 请求头的使用方，用于日志
+//   - headers: 配置的自定义请求头
+//
+// 返回:
+//   - 可以附加到请求上的请求头
+func customHeaders(target string, headers map[string]string) http.Header {
+	result := make(http.Header, len(headers))
+	for name, value := range headers {
+		key := http.CanonicalHeaderKey(name)
+		if reservedHeaders[key] {
+			logger.Warn("Ignoring custom header that would override a reserved header",
+				zap.String("target", target),
+				zap.String("header", key))
+			continue
+		}
+		result.Set(key, value)
+	}
+	return result
+}
+
+// applyHeaders 将自定义请求头附加到请求上
+func applyHeaders(req *http.Request, headers http.Header) {
+	for key, values := range headers {
+		req.Header[key] = values
+	}
+}
@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCustomHeaders(t *testing.T) {
+	headers := customHeaders("llm", map[string]string{
+		"user-agent":    "syt-go-queue/1.0",
+		"X-Route":       "premium",
+		"authorization": "Bearer override",
+		"Content-Type":  "text/plain",
+	})
+
+	if got := headers.Get("User-Agent"); got != "syt-go-queue/1.0" {
+		t.Errorf("Expected User-Agent %q, got %q", "syt-go-queue/1.0", got)
+	}
+	if got := headers.Get("X-Route"); got != "premium" {
+		t.Errorf("Expected X-Route %q, got %q", "premium", got)
+	}
+	if _, ok := headers["Authorization"]; ok {
+		t.Errorf("Expected Authorization override to be ignored")
+	}
+	if _, ok := headers["Content-Type"]; ok {
+		t.Errorf("Expected Content-Type override to be ignored")
+	}
+
+	req, _ := http.NewRequest("POST", "http://example.com", nil)
+	req.Header.Set("Authorization", "Bearer key")
+	applyHeaders(req, headers)
+	if got := req.Header.Get("Authorization"); got != "Bearer key" {
+		t.Errorf("Expected Authorization to be preserved, got %q", got)
+	}
+	if got := req.Header.Get("X-Route"); got != "premium" {
+		t.Errorf("Expected X-Route %q, got %q", "premium", got)
+	}
+}
@@ -0,0 +1,43 @@
+package worker
+
+import (
+	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/task"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// validateLLMRequest 在调用 API 前检查组装好的请求体：模型不能为空，消息不能为空，
+// 每条消息都要有角色，max_tokens 为正数且不超过 model_max_tokens 中该模型的上限。
+// 请求不合法时重试也不会成功，返回包含 asynq.SkipRetry 的错误，错误信息写入记录的 failed_info，
+// 比服务端返回的 400 更容易定位是哪项配置或数据有问题
+func (h *TaskHandler) validateLLMRequest(payload map[string]interface{}) error {
+	model, _ := payload["model"].(string)
+	if strings.TrimSpace(model) == "" {
+		return invalidLLMRequest("model is empty")
+	}
+
+	messages, _ := payload["messages"].([]task.Message)
+	if len(messages) == 0 {
+		return invalidLLMRequest("messages are empty")
+	}
+	for i, message := range messages {
+		if message.Role == "" {
+			return invalidLLMRequest("messages[%d] has no role", i)
+		}
+	}
+
+	maxTokens, _ := payload["max_tokens"].(int)
+	if maxTokens <= 0 {
+		return invalidLLMRequest("max_tokens must be positive, got %d", maxTokens)
+	}
+	if limit := h.deepseek.MaxTokensLimit(model); limit > 0 && maxTokens > limit {
+		return invalidLLMRequest("max_tokens %d exceeds the limit of %d for model %s", maxTokens, limit, model)
+	}
+
+	return nil
+}
+
+func invalidLLMRequest(format string, args ...interface{}) error {
+	return errors.Wrapf(asynq.SkipRetry, "invalid LLM request: "+format, args...)
+}
@@ -0,0 +1,96 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/igwen6w/syt-go-queue/internal/database"
+	"github.com/igwen6w/syt-go-queue/internal/task"
+	"strings"
+	"testing"
+)
+
+func TestTaskHandler_ValidateLLMRequest(t *testing.T) {
+	handler := &TaskHandler{deepseek: config.DeepseekConfig{
+		Model:          "deepseek-chat",
+		MaxTokens:      2000,
+		ModelMaxTokens: map[string]int{"deepseek-chat": 8192},
+	}}
+	messages := task.BuildMessages("system", "user")
+
+	tests := []struct {
+		name    string
+		payload map[string]interface{}
+		wantErr string
+	}{
+		{
+			name:    "valid request",
+			payload: map[string]interface{}{"model": "deepseek-chat", "messages": messages, "max_tokens": 8192},
+		},
+		{
+			name:    "model without limit",
+			payload: map[string]interface{}{"model": "other-model", "messages": messages, "max_tokens": 100000},
+		},
+		{
+			name:    "empty model",
+			payload: map[string]interface{}{"model": "", "messages": messages, "max_tokens": 100},
+			wantErr: "model is empty",
+		},
+		{
+			name:    "no messages",
+			payload: map[string]interface{}{"model": "deepseek-chat", "messages": []task.Message{}, "max_tokens": 100},
+			wantErr: "messages are empty",
+		},
+		{
+			name:    "message without role",
+			payload: map[string]interface{}{"model": "deepseek-chat", "messages": []task.Message{{Content: "hi"}}, "max_tokens": 100},
+			wantErr: "messages[0] has no role",
+		},
+		{
+			name:    "zero max tokens",
+			payload: map[string]interface{}{"model": "deepseek-chat", "messages": messages, "max_tokens": 0},
+			wantErr: "max_tokens must be positive",
+		},
+		{
+			name:    "max tokens over limit",
+			payload: map[string]interface{}{"model": "deepseek-chat", "messages": messages, "max_tokens": 8193},
+			wantErr: "max_tokens 8193 exceeds the limit of 8192 for model deepseek-chat",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := handler.validateLLMRequest(tt.payload)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateLLMRequest() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("validateLLMRequest() error = %v, want %q", err, tt.wantErr)
+			}
+			if !errors.Is(err, asynq.SkipRetry) {
+				t.Errorf("validateLLMRequest() error should skip retries, got %v", err)
+			}
+		})
+	}
+}
+
+func TestTaskHandler_ProcessLLM_InvalidRequest(t *testing.T) {
+	// 请求不合法时不调用 API，client 为 nil 时调用会直接 panic
+	handler := &TaskHandler{deepseek: config.DeepseekConfig{
+		Model:          "deepseek-chat",
+		MaxTokens:      2000,
+		ModelMaxTokens: map[string]int{"deepseek-chat": 8192},
+	}}
+
+	_, err := handler.processLLM(context.Background(), &database.ValuationRecord{ID: 1, UserMessage: "hi"}, task.LLMPayload{MaxTokens: 10000})
+	if err == nil || !strings.Contains(err.Error(), "exceeds the limit") {
+		t.Fatalf("processLLM() error = %v, want max_tokens limit error", err)
+	}
+	if !errors.Is(err, asynq.SkipRetry) {
+		t.Errorf("processLLM() error should skip retries, got %v", err)
+	}
+}
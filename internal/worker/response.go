@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+)
+
+// extractJSONPath 将 LLM 回复解析为 JSON，并按路径取出字段作为报告。
+// 路径以 . 分隔，对象按键名取值，数组按下标取值，如 result.items.0.text。
+// 回复被 Markdown 代码块包裹时先去掉代码块标记。
+// 取出的值为字符串时原样返回，其他类型重新编码为 JSON
+//
+// 参数:
+//   - content: LLM 回复的内容
+//   - path: 要取出的字段路径
+//
+// 返回:
+//   - 取出的字段内容
+//   - 回复不是合法 JSON 或路径不存在时返回错误
+func extractJSONPath(content, path string) (string, error) {
+	var value interface{}
+	if err := json.Unmarshal([]byte(stripCodeFence(content)), &value); err != nil {
+		return "", errors.Wrap(err, "LLM response is not valid JSON")
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, ok := v[key]
+			if !ok {
+				return "", errors.Errorf("field %q not found in LLM response (path %s)", key, path)
+			}
+			value = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return "", errors.Errorf("index %q out of range in LLM response (path %s)", key, path)
+			}
+			value = v[i]
+		default:
+			return "", errors.Errorf("cannot read %q from a non-container value in LLM response (path %s)", key, path)
+		}
+	}
+
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	out, err := json.Marshal(value)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode extracted field")
+	}
+	return string(out), nil
+}
+
+// stripCodeFence 去掉回复首尾的空白和 Markdown 代码块标记，如 ```json ... ```
+func stripCodeFence(content string) string {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "```") || !strings.HasSuffix(content, "```") || len(content) < 6 {
+		return content
+	}
+	content = strings.TrimSuffix(content[3:], "```")
+	// 去掉代码块的语言标记
+	if i := strings.IndexByte(content, '\n'); i >= 0 {
+		content = content[i+1:]
+	}
+	return strings.TrimSpace(content)
+}
@@ -0,0 +1,83 @@
+package worker
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractJSONPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		path    string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "string field",
+			content: `{"report": "估值结果"}`,
+			path:    "report",
+			want:    "估值结果",
+		},
+		{
+			name:    "nested field with array index",
+			content: `{"result": {"items": [{"text": "a"}, {"text": "b"}]}}`,
+			path:    "result.items.1.text",
+			want:    "b",
+		},
+		{
+			name:    "non-string value is re-encoded",
+			content: `{"result": {"price": 100, "tags": ["x"]}}`,
+			path:    "result",
+			want:    `{"price":100,"tags":["x"]}`,
+		},
+		{
+			name:    "code fence",
+			content: "```json\n{\"report\": \"ok\"}\n```",
+			path:    "report",
+			want:    "ok",
+		},
+		{
+			name:    "invalid JSON",
+			content: "not json",
+			path:    "report",
+			wantErr: "not valid JSON",
+		},
+		{
+			name:    "missing field",
+			content: `{"report": "ok"}`,
+			path:    "summary",
+			wantErr: `field "summary" not found`,
+		},
+		{
+			name:    "index out of range",
+			content: `{"items": []}`,
+			path:    "items.0",
+			wantErr: `index "0" out of range`,
+		},
+		{
+			name:    "path through scalar",
+			content: `{"report": "ok"}`,
+			path:    "report.text",
+			wantErr: "non-container value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractJSONPath(tt.content, tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("extractJSONPath() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractJSONPath() returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("extractJSONPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
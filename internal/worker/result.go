@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/logger"
+	"github.com/igwen6w/syt-go-queue/internal/task"
+	"go.uber.org/zap"
+	"unicode/utf8"
+)
+
+// DefaultTaskResultMaxBytes 未配置 queue.task_result_max_bytes 时写入任务结果的报告字节数上限
+const DefaultTaskResultMaxBytes = 64 << 10
+
+// writeTaskResult 将报告写入 asynq 任务结果，API 查询任务状态时直接从 Redis 返回。
+// 写入失败不影响任务完成，只记录日志
+func (h *TaskHandler) writeTaskResult(ctx context.Context, t *asynq.Task, report string) {
+	limit := h.queue.TaskResultMaxBytes
+	if limit < 0 || t.ResultWriter() == nil {
+		return
+	}
+	if limit == 0 {
+		limit = DefaultTaskResultMaxBytes
+	}
+
+	data, err := json.Marshal(newLLMResult(report, limit))
+	if err == nil {
+		_, err = t.ResultWriter().Write(data)
+	}
+	if err != nil {
+		logger.Warn("Failed to write task result",
+			logger.RequestIDField(ctx),
+			zap.String("task_id", t.ResultWriter().TaskID()),
+			zap.Error(err))
+	}
+}
+
+// newLLMResult 返回写入任务结果的内容，报告超过 limit 字节时在字符边界处截断
+func newLLMResult(report string, limit int) task.LLMResult {
+	if len(report) <= limit {
+		return task.LLMResult{Report: report}
+	}
+
+	n := limit
+	for n > 0 && !utf8.RuneStart(report[n]) {
+		n--
+	}
+	return task.LLMResult{Report: report[:n], Truncated: true}
+}
@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"context"
+	"github.com/hibiken/asynq"
+	"testing"
+)
+
+func TestNewLLMResult(t *testing.T) {
+	tests := []struct {
+		name          string
+		report        string
+		limit         int
+		wantReport    string
+		wantTruncated bool
+	}{
+		{name: "within limit", report: "report", limit: 10, wantReport: "report"},
+		{name: "exactly at limit", report: "report", limit: 6, wantReport: "report"},
+		{name: "over limit", report: "long report", limit: 4, wantReport: "long", wantTruncated: true},
+		// "估值" 每个字 3 字节，截断不能落在字符中间
+		{name: "multibyte boundary", report: "估值报告", limit: 7, wantReport: "估值", wantTruncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newLLMResult(tt.report, tt.limit)
+			if got.Report != tt.wantReport || got.Truncated != tt.wantTruncated {
+				t.Errorf("newLLMResult() = %+v, want report %q truncated %v", got, tt.wantReport, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestTaskHandler_WriteTaskResult_NoWriter(t *testing.T) {
+	// 未经 asynq 服务端分发的任务没有结果写入器，不应 panic
+	handler := &TaskHandler{}
+	handler.writeTaskResult(context.Background(), asynq.NewTask("llm:process", nil), "report")
+}
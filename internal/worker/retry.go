@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// 未配置 queue.retry_transient_delay 和 queue.retry_rate_limit_delay 时的重试基础延迟
+const (
+	DefaultRetryTransientDelay = 10 * time.Second
+	DefaultRetryRateLimitDelay = 5 * time.Minute
+)
+
+// 任务错误的重试类别，由 ClassifiedRetryDelayFunc 按类别选择重试延迟。
+// 不能重试的错误（如载荷无法解析、表名不合法）包含 asynq.SkipRetry，直接归档
+var (
+	// ErrTransient 网络错误或超时，通常很快恢复，使用较短的重试延迟
+	ErrTransient = errors.New("transient error")
+	// ErrRateLimited LLM API 返回 429，使用较长的重试延迟，且不短于 Retry-After
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// classifiedError 为错误附加重试类别，错误信息不变，
+// errors.Is 可以同时匹配类别和原始错误
+type classifiedError struct {
+	class error
+	err   error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.class, e.err}
+}
+
+// classify 返回附加了重试类别的错误，err 为 nil 时返回 nil
+func classify(class, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{class: class, err: err}
+}
+
+// ClassifiedRetryDelayFunc 返回按错误类别选择延迟的重试延迟函数：
+// ErrTransient 以 retry_transient_delay 为基础延迟，ErrRateLimited 以 retry_rate_limit_delay 为基础延迟，
+// 并且不短于响应头 Retry-After 要求的时间，其他错误以 retry_base_delay 为基础延迟。
+// 各类别都按 RetryDelayFunc 指数增长并加入随机抖动，不超过 retry_max_delay
+func ClassifiedRetryDelayFunc(cfg config.QueueConfig) asynq.RetryDelayFunc {
+	transientBase := cfg.RetryTransientDelay
+	if transientBase <= 0 {
+		transientBase = DefaultRetryTransientDelay
+	}
+	rateLimitBase := cfg.RetryRateLimitDelay
+	if rateLimitBase <= 0 {
+		rateLimitBase = DefaultRetryRateLimitDelay
+	}
+
+	transient := RetryDelayFunc(transientBase, cfg.RetryMaxDelay)
+	rateLimited := RetryDelayFunc(rateLimitBase, cfg.RetryMaxDelay)
+	other := RetryDelayFunc(cfg.RetryBaseDelay, cfg.RetryMaxDelay)
+
+	return func(n int, err error, t *asynq.Task) time.Duration {
+		switch {
+		case errors.Is(err, ErrRateLimited):
+			delay := rateLimited(n, err, t)
+			var limited *rateLimitedError
+			if errors.As(err, &limited) && limited.retryAfter > delay {
+				delay = limited.retryAfter
+			}
+			return delay
+		case errors.Is(err, ErrTransient):
+			return transient(n, err, t)
+		default:
+			return other(n, err, t)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"context"
+	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/pkg/errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClassifiedRetryDelayFunc(t *testing.T) {
+	delayFunc := ClassifiedRetryDelayFunc(config.QueueConfig{
+		RetryBaseDelay:      time.Minute,
+		RetryMaxDelay:       time.Hour,
+		RetryTransientDelay: 5 * time.Second,
+		RetryRateLimitDelay: 10 * time.Minute,
+	})
+
+	tests := []struct {
+		name     string
+		err      error
+		min, max time.Duration
+	}{
+		{
+			name: "transient",
+			err:  errors.Wrap(classify(ErrTransient, errors.New("connection refused")), "failed to process LLM"),
+			min:  2500 * time.Millisecond,
+			max:  5 * time.Second,
+		},
+		{
+			name: "rate limited",
+			err:  errors.Wrap(&rateLimitedError{body: "slow down"}, "failed to process LLM"),
+			min:  5 * time.Minute,
+			max:  10 * time.Minute,
+		},
+		{
+			name: "rate limited with longer Retry-After",
+			err:  errors.Wrap(&rateLimitedError{retryAfter: 20 * time.Minute}, "failed to process LLM"),
+			min:  20 * time.Minute,
+			max:  20 * time.Minute,
+		},
+		{
+			name: "other",
+			err:  errors.New("LLM API request failed with status: 500"),
+			min:  30 * time.Second,
+			max:  time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 50; i++ {
+			if delay := delayFunc(0, tt.err, nil); delay < tt.min || delay > tt.max {
+				t.Fatalf("%s: delay %v out of bounds [%v, %v]", tt.name, delay, tt.min, tt.max)
+			}
+		}
+	}
+
+	// 未配置时使用默认的基础延迟
+	delay := ClassifiedRetryDelayFunc(config.QueueConfig{})(0, classify(ErrTransient, errors.New("timeout")), nil)
+	if delay < DefaultRetryTransientDelay/2 || delay > DefaultRetryTransientDelay {
+		t.Errorf("Expected default transient delay within [%v, %v], got %v", DefaultRetryTransientDelay/2, DefaultRetryTransientDelay, delay)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	original := errors.New("failed to send LLM API request: connection refused")
+	err := classify(ErrTransient, original)
+
+	if err.Error() != original.Error() {
+		t.Errorf("Expected classified error to keep the message %q, got %q", original.Error(), err.Error())
+	}
+	if !errors.Is(err, ErrTransient) || !errors.Is(err, original) {
+		t.Error("Expected classified error to match both the class and the original error")
+	}
+	if classify(ErrTransient, nil) != nil {
+		t.Error("Expected classify to return nil for a nil error")
+	}
+}
+
+func TestTaskHandler_CallLLM_ErrorClass(t *testing.T) {
+	handler := NewTaskHandler(nil, testConfig)
+
+	// 连接失败属于网络错误
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closedURL := closed.URL
+	closed.Close()
+	if _, err := handler.callLLM(context.Background(), closedURL, "key", []byte("{}")); !errors.Is(err, ErrTransient) {
+		t.Errorf("Expected network error to be classified as transient, got %v", err)
+	}
+
+	// 429 属于限流
+	limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer limited.Close()
+	_, err := handler.callLLM(context.Background(), limited.URL, "key", []byte("{}"))
+	if !errors.Is(err, ErrRateLimited) || errors.Is(err, ErrTransient) {
+		t.Errorf("Expected 429 to be classified as rate limited only, got %v", err)
+	}
+}
+
+func TestTaskHandler_HandleLLMTask_InvalidPayload(t *testing.T) {
+	handler := NewTaskHandler(nil, testConfig)
+
+	// 载荷无法解析时重试也不会成功，直接归档
+	err := handler.HandleLLMTask(context.Background(), asynq.NewTask("llm:process", []byte("not json")))
+	if !errors.Is(err, asynq.SkipRetry) {
+		t.Errorf("Expected SkipRetry for an invalid payload, got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package worker
+
+import (
+	"context"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/igwen6w/syt-go-queue/internal/database"
+	"github.com/igwen6w/syt-go-queue/internal/logger"
+	"github.com/igwen6w/syt-go-queue/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// DefaultStatusInterval 是未配置 metrics.status_interval 时按状态统计记录数的间隔
+const DefaultStatusInterval = time.Minute
+
+// statusCounter 是按状态统计记录数所需的数据库方法
+type statusCounter interface {
+	CountRecordsByStatus(ctx context.Context, tableName string) (map[string]int64, error)
+}
+
+// StatusReporter 定期按状态统计配置的表中的记录数，写入 records_by_status 指标，
+// 便于在业务层面观察处理中、已完成和失败的记录数量
+type StatusReporter struct {
+	db       statusCounter
+	tables   []string
+	interval time.Duration
+
+	mu     sync.Mutex
+	cancel context.CancelFunc // Start 后用于停止统计
+}
+
+// NewStatusReporter 创建并返回一个新的状态统计器实例。
+//
+// 参数:
+//   - cfg: 指标配置，包含需要统计的表和统计间隔
+//   - db: 数据库实例，配置了只读副本时在副本上查询
+//
+// 返回:
+//   - 配置好的状态统计器实例
+func NewStatusReporter(cfg config.MetricsConfig, db *database.Database) *StatusReporter {
+	interval := cfg.StatusInterval
+	if interval <= 0 {
+		interval = DefaultStatusInterval
+	}
+	return &StatusReporter{
+		db:       db,
+		tables:   cfg.StatusTables,
+		interval: interval,
+	}
+}
+
+// Start 立即统计一次，然后在后台定期统计，直到调用 Stop
+func (r *StatusReporter) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.mu.Lock()
+	r.cancel = cancel
+	r.mu.Unlock()
+
+	go r.run(ctx)
+}
+
+// Stop 停止统计，进行中的查询会被取消
+func (r *StatusReporter) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		r.cancel()
+	}
+}
+
+// run 按间隔执行统计，ctx 取消后退出
+func (r *StatusReporter) run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	r.Report(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Report(ctx)
+		}
+	}
+}
+
+// Report 对每个配置的表统计一次各状态的记录数并更新指标。
+// 表中已不存在的状态会从指标中移除；单个表查询失败时保留该表上一次的结果，不影响其他表
+func (r *StatusReporter) Report(ctx context.Context) {
+	for _, table := range r.tables {
+		counts, err := r.db.CountRecordsByStatus(ctx, table)
+		if err != nil {
+			logger.Error("Failed to count records by status",
+				zap.String("table_name", table),
+				zap.Error(err))
+			continue
+		}
+
+		metrics.RecordsByStatus.DeletePartialMatch(prometheus.Labels{"table": table})
+		for status, count := range counts {
+			metrics.RecordsByStatus.WithLabelValues(table, status).Set(float64(count))
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/igwen6w/syt-go-queue/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"testing"
+)
+
+// fakeStatusCounter 按表返回预设的统计结果，表不存在时返回错误
+type fakeStatusCounter struct {
+	counts map[string]map[string]int64
+}
+
+func (f *fakeStatusCounter) CountRecordsByStatus(ctx context.Context, tableName string) (map[string]int64, error) {
+	counts, ok := f.counts[tableName]
+	if !ok {
+		return nil, errors.New("table not found")
+	}
+	return counts, nil
+}
+
+// statusGauges 返回指标中某个表每种状态的记录数
+func statusGauges(t *testing.T, table string) map[string]float64 {
+	t.Helper()
+	ch := make(chan prometheus.Metric, 16)
+	metrics.RecordsByStatus.Collect(ch)
+	close(ch)
+
+	values := make(map[string]float64)
+	for metric := range ch {
+		var m dto.Metric
+		if err := metric.Write(&m); err != nil {
+			t.Fatalf("Failed to read gauge: %v", err)
+		}
+		labels := make(map[string]string)
+		for _, label := range m.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		if labels["table"] == table {
+			values[labels["status"]] = m.GetGauge().GetValue()
+		}
+	}
+	return values
+}
+
+func TestStatusReporter_Report(t *testing.T) {
+	db := &fakeStatusCounter{counts: map[string]map[string]int64{
+		"status_test_table": {config.DefaultStatusProcessing: 2, config.DefaultStatusCompleted: 5, config.DefaultStatusFailed: 1},
+	}}
+	reporter := &StatusReporter{db: db, tables: []string{"status_test_table", "missing_table"}}
+
+	reporter.Report(context.Background())
+	got := statusGauges(t, "status_test_table")
+	if len(got) != 3 || got[config.DefaultStatusProcessing] != 2 || got[config.DefaultStatusCompleted] != 5 || got[config.DefaultStatusFailed] != 1 {
+		t.Errorf("Unexpected records_by_status values: %v", got)
+	}
+
+	// 已不存在的状态从指标中移除
+	db.counts["status_test_table"] = map[string]int64{config.DefaultStatusCompleted: 8}
+	reporter.Report(context.Background())
+	got = statusGauges(t, "status_test_table")
+	if len(got) != 1 || got[config.DefaultStatusCompleted] != 8 {
+		t.Errorf("Expected only %s=8 after the other statuses disappeared, got %v", config.DefaultStatusCompleted, got)
+	}
+
+	// 查询失败的表不写入指标
+	if got := statusGauges(t, "missing_table"); len(got) != 0 {
+		t.Errorf("Expected no values for a table that failed to query, got %v", got)
+	}
+}
@@ -0,0 +1,178 @@
+package worker
+
+import (
+	"context"
+	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/igwen6w/syt-go-queue/internal/database"
+	"github.com/igwen6w/syt-go-queue/internal/logger"
+	"github.com/igwen6w/syt-go-queue/internal/metrics"
+	"github.com/igwen6w/syt-go-queue/internal/task"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// DefaultClaimSweepInterval 是未配置 queue.claim_sweep_interval 时的巡检间隔
+const DefaultClaimSweepInterval = time.Minute
+
+// sweepBatchSize 每次巡检单个表最多恢复的记录数
+const sweepBatchSize = 100
+
+// taskEnqueuer 是巡检重新入队所需的 asynq.Client 方法
+type taskEnqueuer interface {
+	Enqueue(task *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error)
+	Close() error
+}
+
+// Sweeper 定期查找认领时间超过有效期仍处于处理中的记录，
+// 将其重置为待处理并重新入队，用于恢复工作者崩溃时遗留的记录。
+// 只有认领时写入了 processing_started_at 的记录才会被找到
+type Sweeper struct {
+	db       *database.Database
+	client   taskEnqueuer
+	queue    config.QueueConfig
+	interval time.Duration
+
+	mu     sync.Mutex
+	cancel context.CancelFunc // Start 后用于停止巡检
+}
+
+// NewSweeper 创建并返回一个新的巡检器实例。
+//
+// 参数:
+//   - cfg: 队列配置，包含认领有效期、巡检间隔和需要巡检的表
+//   - db: 数据库实例，应启用认领时间（WithClaimTime）
+//   - client: 重新入队使用的 asynq 客户端，巡检停止时关闭
+//
+// 返回:
+//   - 配置好的巡检器实例
+func NewSweeper(cfg config.QueueConfig, db *database.Database, client taskEnqueuer) *Sweeper {
+	interval := cfg.ClaimSweepInterval
+	if interval <= 0 {
+		interval = DefaultClaimSweepInterval
+	}
+	return &Sweeper{
+		db:       db,
+		client:   client,
+		queue:    cfg,
+		interval: interval,
+	}
+}
+
+// Start 在后台启动定期巡检，直到调用 Stop
+func (s *Sweeper) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.cancel = cancel
+	s.mu.Unlock()
+
+	go s.run(ctx)
+}
+
+// Stop 停止巡检，进行中的巡检会被取消
+func (s *Sweeper) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
+
+// run 按间隔执行巡检，ctx 取消后关闭客户端并退出
+func (s *Sweeper) run(ctx context.Context) {
+	defer func() {
+		if err := s.client.Close(); err != nil {
+			logger.Warn("Failed to close sweeper client", zap.Error(err))
+		}
+	}()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Sweep(ctx)
+		}
+	}
+}
+
+// Sweep 对每个配置的表执行一次巡检，返回成功恢复的记录数。
+// 单个表或记录出错时只记录日志，不影响其他表和记录
+func (s *Sweeper) Sweep(ctx context.Context) int {
+	before := time.Now().Add(-s.queue.ClaimTTL)
+	processing := s.queue.RecordStatuses().Processing
+	recovered := 0
+
+	for _, table := range s.queue.ClaimSweepTables {
+		ids, err := s.db.ListStuckRecordIDs(ctx, table, processing, before, sweepBatchSize)
+		if err != nil {
+			logger.Error("Failed to list stuck records",
+				zap.String("table_name", table),
+				zap.Error(err))
+			continue
+		}
+		metrics.StuckRecords.WithLabelValues(table).Set(float64(len(ids)))
+
+		for _, id := range ids {
+			if err := s.recover(ctx, table, id); err != nil {
+				logger.Error("Failed to recover stuck record",
+					zap.String("table_name", table),
+					zap.Int64("record_id", id),
+					zap.Error(err))
+				continue
+			}
+			recovered++
+		}
+	}
+
+	return recovered
+}
+
+// recover 将处理中的记录重置为待处理并重新入队。
+// 重置以记录仍处于处理中为条件，避免覆盖刚刚完成的结果；
+// 入队失败时恢复处理中状态，下次巡检会再次尝试
+func (s *Sweeper) recover(ctx context.Context, table string, id int64) error {
+	statuses := s.queue.RecordStatuses()
+	rows, err := s.db.UpdateRecordIf(ctx, table, id,
+		map[string]interface{}{"status": statuses.Processing},
+		map[string]interface{}{"status": statuses.Pending})
+	if err != nil {
+		return errors.Wrap(err, "failed to reset record status")
+	}
+	if rows == 0 {
+		// 记录在查询后已被处理完成或重置
+		return nil
+	}
+
+	t, err := task.NewLLMTask(task.LLMPayload{TableName: table, ID: id})
+	if err != nil {
+		return errors.Wrap(err, "failed to create task")
+	}
+
+	opts := []asynq.Option{asynq.Queue(s.queue.QueueFor(task.TypeLLM))}
+	if s.queue.Retention > 0 {
+		opts = append(opts, asynq.Retention(s.queue.Retention))
+	}
+	info, err := s.client.Enqueue(t, opts...)
+	if err != nil {
+		if restoreErr := s.db.UpdateStatus(ctx, table, id, statuses.Processing); restoreErr != nil {
+			logger.Warn("Failed to restore stuck record status",
+				zap.String("table_name", table),
+				zap.Int64("record_id", id),
+				zap.Error(restoreErr))
+		}
+		return errors.Wrap(err, "failed to enqueue task")
+	}
+
+	logger.Warn("Recovered record stuck in processing",
+		zap.String("table_name", table),
+		zap.Int64("record_id", id),
+		zap.String("task_id", info.ID),
+		zap.Duration("claim_ttl", s.queue.ClaimTTL))
+	return nil
+}
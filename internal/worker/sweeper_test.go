@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/hibiken/asynq"
+	"github.com/igwen6w/syt-go-queue/internal/config"
+	"github.com/igwen6w/syt-go-queue/internal/task"
+	"github.com/pkg/errors"
+	"testing"
+	"time"
+)
+
+// fakeEnqueuer 记录入队的任务，err 不为空时入队失败
+type fakeEnqueuer struct {
+	tasks []*asynq.Task
+	err   error
+}
+
+func (f *fakeEnqueuer) Enqueue(t *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.tasks = append(f.tasks, t)
+	return &asynq.TaskInfo{ID: "task-1"}, nil
+}
+
+func (f *fakeEnqueuer) Close() error {
+	return nil
+}
+
+func TestSweeper_Sweep(t *testing.T) {
+	testDB, db := setupTestDB(t)
+	defer db.Close()
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS sweep_test_table (
+			id INT PRIMARY KEY,
+			status VARCHAR(50),
+			processing_started_at DATETIME NULL
+		)
+	`); err != nil {
+		t.Fatalf("Failed to create test table: %v", err)
+	}
+	if _, err := db.Exec("DELETE FROM sweep_test_table"); err != nil {
+		t.Fatalf("Failed to clean test data: %v", err)
+	}
+
+	// 1: 认领已超过有效期；2: 刚刚认领；3: 没有认领时间；4: 已完成
+	now := time.Now()
+	if _, err := db.Exec(`
+		INSERT INTO sweep_test_table (id, status, processing_started_at) VALUES
+		(1, '处理中', ?), (2, '处理中', ?), (3, '处理中', NULL), (4, '已完成', ?)
+	`, now.Add(-time.Hour), now, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+
+	cfg := config.QueueConfig{ClaimTTL: 10 * time.Minute, ClaimSweepTables: []string{"sweep_test_table"}}
+
+	// 入队失败时恢复处理中状态，留给下次巡检
+	client := &fakeEnqueuer{err: errors.New("redis unavailable")}
+	sweeper := NewSweeper(cfg, testDB.WithClaimTime(), client)
+	if recovered := sweeper.Sweep(context.Background()); recovered != 0 {
+		t.Errorf("Expected no records recovered when enqueue fails, got %d", recovered)
+	}
+
+	statusOf := func(id int64) string {
+		var status string
+		if err := db.Get(&status, "SELECT status FROM sweep_test_table WHERE id = ?", id); err != nil {
+			t.Fatalf("Failed to query status: %v", err)
+		}
+		return status
+	}
+	if status := statusOf(1); status != config.DefaultStatusProcessing {
+		t.Errorf("Expected status %q after failed enqueue, got %q", config.DefaultStatusProcessing, status)
+	}
+
+	client.err = nil
+	if recovered := sweeper.Sweep(context.Background()); recovered != 1 {
+		t.Fatalf("Expected 1 record recovered, got %d", recovered)
+	}
+	if len(client.tasks) != 1 {
+		t.Fatalf("Expected 1 task enqueued, got %d", len(client.tasks))
+	}
+
+	var p task.LLMPayload
+	if err := json.Unmarshal(client.tasks[0].Payload(), &p); err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	if p.TableName != "sweep_test_table" || p.ID != 1 {
+		t.Errorf("Expected task for sweep_test_table/1, got %s/%d", p.TableName, p.ID)
+	}
+
+	expected := map[int64]string{1: config.DefaultStatusPending, 2: config.DefaultStatusProcessing, 3: config.DefaultStatusProcessing, 4: config.DefaultStatusCompleted}
+	for id, want := range expected {
+		if status := statusOf(id); status != want {
+			t.Errorf("Record %d: expected status %q, got %q", id, want, status)
+		}
+	}
+}
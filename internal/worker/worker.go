@@ -3,6 +3,7 @@
 package worker
 
 import (
+	"context"
 	"fmt"
 	"github.com/hibiken/asynq"
 	"github.com/igwen6w/syt-go-queue/internal/config"
@@ -11,14 +12,39 @@ import (
 	"github.com/igwen6w/syt-go-queue/internal/metrics"
 	"github.com/igwen6w/syt-go-queue/internal/task"
 	"go.uber.org/zap"
+	"math/rand"
 	"time"
 )
 
+// DefaultDrainTimeout 是未配置 queue.drain_timeout 时等待进行中任务完成的时间
+const DefaultDrainTimeout = 30 * time.Second
+
+// 未配置 queue.retry_base_delay 和 queue.retry_max_delay 时的重试延迟
+const (
+	DefaultRetryBaseDelay = time.Minute
+	DefaultRetryMaxDelay  = time.Hour
+)
+
+// queueInspector 是 Stats 和 Shutdown 查询队列任务数所需的 asynq.Inspector 方法
+type queueInspector interface {
+	GetQueueInfo(queue string) (*asynq.QueueInfo, error)
+	Close() error
+}
+
 // Worker 表示一个异步任务处理器，负责处理队列中的任务。
 // 它封装了 asynq 服务器和路由器，用于处理不同类型的任务。
 type Worker struct {
-	server *asynq.Server   // asynq 服务器实例
-	mux    *asynq.ServeMux // 任务路由器
+	server    *asynq.Server   // asynq 服务器实例
+	mux       *asynq.ServeMux // 任务路由器
+	handler   *TaskHandler    // 任务处理器
+	inspector queueInspector  // 队列检查器，用于关闭时统计剩余任务
+	queues    []string        // 工作者处理的队列名称
+	sweeper   *Sweeper        // 处理中记录巡检器，未配置 claim_ttl 时为 nil
+	status    *StatusReporter // 按状态统计记录数，未配置 metrics.status_tables 时为 nil
+	// 关闭时轮询活跃任务的间隔
+	drainPollInterval time.Duration
+	// 关闭时等待进行中 LLM 调用的宽限期，为 0 时不取消调用
+	gracePeriod time.Duration
 }
 
 // NewWorker 创建并返回一个新的 Worker 实例。
@@ -30,39 +56,115 @@ type Worker struct {
 //
 // 返回:
 //   - 配置好的 Worker 实例
-func NewWorker(cfg *config.Config, db *database.Database) *Worker {
+//   - Redis 连接配置无效（如 TLS 证书无法加载）时返回错误
+func NewWorker(cfg *config.Config, db *database.Database) (*Worker, error) {
 	// 记录工作者数量
 	metrics.WorkerCount.Set(float64(cfg.Queue.Concurrency))
 
+	// 按配置的单机、Sentinel 或 Cluster 模式连接 Redis
+	redisOpt, err := cfg.Redis.ConnOpt()
+	if err != nil {
+		return nil, err
+	}
+
+	// 队列及其权重，按任务类型路由的队列按权重分配并发
+	queues := cfg.Queue.Weights()
+
 	// 创建服务器配置
 	server := asynq.NewServer(
-		asynq.RedisClientOpt{
-			Addr:     cfg.Redis.Addr,
-			Password: cfg.Redis.Password,
-			DB:       cfg.Redis.DB,
-		},
+		redisOpt,
 		asynq.Config{
 			Concurrency: cfg.Queue.Concurrency,
-			RetryDelayFunc: func(n int, err error, t *asynq.Task) time.Duration {
-				return time.Duration(n) * time.Minute
-			},
+			// 关闭时等待进行中任务完成的时间，与 Shutdown 的排空时间保持一致
+			ShutdownTimeout: DrainTimeout(cfg),
+			// 指数退避加随机抖动，避免失败的任务同时重试冲击 LLM 服务
+			// 网络错误和限流分别使用较短和较长的基础延迟
+			RetryDelayFunc: ClassifiedRetryDelayFunc(cfg.Queue),
+			// 任务重试耗尽时发送死信回调
+			ErrorHandler: NewDeadLetterHandler(cfg.Callback),
 			// 添加队列大小监控
-			Queues: map[string]int{
-				"default": 10,
-			},
+			Queues: queues,
 			// 添加队列状态监控
 			// 注意：当前版本的 asynq 不支持 QueueStatsUpdater
 			// 如果需要此功能，请升级到更高版本
 		},
 	)
 
-	taskHandler := NewTaskHandler(db, cfg.Deepseek)
+	// 启用巡检时认领记录需要写入认领时间
+	var sweeper *Sweeper
+	if cfg.Queue.ClaimTTL > 0 {
+		db = db.WithClaimTime()
+		sweeper = NewSweeper(cfg.Queue, db, asynq.NewClient(redisOpt))
+	}
+
+	// 配置了需要统计的表时定期按状态统计记录数
+	var status *StatusReporter
+	if len(cfg.Metrics.StatusTables) > 0 {
+		status = NewStatusReporter(cfg.Metrics, db)
+	}
+
+	taskHandler := NewTaskHandler(db, cfg)
+	if cfg.Callback.Async {
+		taskHandler.callbackQueue = asynq.NewClient(redisOpt)
+	}
 	mux := asynq.NewServeMux()
 	mux.HandleFunc(task.TypeLLM, taskHandler.HandleLLMTask)
+	// 始终处理回调任务，关闭 callback.async 后仍能发送之前入队的回调
+	mux.HandleFunc(task.TypeCallback, taskHandler.HandleCallbackTask)
+
+	queueNames := make([]string, 0, len(queues))
+	for name := range queues {
+		queueNames = append(queueNames, name)
+	}
 
 	return &Worker{
-		server: server,
-		mux:    mux,
+		server:            server,
+		mux:               mux,
+		handler:           taskHandler,
+		inspector:         asynq.NewInspector(redisOpt),
+		queues:            queueNames,
+		sweeper:           sweeper,
+		status:            status,
+		drainPollInterval: time.Second,
+		gracePeriod:       cfg.Queue.ShutdownGracePeriod,
+	}, nil
+}
+
+// DrainTimeout 返回关闭时等待进行中任务完成的时间，未配置时使用 DefaultDrainTimeout。
+func DrainTimeout(cfg *config.Config) time.Duration {
+	if cfg.Queue.DrainTimeout > 0 {
+		return cfg.Queue.DrainTimeout
+	}
+	return DefaultDrainTimeout
+}
+
+// RetryDelayFunc 返回带随机抖动的指数退避重试延迟函数。
+// 第 n 次重试的延迟上限为 base * 2^n，不超过 max，实际延迟在上限的一半到上限之间随机取值，
+// 既保证延迟随重试次数增长，又让同时失败的任务错开重试时间。
+// base 或 max 为 0 时使用 DefaultRetryBaseDelay 和 DefaultRetryMaxDelay。
+func RetryDelayFunc(base, max time.Duration) asynq.RetryDelayFunc {
+	if base <= 0 {
+		base = DefaultRetryBaseDelay
+	}
+	if max <= 0 {
+		max = DefaultRetryMaxDelay
+	}
+	if max < base {
+		max = base
+	}
+
+	return func(n int, err error, t *asynq.Task) time.Duration {
+		// 逐次翻倍直到达到上限，避免较大的 n 导致溢出
+		delay := base
+		for i := 0; i < n && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+
+		half := delay / 2
+		return half + time.Duration(rand.Int63n(int64(delay-half)+1))
 	}
 }
 
@@ -72,15 +174,139 @@ func NewWorker(cfg *config.Config, db *database.Database) *Worker {
 // 返回:
 //   - 如果服务器启动失败，返回错误
 func (w *Worker) Run() error {
+	if w.sweeper != nil {
+		w.sweeper.Start()
+	}
+	if w.status != nil {
+		w.status.Start()
+	}
 	return w.server.Run(w.mux)
 }
 
 // Stop 优雅地停止工作者。
-// 该方法会停止接受新任务，并等待正在运行的任务完成。
+// 该方法会停止接受新任务和处理中记录的巡检，并等待正在运行的任务完成。
 func (w *Worker) Stop() {
+	if w.sweeper != nil {
+		w.sweeper.Stop()
+	}
+	if w.status != nil {
+		w.status.Stop()
+	}
 	w.server.Stop()
 }
 
+// Shutdown 停止接受新任务，并轮询工作者队列中的活跃任务数，
+// 直到全部完成或 ctx 到期。活跃任务数按队列统计，包含同一队列上其他工作者进程的任务。
+// 配置了宽限期时，宽限期结束后取消进行中的 LLM 调用，使工作者尽快退出。
+//
+// 参数:
+//   - ctx: 控制等待时间的上下文，通常带有排空超时
+//
+// 返回:
+//   - 如果 ctx 到期时仍有活跃任务，返回错误
+func (w *Worker) Shutdown(ctx context.Context) error {
+	w.Stop()
+	defer func() {
+		if err := w.inspector.Close(); err != nil {
+			logger.Warn("Failed to close queue inspector", zap.Error(err))
+		}
+		// 进行中的任务可能还会入队回调，排空结束后再关闭回调客户端
+		if w.handler != nil && w.handler.callbackQueue != nil {
+			if err := w.handler.callbackQueue.Close(); err != nil {
+				logger.Warn("Failed to close callback client", zap.Error(err))
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(w.drainPollInterval)
+	defer ticker.Stop()
+
+	var grace <-chan time.Time
+	if w.gracePeriod > 0 && w.handler != nil {
+		timer := time.NewTimer(w.gracePeriod)
+		defer timer.Stop()
+		grace = timer.C
+	}
+
+	for {
+		stats, err := w.Stats()
+		active := stats.Active
+		if err != nil {
+			logger.Warn("Failed to count active tasks", zap.Error(err))
+		} else if active == 0 {
+			// 等待中的任务留在 Redis 中，由下次启动的工作者处理
+			logger.Info("All in-flight tasks drained", stats.LogFields()...)
+			return nil
+		} else {
+			fields := stats.LogFields()
+			if deadline, ok := ctx.Deadline(); ok {
+				fields = append(fields, zap.Duration("remaining_time", time.Until(deadline).Round(time.Second)))
+			}
+			logger.Info("Waiting for in-flight tasks to finish", fields...)
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Warn("Drain stopped before in-flight tasks finished", stats.LogFields()...)
+			return fmt.Errorf("drain stopped with %d tasks still active: %w", active, ctx.Err())
+		case <-grace:
+			grace = nil
+			cancelled := w.handler.CancelInFlight()
+			logger.Warn("Shutdown grace period elapsed, cancelling in-flight LLM calls",
+				zap.Duration("grace_period", w.gracePeriod),
+				zap.Int("force_cancelled", cancelled))
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stats 工作者所有队列的任务数统计，按队列统计，包含同一队列上其他工作者进程的任务
+type Stats struct {
+	Active    int // 正在处理的任务数
+	Pending   int // 等待处理的任务数
+	Scheduled int // 等待计划时间到达的任务数
+	Retry     int // 等待重试的任务数
+}
+
+// LogFields 返回用于日志的统计字段
+func (s Stats) LogFields() []zap.Field {
+	return []zap.Field{
+		zap.Int("active", s.Active),
+		zap.Int("pending", s.Pending),
+		zap.Int("scheduled", s.Scheduled),
+		zap.Int("retry", s.Retry),
+	}
+}
+
+// Stats 返回工作者所有队列中各状态的任务总数，用于关闭时记录排空进度。
+// Shutdown 返回后队列检查器已关闭，不能再调用
+func (w *Worker) Stats() (Stats, error) {
+	var stats Stats
+	for _, queue := range w.queues {
+		info, err := w.inspector.GetQueueInfo(queue)
+		if err != nil {
+			return Stats{}, fmt.Errorf("get queue info for %s: %w", queue, err)
+		}
+		stats.Active += info.Active
+		stats.Pending += info.Pending
+		stats.Scheduled += info.Scheduled
+		stats.Retry += info.Retry
+	}
+	return stats, nil
+}
+
+// ApplyConfig 应用热加载的配置。
+// 目前仅支持调整断路器的参数（max_requests、interval、timeout、fail_threshold），
+// 其他字段需要重启才能生效。
+//
+// 参数:
+//   - cfg: 重新加载并通过校验的配置
+func (w *Worker) ApplyConfig(cfg *config.Config) {
+	if cfg.Deepseek.CircuitBreaker.Enabled {
+		w.handler.circuitBreaker.Reconfigure(llmCircuitBreakerConfig(cfg.Deepseek.CircuitBreaker))
+	}
+}
+
 // ValidateWorkerConfig 验证工作者配置是否有效。
 // 它检查并确保并发数、重试次数和保留时间等参数符合要求。
 //
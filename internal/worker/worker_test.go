@@ -1,6 +1,9 @@
 package worker
 
 import (
+	"context"
+	"fmt"
+	"github.com/hibiken/asynq"
 	"github.com/igwen6w/syt-go-queue/internal/config"
 	"github.com/igwen6w/syt-go-queue/internal/database"
 	"github.com/jmoiron/sqlx"
@@ -31,7 +34,10 @@ func TestNewWorker(t *testing.T) {
 	defer db.Close()
 
 	// 创建 worker
-	worker := NewWorker(testConfig, newDatabase)
+	worker, err := NewWorker(testConfig, newDatabase)
+	if err != nil {
+		t.Fatalf("NewWorker returned error: %v", err)
+	}
 	if worker == nil {
 		t.Error("NewWorker returned nil")
 	}
@@ -54,7 +60,10 @@ func TestWorker_RunAndStop(t *testing.T) {
 	defer db.Close()
 
 	// 创建 worker
-	worker := NewWorker(testConfig, newDatabase)
+	worker, err := NewWorker(testConfig, newDatabase)
+	if err != nil {
+		t.Fatalf("NewWorker returned error: %v", err)
+	}
 
 	// 创建一个通道来捕获错误
 	errCh := make(chan error, 1)
@@ -165,3 +174,178 @@ func TestWorkerConfigValidation(t *testing.T) {
 		})
 	}
 }
+
+// fakeInspector 按顺序返回预设的活跃任务数
+type fakeInspector struct {
+	active []int
+	calls  int
+	closed bool
+}
+
+func (f *fakeInspector) GetQueueInfo(queue string) (*asynq.QueueInfo, error) {
+	n := f.active[len(f.active)-1]
+	if f.calls < len(f.active) {
+		n = f.active[f.calls]
+	}
+	f.calls++
+	return &asynq.QueueInfo{Queue: queue, Active: n}, nil
+}
+
+func (f *fakeInspector) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestWorker_Shutdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		active  []int
+		timeout time.Duration
+		wantErr bool
+	}{
+		{
+			name:    "drains before deadline",
+			active:  []int{2, 1, 0},
+			timeout: time.Second,
+			wantErr: false,
+		},
+		{
+			name:    "deadline exceeded",
+			active:  []int{1},
+			timeout: 50 * time.Millisecond,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inspector := &fakeInspector{active: tt.active}
+			w := &Worker{
+				server:            asynq.NewServer(asynq.RedisClientOpt{Addr: "127.0.0.1:1"}, asynq.Config{}),
+				inspector:         inspector,
+				queues:            []string{"default"},
+				drainPollInterval: 10 * time.Millisecond,
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+
+			err := w.Shutdown(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Shutdown() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && inspector.calls != len(tt.active) {
+				t.Errorf("Expected %d polls, got %d", len(tt.active), inspector.calls)
+			}
+			if !inspector.closed {
+				t.Error("Expected inspector to be closed")
+			}
+		})
+	}
+}
+
+func TestWorker_Stats(t *testing.T) {
+	w := &Worker{
+		inspector: &statsInspector{infos: map[string]*asynq.QueueInfo{
+			"default":  {Active: 2, Pending: 5, Scheduled: 1, Retry: 3},
+			"critical": {Active: 1, Pending: 4},
+		}},
+		queues: []string{"default", "critical"},
+	}
+
+	stats, err := w.Stats()
+	if err != nil {
+		t.Fatalf("Stats() returned error: %v", err)
+	}
+	want := Stats{Active: 3, Pending: 9, Scheduled: 1, Retry: 3}
+	if stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+
+	// 任一队列查询失败时返回错误
+	w.queues = append(w.queues, "missing")
+	if _, err := w.Stats(); err == nil {
+		t.Error("Expected error for unknown queue")
+	}
+}
+
+// statsInspector 按队列返回预设的队列信息
+type statsInspector struct {
+	infos map[string]*asynq.QueueInfo
+}
+
+func (s *statsInspector) GetQueueInfo(queue string) (*asynq.QueueInfo, error) {
+	info, ok := s.infos[queue]
+	if !ok {
+		return nil, fmt.Errorf("queue %s not found", queue)
+	}
+	return info, nil
+}
+
+func (s *statsInspector) Close() error {
+	return nil
+}
+
+func TestWorker_Shutdown_GracePeriod(t *testing.T) {
+	handler := NewTaskHandler(nil, testConfig)
+	llmCtx, stop := handler.llmContext(context.Background())
+	defer stop()
+
+	w := &Worker{
+		server:            asynq.NewServer(asynq.RedisClientOpt{Addr: "127.0.0.1:1"}, asynq.Config{}),
+		handler:           handler,
+		inspector:         &fakeInspector{active: []int{1}},
+		queues:            []string{"default"},
+		drainPollInterval: 10 * time.Millisecond,
+		gracePeriod:       20 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	if err := w.Shutdown(ctx); err == nil {
+		t.Error("Expected Shutdown to report the task still active")
+	}
+
+	// 宽限期结束后进行中的 LLM 调用应被取消，之后开始的调用也会立即取消
+	if llmCtx.Err() != context.Canceled {
+		t.Errorf("Expected in-flight LLM context to be cancelled, got %v", llmCtx.Err())
+	}
+	lateCtx, lateStop := handler.llmContext(context.Background())
+	defer lateStop()
+	if lateCtx.Err() == nil {
+		t.Error("Expected LLM context created after cancellation to be cancelled")
+	}
+}
+
+func TestRetryDelayFunc(t *testing.T) {
+	base := time.Second
+	max := 30 * time.Second
+	delayFunc := RetryDelayFunc(base, max)
+
+	// 每次重试的延迟在上限的一半到上限之间，上限随重试次数翻倍直到 max
+	upper := base
+	for n := 0; n < 10; n++ {
+		for i := 0; i < 100; i++ {
+			delay := delayFunc(n, nil, nil)
+			if delay < upper/2 || delay > upper {
+				t.Fatalf("retry %d: delay %v out of bounds [%v, %v]", n, delay, upper/2, upper)
+			}
+		}
+		upper *= 2
+		if upper > max {
+			upper = max
+		}
+	}
+
+	// 重试次数很大时不溢出，仍不超过上限
+	if delay := delayFunc(1000, nil, nil); delay < max/2 || delay > max {
+		t.Errorf("Expected delay within [%v, %v] for large n, got %v", max/2, max, delay)
+	}
+
+	// 未配置时使用默认值
+	delay := RetryDelayFunc(0, 0)(0, nil, nil)
+	if delay < DefaultRetryBaseDelay/2 || delay > DefaultRetryBaseDelay {
+		t.Errorf("Expected default delay within [%v, %v], got %v", DefaultRetryBaseDelay/2, DefaultRetryBaseDelay, delay)
+	}
+}